@@ -0,0 +1,116 @@
+// Package auth finds GitHub tokens. Each provider knows one place a token
+// can live, a Resolver asks them in the configured order.
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arjungandhi/atp/config"
+)
+
+// DefaultHost is the host used when none is given
+const DefaultHost = config.DefaultGitHubHost
+
+// Provider names, as used in the auth.order config
+const (
+	SourceConfig  = "config"
+	SourceEnv     = "env"
+	SourceNetrc   = "netrc"
+	SourceGH      = "gh"
+	SourceGit     = "git"
+	SourceKeyring = "keyring"
+)
+
+// DefaultOrder is the resolution order used when the config doesn't set one.
+// Per-organization tokens from the config come first so they can't be
+// shadowed by a general purpose token.
+var DefaultOrder = []string{SourceConfig, SourceEnv, SourceNetrc, SourceGH, SourceGit, SourceKeyring}
+
+// Credential is a token and the provider it came from
+type Credential struct {
+	Token  string
+	Source string
+}
+
+// Provider looks up a token for an organization (or user) on a host. It
+// returns an empty token and no error when it simply has none.
+type Provider interface {
+	Name() string
+	Token(host string, org string) (string, error)
+}
+
+// Resolver asks providers for a token in order until one has it
+type Resolver struct {
+	providers []Provider
+}
+
+// NewResolver builds a resolver from the auth config
+func NewResolver(cfg config.AuthConfig) (*Resolver, error) {
+	order := cfg.Order
+	if len(order) == 0 {
+		order = DefaultOrder
+	}
+
+	var providers []Provider
+	for _, name := range order {
+		switch strings.ToLower(name) {
+		case SourceConfig:
+			providers = append(providers, &configProvider{tokens: cfg.Tokens})
+		case SourceEnv:
+			providers = append(providers, &envProvider{})
+		case SourceNetrc:
+			providers = append(providers, &netrcProvider{})
+		case SourceGH:
+			providers = append(providers, &ghProvider{})
+		case SourceGit:
+			providers = append(providers, &gitProvider{})
+		case SourceKeyring:
+			providers = append(providers, &commandProvider{command: cfg.KeyringCommand})
+		default:
+			return nil, fmt.Errorf("unknown credential provider '%s' (expected one of %s)", name, strings.Join(DefaultOrder, ", "))
+		}
+	}
+
+	return &Resolver{providers: providers}, nil
+}
+
+// Providers returns the providers in resolution order
+func (r *Resolver) Providers() []Provider {
+	return r.providers
+}
+
+// Resolve returns the first token found for the organization on the host.
+// An empty org asks for the user's general token.
+func (r *Resolver) Resolve(host string, org string) (*Credential, error) {
+	if host == "" {
+		host = DefaultHost
+	}
+
+	var tried []string
+	var errs []string
+	for _, provider := range r.providers {
+		token, err := provider.Token(host, org)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+		if token != "" {
+			return &Credential{Token: token, Source: provider.Name()}, nil
+		}
+		tried = append(tried, provider.Name())
+	}
+
+	msg := fmt.Sprintf("no GitHub token found for %s", host)
+	if org != "" {
+		msg = fmt.Sprintf("no GitHub token found for %s on %s", org, host)
+	}
+	if len(tried) > 0 {
+		msg += fmt.Sprintf(" (tried %s)", strings.Join(tried, ", "))
+	}
+	if len(errs) > 0 {
+		msg += ": " + strings.Join(errs, "; ")
+	}
+
+	return nil, fmt.Errorf("%s", msg)
+}
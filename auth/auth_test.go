@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arjungandhi/atp/config"
+)
+
+// isolate points every provider at an empty environment
+func isolate(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GH_CONFIG_DIR", filepath.Join(home, "gh"))
+	return home
+}
+
+func TestResolverOrder(t *testing.T) {
+	home := isolate(t)
+	t.Setenv("GITHUB_TOKEN", "env-token")
+	os.WriteFile(filepath.Join(home, ".netrc"), []byte("machine github.com login me password netrc-token\n"), 0600)
+
+	cfg := config.AuthConfig{Tokens: map[string]string{"Acme": "acme-token"}}
+
+	tests := []struct {
+		name   string
+		order  []string
+		org    string
+		token  string
+		source string
+	}{
+		{"org token first by default", nil, "acme", "acme-token", SourceConfig},
+		{"env for other orgs", nil, "other", "env-token", SourceEnv},
+		{"env for the default token", nil, "", "env-token", SourceEnv},
+		{"custom order", []string{"netrc", "config", "env"}, "acme", "netrc-token", SourceNetrc},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg.Order = test.order
+			resolver, err := NewResolver(cfg)
+			if err != nil {
+				t.Fatalf("NewResolver() error = %v", err)
+			}
+
+			credential, err := resolver.Resolve("", test.org)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if credential.Token != test.token || credential.Source != test.source {
+				t.Errorf("Resolve() = %+v, want %s from %s", credential, test.token, test.source)
+			}
+		})
+	}
+}
+
+func TestResolverNoToken(t *testing.T) {
+	isolate(t)
+
+	resolver, err := NewResolver(config.AuthConfig{Order: []string{"env", "netrc"}})
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	_, err = resolver.Resolve("", "acme")
+	if err == nil || !strings.Contains(err.Error(), "tried env, netrc") {
+		t.Errorf("Resolve() error = %v, want providers listed", err)
+	}
+}
+
+func TestNewResolverUnknownProvider(t *testing.T) {
+	if _, err := NewResolver(config.AuthConfig{Order: []string{"env", "vault"}}); err == nil {
+		t.Error("NewResolver() error = nil, want unknown provider error")
+	}
+}
+
+func TestParseGHHosts(t *testing.T) {
+	hosts := `github.com:
+    users:
+        octocat:
+            oauth_token: nested-token
+    user: octocat
+    oauth_token: gho_active
+    git_protocol: https
+ghe.example.com:
+    oauth_token: "ghe-token"
+`
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"github.com", "gho_active"},
+		{"ghe.example.com", "ghe-token"},
+		{"other.example.com", ""},
+	}
+
+	for _, test := range tests {
+		if got := parseGHHosts([]byte(hosts), test.host); got != test.want {
+			t.Errorf("parseGHHosts(%q) = %q, want %q", test.host, got, test.want)
+		}
+	}
+}
+
+func TestCommandProvider(t *testing.T) {
+	isolate(t)
+
+	resolver, err := NewResolver(config.AuthConfig{
+		Order:          []string{"keyring"},
+		KeyringCommand: "echo token-for-{org}-on-$ATP_GITHUB_HOST",
+	})
+	if err != nil {
+		t.Fatalf("NewResolver() error = %v", err)
+	}
+
+	credential, err := resolver.Resolve("", "acme")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if credential.Token != "token-for-acme-on-github.com" || credential.Source != SourceKeyring {
+		t.Errorf("Resolve() = %+v", credential)
+	}
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bgentry/go-netrc/netrc"
+)
+
+// ------------------------------- Config -------------------------------
+
+// configProvider hands out the per-organization tokens from config.toml
+type configProvider struct {
+	tokens map[string]string
+}
+
+func (p *configProvider) Name() string { return SourceConfig }
+
+func (p *configProvider) Token(host string, org string) (string, error) {
+	if org == "" {
+		return "", nil
+	}
+	for name, token := range p.tokens {
+		if strings.EqualFold(name, org) {
+			return token, nil
+		}
+	}
+	return "", nil
+}
+
+// ------------------------------- Environment -------------------------------
+
+// envProvider reads the same variables as the gh CLI
+type envProvider struct{}
+
+func (p *envProvider) Name() string { return SourceEnv }
+
+func (p *envProvider) Token(host string, org string) (string, error) {
+	vars := []string{"GITHUB_TOKEN", "GH_TOKEN"}
+	if host != DefaultHost {
+		vars = []string{"GITHUB_ENTERPRISE_TOKEN", "GH_ENTERPRISE_TOKEN"}
+	}
+
+	for _, name := range vars {
+		if token := os.Getenv(name); token != "" {
+			return token, nil
+		}
+	}
+	return "", nil
+}
+
+// ------------------------------- Netrc -------------------------------
+
+type netrcProvider struct{}
+
+func (p *netrcProvider) Name() string { return SourceNetrc }
+
+func (p *netrcProvider) Token(host string, org string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %w", err)
+	}
+
+	netrcPath := filepath.Join(homeDir, ".netrc")
+	if _, err := os.Stat(netrcPath); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	n, err := netrc.ParseFile(netrcPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse .netrc file: %w", err)
+	}
+
+	// API hosts are what curl and friends would look up, the default
+	// machine is only used when neither is listed
+	for _, machineName := range []string{host, "api." + host} {
+		machine := n.FindMachine(machineName)
+		if machine != nil && !machine.IsDefault() && machine.Password != "" {
+			return machine.Password, nil
+		}
+	}
+
+	if machine := n.FindMachine(host); machine != nil {
+		return machine.Password, nil
+	}
+
+	return "", nil
+}
+
+// ------------------------------- gh CLI -------------------------------
+
+// ghProvider reads the token the gh CLI stored in its hosts.yml
+type ghProvider struct{}
+
+func (p *ghProvider) Name() string { return SourceGH }
+
+func (p *ghProvider) Token(host string, org string) (string, error) {
+	data, err := os.ReadFile(ghHostsPath())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read gh hosts file: %w", err)
+	}
+
+	return parseGHHosts(data, host), nil
+}
+
+// ghHostsPath follows the gh CLI's config directory lookup
+func ghHostsPath() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "gh", "hosts.yml")
+}
+
+// parseGHHosts finds the active oauth_token of a host in gh's hosts.yml:
+//
+//	github.com:
+//	    user: octocat
+//	    oauth_token: gho_xxx
+//
+// Nested per-user entries are ignored, the host level token is the active one.
+// Newer gh versions keep the token in the system keyring instead, in which
+// case there is nothing to find here.
+func parseGHHosts(data []byte, host string) string {
+	inHost := false
+	hostIndent := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		if indent == 0 {
+			inHost = strings.TrimSuffix(trimmed, ":") == host
+			hostIndent = -1
+			continue
+		}
+		if !inHost {
+			continue
+		}
+
+		// the first key sets the indentation of the host's own keys
+		if hostIndent < 0 {
+			hostIndent = indent
+		}
+		if indent != hostIndent {
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if ok && key == "oauth_token" {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+
+	return ""
+}
+
+// ------------------------------- git credential -------------------------------
+
+// gitProvider asks the configured git credential helpers
+type gitProvider struct{}
+
+func (p *gitProvider) Name() string { return SourceGit }
+
+func (p *gitProvider) Token(host string, org string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", nil
+	}
+
+	input := fmt.Sprintf("protocol=https\nhost=%s\n", host)
+	if org != "" {
+		// only used by helpers with credential.useHttpPath set
+		input += fmt.Sprintf("path=%s\n", org)
+	}
+
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input + "\n")
+	// never fall back to prompting on the terminal
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+
+	output, err := cmd.Output()
+	if err != nil {
+		// git exits non-zero when no helper has a credential
+		return "", nil
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if value, ok := strings.CutPrefix(line, "password="); ok {
+			return value, nil
+		}
+	}
+
+	return "", nil
+}
+
+// ------------------------------- Keyring -------------------------------
+
+// commandProvider runs a user supplied command that prints a token, e.g. a
+// keyring lookup. {host} and {org} are replaced before running it.
+type commandProvider struct {
+	command string
+}
+
+func (p *commandProvider) Name() string { return SourceKeyring }
+
+func (p *commandProvider) Token(host string, org string) (string, error) {
+	if p.command == "" {
+		return "", nil
+	}
+
+	command := strings.NewReplacer("{host}", host, "{org}", org).Replace(p.command)
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), "ATP_GITHUB_HOST="+host, "ATP_GITHUB_ORG="+org)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keyring command failed: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,379 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// fakeServer is a minimal CalDAV calendar at /cal/ that keeps objects in
+// memory, like a local Radicale would
+type fakeServer struct {
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	version int
+}
+
+type fakeObject struct {
+	data string
+	etag string
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "REPORT":
+		var paths []string
+		for path := range f.objects {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+		for _, path := range paths {
+			o := f.objects[path]
+			fmt.Fprintf(w, `<d:response><d:href>%s</d:href><d:propstat><d:prop><d:getetag>%s</d:getetag><c:calendar-data>%s</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+				path, html.EscapeString(o.etag), html.EscapeString(o.data))
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+
+	case http.MethodPut:
+		existing := f.objects[r.URL.Path]
+		if match := r.Header.Get("If-Match"); match != "" && (existing == nil || existing.etag != match) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && existing != nil {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		etag := f.store(r.URL.Path, string(body))
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodDelete:
+		existing := f.objects[r.URL.Path]
+		if existing == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && existing.etag != match {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeServer) store(path string, data string) string {
+	f.version++
+	etag := fmt.Sprintf(`"%d"`, f.version)
+	f.objects[path] = &fakeObject{data: data, etag: etag}
+	return etag
+}
+
+// edit changes an object like a calendar app would
+func (f *fakeServer) edit(t *testing.T, uid string, replace ...string) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for path, o := range f.objects {
+		if strings.Contains(o.data, "UID:"+uid+"\r\n") {
+			f.store(path, strings.NewReplacer(replace...).Replace(o.data))
+			return
+		}
+	}
+	t.Fatalf("no object with uid %s", uid)
+}
+
+func (f *fakeServer) add(uid string, props string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//phone//EN\r\nBEGIN:VTODO\r\nUID:" + uid + "\r\n" + props + "END:VTODO\r\nEND:VCALENDAR\r\n"
+	f.store("/cal/"+uid+".ics", data)
+}
+
+func (f *fakeServer) summaries() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var summaries []string
+	for _, o := range f.objects {
+		for _, line := range strings.Split(o.data, "\r\n") {
+			if summary, ok := strings.CutPrefix(line, "SUMMARY:"); ok {
+				summaries = append(summaries, summary)
+			}
+		}
+	}
+	sort.Strings(summaries)
+	return strings.Join(summaries, ",")
+}
+
+type testEnv struct {
+	server  *fakeServer
+	client  *Client
+	atpDir  string
+	todoDir string
+}
+
+func newTestEnv(t *testing.T, todoLines string) *testEnv {
+	t.Helper()
+	server := &fakeServer{objects: make(map[string]*fakeObject)}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	client, err := NewClient(config.CalDAVConfig{URL: ts.URL + "/cal", Username: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atpDir := t.TempDir()
+	todoDir := filepath.Join(atpDir, "todo")
+	os.MkdirAll(todoDir, 0755)
+	os.WriteFile(todo.ActiveTodoPath(todoDir), []byte(todoLines), 0644)
+	os.WriteFile(todo.DoneTodoPath(todoDir), nil, 0644)
+	os.WriteFile(todo.ReminderTasksPath(todoDir), nil, 0644)
+
+	return &testEnv{server: server, client: client, atpDir: atpDir, todoDir: todoDir}
+}
+
+func (e *testEnv) sync(t *testing.T, opts Options) []string {
+	t.Helper()
+	opts.Now = time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	report, err := Sync(context.Background(), e.client, e.todoDir, e.atpDir, opts)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	var changes []string
+	for _, c := range report.Changes {
+		changes = append(changes, c.String())
+	}
+	return changes
+}
+
+func (e *testEnv) todos(t *testing.T) []*todo.Todo {
+	t.Helper()
+	todos, err := todo.LoadTodoDir(e.todoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return todos
+}
+
+func (e *testEnv) find(t *testing.T, description string) *todo.Todo {
+	t.Helper()
+	for _, td := range e.todos(t) {
+		if td.Description == description {
+			return td
+		}
+	}
+	t.Fatalf("no todo '%s' in %v", description, e.todos(t))
+	return nil
+}
+
+func (e *testEnv) rewrite(t *testing.T, fn func(todos []*todo.Todo) []*todo.Todo) {
+	t.Helper()
+	if err := todo.WriteTodoDir(e.todoDir, fn(e.todos(t))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectChanges(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSyncBothWays(t *testing.T) {
+	env := newTestEnv(t, "(A) Pay rent +home due:2025-03-15\nNo date todo\n")
+
+	// dated todos are pushed and get a uid
+	expectChanges(t, env.sync(t, Options{}), "create remote: Pay rent")
+	rent := env.find(t, "Pay rent")
+	uid := rent.Labels["uid"]
+	if uid == "" {
+		t.Fatal("pushed todo has no uid: label")
+	}
+	if got := env.server.summaries(); got != "Pay rent" {
+		t.Errorf("server has %s", got)
+	}
+
+	// nothing changed
+	expectChanges(t, env.sync(t, Options{}))
+
+	// completed in the calendar app
+	env.server.edit(t, uid, "STATUS:NEEDS-ACTION", "STATUS:COMPLETED\r\nCOMPLETED:20250314T100000Z", "PRIORITY:1", "PRIORITY:2")
+	expectChanges(t, env.sync(t, Options{}), "update local: Pay rent")
+	rent = env.find(t, "Pay rent")
+	if !rent.Done || rent.CompletionDate.Format("2006-01-02") != "2025-03-14" || rent.Priority != "B" {
+		t.Errorf("pulled todo = %s", rent.String())
+	}
+
+	// renamed locally, other labels don't count as changes
+	env.rewrite(t, func(todos []*todo.Todo) []*todo.Todo {
+		for _, td := range todos {
+			if td.Labels["uid"] == uid {
+				td.Description = "Pay March rent"
+			}
+		}
+		return todos
+	})
+	expectChanges(t, env.sync(t, Options{}), "update remote: Pay March rent")
+	if got := env.server.summaries(); got != "Pay March rent" {
+		t.Errorf("server has %s", got)
+	}
+
+	// added in the calendar app
+	env.server.add("phone-1", "SUMMARY:Buy flowers\r\nDUE;VALUE=DATE:20250320\r\nCATEGORIES:@town\r\n")
+	expectChanges(t, env.sync(t, Options{}), "create local: Buy flowers")
+	flowers := env.find(t, "Buy flowers")
+	if flowers.String() != "Buy flowers @town due:2025-03-20 uid:phone-1" {
+		t.Errorf("new todo = %s", flowers.String())
+	}
+
+	// deleted locally
+	env.rewrite(t, func(todos []*todo.Todo) []*todo.Todo {
+		var kept []*todo.Todo
+		for _, td := range todos {
+			if td.Description != "Buy flowers" {
+				kept = append(kept, td)
+			}
+		}
+		return kept
+	})
+	expectChanges(t, env.sync(t, Options{}), "delete remote: Buy flowers")
+	if got := env.server.summaries(); got != "Pay March rent" {
+		t.Errorf("server has %s", got)
+	}
+}
+
+func TestSyncRemoteDelete(t *testing.T) {
+	env := newTestEnv(t, "Call mom remind:2025-03-20\n")
+	env.sync(t, Options{})
+
+	env.server.mu.Lock()
+	env.server.objects = make(map[string]*fakeObject)
+	env.server.mu.Unlock()
+
+	expectChanges(t, env.sync(t, Options{}), "delete local: Call mom")
+	if len(env.todos(t)) != 0 {
+		t.Errorf("todos = %v", env.todos(t))
+	}
+}
+
+func TestSyncConflict(t *testing.T) {
+	for _, prefer := range []string{config.PreferLocal, config.PreferRemote} {
+		env := newTestEnv(t, "Write report due:2025-03-15\n")
+		env.sync(t, Options{})
+		uid := env.find(t, "Write report").Labels["uid"]
+
+		env.server.edit(t, uid, "DUE;VALUE=DATE:20250315", "DUE;VALUE=DATE:20250318")
+		env.rewrite(t, func(todos []*todo.Todo) []*todo.Todo {
+			todos[0].Labels["due"] = "2025-03-16"
+			return todos
+		})
+
+		changes := env.sync(t, Options{Prefer: prefer})
+		due := "2025-03-16"
+		want := "update remote: Write report (changed on both sides)"
+		if prefer == config.PreferRemote {
+			due = "2025-03-18"
+			want = "update local: Write report (changed on both sides)"
+		}
+		expectChanges(t, changes, want)
+		if got := env.find(t, "Write report").Labels["due"]; got != due {
+			t.Errorf("prefer %s: due = %s, want %s", prefer, got, due)
+		}
+		expectChanges(t, env.sync(t, Options{}))
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	env := newTestEnv(t, "Pay rent due:2025-03-15\n")
+	expectChanges(t, env.sync(t, Options{DryRun: true}), "create remote: Pay rent")
+	if env.server.summaries() != "" {
+		t.Error("dry run wrote to the server")
+	}
+	if _, ok := env.find(t, "Pay rent").Labels["uid"]; ok {
+		t.Error("dry run wrote todos")
+	}
+}
+
+func TestSyncLockExcludesRequests(t *testing.T) {
+	env := newTestEnv(t, "Pay rent due:2025-03-15\n")
+
+	// the calendar must not be written while the todos are locked
+	locks := 0
+	lock := func(fn func() error) error {
+		locks++
+		env.server.mu.Lock()
+		before := env.server.version
+		env.server.mu.Unlock()
+		err := fn()
+		env.server.mu.Lock()
+		defer env.server.mu.Unlock()
+		if env.server.version != before {
+			t.Error("calendar was written while locked")
+		}
+		return err
+	}
+
+	expectChanges(t, env.sync(t, Options{Lock: lock}), "create remote: Pay rent")
+	if locks != 2 {
+		t.Errorf("locked %d times, want for writing the todos and the state", locks)
+	}
+	if env.server.summaries() != "Pay rent" {
+		t.Errorf("server has %q", env.server.summaries())
+	}
+	expectChanges(t, env.sync(t, Options{}))
+}
+
+func TestSyncKeepsUnknownProperties(t *testing.T) {
+	env := newTestEnv(t, "Pay rent due:2025-03-15\n")
+	env.sync(t, Options{})
+	uid := env.find(t, "Pay rent").Labels["uid"]
+
+	env.server.edit(t, uid, "END:VTODO", "X-APPLE-SORT-ORDER:5\r\nBEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT1H\r\nEND:VALARM\r\nEND:VTODO")
+	env.sync(t, Options{})
+	env.rewrite(t, func(todos []*todo.Todo) []*todo.Todo {
+		todos[0].Priority = "A"
+		return todos
+	})
+	expectChanges(t, env.sync(t, Options{}), "update remote: Pay rent")
+
+	env.server.mu.Lock()
+	defer env.server.mu.Unlock()
+	for _, o := range env.server.objects {
+		for _, want := range []string{"X-APPLE-SORT-ORDER:5", "BEGIN:VALARM", "PRIORITY:1"} {
+			if !strings.Contains(o.data, want) {
+				t.Errorf("object is missing %s:\n%s", want, o.data)
+			}
+		}
+	}
+}
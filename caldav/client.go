@@ -0,0 +1,267 @@
+// Package caldav syncs todos with due: or remind: dates with the VTODOs of
+// a CalDAV calendar.
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/ical"
+	"github.com/bgentry/go-netrc/netrc"
+)
+
+// PasswordEnv holds the CalDAV password when the config has none
+const PasswordEnv = "ATP_CALDAV_PASSWORD"
+
+// ErrChanged is returned when a write is rejected because the object
+// changed on the server since it was listed
+var ErrChanged = errors.New("calendar object changed on the server")
+
+// Client talks to one calendar collection
+type Client struct {
+	URL      *url.URL
+	Username string
+	Password string
+	HTTP     *http.Client
+}
+
+// Object is a calendar resource holding one VTODO
+type Object struct {
+	Href     string
+	ETag     string
+	Calendar *ical.Component
+}
+
+// VTodo returns the object's VTODO, the master one when it recurs
+func (o *Object) VTodo() *ical.Component {
+	var first *ical.Component
+	for _, c := range o.Calendar.Components {
+		if c.Name != "VTODO" {
+			continue
+		}
+		if c.Get("RECURRENCE-ID") == nil {
+			return c
+		}
+		if first == nil {
+			first = c
+		}
+	}
+	return first
+}
+
+// NewClient creates a client for the configured calendar, the password is
+// looked up in the config, $ATP_CALDAV_PASSWORD and ~/.netrc
+func NewClient(cfg config.CalDAVConfig) (*Client, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("caldav: invalid url '%s'", cfg.URL)
+	}
+	// the collection is a directory, resource hrefs resolve inside it
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	c := &Client{
+		URL:      u,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		HTTP:     &http.Client{Timeout: 30 * time.Second},
+	}
+	if c.Password == "" {
+		c.Password = os.Getenv(PasswordEnv)
+	}
+	if c.Password == "" {
+		login, password := netrcLogin(u.Hostname())
+		if c.Username == "" {
+			c.Username = login
+		}
+		c.Password = password
+	}
+	return c, nil
+}
+
+func netrcLogin(host string) (string, string) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", ""
+	}
+	n, err := netrc.ParseFile(filepath.Join(home, ".netrc"))
+	if err != nil {
+		return "", ""
+	}
+	m := n.FindMachine(host)
+	if m == nil || m.IsDefault() {
+		return "", ""
+	}
+	return m.Login, m.Password
+}
+
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop>
+    <d:getetag/>
+    <c:calendar-data/>
+  </d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VTODO"/>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ETag         string `xml:"DAV: getetag"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// List fetches every VTODO in the calendar
+func (c *Client) List(ctx context.Context) ([]*Object, error) {
+	resp, err := c.do(ctx, "REPORT", c.URL.String(), strings.NewReader(calendarQuery), map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError("list calendar", resp)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to read calendar listing: %w", err)
+	}
+
+	var objects []*Object
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstats {
+			if !strings.Contains(ps.Status, " 200 ") || ps.Prop.CalendarData == "" {
+				continue
+			}
+			cal, err := ical.Decode(strings.NewReader(ps.Prop.CalendarData))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", r.Href, err)
+			}
+			o := &Object{Href: c.resolve(r.Href), ETag: ps.Prop.ETag, Calendar: cal}
+			if o.VTodo() != nil {
+				objects = append(objects, o)
+			}
+		}
+	}
+	return objects, nil
+}
+
+// Put writes a calendar object. With an etag the write only succeeds if
+// the object is unchanged, without one only if it doesn't exist yet. It
+// returns the new etag, empty if the server didn't send one.
+func (c *Client) Put(ctx context.Context, href string, cal *ical.Component, etag string) (string, error) {
+	var body bytes.Buffer
+	if err := ical.Encode(&body, cal); err != nil {
+		return "", err
+	}
+
+	headers := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+	if etag != "" {
+		headers["If-Match"] = etag
+	} else {
+		headers["If-None-Match"] = "*"
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, href, &body, headers)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return resp.Header.Get("ETag"), nil
+	case http.StatusPreconditionFailed:
+		return "", ErrChanged
+	default:
+		return "", statusError("write "+href, resp)
+	}
+}
+
+// Delete removes a calendar object if it still has the etag
+func (c *Client) Delete(ctx context.Context, href string, etag string) error {
+	headers := map[string]string{}
+	if etag != "" {
+		headers["If-Match"] = etag
+	}
+	resp, err := c.do(ctx, http.MethodDelete, href, nil, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	case http.StatusPreconditionFailed:
+		return ErrChanged
+	default:
+		return statusError("delete "+href, resp)
+	}
+}
+
+// Href is where a new object with the uid is stored
+func (c *Client) Href(uid string) string {
+	return c.URL.ResolveReference(&url.URL{Path: url.PathEscape(uid) + ".ics"}).String()
+}
+
+func (c *Client) resolve(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return c.URL.ResolveReference(u).String()
+}
+
+func (c *Client) do(ctx context.Context, method string, target string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach calendar: %w", err)
+	}
+	return resp, nil
+}
+
+func statusError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	msg := strings.TrimSpace(string(body))
+	if msg != "" {
+		return fmt.Errorf("failed to %s: %s: %s", action, resp.Status, msg)
+	}
+	return fmt.Errorf("failed to %s: %s", action, resp.Status)
+}
@@ -0,0 +1,449 @@
+package caldav
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/ical"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// StateFile records what each synced todo looked like after the last sync
+const StateFile = "caldav_state.json"
+
+// entry is the last synced version of one calendar object
+type entry struct {
+	Href string `json:"href"`
+	ETag string `json:"etag"`
+	// Hash of the todo's calendar fields, a different hash means the todo
+	// changed locally
+	Hash string `json:"hash"`
+}
+
+// State maps calendar UIDs to their last synced version
+type State struct {
+	path  string
+	Items map[string]*entry `json:"items"`
+}
+
+// LoadState reads the sync state, a missing file is an empty state
+func LoadState(atpDir string) (*State, error) {
+	s := &State{path: filepath.Join(atpDir, StateFile), Items: make(map[string]*entry)}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read caldav state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse caldav state: %w", err)
+	}
+	if s.Items == nil {
+		s.Items = make(map[string]*entry)
+	}
+	return s, nil
+}
+
+// Save writes the state atomically
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode caldav state: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write caldav state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write caldav state: %w", err)
+	}
+	return nil
+}
+
+// Where a change was made
+const (
+	Local  = "local"
+	Remote = "remote"
+)
+
+// What a change did
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change is one todo created, updated or deleted on one side
+type Change struct {
+	Side   string
+	Action string
+	Todo   string
+	// Conflict is set when the todo changed on both sides and Side won
+	Conflict bool
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s: %s", c.Action, c.Side, c.Todo)
+	if c.Conflict {
+		s += " (changed on both sides)"
+	}
+	return s
+}
+
+// Report lists what a sync changed, Skipped are objects that changed on the
+// server during the sync and are picked up next time
+type Report struct {
+	Changes []Change
+	Skipped []string
+}
+
+// Options control a sync
+type Options struct {
+	// Prefer is local (default) or remote
+	Prefer string
+	// DryRun reports the changes without making them
+	DryRun bool
+	Now    time.Time
+	// Lock, when set, wraps reading and writing the local files. The
+	// calendar is listed and updated outside of it.
+	Lock func(fn func() error) error
+}
+
+// item is a synced todo and the file it lives in
+type item struct {
+	todo     *todo.Todo
+	reminder bool
+}
+
+// Sync reconciles the todos in todoDir with the calendar. Todos are synced
+// once they have a due: or remind: date, they get a uid: label and stay
+// synced from then on. Each side's changes since the last sync are applied
+// to the other side; when both changed Prefer decides.
+func Sync(ctx context.Context, client *Client, todoDir string, atpDir string, opts Options) (*Report, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Prefer == "" {
+		opts.Prefer = config.PreferLocal
+	}
+	if opts.Prefer != config.PreferLocal && opts.Prefer != config.PreferRemote {
+		return nil, fmt.Errorf("caldav: invalid prefer '%s' (expected local or remote)", opts.Prefer)
+	}
+
+	if opts.Lock == nil {
+		opts.Lock = func(fn func() error) error { return fn() }
+	}
+
+	objects, err := client.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &syncer{
+		client:  client,
+		opts:    opts,
+		report:  &Report{},
+		local:   make(map[string]*item),
+		remote:  make(map[string]*Object),
+		deleted: make(map[*todo.Todo]bool),
+	}
+	for _, o := range objects {
+		if uid := o.VTodo().Text("UID"); uid != "" {
+			s.remote[uid] = o
+		}
+	}
+
+	// local changes are decided and written while the files are locked,
+	// the calendar is only updated once they are released
+	err = opts.Lock(func() error { return s.reconcileLocal(todoDir, atpDir) })
+	if err != nil || opts.DryRun {
+		return s.report, err
+	}
+
+	err = s.flush(ctx)
+	if saveErr := opts.Lock(s.state.Save); err == nil {
+		err = saveErr
+	}
+	return s.report, err
+}
+
+// reconcileLocal loads the todos, decides what changes on each side and
+// writes the local side
+func (s *syncer) reconcileLocal(todoDir string, atpDir string) error {
+	todos, err := todo.LoadTodoDir(todoDir)
+	if err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+	reminderPath := todo.ReminderTasksPath(todoDir)
+	reminders, err := todo.LoadReminderTasks(reminderPath)
+	if err != nil {
+		return fmt.Errorf("failed to load reminders: %w", err)
+	}
+	s.state, err = LoadState(atpDir)
+	if err != nil {
+		return err
+	}
+
+	s.collect(todos, false)
+	s.collect(reminders, true)
+	for _, uid := range s.uids() {
+		s.reconcile(uid)
+	}
+
+	if s.opts.DryRun {
+		return nil
+	}
+
+	todos = append(s.keep(todos), s.newTodos...)
+	reminders = append(s.keep(reminders), s.newReminders...)
+	if err := todo.WriteTodoDir(todoDir, todos); err != nil {
+		return fmt.Errorf("failed to write todos: %w", err)
+	}
+	if err := todo.WriteReminderTasks(reminderPath, reminders); err != nil {
+		return fmt.Errorf("failed to write reminders: %w", err)
+	}
+	return s.state.Save()
+}
+
+// flush makes the calendar changes decided by reconcile. Objects that
+// changed on the server in the meantime are skipped, other errors stop the
+// sync.
+func (s *syncer) flush(ctx context.Context) error {
+	skipped := make(map[int]bool)
+	var err error
+	for _, op := range s.ops {
+		if err = op.run(ctx); errors.Is(err, ErrChanged) {
+			skipped[op.change] = true
+			s.report.Skipped = append(s.report.Skipped, op.uid)
+			err = nil
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	changes := s.report.Changes[:0]
+	for i, c := range s.report.Changes {
+		if !skipped[i] {
+			changes = append(changes, c)
+		}
+	}
+	s.report.Changes = changes
+	return err
+}
+
+type syncer struct {
+	client *Client
+	state  *State
+	opts   Options
+	report *Report
+
+	local  map[string]*item
+	remote map[string]*Object
+
+	deleted      map[*todo.Todo]bool
+	newTodos     []*todo.Todo
+	newReminders []*todo.Todo
+	// ops are the calendar changes, sent after the local files are written
+	ops []remoteOp
+}
+
+// remoteOp is a calendar change and the report entry it belongs to
+type remoteOp struct {
+	uid    string
+	change int
+	run    func(ctx context.Context) error
+}
+
+// collect picks the todos to sync. New ones need a date and to be open,
+// done todos with a date are history, not something to put on a calendar.
+func (s *syncer) collect(todos []*todo.Todo, reminders bool) {
+	for _, t := range todos {
+		uid := ""
+		if t.Labels[ical.UIDLabel] != "" {
+			uid = ical.UID(t)
+		}
+		_, synced := s.state.Items[uid]
+		dated := t.Labels["due"] != "" || t.Labels["remind"] != ""
+
+		switch {
+		case uid != "" && (synced || dated):
+		case uid == "" && dated && !t.Done:
+			uid = ical.UID(t)
+			for n := 2; s.local[uid] != nil || s.state.Items[uid] != nil; n++ {
+				uid = fmt.Sprintf("%s-%d", ical.UID(t), n)
+			}
+			ical.SetUID(t, uid)
+		default:
+			continue
+		}
+		s.local[uid] = &item{todo: t, reminder: reminders}
+	}
+}
+
+func (s *syncer) uids() []string {
+	seen := make(map[string]bool)
+	for uid := range s.local {
+		seen[uid] = true
+	}
+	for uid := range s.remote {
+		seen[uid] = true
+	}
+	for uid := range s.state.Items {
+		seen[uid] = true
+	}
+	uids := make([]string, 0, len(seen))
+	for uid := range seen {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+func (s *syncer) reconcile(uid string) {
+	local, remote, last := s.local[uid], s.remote[uid], s.state.Items[uid]
+
+	switch {
+	case local != nil && remote != nil:
+		pulled := local.todo.Clone()
+		ical.UpdateTodo(pulled, remote.VTodo())
+		if hash(pulled) == hash(local.todo) {
+			// same content, only the etag moved
+			s.record(uid, remote.Href, remote.ETag, local.todo)
+			return
+		}
+
+		localChanged := last == nil || hash(local.todo) != last.Hash
+		remoteChanged := last == nil || remote.ETag != last.ETag
+		conflict := localChanged && remoteChanged
+		if (remoteChanged && !localChanged) || (conflict && s.opts.Prefer == config.PreferRemote) {
+			s.pull(local, remote, conflict)
+			return
+		}
+		if localChanged {
+			s.push(local, remote, conflict)
+			return
+		}
+		s.record(uid, remote.Href, remote.ETag, local.todo)
+
+	case local != nil && last != nil:
+		// deleted on the server, unless it was edited here since
+		if hash(local.todo) == last.Hash {
+			s.change(Local, ActionDelete, local.todo, false)
+			s.deleted[local.todo] = true
+			delete(s.state.Items, uid)
+			return
+		}
+		s.push(local, nil, false)
+
+	case local != nil:
+		s.push(local, nil, false)
+
+	case remote != nil && last != nil && remote.ETag == last.ETag:
+		// deleted here
+		s.change(Remote, ActionDelete, remoteTodo(remote), false)
+		s.send(uid, func(ctx context.Context) error {
+			if err := s.client.Delete(ctx, remote.Href, remote.ETag); err != nil {
+				return err
+			}
+			delete(s.state.Items, uid)
+			return nil
+		})
+
+	case remote != nil:
+		// new on the server, or edited there after it was deleted here
+		t := remoteTodo(remote)
+		s.change(Local, ActionCreate, t, false)
+		if remind := t.Labels["remind"]; !t.Done && remind > s.opts.Now.Format("2006-01-02") {
+			s.newReminders = append(s.newReminders, t)
+		} else {
+			s.newTodos = append(s.newTodos, t)
+		}
+		s.record(uid, remote.Href, remote.ETag, t)
+
+	default:
+		// gone on both sides
+		delete(s.state.Items, uid)
+	}
+}
+
+func (s *syncer) pull(local *item, remote *Object, conflict bool) {
+	s.change(Local, ActionUpdate, local.todo, conflict)
+	ical.UpdateTodo(local.todo, remote.VTodo())
+	s.record(remote.VTodo().Text("UID"), remote.Href, remote.ETag, local.todo)
+}
+
+func (s *syncer) push(local *item, remote *Object, conflict bool) {
+	uid := ical.UID(local.todo)
+	href, etag := s.client.Href(uid), ""
+	var existing *ical.Component
+	action := ActionCreate
+	if remote != nil {
+		href, etag, existing, action = remote.Href, remote.ETag, remote.VTodo(), ActionUpdate
+	}
+	s.change(Remote, action, local.todo, conflict)
+
+	cal := ical.NewCalendar(ical.ProdID)
+	cal.Components = append(cal.Components, ical.MergeVTodo(existing, local.todo, s.opts.Now))
+	t := local.todo
+	s.send(uid, func(ctx context.Context) error {
+		newETag, err := s.client.Put(ctx, href, cal, etag)
+		if err != nil {
+			return err
+		}
+		s.record(uid, href, newETag, t)
+		return nil
+	})
+}
+
+// send queues a calendar change for the change just reported
+func (s *syncer) send(uid string, run func(ctx context.Context) error) {
+	if s.opts.DryRun {
+		return
+	}
+	s.ops = append(s.ops, remoteOp{uid: uid, change: len(s.report.Changes) - 1, run: run})
+}
+
+func (s *syncer) record(uid string, href string, etag string, t *todo.Todo) {
+	s.state.Items[uid] = &entry{Href: href, ETag: etag, Hash: hash(t)}
+}
+
+func (s *syncer) change(side string, action string, t *todo.Todo, conflict bool) {
+	s.report.Changes = append(s.report.Changes, Change{Side: side, Action: action, Todo: t.Description, Conflict: conflict})
+}
+
+func (s *syncer) keep(todos []*todo.Todo) []*todo.Todo {
+	kept := []*todo.Todo{}
+	for _, t := range todos {
+		if !s.deleted[t] {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func remoteTodo(o *Object) *todo.Todo {
+	t := todo.NewTodo()
+	ical.UpdateTodo(t, o.VTodo())
+	return t
+}
+
+// hash covers the fields that are synced, so changing other labels
+// doesn't count as a change
+func hash(t *todo.Todo) string {
+	var b strings.Builder
+	ical.Encode(&b, ical.VTodo(t, time.Time{}))
+	sum := sha1.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
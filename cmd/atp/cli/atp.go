@@ -14,5 +14,16 @@ var Cmd = &Z.Cmd{
 		help.Cmd,
 		ProjectCmd,
 		TodoCmd,
+		TodayCmd,
+		NextCmd,
+		ContextCmd,
+		ReviewCmd,
+		StatsCmd,
+		ExportCmd,
+		ImportCmd,
+		TuiCmd,
+		ServeCmd,
+		AuthCmd,
+		DaemonCmd,
 	},
 }
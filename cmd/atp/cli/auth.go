@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arjungandhi/atp/auth"
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/github"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var AuthCmd = &Z.Cmd{
+	Name:    "auth",
+	Summary: "inspect GitHub credentials",
+	Commands: []*Z.Cmd{
+		help.Cmd,
+		authStatusCmd,
+	},
+}
+
+var authStatusCmd = &Z.Cmd{
+	Name:    "status",
+	Aliases: []string{"s"},
+	Summary: "show which token each GitHub owner uses",
+	Description: `Show where the GitHub token for each configured owner comes from
+and what it can do.
+
+Tokens are looked up with the providers in [github.auth] order:
+
+  config   per-organization tokens from [github.auth.tokens]
+  env      GITHUB_TOKEN / GH_TOKEN
+  netrc    ~/.netrc
+  gh       the gh CLI's hosts.yml
+  git      git credential fill
+  keyring  the output of [github.auth] keyring_command`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.LoadConfig(atpDir)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		resolver, err := auth.NewResolver(cfg.GitHub.Auth)
+		if err != nil {
+			return err
+		}
+
+		var order []string
+		for _, provider := range resolver.Providers() {
+			order = append(order, provider.Name())
+		}
+		fmt.Printf("Providers: %s\n", strings.Join(order, ", "))
+
+		opts := github.ClientOptionsFromConfig(cfg, atpDir)
+		host := ""
+		for _, owner := range github.TokenOwners(cfg) {
+			if owner.Host != host {
+				host = owner.Host
+				fmt.Printf("\n%s\n", host)
+			}
+
+			name := owner.Owner
+			if name == "" {
+				name = "(default)"
+			}
+
+			credential, err := resolver.Resolve(owner.Host, owner.Owner)
+			if err != nil {
+				fmt.Printf("  ✗ %-16s %v\n", name, err)
+				continue
+			}
+
+			status, err := github.CheckToken(owner.Host, credential.Token, opts)
+			if err != nil {
+				fmt.Printf("  ✗ %-16s from %s: %v\n", name, credential.Source, err)
+				continue
+			}
+
+			fmt.Printf("  ✓ %-16s from %-8s as %s, %s\n", name, credential.Source, status.Login, describeToken(status))
+		}
+
+		return nil
+	},
+}
+
+func describeToken(status *github.TokenStatus) string {
+	var desc string
+	switch {
+	case status.FineGrained:
+		desc = "fine-grained token"
+	case len(status.Scopes) == 0:
+		desc = "no scopes"
+	default:
+		desc = "scopes: " + strings.Join(status.Scopes, ", ")
+	}
+
+	if status.Expiration != "" {
+		desc += " (expires " + status.Expiration + ")"
+	}
+	return desc
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arjungandhi/atp/caldav"
+	"github.com/arjungandhi/atp/config"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var caldavCmd = &Z.Cmd{
+	Name:     "caldav",
+	Aliases:  []string{"cal"},
+	Summary:  "sync todos with due or remind dates with a CalDAV calendar",
+	Commands: []*Z.Cmd{help.Cmd, caldavSyncCmd},
+}
+
+var caldavSyncCmd = &Z.Cmd{
+	Name:    "sync",
+	Aliases: []string{"s"},
+	Summary: "two-way sync of dated todos with the calendar's VTODOs",
+	Description: `Sync todos that have a due: or remind: date with a CalDAV calendar.
+Synced todos get a uid: label and stay synced after their dates are
+removed. Changes on either side since the last sync are copied to the
+other; a todo deleted on one side is deleted on the other.
+
+Configure the calendar collection in config.toml:
+
+  [caldav]
+  url = "https://dav.example.com/alice/tasks/"
+  username = "alice"
+  prefer = "local"  # or "remote", wins when both sides changed
+
+The password comes from the config, $ATP_CALDAV_PASSWORD or ~/.netrc.
+
+Flags:
+  --dry-run        print the changes without making them
+  --prefer SIDE    override the configured prefer for this sync`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.LoadConfig(atpDir)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if !cfg.CalDAV.Enabled() {
+			return fmt.Errorf("no calendar configured, set url in the [caldav] section of config.toml")
+		}
+
+		opts := caldav.Options{Prefer: cfg.CalDAV.Prefer}
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--dry-run":
+				opts.DryRun = true
+			case "--prefer":
+				if i+1 >= len(args) {
+					return fmt.Errorf("--prefer needs local or remote")
+				}
+				opts.Prefer = args[i+1]
+				i++
+			default:
+				return fmt.Errorf("unknown argument: %s", args[i])
+			}
+		}
+
+		client, err := caldav.NewClient(cfg.CalDAV)
+		if err != nil {
+			return err
+		}
+		todoDir, err := TodoDir()
+		if err != nil {
+			return err
+		}
+
+		if !opts.DryRun {
+			l, err := LockAtpDir()
+			if err != nil {
+				return err
+			}
+			defer l.Release()
+		}
+
+		var report *caldav.Report
+		sync := func() error {
+			report, err = caldav.Sync(context.Background(), client, todoDir, atpDir, opts)
+			return err
+		}
+		if opts.DryRun {
+			err = sync()
+		} else {
+			err = runSync("caldav", sync)
+		}
+		if report != nil {
+			printCalDAVReport(report, opts.DryRun)
+		}
+		if err != nil {
+			return fmt.Errorf("caldav sync failed: %w", err)
+		}
+		return nil
+	},
+}
+
+func printCalDAVReport(report *caldav.Report, dryRun bool) {
+	if len(report.Changes) == 0 && len(report.Skipped) == 0 {
+		fmt.Println("✓ Calendar is in sync")
+		return
+	}
+
+	prefix := "  "
+	if dryRun {
+		fmt.Println("Planned changes (dry run):")
+	}
+	for _, c := range report.Changes {
+		fmt.Printf("%s%s\n", prefix, c)
+	}
+	for _, uid := range report.Skipped {
+		fmt.Printf("%sskipped %s, it changed on the server during the sync\n", prefix, uid)
+	}
+	if !dryRun {
+		fmt.Printf("✓ Synced %d changes\n", len(report.Changes))
+	}
+}
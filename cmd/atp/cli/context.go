@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/contexts"
+	"github.com/arjungandhi/atp/todo"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var ContextCmd = &Z.Cmd{
+	Name:    "context",
+	Aliases: []string{"ctx"},
+	Summary: "show, set or clear the current @context",
+	Description: `Contexts are defined in the [contexts] section of config.toml with the
+times they are available:
+
+  [contexts.office]
+  windows = ["mon-fri 9-17"]
+
+  [contexts.errands]
+  windows = ["sat-sun"]
+
+Windows are days (mon-fri, sat,sun, weekdays, weekends) and hours (9-17,
+8:30-12), either may be left out. A context without windows is always
+available.
+
+The current contexts are the ones whose windows are open, unless one was
+set with 'atp context set'. It stays until 'atp context clear'. Without a
+subcommand the current and the defined contexts are printed.`,
+	Commands: []*Z.Cmd{help.Cmd, contextSetCmd, contextClearCmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		defs, set, err := loadContexts()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		current := defs.Current(set, now)
+		switch {
+		case set != "":
+			fmt.Printf("Current: @%s (set by hand)\n", set)
+		case len(current) > 0:
+			fmt.Printf("Current: @%s\n", strings.Join(current, ", @"))
+		default:
+			fmt.Println("Current: none")
+		}
+
+		for _, name := range defs.Names() {
+			c := defs[name]
+			var windows []string
+			for _, w := range c.Windows {
+				windows = append(windows, w.String())
+			}
+			if len(windows) == 0 {
+				windows = []string{"always"}
+			}
+			open := ""
+			if c.OpenAt(now) {
+				open = " (open)"
+			}
+			fmt.Printf("  @%-12s %s%s\n", name, strings.Join(windows, ", "), open)
+		}
+		return nil
+	},
+}
+
+var contextSetCmd = &Z.Cmd{
+	Name:     "set",
+	Summary:  "set the current context by hand",
+	Usage:    "<context>",
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("usage: atp context set <context>")
+		}
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+		if err := contexts.Save(atpDir, args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Context set to @%s\n", strings.TrimPrefix(args[0], "@"))
+		return nil
+	},
+}
+
+var contextClearCmd = &Z.Cmd{
+	Name:     "clear",
+	Summary:  "go back to the contexts whose windows are open",
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+		if err := contexts.Save(atpDir, ""); err != nil {
+			return err
+		}
+		fmt.Println("Context follows the time windows again")
+		return nil
+	},
+}
+
+var NextCmd = &Z.Cmd{
+	Name:    "next",
+	Aliases: []string{"n"},
+	Summary: "list the todos actionable in the current context",
+	Usage:   "[--in <context>]",
+	Description: `List the open todos that can be worked on right now: not waiting for
+another todo, past their t: threshold and available in the current
+context (see 'atp context'). Todos without a context, or only with
+contexts that have no definition, are available everywhere.
+
+--in lists what is actionable in another context instead.`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		defs, set, err := loadContexts()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--in":
+				if i+1 >= len(args) {
+					return fmt.Errorf("--in needs a context")
+				}
+				set = strings.TrimPrefix(args[i+1], "@")
+				i++
+			default:
+				return fmt.Errorf("unknown argument: %s", args[i])
+			}
+		}
+
+		todos, err := GetTodos()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		current := defs.Current(set, now)
+
+		next := defs.Next(todos, current, now)
+		sort.SliceStable(next, func(i, j int) bool {
+			return nextKey(next[i], now) < nextKey(next[j], now)
+		})
+
+		where := "anywhere"
+		if len(current) > 0 {
+			where = "@" + strings.Join(current, ", @")
+		}
+		if len(next) == 0 {
+			fmt.Printf("Nothing to do in %s\n", where)
+			return nil
+		}
+		fmt.Printf("Next in %s:\n", where)
+		for _, t := range next {
+			fmt.Printf("  %s\n", t.String())
+		}
+		return nil
+	},
+}
+
+// nextKey orders today's focus first, then by priority, due date and name
+func nextKey(t *todo.Todo, now time.Time) string {
+	focus := "1"
+	if todo.IsFocused(t, now) {
+		focus = "0"
+	}
+	priority := t.Priority
+	if priority == "" {
+		priority = "~"
+	}
+	due := t.Labels["due"]
+	if due == "" {
+		due = "9999-99-99"
+	}
+	return focus + priority + due + strings.ToLower(t.Description)
+}
+
+// loadContexts returns the configured contexts and the one set by hand
+func loadContexts() (contexts.Definitions, string, error) {
+	atpDir, err := AtpDir()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := config.LoadConfig(atpDir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+	defs, err := contexts.FromConfig(cfg.Contexts)
+	if err != nil {
+		return nil, "", err
+	}
+	set, err := contexts.Load(atpDir)
+	if err != nil {
+		return nil, "", err
+	}
+	return defs, set, nil
+}
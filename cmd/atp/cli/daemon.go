@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/daemon"
+	"github.com/arjungandhi/atp/events"
+	"github.com/arjungandhi/atp/github"
+	"github.com/arjungandhi/atp/hooks"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var DaemonCmd = &Z.Cmd{
+	Name:    "daemon",
+	Aliases: []string{"d"},
+	Summary: "run recur, remind and sync in the background",
+	Description: `Run in the foreground until interrupted, doing what would otherwise
+need a crontab:
+
+  - generate recurring todos and process reminders at day rollover
+  - sync GitHub on an interval, sending queued changes as well
+  - watch the ATP directory and react once edits settle
+
+Settings live in the [daemon] section of config.toml:
+
+  [daemon]
+  sync_interval = "15m"   # "0" turns syncing off
+  debounce = "2s"
+  poll_interval = "1s"
+  sync_on_change = false
+  log_file = "~/.atp/daemon.log"
+
+Other atp commands wait for the daemon to finish writing before they
+change todos.`,
+	Commands: []*Z.Cmd{
+		help.Cmd,
+		daemonStatusCmd,
+		daemonStopCmd,
+	},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.LoadConfig(atpDir)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		logPath := cfg.Daemon.LogFile
+		if logPath == "" {
+			logPath = filepath.Join(atpDir, "daemon.log")
+		}
+		logFile, err := os.OpenFile(expandHome(logPath), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open daemon log: %w", err)
+		}
+		defer logFile.Close()
+
+		// sync progress goes to the log too
+		github.Output = logFile
+		events.Output = logFile
+		hooks.Output = logFile
+		logger := log.New(io.MultiWriter(logFile, os.Stderr), "atp daemon: ", log.LstdFlags)
+
+		d, err := daemon.New(atpDir, cfg.Daemon, logger)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return d.Run(ctx)
+	},
+}
+
+var daemonStatusCmd = &Z.Cmd{
+	Name:     "status",
+	Aliases:  []string{"s"},
+	Summary:  "show what the running daemon is doing",
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+
+		status, err := daemon.QueryStatus(atpDir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Running (pid %d) since %s\n", status.PID, formatTime(status.StartedAt))
+		fmt.Printf("  Last rollover: %s\n", formatTime(status.LastRollover))
+		fmt.Printf("  Sync interval: %s\n", status.SyncInterval)
+		fmt.Printf("  Last sync:     %s\n", formatTime(status.LastSync))
+		if status.SyncInterval != "off" {
+			fmt.Printf("  Next sync:     %s\n", formatTime(status.NextSync))
+		}
+		fmt.Printf("  Last change:   %s (%d handled)\n", formatTime(status.LastChange), status.ChangesHandled)
+		if status.LastError != "" {
+			fmt.Printf("  Last error:    %s at %s\n", status.LastError, formatTime(status.LastErrorAt))
+		}
+		return nil
+	},
+}
+
+var daemonStopCmd = &Z.Cmd{
+	Name:     "stop",
+	Summary:  "ask the running daemon to exit",
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+
+		if err := daemon.Stop(atpDir); err != nil {
+			return err
+		}
+
+		fmt.Println("✓ Daemon stopping")
+		return nil
+	},
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// expandHome resolves a leading ~/ in paths from the config
+func expandHome(path string) string {
+	if len(path) < 2 || path[:2] != "~/" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/todo"
+	"github.com/arjungandhi/go-utils/pkg/prompt"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var taskDoneCmd = &Z.Cmd{
+	Name:    "done",
+	Aliases: []string{"x"},
+	Summary: "complete a todo and its subtasks",
+	Usage:   "[--yes] <id|words...>",
+	Description: `Complete a todo picked by its id: label or by words from its description
+that match only one open todo.
+
+Subtasks point at their parent with a parent: label holding its id or,
+for synced items, its url:
+
+  Launch site id:1
+  Write copy parent:1
+  Build pages parent:1
+
+Completing a todo with open subtasks offers to complete them too, and
+completing the last open subtask of a todo offers to complete the parent.
+--yes answers yes to both.`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		yes := false
+		var words []string
+		for _, arg := range args {
+			switch arg {
+			case "--yes", "-y":
+				yes = true
+			default:
+				words = append(words, arg)
+			}
+		}
+		if len(words) == 0 {
+			return fmt.Errorf("usage: atp todo done [--yes] <id|words...>")
+		}
+
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
+		todos, err := GetTodos()
+		if err != nil {
+			return err
+		}
+		t, err := resolveTodo(todos, words)
+		if err != nil {
+			return err
+		}
+
+		today := time.Now()
+		completed := []*todo.Todo{t}
+		subtasks := todo.NewSubtasks(todos)
+
+		var open []*todo.Todo
+		for _, child := range subtasks.Descendants(t) {
+			if !child.Done {
+				open = append(open, child)
+			}
+		}
+		if len(open) > 0 && confirm(fmt.Sprintf("Also complete %d open subtasks of %s? [y/N]", len(open), t.Description), yes) {
+			completed = append(completed, open...)
+		}
+		for _, c := range completed {
+			c.Done = true
+			c.CompletionDate = today
+		}
+
+		// finishing the last subtask can finish the parents up the tree
+		for parent := subtasks.CompletableParent(t); parent != nil; parent = subtasks.CompletableParent(parent) {
+			if !confirm(fmt.Sprintf("All subtasks of %s are done, complete it too? [y/N]", parent.Description), yes) {
+				break
+			}
+			parent.Done = true
+			parent.CompletionDate = today
+			completed = append(completed, parent)
+		}
+
+		if err := WriteTodos(todos); err != nil {
+			return err
+		}
+		for _, c := range completed {
+			fmt.Printf("Completed: %s\n", c.String())
+		}
+		return nil
+	},
+}
+
+// confirm asks a yes/no question, yes skips asking
+func confirm(question string, yes bool) bool {
+	if yes {
+		return true
+	}
+	answer, _ := prompt.PromptString(question)
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y")
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/arjungandhi/atp/export"
+	"github.com/arjungandhi/atp/todo"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var ExportCmd = &Z.Cmd{
+	Name:    "export",
+	Summary: "export todos or projects as json, csv, markdown or ical",
+	Usage:   "<format> [flags] [filter...]",
+	Description: `Write todos or projects in another format:
+
+  json      every field and label, plus the todo.txt line
+  csv       one row per todo or project
+  markdown  checklists grouped by +project (or @context with --group)
+  ical      a VTODO per due: todo and an all day VEVENT per remind: date
+
+Flags:
+  --projects        export projects instead of todos
+  --reminders       include pending reminders from reminders.txt
+  --group context   group markdown checklists by @context
+  --output FILE     write to FILE instead of stdout
+
+Todos and projects are selected with the same filters as 'atp todo list',
+e.g. 'atp export markdown +garden --all' or 'atp export ical due:*'.`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("usage: atp export <format> [flags] [filter...] (formats: %s)", strings.Join(export.Names(), ", "))
+		}
+		format := args[0]
+		if _, err := export.Lookup(format); err != nil {
+			return err
+		}
+
+		var opts export.Options
+		var output string
+		var projects, reminders bool
+		var filterArgs []string
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--projects":
+				projects = true
+			case "--reminders":
+				reminders = true
+			case "--group", "--output", "-o":
+				if i+1 >= len(args) {
+					return fmt.Errorf("%s needs a value", args[i])
+				}
+				if args[i] == "--group" {
+					opts.GroupBy = args[i+1]
+				} else {
+					output = args[i+1]
+				}
+				i++
+			default:
+				filterArgs = append(filterArgs, args[i])
+			}
+		}
+		if opts.GroupBy != "" && opts.GroupBy != export.GroupProject && opts.GroupBy != export.GroupContext {
+			return fmt.Errorf("invalid --group '%s' (expected project or context)", opts.GroupBy)
+		}
+
+		filter, err := todo.ParseFilter(filterArgs)
+		if err != nil {
+			return err
+		}
+
+		var data export.Data
+		if projects {
+			all, err := loadProjectsForReview()
+			if err != nil {
+				return err
+			}
+			data.Projects = export.FilterProjects(filter, all)
+		} else {
+			todos, err := GetTodos()
+			if err != nil {
+				return err
+			}
+			if reminders {
+				todoDir, err := TodoDir()
+				if err != nil {
+					return err
+				}
+				pending, err := todo.LoadReminderTasks(todo.ReminderTasksPath(todoDir))
+				if err != nil {
+					return fmt.Errorf("failed to load reminders: %w", err)
+				}
+				todos = append(todos, pending...)
+			}
+			data.Todos = filter.Apply(todos)
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", output, err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err := export.Write(w, format, data, opts); err != nil {
+			return err
+		}
+
+		if output != "" {
+			fmt.Fprintf(os.Stderr, "Exported %d todos and %d projects to %s\n", len(data.Todos), len(data.Projects), output)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arjungandhi/atp/todo"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var taskGraphCmd = &Z.Cmd{
+	Name:    "graph",
+	Aliases: []string{"deps"},
+	Summary: "print dependency chains between todos and find cycles",
+	Usage:   "[--all]",
+	Description: `Print the todos linked by dep: and blocks: labels as trees, each todo
+followed by the todos waiting for it. Labels hold comma separated ids or,
+for synced items, urls:
+
+  Write spec id:1
+  Build API id:2 dep:1
+  Ship it blocks:3,4
+
+Blocked todos are hidden from 'atp todo list' and 'atp today' until the
+todos they wait for are done. Chains that are done are left out unless
+--all is given.
+
+A cycle blocks its todos for good, they are listed and the command fails.`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		all := false
+		for _, arg := range args {
+			switch arg {
+			case "--all":
+				all = true
+			default:
+				return fmt.Errorf("unknown argument: %s", arg)
+			}
+		}
+
+		todos, err := GetTodos()
+		if err != nil {
+			return err
+		}
+		g := todo.NewGraph(todos)
+
+		printed := 0
+		for _, t := range todos {
+			// chains start at todos that don't wait for anything
+			if len(g.Dependents(t)) == 0 || len(g.Dependencies(t)) > 0 {
+				continue
+			}
+			if !all && chainDone(g, t, map[*todo.Todo]bool{}) {
+				continue
+			}
+			printChain(g, t, "", "", map[*todo.Todo]bool{})
+			printed++
+		}
+
+		for _, t := range todos {
+			if missing := g.Missing(t); len(missing) > 0 && (all || !t.Done) {
+				fmt.Printf("%s refers to missing %s\n", graphLabel(g, t), strings.Join(missing, ", "))
+				printed++
+			}
+		}
+
+		cycles := g.Cycles()
+		if len(cycles) > 0 {
+			fmt.Println("Cycles:")
+			for _, cycle := range cycles {
+				var names []string
+				for _, t := range cycle {
+					names = append(names, graphLabel(g, t))
+				}
+				fmt.Printf("  %s\n", strings.Join(names, " → "))
+			}
+			if len(cycles) == 1 {
+				return fmt.Errorf("found a dependency cycle")
+			}
+			return fmt.Errorf("found %d dependency cycles", len(cycles))
+		}
+
+		if printed == 0 {
+			fmt.Println("No dependencies")
+		}
+		return nil
+	},
+}
+
+// printChain prints t and, indented below it, the todos waiting for it
+func printChain(g *todo.Graph, t *todo.Todo, prefix string, branch string, seen map[*todo.Todo]bool) {
+	fmt.Printf("%s%s%s\n", prefix, branch, graphLabel(g, t))
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	switch branch {
+	case "├─ ":
+		prefix += "│  "
+	case "└─ ":
+		prefix += "   "
+	}
+	dependents := g.Dependents(t)
+	for i, dependent := range dependents {
+		next := "├─ "
+		if i == len(dependents)-1 {
+			next = "└─ "
+		}
+		printChain(g, dependent, prefix, next, seen)
+	}
+}
+
+// chainDone reports whether t and everything waiting for it is done
+func chainDone(g *todo.Graph, t *todo.Todo, seen map[*todo.Todo]bool) bool {
+	if !t.Done {
+		return false
+	}
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	for _, dependent := range g.Dependents(t) {
+		if !chainDone(g, dependent, seen) {
+			return false
+		}
+	}
+	return true
+}
+
+func graphLabel(g *todo.Graph, t *todo.Todo) string {
+	label := t.Description
+	if id := t.ID(); id != "" {
+		label += " id:" + id
+	}
+	switch {
+	case t.Done:
+		label = "✓ " + label
+	case g.Blocked(t):
+		label += " (blocked)"
+	}
+	return label
+}
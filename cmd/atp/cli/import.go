@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/arjungandhi/atp/importer"
+	"github.com/arjungandhi/go-utils/pkg/prompt"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var ImportCmd = &Z.Cmd{
+	Name:    "import",
+	Summary: "import tasks from taskwarrior, todoist, csv or markdown",
+	Usage:   "<format> [file] [flags]",
+	Description: `Read tasks exported from another tool and add them as todos:
+
+  taskwarrior  the JSON 'task export' prints
+  todoist      a project csv from a Todoist backup
+  csv          any csv, columns mapped with --map
+  github       the csv a GitHub project view exports
+  markdown     "- [ ]" and "- [x]" checklist items
+
+Input is read from the file or stdin. Tasks that already exist, with the
+same description or the same uuid:, todoist: or url: label, are skipped. The
+todos are previewed and only added after confirming.
+
+Flags:
+  --map FIELD=COLUMN,...  csv column for each field: description, done,
+                          priority, creation_date, completion_date, due,
+                          projects, contexts, tags, labels or label:<key>
+  --project NAME          add +NAME to every imported todo
+  --dry-run               only print the preview
+  --yes                   add without asking
+
+Without --map a csv needs columns named like the fields, which is what
+'atp export csv' writes.`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("usage: atp import <format> [file] (formats: %s)", strings.Join(importer.Names(), ", "))
+		}
+		format := args[0]
+		if _, err := importer.Lookup(format); err != nil {
+			return err
+		}
+
+		var opts importer.Options
+		var path string
+		var dryRun, yes bool
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--map", "--project":
+				if i+1 >= len(args) {
+					return fmt.Errorf("%s needs a value", args[i])
+				}
+				if args[i] == "--project" {
+					opts.Project = args[i+1]
+				} else {
+					mapping, err := importer.ParseMapping(args[i+1])
+					if err != nil {
+						return err
+					}
+					opts.Mapping = mapping
+				}
+				i++
+			case "--dry-run":
+				dryRun = true
+			case "--yes", "-y":
+				yes = true
+			default:
+				if strings.HasPrefix(args[i], "--") || path != "" {
+					return fmt.Errorf("unknown argument: %s", args[i])
+				}
+				path = args[i]
+			}
+		}
+
+		var r io.Reader = os.Stdin
+		if path != "" && path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", path, err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		result, err := importer.Read(r, format, opts)
+		if err != nil {
+			return err
+		}
+
+		existing, err := GetTodos()
+		if err != nil {
+			return err
+		}
+		fresh, duplicates := importer.Dedupe(existing, result.Todos)
+
+		for _, warning := range result.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		fmt.Printf("%d new todos, %d already exist\n", len(fresh), len(duplicates))
+		for _, t := range fresh {
+			fmt.Printf("  + %s\n", t.String())
+		}
+		for _, t := range duplicates {
+			fmt.Printf("  = %s\n", t.String())
+		}
+
+		if dryRun || len(fresh) == 0 {
+			return nil
+		}
+		if !yes {
+			answer, _ := prompt.PromptString(fmt.Sprintf("Add %d todos? [y/N]", len(fresh)))
+			if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y") {
+				fmt.Println("Nothing imported")
+				return nil
+			}
+		}
+
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
+		// todos may have changed while the preview was open
+		todos, err := GetTodos()
+		if err != nil {
+			return err
+		}
+		fresh, _ = importer.Dedupe(todos, fresh)
+		if err := WriteTodos(append(todos, fresh...)); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Imported %d todos\n", len(fresh))
+		return nil
+	},
+}
@@ -3,12 +3,19 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"github.com/arjungandhi/atp/events"
+	"github.com/arjungandhi/atp/hooks"
+	"github.com/arjungandhi/atp/lock"
 	"github.com/arjungandhi/atp/project"
 	"github.com/arjungandhi/atp/repo"
+	"github.com/arjungandhi/atp/store"
+	"github.com/arjungandhi/atp/timelog"
 	"github.com/arjungandhi/atp/todo"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // get the user specified ATP directory
@@ -47,6 +54,74 @@ func AtpDir() (string, error) {
 	return atp_dir, nil
 }
 
+// lockTimeout is how long a command waits for the daemon or another atp
+// process to finish writing
+const lockTimeout = 2 * time.Minute
+
+// LockAtpDir takes the ATP directory lock so commands that change todos
+// don't overwrite changes made by the daemon at the same time
+func LockAtpDir() (*AtpLock, error) {
+	atp_dir, err := AtpDir()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := lock.TryAcquire(lock.Path(atp_dir))
+	if errors.Is(err, lock.ErrLocked) {
+		fmt.Fprintln(os.Stderr, "Waiting for another atp process to finish...")
+		l, err = lock.Acquire(lock.Path(atp_dir), lockTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", atp_dir, err)
+	}
+
+	held := &AtpLock{Lock: l, atpDir: atp_dir}
+	held.before, held.snapshotErr = events.TodoSnapshot(atp_dir)
+	return held, nil
+}
+
+// AtpLock is the held ATP directory lock. Releasing it records events for
+// the todos and reminders the command changed, projects are recorded by
+// WriteProjects.
+type AtpLock struct {
+	*lock.Lock
+	atpDir      string
+	before      events.Snapshot
+	snapshotErr error
+}
+
+// Release records the events and gives up the lock
+func (l *AtpLock) Release() error {
+	if l.snapshotErr != nil {
+		events.Warn(l.snapshotErr)
+	} else {
+		after, err := events.TodoSnapshot(l.atpDir)
+		if err == nil {
+			err = events.Emit(l.atpDir, events.Diff(l.before, after, time.Now())...)
+		}
+		if err != nil {
+			events.Warn(err)
+		}
+	}
+	return l.Lock.Release()
+}
+
+// runSync runs a sync between the pre-sync and post-sync hooks and records
+// it in the event log when it succeeds
+func runSync(source string, sync func() error) error {
+	atp_dir, err := AtpDir()
+	if err != nil {
+		return err
+	}
+	if err := hooks.New(atp_dir).Sync(source, sync); err != nil {
+		return err
+	}
+	if err := events.Emit(atp_dir, events.Synced(source, time.Now())); err != nil {
+		events.Warn(err)
+	}
+	return nil
+}
+
 // ------------------------------- Repo Utils -------------------------------
 
 func RepoDir() (string, error) {
@@ -127,6 +202,22 @@ func WriteTodos(todos []*todo.Todo) error {
 		return fmt.Errorf("Unable to load todo file into todos: %w", err)
 	}
 
+	// hooks get to see and veto what changed since the todos were loaded
+	atp_dir := filepath.Dir(todo_dir)
+	before, err := store.New(atp_dir, nil).Todos()
+	if err != nil {
+		return err
+	}
+	err = hooks.New(atp_dir).Todos(before, todos)
+	if err != nil {
+		return err
+	}
+	// completing a todo stops its timer
+	err = timelog.StopDone(atp_dir, todos, time.Now())
+	if err != nil {
+		return err
+	}
+
 	err = todo.WriteTodoDir(todo_dir, todos)
 	if err != nil {
 		return fmt.Errorf("Unable to write todos to file: %w", err)
@@ -135,6 +226,124 @@ func WriteTodos(todos []*todo.Todo) error {
 	return nil
 }
 
+// EditTodoFiles opens todo files in the editor. Once it exits the edit is
+// handled like any other write: hooks run for what changed, completed todos
+// stop their timers and the changes are recorded as events.
+func EditTodoFiles(paths ...string) error {
+	atp_dir, err := AtpDir()
+	if err != nil {
+		return err
+	}
+	todo_dir := filepath.Join(atp_dir, "todo")
+
+	// the editor isn't run under the lock, the daemon would wait for the
+	// user to finish editing
+	before, err := events.TodoSnapshot(atp_dir)
+	if err != nil {
+		return err
+	}
+
+	err = runEditor(paths...)
+	if err != nil {
+		return err
+	}
+
+	l, err := LockAtpDir()
+	if err != nil {
+		return err
+	}
+	// events cover the whole edit, not only what changed under the lock
+	l.before, l.snapshotErr = before, nil
+	defer l.Release()
+
+	todos, err := todo.LoadTodoDir(todo_dir)
+	if err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+	edited := make(map[*todo.Todo]string, len(todos))
+	for _, t := range todos {
+		edited[t] = t.String()
+	}
+
+	// hooks see each change on its own, a veto only undoes the todos it
+	// rejected and keeps the rest of the edit and what others wrote meanwhile
+	runner := hooks.New(atp_dir)
+	var vetoes []error
+	for _, c := range todo.Changes(before.Todos, todos) {
+		if c.After == nil {
+			continue
+		}
+		var original []*todo.Todo
+		if c.Before != nil {
+			original = []*todo.Todo{c.Before}
+		}
+		if err := runner.Todos(original, []*todo.Todo{c.After}); err != nil {
+			vetoes = append(vetoes, fmt.Errorf("%s: %w", edited[c.After], err))
+			todos = revert(todos, c)
+		}
+	}
+	// completing a todo in the editor stops its timer
+	err = timelog.StopDone(atp_dir, todos, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// only rewrite the files when a change was reverted, a hook replaced a
+	// todo or a stopped timer recorded the time spent
+	rewrite := len(todos) != len(edited)
+	for _, t := range todos {
+		if line, ok := edited[t]; !ok || line != t.String() {
+			rewrite = true
+		}
+	}
+	if rewrite {
+		err = todo.WriteTodoDir(todo_dir, todos)
+		if err != nil {
+			return fmt.Errorf("Unable to write todos to file: %w", err)
+		}
+	}
+
+	if len(vetoes) > 0 {
+		return fmt.Errorf("rejected changes reverted: %w", errors.Join(vetoes...))
+	}
+	return nil
+}
+
+// revert undoes a change in the todos: a changed todo gets its old version
+// back and an added one is dropped
+func revert(todos []*todo.Todo, c todo.Change) []*todo.Todo {
+	for i, t := range todos {
+		if t != c.After {
+			continue
+		}
+		if c.Before == nil {
+			return append(todos[:i], todos[i+1:]...)
+		}
+		todos[i] = c.Before
+		return todos
+	}
+	return todos
+}
+
+// runEditor runs $EDITOR on the paths and waits for it to exit. Unlike
+// shell.OpenInEditor it doesn't replace the process, so the edit can be
+// handled afterwards.
+func runEditor(paths ...string) error {
+	editor, exists := os.LookupEnv("EDITOR")
+	if !exists {
+		editor = "vi"
+	}
+
+	cmd := exec.Command(editor, paths...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %s: %w", editor, err)
+	}
+	return nil
+}
+
 // ------------------------------- Project Utils -------------------------------
 
 func ProjectDir() (string, error) {
@@ -181,7 +390,19 @@ func WriteProjects(projects []*project.Project) error {
 		return fmt.Errorf("Unable to load project file into projects: %w", err)
 	}
 
-	err = project.WriteProjectsDir(project_dir, projects)
+	atp_dir := filepath.Dir(project_dir)
+	before, err := store.New(atp_dir, nil).Projects()
+	if err != nil {
+		return err
+	}
+	err = hooks.New(atp_dir).Projects(before, projects)
+	if err != nil {
+		return err
+	}
+
+	err = events.RecordSnapshot(atp_dir, events.ProjectSnapshot, func() error {
+		return project.WriteProjectsDir(project_dir, projects)
+	})
 	if err != nil {
 		return fmt.Errorf("Unable to write projects to file: %w", err)
 	}
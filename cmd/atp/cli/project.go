@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/arjungandhi/atp/project"
 	"github.com/arjungandhi/atp/todo"
@@ -26,6 +27,8 @@ var ProjectCmd = &bonzai.Cmd{
 		projectReorgCmd,
 		projectActivateCmd,
 		projectDeactivateCmd,
+		projectPhaseCmd,
+		projectStatusCmd,
 	},
 }
 
@@ -104,6 +107,12 @@ var projectAddCmd = &bonzai.Cmd{
 			return err
 		}
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		projects, err := GetProjects()
 		if err != nil {
 			return err
@@ -132,6 +141,12 @@ var projectDeleteCmd = &bonzai.Cmd{
 		// get input from user
 		input := strings.Join(args, " ")
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		// load projects
 		projects, err := GetProjects()
 		if err != nil {
@@ -167,6 +182,12 @@ var projectActivateCmd = &bonzai.Cmd{
 		// get input from user
 		input := strings.Join(args, " ")
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		// load projects
 		projects, err := GetProjects()
 		if err != nil {
@@ -191,9 +212,11 @@ var projectActivateCmd = &bonzai.Cmd{
 		// mark the project as active
 		selection.Active = true
 		// set the phase to 1 if it is not set
-		if selection.Phase == "" {
-			selection.Phase = "1"
+		phase := selection.Phase
+		if phase == "" {
+			phase = "1"
 		}
+		selection.SetPhase(phase, time.Now())
 
 		if selection.Repo == nil {
 
@@ -233,6 +256,12 @@ var projectDeactivateCmd = &bonzai.Cmd{
 		// get input from user
 		input := strings.Join(args, " ")
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		// load projects
 		projects, err := GetProjects()
 		if err != nil {
@@ -264,6 +293,59 @@ var projectDeactivateCmd = &bonzai.Cmd{
 	},
 }
 
+var projectPhaseCmd = &bonzai.Cmd{
+	Name:    "phase",
+	Summary: "move an active project to another phase",
+	Description: `Set the phase of an active project, e.g. 'atp project phase 2'.
+The day each phase is entered is recorded as a phase<N>: label so
+'atp stats' can report the time spent per phase.`,
+	Commands: []*bonzai.Cmd{help.Cmd},
+	Call: func(cmd *bonzai.Cmd, args ...string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("usage: atp project phase <phase> [search]")
+		}
+		phase := args[0]
+		input := strings.Join(args[1:], " ")
+
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
+		// load projects
+		projects, err := GetProjects()
+		if err != nil {
+			return err
+		}
+
+		// get all active projects
+		active_projects := []*project.Project{}
+		for _, project := range projects {
+			if project.Active {
+				active_projects = append(active_projects, project)
+			}
+		}
+
+		// get the project
+		index, err := shell.FzfSearch(active_projects, input)
+		if err != nil {
+			return err
+		}
+
+		active_projects[index].SetPhase(phase, time.Now())
+
+		err = WriteProjects(projects)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Moved project %s to phase %s\n", active_projects[index].String(), phase)
+
+		return nil
+	},
+}
+
 var projectFinishCmd = &bonzai.Cmd{
 	Name:     "finish",
 	Summary:  "mark a project as completed and move to done.txt",
@@ -272,6 +354,12 @@ var projectFinishCmd = &bonzai.Cmd{
 		// get input from user
 		input := strings.Join(args, " ")
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		// load projects
 		projects, err := GetProjects()
 		if err != nil {
@@ -293,8 +381,7 @@ var projectFinishCmd = &bonzai.Cmd{
 		}
 
 		// mark the project as done
-		not_done_projects[index].Active = false
-		not_done_projects[index].Done = true
+		not_done_projects[index].Finish(time.Now())
 
 		err = WriteProjects(projects)
 
@@ -349,6 +436,12 @@ var projectReorgCmd = &bonzai.Cmd{
 		help.Cmd,
 	},
 	Call: func(cmd *bonzai.Cmd, args ...string) error {
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		// get all the projects
 		projects, err := GetProjects()
 		if err != nil {
@@ -366,3 +459,65 @@ var projectReorgCmd = &bonzai.Cmd{
 		return nil
 	},
 }
+
+var projectStatusCmd = &bonzai.Cmd{
+	Name:    "status",
+	Aliases: []string{"s"},
+	Summary: "show how far along projects are by their todos",
+	Usage:   "[--all]",
+	Description: `Show the percent complete of each active project, counting its done
+and open todos. A todo belongs to a project through a +project tag or
+the project's repo: label, and its subtasks belong to the project too.
+A todo with subtasks counts through its subtasks.
+
+--all includes inactive projects.`,
+	Commands: []*bonzai.Cmd{help.Cmd},
+	Call: func(cmd *bonzai.Cmd, args ...string) error {
+		all := false
+		for _, arg := range args {
+			switch arg {
+			case "--all":
+				all = true
+			default:
+				return fmt.Errorf("unknown argument: %s", arg)
+			}
+		}
+
+		projects, err := GetProjects()
+		if err != nil {
+			return err
+		}
+		todos, err := GetTodos()
+		if err != nil {
+			return err
+		}
+
+		var shown []*project.Project
+		width := 0
+		for _, p := range projects {
+			if p.Done || (!all && !p.Active) {
+				continue
+			}
+			shown = append(shown, p)
+			width = max(width, len(p.Name))
+		}
+		if len(shown) == 0 {
+			fmt.Println("No projects")
+			return nil
+		}
+		project.SortProjects(shown)
+
+		subtasks := todo.NewSubtasks(todos)
+		for _, p := range shown {
+			s := project.Rollup(p, todos, subtasks)
+			phase := "-"
+			if p.Phase != "" {
+				phase = p.Phase
+			}
+			filled := s.Percent() / 10
+			bar := strings.Repeat("█", filled) + strings.Repeat("░", 10-filled)
+			fmt.Printf("%-*s  phase %-3s %s %3d%% (%d/%d)\n", width, p.Name, phase, bar, s.Percent(), s.Done, s.Total)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/review"
+	"github.com/arjungandhi/atp/todo"
+	"github.com/arjungandhi/go-utils/pkg/prompt"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var ReviewCmd = &Z.Cmd{
+	Name:    "review",
+	Summary: "step back and review todos and projects",
+	Commands: []*Z.Cmd{
+		help.Cmd,
+		reviewWeeklyCmd,
+	},
+}
+
+var reviewWeeklyCmd = &Z.Cmd{
+	Name:    "weekly",
+	Aliases: []string{"w"},
+	Summary: "walk through a GTD style weekly review",
+	Description: `Walk through the weekly review step by step:
+
+  1. todos completed in the last 7 days
+  2. open todos with no activity for a while
+  3. active projects without an open next action
+  4. active projects nothing was completed for: keep, deactivate or kill
+  5. reminders and recurring todos coming up
+
+A summary is written to $ATP_DIR/reviews/<date>-weekly.md.
+
+Flags:
+  --stale-days N  days without activity before a todo is stale (default 14)
+  --horizon N     days ahead to show reminders and recurring todos (default 7)
+  --no-prompt     don't ask about projects, just write the summary`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		var opts review.Options
+		ask := true
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--stale-days", "--horizon":
+				if i+1 >= len(args) {
+					return fmt.Errorf("%s needs a number of days", args[i])
+				}
+				days, err := strconv.Atoi(args[i+1])
+				if err != nil || days <= 0 {
+					return fmt.Errorf("invalid number of days: %s", args[i+1])
+				}
+				if args[i] == "--stale-days" {
+					opts.StaleDays = days
+				} else {
+					opts.Horizon = days
+				}
+				i++
+			case "--no-prompt":
+				ask = false
+			default:
+				return fmt.Errorf("unknown argument: %s", args[i])
+			}
+		}
+
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+		todoDir, err := TodoDir()
+		if err != nil {
+			return err
+		}
+
+		todos, err := todo.LoadTodoDir(todoDir)
+		if err != nil {
+			return fmt.Errorf("failed to load todos: %w", err)
+		}
+		reminders, err := todo.LoadReminderTasks(todo.ReminderTasksPath(todoDir))
+		if err != nil {
+			return fmt.Errorf("failed to load reminders: %w", err)
+		}
+		recurring, err := todo.LoadRecurringTasks(todo.RecurringTasksPath(todoDir))
+		if err != nil {
+			return fmt.Errorf("failed to load recurring tasks: %w", err)
+		}
+		projects, err := loadProjectsForReview()
+		if err != nil {
+			return err
+		}
+
+		w := review.NewWeekly(time.Now(), opts, todos, reminders, recurring, projects)
+
+		reviewStep(1, fmt.Sprintf("Completed this week (%d)", len(w.Completed)), w.Completed)
+		reviewStep(2, fmt.Sprintf("Stale, no activity for %d days (%d)", w.Options.StaleDays, len(w.Stale)), w.Stale)
+
+		fmt.Printf("\n3. Active projects without a next action (%d)\n", len(w.NoNextAction))
+		for _, p := range w.NoNextAction {
+			fmt.Printf("   %s\n", p.TodoString())
+		}
+
+		fmt.Printf("\n4. Active projects with nothing completed this week (%d)\n", len(w.Inactive))
+		if ask && len(w.Inactive) > 0 {
+			if err := decideProjects(w); err != nil {
+				return err
+			}
+		} else {
+			for _, p := range w.Inactive {
+				fmt.Printf("   %s\n", p.TodoString())
+			}
+		}
+
+		reviewStep(5, fmt.Sprintf("Reminders in the next %d days (%d)", w.Options.Horizon, len(w.Reminders)), w.Reminders)
+		for _, o := range w.Recurring {
+			fmt.Printf("   %s %s (%d times)\n", o.Task.ScheduleStr, o.Task.Todo.Description, len(o.Dates))
+		}
+
+		path, err := w.Write(atpDir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n✓ Review written to %s\n", path)
+		return nil
+	},
+}
+
+func reviewStep(n int, title string, todos []*todo.Todo) {
+	fmt.Printf("\n%d. %s\n", n, title)
+	for _, t := range todos {
+		fmt.Printf("   %s\n", t.String())
+	}
+}
+
+// decideProjects asks what to do with each inactive project and saves the
+// answers. The projects are reloaded under the lock once every answer is in,
+// so changes made while the user was answering aren't overwritten.
+func decideProjects(w *review.Weekly) error {
+	changed := false
+	for _, p := range w.Inactive {
+		fmt.Printf("   %s\n", p.TodoString())
+		// an empty answer keeps the project
+		answer, _ := prompt.PromptString("[k]eep, [d]eactivate or kill [x]?")
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "", "k", "keep":
+			w.Decide(p, review.DecisionKeep)
+		case "d", "deactivate":
+			w.Decide(p, review.DecisionDeactivate)
+			changed = true
+		case "x", "kill":
+			w.Decide(p, review.DecisionKill)
+			changed = true
+		default:
+			fmt.Printf("   unknown answer '%s', keeping %s\n", answer, p.Name)
+			w.Decide(p, review.DecisionKeep)
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	l, err := LockAtpDir()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	projects, err := loadProjectsForReview()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*project.Project)
+	for _, p := range projects {
+		byName[p.Name] = p
+	}
+
+	for _, d := range w.Decisions {
+		p, ok := byName[d.Project]
+		if !ok {
+			if d.Action != review.DecisionKeep {
+				fmt.Printf("   project %s is gone, not applying %s\n", d.Project, d.Action)
+			}
+			continue
+		}
+		switch d.Action {
+		case review.DecisionDeactivate:
+			p.Active = false
+		case review.DecisionKill:
+			p.Kill(time.Now())
+		}
+	}
+
+	return WriteProjects(projects)
+}
+
+// loadProjectsForReview loads the projects even when $REPOS isn't set, the
+// review doesn't need the local clones
+func loadProjectsForReview() ([]*project.Project, error) {
+	projectDir, err := ProjectDir()
+	if err != nil {
+		return nil, err
+	}
+
+	repos, err := GetRepos()
+	if err != nil {
+		repos = nil
+	}
+
+	projects, err := project.LoadProjectsDir(projectDir, repos)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+	return projects, nil
+}
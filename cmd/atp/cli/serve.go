@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/arjungandhi/atp/caldav"
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/contexts"
+	"github.com/arjungandhi/atp/events"
+	"github.com/arjungandhi/atp/github"
+	"github.com/arjungandhi/atp/server"
+	"github.com/arjungandhi/atp/store"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+// tokenFile keeps the generated API token between runs
+const tokenFile = "serve_token"
+
+var ServeCmd = &Z.Cmd{
+	Name:    "serve",
+	Summary: "serve todos, reminders and projects as a JSON API",
+	Usage:   "[--addr HOST:PORT] [--token TOKEN]",
+	Description: `Start an HTTP server for phone shortcuts, widgets and editor plugins.
+Every request needs an "Authorization: Bearer <token>" header. The token
+comes from --token, [serve] token in config.toml or $ATP_TOKEN; without one
+a token is generated and kept in $ATP_DIR/serve_token.
+
+  GET    /todos?q=FILTER           todos matching an 'atp todo list' filter
+  POST   /todos                    add {"text": "..."} or a todo object
+  GET    /todos/{id}
+  PUT    /todos/{id}               replace the todo
+  POST   /todos/{id}/complete
+  DELETE /todos/{id}
+  GET    /reminders?q=FILTER
+  POST   /reminders                add a todo with a remind: label
+  DELETE /reminders/{id}
+  GET    /projects?q=FILTER
+  POST   /recur, /remind, /sync    run the daily processors or a sync
+  GET    /events?types=TYPE,...    server-sent events as todos change
+  GET    /schema/todo, /schema/project
+
+Responses carry an ETag of the files they were read from. Send it back in
+If-Match and a change fails with 412 when the files changed in between.
+
+The server listens on 127.0.0.1:7878 unless --addr or [serve] addr say
+otherwise.`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.LoadConfig(atpDir)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		addr, token := cfg.Serve.Addr, cfg.Serve.Token
+		if addr == "" {
+			addr = config.DefaultServeAddr
+		}
+		if token == "" {
+			token = os.Getenv("ATP_TOKEN")
+		}
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--addr", "--token":
+				if i+1 >= len(args) {
+					return fmt.Errorf("%s needs a value", args[i])
+				}
+				if args[i] == "--addr" {
+					addr = args[i+1]
+				} else {
+					token = args[i+1]
+				}
+				i++
+			default:
+				return fmt.Errorf("unknown argument: %s", args[i])
+			}
+		}
+		if token == "" {
+			if token, err = generatedToken(atpDir); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Using the token in %s\n", filepath.Join(atpDir, tokenFile))
+		}
+
+		// repos are only used for project lookups, so a missing repo dir is fine
+		repos, _ := GetRepos()
+		srv := server.New(store.New(atpDir, repos), token)
+		srv.Sync = func(ctx context.Context, lock func(fn func() error) error) error {
+			return syncAll(ctx, atpDir, lock)
+		}
+		srv.Events = events.NewBus()
+		if srv.Contexts, err = contexts.FromConfig(cfg.Contexts); err != nil {
+			return err
+		}
+
+		httpServer := &http.Server{Addr: addr, Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// events from every atp process reach the stream through the log
+		go func() {
+			err := events.Follow(ctx, atpDir, events.End(atpDir), time.Second, srv.Events.Publish)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: stopped following events: %v\n", err)
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdown)
+		}()
+
+		fmt.Fprintf(os.Stderr, "Serving %s on http://%s\n", atpDir, addr)
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to serve: %w", err)
+		}
+		return nil
+	},
+}
+
+// generatedToken reads the token kept in the ATP directory, creating it on
+// first use
+func generatedToken(atpDir string) (string, error) {
+	path := filepath.Join(atpDir, tokenFile)
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate a token: %w", err)
+	}
+	token := hex.EncodeToString(random)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return token, nil
+}
+
+// syncAll runs the configured CalDAV and GitHub syncs like the daemon does.
+// lock is only taken to read and write files, not across requests.
+func syncAll(ctx context.Context, atpDir string, lock func(fn func() error) error) error {
+	cfg, err := config.LoadConfig(atpDir)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	todoDir := filepath.Join(atpDir, "todo")
+	github.Output = os.Stderr
+
+	if cfg.CalDAV.Enabled() {
+		client, err := caldav.NewClient(cfg.CalDAV)
+		if err != nil {
+			return err
+		}
+		err = runSync("caldav", func() error {
+			_, err := caldav.Sync(ctx, client, todoDir, atpDir, caldav.Options{Prefer: cfg.CalDAV.Prefer, Lock: lock})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("caldav sync failed: %w", err)
+		}
+	}
+
+	// nothing configured, queued operations are still worth sending
+	if len(cfg.GetAllGitHubProjects()) == 0 {
+		_, err := github.ReplayOutboxUnlocked(todoDir, false, lock)
+		return err
+	}
+
+	return runSync("github", func() error {
+		plan, err := github.PlanAllGitHubProjects(todoDir)
+		if err != nil {
+			return err
+		}
+		return github.ApplySyncUnlocked(todoDir, plan, lock)
+	})
+}
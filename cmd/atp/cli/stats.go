@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/arjungandhi/atp/stats"
+	"github.com/arjungandhi/atp/todo"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var StatsCmd = &Z.Cmd{
+	Name:    "stats",
+	Summary: "productivity statistics from done todos and projects",
+	Description: `Report on completed todos and projects:
+
+  - todos completed per day, week and month
+  - lead time from creation to completion
+  - completions by +project and @context
+  - how many generated recurring todos got done
+  - projects finished per quarter and the average time per phase
+
+Phase times come from the phase<N>: labels 'atp project activate' and
+'atp project phase' record.
+
+Flags:
+  --days N    days in the daily chart (default 14)
+  --weeks N   weeks in the weekly chart (default 12)
+  --months N  months in the monthly chart (default 12)
+  --csv       print metric,label,value rows for plotting
+  --json      print the report as JSON`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		var opts stats.Options
+		format := "text"
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--days", "--weeks", "--months":
+				if i+1 >= len(args) {
+					return fmt.Errorf("%s needs a number", args[i])
+				}
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n <= 0 {
+					return fmt.Errorf("invalid number: %s", args[i+1])
+				}
+				switch args[i] {
+				case "--days":
+					opts.Days = n
+				case "--weeks":
+					opts.Weeks = n
+				case "--months":
+					opts.Months = n
+				}
+				i++
+			case "--csv":
+				format = "csv"
+			case "--json":
+				format = "json"
+			default:
+				return fmt.Errorf("unknown argument: %s", args[i])
+			}
+		}
+
+		todoDir, err := TodoDir()
+		if err != nil {
+			return err
+		}
+		todos, err := todo.LoadTodoDir(todoDir)
+		if err != nil {
+			return fmt.Errorf("failed to load todos: %w", err)
+		}
+		projects, err := loadProjectsForReview()
+		if err != nil {
+			return err
+		}
+
+		report := stats.Compute(todos, projects, time.Now(), opts)
+
+		switch format {
+		case "csv":
+			return report.WriteCSV(os.Stdout)
+		case "json":
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode stats: %w", err)
+			}
+			fmt.Println(string(data))
+		default:
+			report.WriteText(os.Stdout)
+		}
+		return nil
+	},
+}
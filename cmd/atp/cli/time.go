@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/timelog"
+	"github.com/arjungandhi/atp/todo"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var taskStartCmd = &Z.Cmd{
+	Name:    "start",
+	Summary: "start a timer on a todo, stopping the running one",
+	Usage:   "<id|words...>",
+	Description: `Start tracking time on a todo. The todo is picked by its id: label or
+by words from its description that match only one open todo. Todos
+without an id get the next free one.
+
+Only one timer runs at a time, starting a timer stops the running one.
+Stopped time is added to the todo's spent: label and completing a todo
+stops its timer. The intervals are kept in $ATP_DIR/timelog.jsonl.`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("usage: atp todo start <id|words...>")
+		}
+
+		now := time.Now()
+		return changeTimeLog(func(todos []*todo.Todo, entries []*timelog.Entry) ([]*timelog.Entry, error) {
+			t, err := resolveTodo(todos, args)
+			if err != nil {
+				return nil, err
+			}
+			if active := timelog.Active(entries); active != nil && active.ID == t.ID() {
+				return nil, fmt.Errorf("already tracking %s", t.Description)
+			}
+
+			entries, stopped := timelog.Start(entries, todos, t, now)
+			if stopped != nil {
+				printStopped(stopped, todos)
+			}
+			fmt.Printf("Started: %s\n", t.String())
+			return entries, nil
+		})
+	},
+}
+
+var taskStopCmd = &Z.Cmd{
+	Name:     "stop",
+	Summary:  "stop the running timer",
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		now := time.Now()
+		return changeTimeLog(func(todos []*todo.Todo, entries []*timelog.Entry) ([]*timelog.Entry, error) {
+			stopped, err := timelog.Stop(entries, todos, now)
+			if err != nil {
+				return nil, err
+			}
+			printStopped(stopped, todos)
+			return entries, nil
+		})
+	},
+}
+
+var taskTimeCmd = &Z.Cmd{
+	Name:    "time",
+	Aliases: []string{"spent"},
+	Summary: "report the time spent per project, repo and phase",
+	Description: `Sum the time log per +project, per repo and per project phase, and
+compare the est: labels of todos with the time spent on them. Estimates
+are durations like est:2h, est:90m or est:1d (8 hours).
+
+A todo's repo is its repo: label or the repo of a project it belongs to.
+Its phase is the phase the project was in when the work was done.
+
+Flags:
+  --today       only time logged today
+  --week        only time logged this week
+  --since DATE  only time logged since DATE (YYYY-MM-DD)
+  --json        print the report as JSON`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		now := time.Now()
+		var since time.Time
+		asJSON := false
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--today":
+				since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			case "--week":
+				today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+				since = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+			case "--since":
+				if i+1 >= len(args) {
+					return fmt.Errorf("--since needs a date")
+				}
+				date, err := time.ParseInLocation("2006-01-02", args[i+1], now.Location())
+				if err != nil {
+					return fmt.Errorf("invalid date: %s", args[i+1])
+				}
+				since = date
+				i++
+			case "--json":
+				asJSON = true
+			default:
+				return fmt.Errorf("unknown argument: %s", args[i])
+			}
+		}
+
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+		entries, err := timelog.Load(atpDir)
+		if err != nil {
+			return err
+		}
+		todos, err := GetTodos()
+		if err != nil {
+			return err
+		}
+		// without projects the report only lacks the phases
+		projects, err := loadProjectsForReview()
+		if errors.Is(err, fs.ErrNotExist) {
+			projects = nil
+		} else if err != nil {
+			return err
+		}
+
+		report := timelog.Compute(entries, todos, projects, since, now)
+		if asJSON {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		report.WriteText(os.Stdout, now)
+		return nil
+	},
+}
+
+// changeTimeLog applies fn to the todos and the time log under the lock.
+// The log is written first so writing the todos doesn't stop the timer a
+// second time, it is put back when writing the todos fails.
+func changeTimeLog(fn func(todos []*todo.Todo, entries []*timelog.Entry) ([]*timelog.Entry, error)) error {
+	l, err := LockAtpDir()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	atpDir, err := AtpDir()
+	if err != nil {
+		return err
+	}
+	todos, err := GetTodos()
+	if err != nil {
+		return err
+	}
+	previous, err := timelog.Load(atpDir)
+	if err != nil {
+		return err
+	}
+	// fn changes entries in place, previous stays as it was
+	entries, err := timelog.Load(atpDir)
+	if err != nil {
+		return err
+	}
+
+	entries, err = fn(todos, entries)
+	if err != nil {
+		return err
+	}
+	if err := timelog.Write(atpDir, entries); err != nil {
+		return err
+	}
+	if err := WriteTodos(todos); err != nil {
+		return errors.Join(err, timelog.Write(atpDir, previous))
+	}
+	return nil
+}
+
+// resolveTodo finds an open todo by its id: label or by words from its
+// description
+func resolveTodo(todos []*todo.Todo, args []string) (*todo.Todo, error) {
+	if len(args) == 1 {
+		if t := todo.FindID(todos, args[0]); t != nil {
+			if t.Done {
+				return nil, fmt.Errorf("todo %s is done", args[0])
+			}
+			return t, nil
+		}
+	}
+
+	words := strings.ToLower(strings.Join(args, " "))
+	var matches []*todo.Todo
+	for _, t := range todos {
+		if !t.Done && strings.Contains(strings.ToLower(t.Description), words) {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no open todo matches '%s'", strings.Join(args, " "))
+	case 1:
+		return matches[0], nil
+	default:
+		var lines []string
+		for _, t := range matches {
+			lines = append(lines, "  "+t.String())
+		}
+		return nil, fmt.Errorf("'%s' matches %d todos, use more words or an id:\n%s",
+			strings.Join(args, " "), len(matches), strings.Join(lines, "\n"))
+	}
+}
+
+func printStopped(e *timelog.Entry, todos []*todo.Todo) {
+	description := todo.FromString(e.Todo).Description
+	spent := ""
+	if t := todo.FindID(todos, e.ID); t != nil {
+		spent = fmt.Sprintf(", %s in total", t.Labels[timelog.SpentLabel])
+	}
+	fmt.Printf("Stopped: %s after %s%s\n", description, timelog.FormatDuration(e.Duration(e.End)), spent)
+}
@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/github"
+	"github.com/arjungandhi/atp/todo"
+	fzf "github.com/junegunn/fzf/src"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var TodayCmd = &Z.Cmd{
+	Name:    "today",
+	Summary: "plan the day: recur, remind, sync and pick a focus list",
+	Description: `Run the daily processors in one go:
+
+  1. generate today's recurring todos
+  2. move due reminders into the todo list
+  3. sync the configured GitHub projects
+
+then rank what needs attention (overdue, due today, priority A, new
+reminders, review requests) and pick today's focus set with fzf. Picked
+todos get the @today context, focused todos that aren't picked again lose
+it. Finally a compact agenda for the rest of the day is printed.
+
+Flags:
+  --offline  skip the GitHub sync
+  --no-pick  keep the current focus set and only print the agenda`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		var offline, pick = false, true
+		for _, arg := range args {
+			switch arg {
+			case "--offline":
+				offline = true
+			case "--no-pick":
+				pick = false
+			default:
+				return fmt.Errorf("unknown argument: %s", arg)
+			}
+		}
+
+		todoDir, err := TodoDir()
+		if err != nil {
+			return err
+		}
+
+		date := time.Now()
+		reminders, err := runDailyProcessors(todoDir, date, offline)
+		if err != nil {
+			return err
+		}
+
+		todos, err := todo.LoadTodoDir(todoDir)
+		if err != nil {
+			return fmt.Errorf("failed to load todos: %w", err)
+		}
+
+		items := todo.RankForToday(todos, reminders, date)
+		printRanking(items)
+
+		if pick && len(items) > 0 {
+			if err := pickFocus(todoDir, todos, items, date); err != nil {
+				return err
+			}
+			if todos, err = todo.LoadTodoDir(todoDir); err != nil {
+				return fmt.Errorf("failed to load todos: %w", err)
+			}
+		}
+
+		printAgenda(todos, date)
+		return nil
+	},
+}
+
+// runDailyProcessors does what used to be recur, remind and sync by hand and
+// returns the reminders that became todos. A failed sync only warns, the
+// rest of the day can still be planned offline.
+func runDailyProcessors(todoDir string, date time.Time, offline bool) ([]*todo.Todo, error) {
+	l, err := LockAtpDir()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Release()
+
+	defs, _, err := loadContexts()
+	if err != nil {
+		return nil, err
+	}
+	if err := todo.AddRecurringTodosToDir(todoDir, date, defs); err != nil {
+		return nil, fmt.Errorf("failed to generate recurring todos: %w", err)
+	}
+
+	reminders, err := todo.ActivateReminders(todoDir, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process reminders: %w", err)
+	}
+
+	if offline {
+		return reminders, nil
+	}
+
+	atpDir, err := AtpDir()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadConfig(atpDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if len(cfg.GetAllGitHubProjects()) == 0 {
+		return reminders, nil
+	}
+
+	// keep the progress chatter out of the way of the agenda
+	github.Output = os.Stderr
+	plan, err := github.PlanAllGitHubProjects(todoDir)
+	if err == nil {
+		err = runSync("github", func() error { return github.ApplySync(todoDir, plan) })
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: GitHub sync failed: %v\n", err)
+	}
+
+	return reminders, nil
+}
+
+func printRanking(items []todo.AgendaItem) {
+	if len(items) == 0 {
+		fmt.Println("Nothing pressing today")
+		return
+	}
+
+	section := ""
+	for _, item := range items {
+		if item.Section != section {
+			section = item.Section
+			fmt.Printf("\n%s\n", section)
+		}
+		fmt.Printf("  %s\n", item.Todo.String())
+	}
+	fmt.Println()
+}
+
+// pickFocus lets the user pick today's focus set. Cancelling fzf keeps the
+// current one.
+func pickFocus(todoDir string, todos []*todo.Todo, items []todo.AgendaItem, date time.Time) error {
+	// the ranked todos first, then the rest of the open ones
+	var candidates []*todo.Todo
+	ranked := make(map[*todo.Todo]bool)
+	for _, item := range items {
+		candidates = append(candidates, item.Todo)
+		ranked[item.Todo] = true
+	}
+	for _, t := range todos {
+		if !t.Done && !ranked[t] {
+			candidates = append(candidates, t)
+		}
+	}
+
+	picked, err := fzfPick(candidates, "Pick today's focus (TAB to select, ENTER to confirm)")
+	if err != nil || picked == nil {
+		return err
+	}
+
+	focus := make(map[string]bool)
+	for _, i := range picked {
+		focus[candidates[i].String()] = true
+	}
+
+	l, err := LockAtpDir()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	// reload, the daemon may have written while fzf was open
+	current, err := todo.LoadTodoDir(todoDir)
+	if err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+	for _, t := range current {
+		if focus[t.String()] {
+			todo.SetFocus(t, date, true)
+		} else if todo.IsFocused(t, date) {
+			todo.SetFocus(t, date, false)
+		}
+	}
+
+	return WriteTodos(current)
+}
+
+// fzfPick lets the user select any number of options. It returns nil when
+// fzf is cancelled.
+func fzfPick[T fmt.Stringer](opts []T, header string) ([]int, error) {
+	inputChan := make(chan string)
+	go func() {
+		for i, opt := range opts {
+			inputChan <- fmt.Sprintf("%d\t%s", i, opt.String())
+		}
+		close(inputChan)
+	}()
+
+	var selected []string
+	outputChan := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		for out := range outputChan {
+			selected = append(selected, out)
+		}
+		close(done)
+	}()
+
+	fzfOpts, err := fzf.ParseOptions(true, []string{
+		"--multi",
+		"--delimiter=\t",
+		"--with-nth=2",
+		"--layout=reverse",
+		"--header=" + header,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start fzf: %w", err)
+	}
+	fzfOpts.Input = inputChan
+	fzfOpts.Output = outputChan
+
+	code, err := fzf.Run(fzfOpts)
+	close(outputChan)
+	<-done
+	if err != nil {
+		return nil, fmt.Errorf("fzf failed: %w", err)
+	}
+	if code == fzf.ExitInterrupt {
+		return nil, nil
+	}
+
+	picked := []int{}
+	for _, line := range selected {
+		var i int
+		if _, err := fmt.Sscanf(line, "%d\t", &i); err != nil {
+			return nil, fmt.Errorf("unexpected fzf output '%s'", line)
+		}
+		picked = append(picked, i)
+	}
+	return picked, nil
+}
+
+// printAgenda shows the focus set and whatever else is due today
+func printAgenda(todos []*todo.Todo, date time.Time) {
+	today := date.Format("2006-01-02")
+
+	var focus, due []*todo.Todo
+	for _, t := range todos {
+		switch {
+		case t.Done:
+		case todo.IsFocused(t, date):
+			focus = append(focus, t)
+		case t.Labels["due"] != "" && t.Labels["due"] <= today:
+			due = append(due, t)
+		}
+	}
+
+	fmt.Printf("Agenda for %s\n", date.Format("Monday, January 2"))
+	if len(focus) == 0 && len(due) == 0 {
+		fmt.Println("  nothing planned")
+		return
+	}
+	for _, t := range focus {
+		fmt.Printf("  ★ %s\n", agendaLine(t, today))
+	}
+	for _, t := range due {
+		fmt.Printf("  · %s\n", agendaLine(t, today))
+	}
+}
+
+// agendaLine is the description with the bits that matter today
+func agendaLine(t *todo.Todo, today string) string {
+	var parts []string
+	if t.Priority != "" {
+		parts = append(parts, "("+t.Priority+")")
+	}
+	parts = append(parts, t.Description)
+	if due := t.Labels["due"]; due != "" {
+		if due < today {
+			parts = append(parts, "[overdue "+due+"]")
+		} else if due == today {
+			parts = append(parts, "[due today]")
+		}
+	}
+	if url := t.Labels["url"]; url != "" {
+		parts = append(parts, url)
+	}
+	return strings.Join(parts, " ")
+}
@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -22,9 +25,16 @@ var TodoCmd = &bonzai.Cmd{
 		help.Cmd,
 		taskEditCmd,
 		taskAddCmd,
+		taskDoneCmd,
+		taskListCmd,
+		taskStartCmd,
+		taskStopCmd,
+		taskTimeCmd,
+		taskGraphCmd,
 		recurCmd,
 		remindCmd,
 		githubCmd,
+		caldavCmd,
 	},
 }
 
@@ -43,9 +53,7 @@ var taskEditCmd = &bonzai.Cmd{
 		todo_path := todo.ActiveTodoPath(path)
 
 		// Open the tasks file in the editor
-		shell.OpenInEditor(todo_path)
-
-		return nil
+		return EditTodoFiles(todo_path)
 	},
 }
 
@@ -66,9 +74,7 @@ var taskEditAllCmd = &bonzai.Cmd{
 		done_path := todo.DoneTodoPath(path)
 
 		// Open the tasks file in the editor
-		shell.OpenInEditor(active_path, done_path)
-
-		return nil
+		return EditTodoFiles(active_path, done_path)
 	},
 }
 
@@ -93,6 +99,12 @@ var taskAddCmd = &bonzai.Cmd{
 		// convert this string to a todo task
 		input_todo := todo.FromString(task_str)
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		todos, err := GetTodos()
 		if err != nil {
 			return err
@@ -102,7 +114,9 @@ var taskAddCmd = &bonzai.Cmd{
 		todos = append(todos, input_todo)
 
 		// write the todos to the file
-		WriteTodos(todos)
+		if err := WriteTodos(todos); err != nil {
+			return err
+		}
 
 		// print confirmation message
 		fmt.Printf("Added task: %s\n", input_todo.String())
@@ -110,10 +124,97 @@ var taskAddCmd = &bonzai.Cmd{
 	},
 }
 
+var taskListCmd = &bonzai.Cmd{
+	Name:    "list",
+	Aliases: []string{"l", "ls"},
+	Summary: "list todos matching a filter",
+	Description: `List todos in todo.txt format. Filter arguments are combined, a
+todo has to match all of them:
+
+  +project    has the project tag
+  @context    has the context
+  key:value   has the label, key:* only needs the key
+  (A), (A-C)  has the priority or one in the range
+  --due DATE  is due on or before DATE (YYYY-MM-DD or today)
+  --done      only completed todos
+  --all       open and completed todos
+  --blocked   only todos waiting for an open dep: or blocks: todo
+  words       the description contains the word
+
+Only open todos that aren't blocked are listed by default. 'atp export'
+takes the same filters and keeps blocked todos unless --actionable is
+given.
+
+Subtasks, todos with a parent: label, are indented below their parent
+and parents end with their progress, e.g. (3/5). --flat lists every todo
+on its own line as it is in todo.txt.`,
+	Commands: []*bonzai.Cmd{help.Cmd},
+	Call: func(cmd *bonzai.Cmd, args ...string) error {
+		flat := false
+		var filterArgs []string
+		for _, arg := range args {
+			if arg == "--flat" {
+				flat = true
+				continue
+			}
+			filterArgs = append(filterArgs, arg)
+		}
+
+		filter, err := todo.ParseFilter(filterArgs)
+		if err != nil {
+			return err
+		}
+
+		todos, err := GetTodos()
+		if err != nil {
+			return err
+		}
+
+		// open todos waiting for others aren't actionable, hide them
+		hidden := 0
+		if filter.Status == todo.StatusOpen && filter.Blocked == todo.BlockedShow {
+			filter.Blocked = todo.BlockedOnly
+			hidden = len(filter.Apply(todos))
+			filter.Blocked = todo.BlockedHide
+		}
+
+		selected := filter.Apply(todos)
+		if len(selected) == 0 {
+			fmt.Println("No matching todos")
+		}
+		if flat {
+			for _, t := range selected {
+				fmt.Println(t.String())
+			}
+		} else {
+			subtasks := todo.NewSubtasks(todos)
+			for _, n := range todo.Flatten(todo.Tree(selected)) {
+				line := strings.Repeat("  ", n.Depth) + n.Todo.String()
+				if progress := subtasks.ProgressString(n.Todo); progress != "" {
+					line += " " + progress
+				}
+				fmt.Println(line)
+			}
+		}
+		if hidden > 0 {
+			fmt.Printf("(%d blocked, --blocked lists them)\n", hidden)
+		}
+		return nil
+	},
+}
+
 var recurCmd = &bonzai.Cmd{
 	Name:    "recur",
 	Aliases: []string{"r"},
 	Summary: "generate recurring todos for today or manage recurring templates",
+	Description: `Generate today's todos from the templates in recur.txt, a schedule
+(@daily, @weekly, @monthly or a cron expression) followed by the todo:
+
+  @weekly Buy groceries @errands
+
+A template with a @context defined in config.toml waits for a day the
+context is open, the weekly @errands todo above comes on Saturday when
+errands are weekends only.`,
 	Commands: []*bonzai.Cmd{
 		help.Cmd,
 		recurEditCmd,
@@ -124,8 +225,19 @@ var recurCmd = &bonzai.Cmd{
 			return err
 		}
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
+		defs, _, err := loadContexts()
+		if err != nil {
+			return err
+		}
+
 		today := time.Now()
-		err = todo.AddRecurringTodosToDir(path, today)
+		err = todo.AddRecurringTodosToDir(path, today, defs)
 		if err != nil {
 			return fmt.Errorf("failed to generate recurring todos: %w", err)
 		}
@@ -180,6 +292,12 @@ var remindCmd = &bonzai.Cmd{
 			processDate = time.Now()
 		}
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		err = todo.ProcessReminders(path, processDate)
 		if err != nil {
 			return fmt.Errorf("failed to process reminders: %w", err)
@@ -239,6 +357,12 @@ var remindAddCmd = &bonzai.Cmd{
 			return err
 		}
 
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
 		err = todo.AddReminderTask(path, reminder)
 		if err != nil {
 			return err
@@ -309,6 +433,8 @@ var githubCmd = &bonzai.Cmd{
 	Commands: []*bonzai.Cmd{
 		help.Cmd,
 		githubSyncCmd,
+		githubPushCmd,
+		githubQueueCmd,
 	},
 }
 
@@ -327,7 +453,11 @@ To add more projects, edit the [[github.projects]] sections:
   project_number = 123
   status_filters = ["Todo", "In Progress"]
 
-Each project becomes a subcommand: 'atp todo github sync myproject'`,
+Each project becomes a subcommand: 'atp todo github sync myproject'
+
+Flags:
+  --dry-run  print the planned changes without touching todo.txt or GitHub
+  --json     with --dry-run, print the plan as JSON`,
 	Commands: []*bonzai.Cmd{help.Cmd},
 	Call: func(cmd *bonzai.Cmd, args ...string) error {
 		todoDir, err := TodoDir()
@@ -335,9 +465,32 @@ Each project becomes a subcommand: 'atp todo github sync myproject'`,
 			return err
 		}
 
-		fmt.Printf("Syncing all configured projects (assigned to you)...\n")
-		
-		err = github.SyncAllGitHubProjects(todoDir)
+		opts, err := parseSyncFlags(args)
+		if err != nil {
+			return err
+		}
+		opts.prepare()
+
+		if !opts.dryRun {
+			l, err := LockAtpDir()
+			if err != nil {
+				return err
+			}
+			defer l.Release()
+		}
+
+		fmt.Fprintf(github.Output, "Syncing all configured projects (assigned to you)...\n")
+
+		plan, err := github.PlanAllGitHubProjects(todoDir)
+		if err != nil {
+			return fmt.Errorf("sync failed: %w", err)
+		}
+
+		if opts.dryRun {
+			return printSyncPlan(plan, opts.json)
+		}
+
+		err = runSync("github", func() error { return github.ApplySync(todoDir, plan) })
 		if err != nil {
 			return fmt.Errorf("sync failed: %w", err)
 		}
@@ -347,6 +500,174 @@ Each project becomes a subcommand: 'atp todo github sync myproject'`,
 	},
 }
 
+var githubPushCmd = &bonzai.Cmd{
+	Name:     "push",
+	Aliases:  []string{"p"},
+	Summary:  "create GitHub issues from local todos",
+	Description: `Create a GitHub issue for every open todo tagged +github with a
+repo:owner/name label and no url: label yet, e.g.
+
+  Add dark mode +github repo:me/app
+
+The description becomes the issue title and the issue is assigned to you.
+If a configured project covers the repo, the issue is added to its board
+with status "In Progress" for priority A and "Planned-This-Week" otherwise.
+The issue: and url: labels are written back so the next sync tracks it.
+
+Flags:
+  --dry-run  list the todos that would be pushed`,
+	Commands: []*bonzai.Cmd{help.Cmd},
+	Call: func(cmd *bonzai.Cmd, args ...string) error {
+		todoDir, err := TodoDir()
+		if err != nil {
+			return err
+		}
+
+		opts, err := parseSyncFlags(args)
+		if err != nil {
+			return err
+		}
+		if opts.json {
+			return fmt.Errorf("--json is not supported by push")
+		}
+
+		if opts.dryRun {
+			todos, err := todo.LoadTodoDir(todoDir)
+			if err != nil {
+				return fmt.Errorf("failed to load todos: %w", err)
+			}
+
+			unpushed := github.FindUnpushedTodos(todos)
+			if len(unpushed) == 0 {
+				fmt.Println("No todos to push")
+				return nil
+			}
+			for _, t := range unpushed {
+				fmt.Printf("  create  %s -> %s\n", t.Description, t.Labels["repo"])
+			}
+			return nil
+		}
+
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
+		pushed, err := github.PushTodos(todoDir)
+		if err != nil {
+			return fmt.Errorf("push failed: %w", err)
+		}
+
+		if len(pushed) == 0 {
+			fmt.Println("No todos pushed")
+			return nil
+		}
+
+		for _, p := range pushed {
+			if p.Project != "" {
+				fmt.Printf("✓ %s (added to %s)\n", p.Todo.Labels["url"], p.Project)
+			} else {
+				fmt.Printf("✓ %s\n", p.Todo.Labels["url"])
+			}
+		}
+		return nil
+	},
+}
+
+var githubQueueCmd = &bonzai.Cmd{
+	Name:     "queue",
+	Aliases:  []string{"q"},
+	Summary:  "inspect GitHub changes waiting to be sent",
+	Description: `Closing issues, updating board statuses, marking notifications
+read and creating issues are queued in github_outbox.json before they are
+sent. Operations that fail, e.g. while offline, are retried with backoff on
+the next sync or push.
+
+  atp todo github queue                list pending operations
+  atp todo github queue retry          send all pending operations now
+  atp todo github queue discard <id>   drop an operation
+  atp todo github queue discard --all  drop every pending operation
+
+A todo whose issue creation is discarded can be pushed again.`,
+	Commands: []*bonzai.Cmd{help.Cmd},
+	Call: func(cmd *bonzai.Cmd, args ...string) error {
+		todoDir, err := TodoDir()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return printQueue(filepath.Dir(todoDir))
+		}
+
+		l, err := LockAtpDir()
+		if err != nil {
+			return err
+		}
+		defer l.Release()
+
+		switch args[0] {
+		case "retry":
+			delivered, err := github.ReplayOutbox(todoDir, true)
+			if err != nil {
+				return fmt.Errorf("retry failed: %w", err)
+			}
+			for _, op := range delivered {
+				fmt.Printf("✓ %s %s\n", op.Action, op.Target())
+			}
+			return printQueue(filepath.Dir(todoDir))
+		case "discard":
+			if len(args) != 2 {
+				return fmt.Errorf("usage: atp todo github queue discard <id>|--all")
+			}
+			ids := []string{args[1]}
+			if args[1] == "--all" {
+				outbox, err := github.LoadOutbox(filepath.Dir(todoDir))
+				if err != nil {
+					return err
+				}
+				ids = nil
+				for _, op := range outbox.Pending() {
+					ids = append(ids, op.ID)
+				}
+			}
+			for _, id := range ids {
+				op, err := github.DiscardOperation(todoDir, id)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Discarded %s %s\n", op.Action, op.Target())
+			}
+			return nil
+		default:
+			return fmt.Errorf("unknown argument: %s", args[0])
+		}
+	},
+}
+
+func printQueue(atpDir string) error {
+	outbox, err := github.LoadOutbox(atpDir)
+	if err != nil {
+		return err
+	}
+
+	pending := outbox.Pending()
+	if len(pending) == 0 {
+		fmt.Println("No pending GitHub operations")
+		return nil
+	}
+
+	for _, op := range pending {
+		fmt.Printf("%s  %-6s  %s\n", op.ID, op.Action, op.Target())
+		if op.Attempts > 0 {
+			fmt.Printf("          %d attempt(s), next after %s: %s\n",
+				op.Attempts, op.NextAttempt.Format("2006-01-02 15:04"), op.LastError)
+		}
+	}
+	return nil
+}
+
 func init() {
 	loadGitHubSyncCommands()
 }
@@ -374,7 +695,30 @@ func loadGitHubSyncCommands() {
 					return err
 				}
 
-				err = github.SyncGitHubProject(todoDir, projectName)
+				opts, err := parseSyncFlags(args)
+				if err != nil {
+					return err
+				}
+				opts.prepare()
+
+				if !opts.dryRun {
+					l, err := LockAtpDir()
+					if err != nil {
+						return err
+					}
+					defer l.Release()
+				}
+
+				plan, err := github.PlanGitHubProject(todoDir, projectName)
+				if err != nil {
+					return fmt.Errorf("sync failed: %w", err)
+				}
+
+				if opts.dryRun {
+					return printSyncPlan(plan, opts.json)
+				}
+
+				err = runSync("github", func() error { return github.ApplySync(todoDir, plan) })
 				if err != nil {
 					return fmt.Errorf("sync failed: %w", err)
 				}
@@ -386,3 +730,49 @@ func loadGitHubSyncCommands() {
 	}
 }
 
+// syncFlags are the flags accepted by the sync commands
+type syncFlags struct {
+	dryRun bool
+	json   bool
+}
+
+func parseSyncFlags(args []string) (syncFlags, error) {
+	var opts syncFlags
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run", "-n":
+			opts.dryRun = true
+		case "--json":
+			opts.json = true
+		default:
+			return opts, fmt.Errorf("unknown argument: %s", arg)
+		}
+	}
+
+	if opts.json && !opts.dryRun {
+		return opts, fmt.Errorf("--json can only be used with --dry-run")
+	}
+
+	return opts, nil
+}
+
+// prepare keeps stdout clean for JSON output by moving sync progress to stderr
+func (opts syncFlags) prepare() {
+	if opts.json {
+		github.Output = os.Stderr
+	}
+}
+
+func printSyncPlan(plan *github.SyncPlan, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode sync plan: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(plan.Diff())
+	return nil
+}
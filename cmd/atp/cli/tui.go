@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/arjungandhi/atp/store"
+	"github.com/arjungandhi/atp/tui"
+	"github.com/gdamore/tcell/v2"
+	Z "github.com/rwxrob/bonzai/z"
+	"github.com/rwxrob/help"
+)
+
+var TuiCmd = &Z.Cmd{
+	Name:    "tui",
+	Aliases: []string{"ui"},
+	Summary: "browse and edit todos, projects and reminders in a terminal UI",
+	Description: `Open a full screen view with today's and other open todos, projects by
+phase and upcoming reminders.
+
+Keys:
+  tab, 1-3    switch pane         j/k, arrows  move
+  /           filter (+p @c k:v)  esc          clear the filter
+  a           add                 x            complete / finish / dismiss
+  p           cycle priority, or toggle a project active
+  e           edit the line       t            tag (+p @c k:v, -tag removes)
+  s           snooze (1d, 2w, tomorrow, YYYY-MM-DD)
+  T           toggle @today       r            reload
+  q           quit
+
+Every change takes the ATP lock and rereads the files first, so the daemon
+and other commands can run alongside it.`,
+	Commands: []*Z.Cmd{help.Cmd},
+	Call: func(cmd *Z.Cmd, args ...string) error {
+		atpDir, err := AtpDir()
+		if err != nil {
+			return err
+		}
+		// repos are only used for project lookups, so a missing repo dir is fine
+		repos, _ := GetRepos()
+
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			return fmt.Errorf("failed to open terminal: %w", err)
+		}
+		if err := screen.Init(); err != nil {
+			return fmt.Errorf("failed to open terminal: %w", err)
+		}
+		defer screen.Fini()
+
+		return tui.New(screen, store.New(atpDir, repos)).Run()
+	},
+}
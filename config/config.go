@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pelletier/go-toml/v2"
 )
@@ -11,18 +14,286 @@ import (
 type Config struct {
 	GitHub GitHubConfig `toml:"github"`
 	Repos  ReposConfig  `toml:"repos"`
+	Daemon DaemonConfig `toml:"daemon"`
+	CalDAV CalDAVConfig `toml:"caldav"`
+	Serve  ServeConfig  `toml:"serve"`
+	// Webhooks are sent by the daemon for every matching event
+	Webhooks []WebhookConfig `toml:"webhooks"`
+	// Contexts define when @contexts are available, keyed by name without
+	// the @
+	Contexts map[string]ContextConfig `toml:"contexts,omitempty"`
+}
+
+// ContextConfig is the definition of a @context
+type ContextConfig struct {
+	// Windows are the times the context is available, e.g. "mon-fri 9-17"
+	// or "sat-sun". A context without windows is always available.
+	Windows []string `toml:"windows,omitempty"`
+}
+
+// Ways to settle a todo that changed locally and on the CalDAV server
+const (
+	PreferLocal  = "local"
+	PreferRemote = "remote"
+)
+
+// CalDAVConfig syncs todos with due: or remind: dates with a CalDAV
+// calendar
+type CalDAVConfig struct {
+	// URL is the calendar collection, e.g.
+	// https://dav.example.com/alice/tasks/
+	URL      string `toml:"url,omitempty"`
+	Username string `toml:"username,omitempty"`
+	// Password falls back to $ATP_CALDAV_PASSWORD and then ~/.netrc
+	Password string `toml:"password,omitempty"`
+	// Prefer wins when a todo changed on both sides, local (default) or
+	// remote
+	Prefer string `toml:"prefer,omitempty"`
+}
+
+// Enabled reports whether a calendar is configured
+func (c CalDAVConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// DefaultServeAddr keeps the API server on the local machine
+const DefaultServeAddr = "127.0.0.1:7878"
+
+// ServeConfig controls the HTTP API started by atp serve
+type ServeConfig struct {
+	// Addr defaults to DefaultServeAddr
+	Addr string `toml:"addr,omitempty"`
+	// Token is required as a bearer token, it falls back to $ATP_TOKEN and
+	// then a generated token stored in serve_token in the ATP directory
+	Token string `toml:"token,omitempty"`
+}
+
+// WebhookConfig is an outbound webhook for ATP events
+type WebhookConfig struct {
+	URL string `toml:"url"`
+	// Secret signs each delivery, the X-Atp-Signature header holds
+	// sha256=<hex HMAC-SHA256 of the body>
+	Secret string `toml:"secret,omitempty"`
+	// Events limits the hook to these event types, e.g. todo.completed. All
+	// events are sent when it's empty.
+	Events []string `toml:"events,omitempty"`
+}
+
+// Wants reports whether the hook is sent for an event type
+func (w WebhookConfig) Wants(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Defaults used by the daemon when the config leaves a setting out
+const (
+	DefaultSyncInterval = 15 * time.Minute
+	DefaultDebounce     = 2 * time.Second
+	DefaultPollInterval = time.Second
+)
+
+// DaemonConfig controls the background daemon. Durations are Go duration
+// strings like "15m" or "30s".
+type DaemonConfig struct {
+	// SyncInterval is how often GitHub is synced, "0" turns it off
+	SyncInterval string `toml:"sync_interval,omitempty"`
+	// Debounce is how long the ATP directory has to be quiet after a change
+	// before the daemon reacts to it
+	Debounce string `toml:"debounce,omitempty"`
+	// PollInterval is how often the ATP directory is checked for changes
+	PollInterval string `toml:"poll_interval,omitempty"`
+	// SyncOnChange syncs after todos are edited, not just on the interval
+	SyncOnChange bool `toml:"sync_on_change,omitempty"`
+	// LogFile defaults to daemon.log in the ATP directory
+	LogFile string `toml:"log_file,omitempty"`
+}
+
+// Durations returns the sync interval, debounce and poll interval with
+// defaults filled in
+func (d DaemonConfig) Durations() (sync time.Duration, debounce time.Duration, poll time.Duration, err error) {
+	if sync, err = parseDuration("sync_interval", d.SyncInterval, DefaultSyncInterval); err != nil {
+		return
+	}
+	if debounce, err = parseDuration("debounce", d.Debounce, DefaultDebounce); err != nil {
+		return
+	}
+	if poll, err = parseDuration("poll_interval", d.PollInterval, DefaultPollInterval); err != nil {
+		return
+	}
+	if poll <= 0 {
+		err = fmt.Errorf("daemon: poll_interval must be positive")
+	}
+	return
+}
+
+func parseDuration(name string, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("daemon: invalid %s '%s'", name, value)
+	}
+	return d, nil
 }
 
 type GitHubConfig struct {
-	Timeout  int             `toml:"timeout"`
-	Projects []GitHubProject `toml:"projects"`
+	Timeout     int          `toml:"timeout"`
+	Concurrency int          `toml:"concurrency"`
+	Enrich      EnrichConfig `toml:"enrich"`
+	// ExcludeDrafts skips your own draft pull requests when syncing
+	ExcludeDrafts bool            `toml:"exclude_drafts"`
+	Projects      []GitHubProject `toml:"projects"`
+
+	Notifications NotificationsConfig `toml:"notifications"`
+	Auth          AuthConfig          `toml:"auth"`
+}
+
+// AuthConfig controls where GitHub tokens are looked up
+type AuthConfig struct {
+	// Order lists the credential providers to try: config, env, netrc, gh,
+	// git and keyring
+	Order []string `toml:"order,omitempty"`
+	// Tokens maps organizations (or users) to their own token
+	Tokens map[string]string `toml:"tokens,omitempty"`
+	// KeyringCommand prints a token, {host} and {org} are substituted
+	KeyringCommand string `toml:"keyring_command,omitempty"`
 }
 
+// NotificationsConfig turns unread GitHub notifications into todos
+type NotificationsConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Reasons are the notification reasons to sync, e.g. mention,
+	// team_mention, author or comment
+	Reasons []string `toml:"reasons,omitempty"`
+}
+
+// EnrichConfig selects which GitHub issue metadata is copied onto todos
+type EnrichConfig struct {
+	// LabelContexts maps GitHub label names to @contexts
+	LabelContexts map[string]string `toml:"label_contexts,omitempty"`
+	// LabelProjects maps GitHub label names to extra +projects
+	LabelProjects map[string]string `toml:"label_projects,omitempty"`
+	// MilestoneDue sets due: from the milestone's due date
+	MilestoneDue bool `toml:"milestone_due,omitempty"`
+	// DueField is a Projects v2 date field to read due: from, it wins over
+	// the milestone
+	DueField string `toml:"due_field,omitempty"`
+	// Checklists expands "- [ ]" items in the issue body into sub-todos
+	Checklists bool `toml:"checklists,omitempty"`
+	// Dependencies adds dep: labels for "blocked by #123" in the issue body
+	Dependencies bool `toml:"dependencies,omitempty"`
+}
+
+// DefaultGitHubHost is the host of public GitHub
+const DefaultGitHubHost = "github.com"
+
+// Owner types a GitHub project can be scoped to
+const (
+	OwnerOrg   = "org"
+	OwnerUser  = "user"
+	OwnerRepos = "repos"
+)
+
 type GitHubProject struct {
-	Name          string   `toml:"name"`
-	Organization  string   `toml:"organization"`
+	Name string `toml:"name"`
+	// OwnerType is org (default), user or repos
+	OwnerType     string   `toml:"owner_type,omitempty"`
+	Organization  string   `toml:"organization,omitempty"`
+	User          string   `toml:"user,omitempty"`
+	Repos         []string `toml:"repos,omitempty"`
 	ProjectNumber int      `toml:"project_number"`
 	StatusFilters []string `toml:"status_filters"`
+	// BaseURL and GraphQLURL point at a GitHub Enterprise Server, e.g.
+	// https://ghe.example.com/api/v3/ and https://ghe.example.com/api/graphql
+	BaseURL    string `toml:"base_url,omitempty"`
+	GraphQLURL string `toml:"graphql_url,omitempty"`
+}
+
+// Host returns the web host of the project's GitHub instance
+func (p *GitHubProject) Host() string {
+	if p.BaseURL == "" {
+		return DefaultGitHubHost
+	}
+
+	u, err := url.Parse(p.BaseURL)
+	if err != nil || u.Hostname() == "" {
+		return DefaultGitHubHost
+	}
+
+	// GitHub Enterprise Cloud serves its API from api.<host>
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "api.")
+}
+
+// Type returns the owner type of the project, defaulting to org
+func (p *GitHubProject) Type() string {
+	if p.OwnerType == "" {
+		return OwnerOrg
+	}
+	return p.OwnerType
+}
+
+// Owner returns the login that owns the project board, empty for repo lists
+func (p *GitHubProject) Owner() string {
+	switch p.Type() {
+	case OwnerUser:
+		return p.User
+	case OwnerRepos:
+		return ""
+	default:
+		return p.Organization
+	}
+}
+
+// Validate checks that the project has what its owner type needs
+func (p *GitHubProject) Validate() error {
+	switch p.Type() {
+	case OwnerOrg:
+		if p.Organization == "" {
+			return fmt.Errorf("GitHub project '%s': organization is required", p.Name)
+		}
+	case OwnerUser:
+		if p.User == "" {
+			return fmt.Errorf("GitHub project '%s': user is required", p.Name)
+		}
+	case OwnerRepos:
+		if len(p.Repos) == 0 {
+			return fmt.Errorf("GitHub project '%s': repos is required", p.Name)
+		}
+		for _, repo := range p.Repos {
+			if len(strings.Split(repo, "/")) != 2 {
+				return fmt.Errorf("GitHub project '%s': invalid repo '%s' (expected owner/name)", p.Name, repo)
+			}
+		}
+	default:
+		return fmt.Errorf("GitHub project '%s': unknown owner_type '%s' (expected org, user or repos)", p.Name, p.OwnerType)
+	}
+
+	for _, endpoint := range []string{p.BaseURL, p.GraphQLURL} {
+		if endpoint == "" {
+			continue
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("GitHub project '%s': invalid API URL '%s'", p.Name, endpoint)
+		}
+	}
+	if p.GraphQLURL != "" && p.BaseURL == "" {
+		return fmt.Errorf("GitHub project '%s': graphql_url requires base_url", p.Name)
+	}
+
+	if p.ProjectNumber == 0 && p.Type() != OwnerRepos {
+		return fmt.Errorf("GitHub project '%s': project_number is required", p.Name)
+	}
+
+	return nil
 }
 
 type ReposConfig struct {
@@ -72,8 +343,9 @@ func SaveConfig(atpDir string, config *Config) error {
 func getDefaultConfig() *Config {
 	return &Config{
 		GitHub: GitHubConfig{
-			Timeout:  30,
-			Projects: []GitHubProject{},
+			Timeout:     30,
+			Concurrency: 4,
+			Projects:    []GitHubProject{},
 		},
 		Repos: ReposConfig{
 			Directory:    "",
@@ -0,0 +1,309 @@
+// Package contexts gives @contexts a meaning: the times they are available,
+// defined in the [contexts] section of the config, and the context set by
+// hand with atp context set. Todos are actionable when one of their
+// contexts is current. Contexts without a definition are always available.
+package contexts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// StateFile holds the context set by hand in the ATP directory
+const StateFile = "context"
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Window is a weekly time span a context is available in
+type Window struct {
+	Days [7]bool
+	// Start and End are minutes since midnight, End is excluded
+	Start int
+	End   int
+	text  string
+}
+
+// ParseWindow parses days and hours, either may be left out: "mon-fri
+// 9-17", "sat,sun", "weekends", "18:30-22" or "fri-mon 8:00-12:30"
+func ParseWindow(s string) (Window, error) {
+	w := Window{Start: 0, End: 24 * 60, text: strings.TrimSpace(s)}
+	days, hours := false, false
+	for _, field := range strings.Fields(strings.ToLower(s)) {
+		switch {
+		case !hours && field[0] >= '0' && field[0] <= '9':
+			start, end, err := parseHours(field)
+			if err != nil {
+				return Window{}, fmt.Errorf("invalid window '%s': %w", s, err)
+			}
+			w.Start, w.End, hours = start, end, true
+		case !days:
+			if err := parseDays(field, &w.Days); err != nil {
+				return Window{}, fmt.Errorf("invalid window '%s': %w", s, err)
+			}
+			days = true
+		default:
+			return Window{}, fmt.Errorf("invalid window '%s': unexpected '%s'", s, field)
+		}
+	}
+	if !days {
+		for i := range w.Days {
+			w.Days[i] = true
+		}
+	}
+	return w, nil
+}
+
+// parseDays reads comma separated days and ranges like mon-fri, ranges may
+// wrap around the weekend
+func parseDays(field string, days *[7]bool) error {
+	for _, part := range strings.Split(field, ",") {
+		switch part {
+		case "daily":
+			*days = [7]bool{true, true, true, true, true, true, true}
+			continue
+		case "weekdays":
+			part = "mon-fri"
+		case "weekends":
+			part = "sat-sun"
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := weekday(from)
+		if err != nil {
+			return err
+		}
+		end := start
+		if isRange {
+			if end, err = weekday(to); err != nil {
+				return err
+			}
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func weekday(name string) (int, error) {
+	if len(name) >= 3 {
+		for i, day := range weekdays {
+			if strings.HasPrefix(name, day) {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown day '%s'", name)
+}
+
+// parseHours reads a span like 9-17 or 8:30-12:15
+func parseHours(field string) (int, int, error) {
+	from, to, ok := strings.Cut(field, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("hours need a start and an end, e.g. 9-17")
+	}
+	start, err := clock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := clock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("'%s' ends before it starts", field)
+	}
+	return start, end, nil
+}
+
+// clock converts 9 or 9:30 to minutes since midnight
+func clock(s string) (int, error) {
+	hour, minute, _ := strings.Cut(s, ":")
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid time '%s'", s)
+	}
+	m := 0
+	if minute != "" {
+		if m, err = strconv.Atoi(minute); err != nil || m < 0 || m > 59 || h == 24 && m > 0 {
+			return 0, fmt.Errorf("invalid time '%s'", s)
+		}
+	}
+	return h*60 + m, nil
+}
+
+// Contains reports whether t falls in the window
+func (w Window) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	return w.Days[t.Weekday()] && minute >= w.Start && minute < w.End
+}
+
+func (w Window) String() string {
+	return w.text
+}
+
+// Context is a defined @context
+type Context struct {
+	Name string
+	// Windows are when it is available, always when there are none
+	Windows []Window
+}
+
+// OpenAt reports whether the context is available at t
+func (c *Context) OpenAt(t time.Time) bool {
+	if len(c.Windows) == 0 {
+		return true
+	}
+	for _, w := range c.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenOn reports whether the context is available at some time on day
+func (c *Context) OpenOn(day time.Time) bool {
+	if len(c.Windows) == 0 {
+		return true
+	}
+	for _, w := range c.Windows {
+		if w.Days[day.Weekday()] {
+			return true
+		}
+	}
+	return false
+}
+
+// Definitions are the configured contexts by name
+type Definitions map[string]*Context
+
+// FromConfig parses the context definitions of the config
+func FromConfig(cfg map[string]config.ContextConfig) (Definitions, error) {
+	defs := make(Definitions, len(cfg))
+	for name, c := range cfg {
+		name = strings.TrimPrefix(name, "@")
+		context := &Context{Name: name}
+		for _, text := range c.Windows {
+			w, err := ParseWindow(text)
+			if err != nil {
+				return nil, fmt.Errorf("context @%s: %w", name, err)
+			}
+			context.Windows = append(context.Windows, w)
+		}
+		defs[name] = context
+	}
+	return defs, nil
+}
+
+// Names returns the defined contexts in order
+func (d Definitions) Names() []string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenOn reports whether a context can be worked in on day, contexts
+// without a definition always can. It makes recurring templates with
+// contexts wait for their day.
+func (d Definitions) OpenOn(context string, day time.Time) bool {
+	c, ok := d[context]
+	return !ok || c.OpenOn(day)
+}
+
+// Current returns the contexts that are current at now: the one set by
+// hand, otherwise the defined contexts whose windows are open
+func (d Definitions) Current(set string, now time.Time) []string {
+	if set != "" {
+		return []string{set}
+	}
+	var current []string
+	for _, name := range d.Names() {
+		if d[name].OpenAt(now) {
+			current = append(current, name)
+		}
+	}
+	return current
+}
+
+// Available reports whether t can be worked on in the current contexts.
+// Todos without contexts can be done anywhere, and contexts without a
+// definition don't restrict a todo.
+func (d Definitions) Available(t *todo.Todo, current []string) bool {
+	if len(t.Contexts) == 0 {
+		return true
+	}
+	for _, context := range t.Contexts {
+		if _, defined := d[context]; !defined {
+			return true
+		}
+		for _, c := range current {
+			if c == context {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Next returns the todos to work on now: open, not waiting for another
+// todo, past their t: threshold and available in the current contexts
+func (d Definitions) Next(todos []*todo.Todo, current []string, now time.Time) []*todo.Todo {
+	today := now.Format("2006-01-02")
+	var next []*todo.Todo
+	for _, t := range todo.Actionable(todos) {
+		if threshold := t.Labels["t"]; threshold > today {
+			continue
+		}
+		if d.Available(t, current) {
+			next = append(next, t)
+		}
+	}
+	return next
+}
+
+// Path returns the state file of an ATP directory
+func Path(atpDir string) string {
+	return filepath.Join(atpDir, StateFile)
+}
+
+// Load returns the context set by hand, empty when none is
+func Load(atpDir string) (string, error) {
+	data, err := os.ReadFile(Path(atpDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read context: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Save sets the context by hand, an empty name goes back to the windows
+func Save(atpDir string, name string) error {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	if name == "" {
+		if err := os.Remove(Path(atpDir)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to clear context: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(Path(atpDir), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write context: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package contexts
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// at is a time in March 2025, the 12th is a Wednesday
+func at(day int, hour int, minute int) time.Time {
+	return time.Date(2025, 3, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		window string
+		open   []time.Time
+		closed []time.Time
+	}{
+		{"mon-fri 9-17", []time.Time{at(12, 9, 0), at(14, 16, 59)}, []time.Time{at(12, 8, 59), at(12, 17, 0), at(15, 10, 0)}},
+		{"sat,sun", []time.Time{at(15, 0, 0), at(16, 23, 59)}, []time.Time{at(14, 12, 0)}},
+		{"weekends", []time.Time{at(15, 12, 0)}, []time.Time{at(17, 12, 0)}},
+		{"18:30-22", []time.Time{at(12, 18, 30), at(16, 21, 0)}, []time.Time{at(12, 18, 29), at(12, 22, 0)}},
+		{"fri-mon 8:00-12:30", []time.Time{at(14, 8, 0), at(17, 12, 29)}, []time.Time{at(12, 9, 0), at(17, 12, 30)}},
+		{"9-24 wed", []time.Time{at(12, 23, 59)}, []time.Time{at(13, 10, 0)}},
+	}
+	for _, tt := range tests {
+		w, err := ParseWindow(tt.window)
+		if err != nil {
+			t.Errorf("ParseWindow(%q) = %v", tt.window, err)
+			continue
+		}
+		for _, now := range tt.open {
+			if !w.Contains(now) {
+				t.Errorf("%q doesn't contain %s", tt.window, now.Format("Mon 15:04"))
+			}
+		}
+		for _, now := range tt.closed {
+			if w.Contains(now) {
+				t.Errorf("%q contains %s", tt.window, now.Format("Mon 15:04"))
+			}
+		}
+	}
+
+	for _, window := range []string{"someday", "mon-fri 9", "17-9", "mon 9-17 tue", "9:75-10", "mon-xyz"} {
+		if _, err := ParseWindow(window); err == nil {
+			t.Errorf("ParseWindow(%q) = nil, want an error", window)
+		}
+	}
+}
+
+func TestCurrentAndNext(t *testing.T) {
+	defs, err := FromConfig(map[string]config.ContextConfig{
+		"office":  {Windows: []string{"mon-fri 9-17"}},
+		"errands": {Windows: []string{"sat-sun"}},
+		"@home":   {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var todos []*todo.Todo
+	for _, line := range []string{
+		"Review PRs @office",
+		"Buy milk @errands",
+		"Fix sink @home",
+		"Call mom @phone",
+		"Read book",
+		"Deploy @office dep:1",
+		"Write spec @office id:1",
+		"Later @home t:2025-04-01",
+		"x Done @office",
+	} {
+		todos = append(todos, todo.FromString(line))
+	}
+
+	tests := []struct {
+		name    string
+		set     string
+		now     time.Time
+		current string
+		next    string
+	}{
+		{"weekday office hours", "", at(12, 10, 0), "home,office", "Review PRs,Fix sink,Call mom,Read book,Write spec"},
+		{"weekday evening", "", at(12, 20, 0), "home", "Fix sink,Call mom,Read book"},
+		{"saturday", "", at(15, 10, 0), "errands,home", "Buy milk,Fix sink,Call mom,Read book"},
+		{"set by hand", "errands", at(12, 10, 0), "errands", "Buy milk,Call mom,Read book"},
+	}
+	for _, tt := range tests {
+		current := defs.Current(tt.set, tt.now)
+		if got := strings.Join(current, ","); got != tt.current {
+			t.Errorf("%s: Current() = %s, want %s", tt.name, got, tt.current)
+		}
+		var next []string
+		for _, n := range defs.Next(todos, current, tt.now) {
+			next = append(next, n.Description)
+		}
+		if got := strings.Join(next, ","); got != tt.next {
+			t.Errorf("%s: Next() = %s, want %s", tt.name, got, tt.next)
+		}
+	}
+
+	if defs.OpenOn("errands", at(12, 0, 0)) || !defs.OpenOn("errands", at(16, 0, 0)) || !defs.OpenOn("phone", at(12, 0, 0)) {
+		t.Errorf("OpenOn() ignores the windows")
+	}
+
+	if _, err := FromConfig(map[string]config.ContextConfig{"gym": {Windows: []string{"mon-fry"}}}); err == nil || !strings.Contains(err.Error(), "@gym") {
+		t.Errorf("FromConfig() = %v, want an error naming @gym", err)
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	dir := t.TempDir()
+	if set, err := Load(dir); err != nil || set != "" {
+		t.Fatalf("Load() = %q, %v, want nothing set", set, err)
+	}
+	if err := Save(dir, "@home"); err != nil {
+		t.Fatal(err)
+	}
+	if set, err := Load(dir); err != nil || set != "home" {
+		t.Errorf("Load() = %q, %v, want home", set, err)
+	}
+	if err := Save(dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	if set, err := Load(dir); err != nil || set != "" {
+		t.Errorf("Load() after clearing = %q, %v", set, err)
+	}
+	// clearing twice is fine
+	if err := Save(dir, ""); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,408 @@
+// Package daemon runs the periodic ATP jobs in the background: recurring
+// todos and reminders at day rollover, GitHub and CalDAV sync on an
+// interval, a watcher that reacts to edits in the ATP directory and the
+// delivery of events to webhooks.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arjungandhi/atp/caldav"
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/contexts"
+	"github.com/arjungandhi/atp/events"
+	"github.com/arjungandhi/atp/github"
+	"github.com/arjungandhi/atp/hooks"
+	"github.com/arjungandhi/atp/lock"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// lockTimeout is how long a job waits for a CLI invocation to finish
+const lockTimeout = 2 * time.Minute
+
+// deliveredFile keeps how far webhooks were sent from the event log, so
+// events logged while the daemon was down are sent when it starts again
+const deliveredFile = ".webhooks_offset"
+
+// maxDeliveryBackoff caps the wait between attempts to read the event log
+// when it fails
+const maxDeliveryBackoff = 5 * time.Minute
+
+// Status is what the daemon reports over its socket
+type Status struct {
+	PID            int       `json:"pid"`
+	StartedAt      time.Time `json:"started_at"`
+	LastRollover   time.Time `json:"last_rollover,omitempty"`
+	LastSync       time.Time `json:"last_sync,omitempty"`
+	NextSync       time.Time `json:"next_sync,omitempty"`
+	LastChange     time.Time `json:"last_change,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	LastErrorAt    time.Time `json:"last_error_at,omitempty"`
+	SyncInterval   string    `json:"sync_interval"`
+	ChangesHandled int       `json:"changes_handled"`
+}
+
+// Daemon runs the ATP jobs on their schedules
+type Daemon struct {
+	atpDir  string
+	todoDir string
+	logger  *log.Logger
+
+	syncEvery    time.Duration
+	debounce     time.Duration
+	poll         time.Duration
+	syncOnChange bool
+
+	// jobs, replaced in tests
+	rollover func(date time.Time) error
+	sync     func() error
+
+	// only touched by the loop in Run
+	day      string
+	nextSync time.Time
+	files    map[string]fileState
+	changed  time.Time // when the last unhandled change was seen
+
+	// status is read by socket clients while jobs run
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a daemon for an ATP directory
+func New(atpDir string, cfg config.DaemonConfig, logger *log.Logger) (*Daemon, error) {
+	d := &Daemon{
+		atpDir:  atpDir,
+		todoDir: filepath.Join(atpDir, "todo"),
+		logger:  logger,
+		status:  Status{PID: os.Getpid()},
+	}
+	d.rollover = d.runRollover
+	d.sync = d.runSync
+
+	if err := d.configure(cfg); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// configure applies the daemon settings from the config
+func (d *Daemon) configure(cfg config.DaemonConfig) error {
+	syncEvery, debounce, poll, err := cfg.Durations()
+	if err != nil {
+		return err
+	}
+
+	d.syncEvery = syncEvery
+	d.debounce = debounce
+	d.poll = poll
+	d.syncOnChange = cfg.SyncOnChange
+
+	interval := "off"
+	if syncEvery > 0 {
+		interval = syncEvery.String()
+	}
+	d.update(func(s *Status) { s.SyncInterval = interval })
+	return nil
+}
+
+// Run blocks until the context is cancelled or a stop request arrives on the
+// status socket
+func (d *Daemon) Run(ctx context.Context) error {
+	// only one daemon per ATP directory
+	instance, err := lock.TryAcquire(filepath.Join(d.atpDir, ".daemon.lock"))
+	if errors.Is(err, lock.ErrLocked) {
+		return fmt.Errorf("a daemon is already running for %s", d.atpDir)
+	}
+	if err != nil {
+		return err
+	}
+	defer instance.Release()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	listener, err := listen(d.atpDir)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	go d.serve(ctx, listener, cancel)
+	go d.deliverEvents(ctx, d.deliveredOffset(), d.poll)
+
+	d.update(func(s *Status) { s.StartedAt = time.Now() })
+	d.files = d.scan()
+
+	d.logger.Printf("started (pid %d, sync every %s)", os.Getpid(), d.Status().SyncInterval)
+
+	ticker := time.NewTicker(d.poll)
+	defer ticker.Stop()
+
+	for {
+		d.tick(time.Now())
+
+		select {
+		case <-ctx.Done():
+			d.logger.Printf("stopped")
+			return nil
+		case <-ticker.C:
+		}
+		// a reloaded config may have changed the poll interval
+		ticker.Reset(d.poll)
+	}
+}
+
+// tick runs whatever is due at the given time
+func (d *Daemon) tick(now time.Time) {
+	// recurring todos and reminders once per day, and on startup
+	if day := now.Format("2006-01-02"); day != d.day {
+		d.day = day
+		d.runJob("rollover", func() error { return d.rollover(now) })
+		d.update(func(s *Status) { s.LastRollover = now })
+	}
+
+	if d.syncEvery > 0 && !now.Before(d.nextSync) {
+		d.runSyncJob(now)
+	}
+
+	files := d.scan()
+	if !sameFiles(files, d.files) {
+		d.files = files
+		d.changed = now
+		d.update(func(s *Status) { s.LastChange = now })
+	}
+
+	// wait for the directory to settle, editors write files in several steps
+	if !d.changed.IsZero() && now.Sub(d.changed) >= d.debounce {
+		d.changed = time.Time{}
+		d.handleChange(now)
+	}
+}
+
+// handleChange reacts to edits: a changed config is reloaded and new
+// recurring templates or reminders due today are picked up right away
+func (d *Daemon) handleChange(now time.Time) {
+	d.logger.Printf("change detected in %s", d.atpDir)
+	d.update(func(s *Status) { s.ChangesHandled++ })
+
+	cfg, err := config.LoadConfig(d.atpDir)
+	if err == nil {
+		err = d.configure(cfg.Daemon)
+	}
+	if err != nil {
+		d.fail("reload config", err)
+	}
+
+	d.runJob("rollover", func() error { return d.rollover(now) })
+
+	if d.syncOnChange && d.syncEvery > 0 {
+		d.runSyncJob(now)
+	}
+}
+
+// runSyncJob syncs and schedules the next sync
+func (d *Daemon) runSyncJob(now time.Time) {
+	d.runJob("sync", d.sync)
+	d.nextSync = now.Add(d.syncEvery)
+	d.update(func(s *Status) {
+		s.LastSync = now
+		s.NextSync = d.nextSync
+	})
+}
+
+// runJob runs a job and records its outcome. The daemon's own writes are
+// absorbed into the file snapshot so they don't count as changes.
+func (d *Daemon) runJob(name string, job func() error) {
+	started := time.Now()
+	if err := job(); err != nil {
+		d.fail(name, err)
+	} else {
+		d.logger.Printf("%s done in %s", name, time.Since(started).Round(time.Millisecond))
+	}
+	d.files = d.scan()
+}
+
+func (d *Daemon) fail(name string, err error) {
+	d.logger.Printf("%s failed: %v", name, err)
+	d.update(func(s *Status) {
+		s.LastError = fmt.Sprintf("%s: %v", name, err)
+		s.LastErrorAt = time.Now()
+	})
+}
+
+func (d *Daemon) update(fn func(s *Status)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(&d.status)
+}
+
+// Status returns a snapshot of the daemon's state
+func (d *Daemon) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// deliverEvents sends events logged after offset to the configured
+// webhooks and saves how far it got. The config is read for every event so
+// edits apply right away.
+func (d *Daemon) deliverEvents(ctx context.Context, offset int64, poll time.Duration) {
+	wait := poll
+	for {
+		logged, next, err := events.Read(d.atpDir, offset)
+		if err != nil {
+			// keep trying, the log may be readable again later
+			d.fail("webhooks", err)
+			wait = min(wait*2, maxDeliveryBackoff)
+		} else {
+			wait = poll
+			for _, e := range logged {
+				d.deliverEvent(ctx, e)
+			}
+			// events cut short by a stop are sent again on the next start
+			if ctx.Err() == nil && next != offset {
+				offset = next
+				d.saveDeliveredOffset(offset)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			d.logger.Printf("webhook delivery stopped at offset %d", offset)
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (d *Daemon) deliverEvent(ctx context.Context, e events.Event) {
+	cfg, err := config.LoadConfig(d.atpDir)
+	if err != nil {
+		d.fail("webhooks", fmt.Errorf("failed to load config: %w", err))
+		return
+	}
+	if len(cfg.Webhooks) == 0 {
+		return
+	}
+	if err := events.NewWebhooks(cfg.Webhooks).Deliver(ctx, e); err != nil {
+		d.fail("webhooks", err)
+	}
+}
+
+// deliveredOffset returns where delivery stopped. The first start begins at
+// the end of the log rather than sending its whole history.
+func (d *Daemon) deliveredOffset() int64 {
+	data, err := os.ReadFile(filepath.Join(d.atpDir, deliveredFile))
+	if err == nil {
+		offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err == nil {
+			return offset
+		}
+		d.fail("webhooks", fmt.Errorf("failed to read %s: %w", deliveredFile, err))
+	} else if !os.IsNotExist(err) {
+		d.fail("webhooks", fmt.Errorf("failed to read %s: %w", deliveredFile, err))
+	}
+
+	offset := events.End(d.atpDir)
+	d.saveDeliveredOffset(offset)
+	return offset
+}
+
+func (d *Daemon) saveDeliveredOffset(offset int64) {
+	path := filepath.Join(d.atpDir, deliveredFile)
+	if err := os.WriteFile(path, []byte(strconv.FormatInt(offset, 10)+"\n"), 0644); err != nil {
+		d.fail("webhooks", fmt.Errorf("failed to save %s: %w", deliveredFile, err))
+	}
+}
+
+// ------------------------------- Jobs -------------------------------
+
+// withLock holds the ATP directory lock so CLI invocations and the daemon
+// never write the todos at the same time, and records what fn changed
+func (d *Daemon) withLock(fn func() error) error {
+	l, err := lock.Acquire(lock.Path(d.atpDir), lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	return events.Record(d.atpDir, fn)
+}
+
+func (d *Daemon) runRollover(date time.Time) error {
+	cfg, err := config.LoadConfig(d.atpDir)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	defs, err := contexts.FromConfig(cfg.Contexts)
+	if err != nil {
+		return err
+	}
+	return d.withLock(func() error {
+		if err := todo.AddRecurringTodosToDir(d.todoDir, date, defs); err != nil {
+			return fmt.Errorf("failed to generate recurring todos: %w", err)
+		}
+		if err := todo.ProcessReminders(d.todoDir, date); err != nil {
+			return fmt.Errorf("failed to process reminders: %w", err)
+		}
+		return nil
+	})
+}
+
+func (d *Daemon) runSync() error {
+	cfg, err := config.LoadConfig(d.atpDir)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	runner := hooks.New(d.atpDir)
+	if cfg.CalDAV.Enabled() {
+		err := runner.Sync("caldav", func() error { return d.runCalDAVSync(cfg.CalDAV) })
+		if err != nil {
+			d.fail("caldav sync", err)
+		}
+	}
+
+	// nothing configured, queued operations are still worth sending
+	if len(cfg.GetAllGitHubProjects()) == 0 {
+		_, err := github.ReplayOutboxUnlocked(d.todoDir, false, d.withLock)
+		return err
+	}
+
+	// GitHub is fetched and written to without the lock so the CLI isn't
+	// blocked on the network, it is only held to read and write the files
+	err = runner.Sync("github", func() error {
+		plan, err := github.PlanAllGitHubProjects(d.todoDir)
+		if err != nil {
+			return err
+		}
+		return github.ApplySyncUnlocked(d.todoDir, plan, d.withLock)
+	})
+	if err != nil {
+		return err
+	}
+	return events.Emit(d.atpDir, events.Synced("github", time.Now()))
+}
+
+func (d *Daemon) runCalDAVSync(cfg config.CalDAVConfig) error {
+	client, err := caldav.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	// only reading and writing the todos holds the lock, not the requests
+	report, err := caldav.Sync(context.Background(), client, d.todoDir, d.atpDir, caldav.Options{Prefer: cfg.Prefer, Lock: d.withLock})
+	if report != nil && len(report.Changes) > 0 {
+		d.logger.Printf("caldav sync made %d changes", len(report.Changes))
+	}
+	if err != nil {
+		return err
+	}
+	return events.Emit(d.atpDir, events.Synced("caldav", time.Now()))
+}
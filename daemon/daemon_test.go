@@ -0,0 +1,287 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/events"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// newTestDaemon returns a daemon whose jobs only count their runs
+func newTestDaemon(t *testing.T, cfg config.DaemonConfig) (*Daemon, *[]time.Time, *int) {
+	t.Helper()
+
+	atpDir := t.TempDir()
+	// changes reload the config, so it has to match
+	if err := config.SaveConfig(atpDir, &config.Config{Daemon: cfg}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := New(atpDir, cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rollovers []time.Time
+	syncs := 0
+	d.rollover = func(date time.Time) error {
+		rollovers = append(rollovers, date)
+		return nil
+	}
+	d.sync = func() error {
+		syncs++
+		return nil
+	}
+	d.files = d.scan()
+	return d, &rollovers, &syncs
+}
+
+func TestTickRollover(t *testing.T) {
+	d, rollovers, _ := newTestDaemon(t, config.DaemonConfig{SyncInterval: "0"})
+	start := time.Date(2024, 3, 1, 23, 59, 0, 0, time.Local)
+
+	d.tick(start)
+	d.tick(start.Add(30 * time.Second))
+	if len(*rollovers) != 1 {
+		t.Fatalf("got %d rollovers on the first day, want 1", len(*rollovers))
+	}
+
+	d.tick(start.Add(2 * time.Minute))
+	if len(*rollovers) != 2 || (*rollovers)[1].Day() != 2 {
+		t.Errorf("rollovers = %v, want a second one on March 2nd", *rollovers)
+	}
+}
+
+func TestTickSyncInterval(t *testing.T) {
+	d, _, syncs := newTestDaemon(t, config.DaemonConfig{SyncInterval: "10m"})
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+
+	for _, offset := range []time.Duration{0, time.Minute, 9 * time.Minute, 10 * time.Minute, 15 * time.Minute, 20 * time.Minute} {
+		d.tick(start.Add(offset))
+	}
+	if *syncs != 3 {
+		t.Errorf("got %d syncs, want 3", *syncs)
+	}
+	if want := start.Add(30 * time.Minute); !d.Status().NextSync.Equal(want) {
+		t.Errorf("NextSync = %v, want %v", d.Status().NextSync, want)
+	}
+}
+
+func TestTickDebounce(t *testing.T) {
+	d, rollovers, syncs := newTestDaemon(t, config.DaemonConfig{SyncInterval: "1h", Debounce: "5s", SyncOnChange: true})
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	d.tick(start)
+
+	todoFile := filepath.Join(d.atpDir, "todo.txt")
+	write := func(content string) {
+		if err := os.WriteFile(todoFile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a burst of writes is handled once, after it settles
+	write("a")
+	d.tick(start.Add(1 * time.Second))
+	write("ab")
+	d.tick(start.Add(3 * time.Second))
+	d.tick(start.Add(7 * time.Second))
+	if got := d.Status().ChangesHandled; got != 0 {
+		t.Fatalf("handled %d changes before the directory settled", got)
+	}
+
+	d.tick(start.Add(8 * time.Second))
+	if got := d.Status().ChangesHandled; got != 1 {
+		t.Fatalf("handled %d changes, want 1", got)
+	}
+	if len(*rollovers) != 2 || *syncs != 2 {
+		t.Errorf("after change: %d rollovers, %d syncs, want 2 and 2", len(*rollovers), *syncs)
+	}
+
+	// ignored files don't count
+	os.WriteFile(filepath.Join(d.atpDir, "daemon.log"), []byte("log"), 0644)
+	os.WriteFile(filepath.Join(d.atpDir, ".lock"), nil, 0644)
+	d.tick(start.Add(20 * time.Second))
+	d.tick(start.Add(30 * time.Second))
+	if got := d.Status().ChangesHandled; got != 1 {
+		t.Errorf("handled %d changes after writing ignored files, want 1", got)
+	}
+}
+
+func TestRunStatusSocket(t *testing.T) {
+	d, _, _ := newTestDaemon(t, config.DaemonConfig{SyncInterval: "0", PollInterval: "10ms"})
+
+	done := make(chan error)
+	go func() { done <- d.Run(context.Background()) }()
+
+	var status *Status
+	var err error
+	for i := 0; i < 100; i++ {
+		if status, err = QueryStatus(d.atpDir); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("QueryStatus() error = %v", err)
+	}
+	if status.PID != os.Getpid() || status.SyncInterval != "off" {
+		t.Errorf("status = %+v", status)
+	}
+
+	// a second daemon for the same directory refuses to start
+	other, _, _ := newTestDaemon(t, config.DaemonConfig{})
+	other.atpDir = d.atpDir
+	if err := other.Run(context.Background()); err == nil {
+		t.Error("second daemon started")
+	}
+
+	if err := Stop(d.atpDir); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon didn't stop")
+	}
+}
+
+func TestRolloverEventsReachWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Get(events.EventHeader))
+	}))
+	defer receiver.Close()
+
+	d, _, _ := newTestDaemon(t, config.DaemonConfig{})
+	config.SaveConfig(d.atpDir, &config.Config{Webhooks: []config.WebhookConfig{{URL: receiver.URL}}})
+	os.MkdirAll(d.todoDir, 0755)
+	os.WriteFile(todo.ReminderTasksPath(d.todoDir), []byte("Dentist remind:2025-03-12\n"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.deliverEvents(ctx, d.deliveredOffset(), 10*time.Millisecond)
+
+	if err := d.runRollover(time.Date(2025, 3, 12, 9, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 500; i++ {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 || received[0] != "reminder.activated" {
+		t.Errorf("webhook received %v, want one reminder.activated", received)
+	}
+}
+
+func TestDeliveryResumes(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Get(events.EventHeader))
+	}))
+	defer receiver.Close()
+	waitFor := func(n int) []string {
+		for i := 0; i < 500; i++ {
+			mu.Lock()
+			got := append([]string(nil), received...)
+			mu.Unlock()
+			if len(got) >= n {
+				return got
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil
+	}
+
+	d, _, _ := newTestDaemon(t, config.DaemonConfig{})
+	config.SaveConfig(d.atpDir, &config.Config{Webhooks: []config.WebhookConfig{{URL: receiver.URL}}})
+	// history from before the first start isn't sent
+	events.Emit(d.atpDir, events.Synced("caldav", time.Now()))
+
+	run := func() func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		offset := d.deliveredOffset()
+		go func() {
+			d.deliverEvents(ctx, offset, 10*time.Millisecond)
+			close(done)
+		}()
+		return func() {
+			cancel()
+			<-done
+		}
+	}
+
+	stop := run()
+	events.Emit(d.atpDir, events.New(events.TodoAdded, time.Now()))
+	waitFor(1)
+	stop()
+
+	// logged while the daemon was down
+	events.Emit(d.atpDir, events.New(events.TodoCompleted, time.Now()))
+	stop = run()
+	defer stop()
+	got := waitFor(2)
+	if len(got) != 2 || got[0] != "todo.added" || got[1] != "todo.completed" {
+		t.Errorf("webhook received %v, want todo.added and todo.completed", got)
+	}
+}
+
+func TestDeliveryRetriesReadErrors(t *testing.T) {
+	received := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(events.EventHeader)
+	}))
+	defer receiver.Close()
+
+	d, _, _ := newTestDaemon(t, config.DaemonConfig{})
+	config.SaveConfig(d.atpDir, &config.Config{Webhooks: []config.WebhookConfig{{URL: receiver.URL}}})
+	// a directory in place of the log can't be read
+	os.Mkdir(events.LogPath(d.atpDir), 0755)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.deliverEvents(ctx, d.deliveredOffset(), 10*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	if d.Status().LastError == "" {
+		t.Error("a failing read isn't reported")
+	}
+	os.Remove(events.LogPath(d.atpDir))
+	events.Emit(d.atpDir, events.New(events.TodoAdded, time.Now()))
+
+	select {
+	case got := <-received:
+		if got != "todo.added" {
+			t.Errorf("webhook received %s, want todo.added", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("delivery didn't recover from the read error")
+	}
+}
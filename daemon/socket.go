@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// socketTimeout bounds a single request on the status socket
+const socketTimeout = 5 * time.Second
+
+// Requests understood by the status socket
+const (
+	requestStatus = "status"
+	requestStop   = "stop"
+)
+
+// SocketPath returns the status socket of the daemon for an ATP directory
+func SocketPath(atpDir string) string {
+	return filepath.Join(atpDir, "daemon.sock")
+}
+
+// listen opens the status socket. A socket left behind by a daemon that
+// crashed is replaced, Run only gets here while holding the instance lock.
+func listen(atpDir string) (net.Listener, error) {
+	path := SocketPath(atpDir)
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open status socket: %w", err)
+	}
+	return listener, nil
+}
+
+// serve answers requests on the status socket until the context ends
+func (d *Daemon) serve(ctx context.Context, listener net.Listener, stop func()) {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(conn, stop)
+	}
+}
+
+// handle reads one request line and writes one JSON response
+func (d *Daemon) handle(conn net.Conn, stop func()) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(socketTimeout))
+
+	request, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	encoder := json.NewEncoder(conn)
+	switch strings.TrimSpace(request) {
+	case requestStatus:
+		encoder.Encode(d.Status())
+	case requestStop:
+		d.logger.Printf("stop requested")
+		encoder.Encode(map[string]string{"result": "stopping"})
+		stop()
+	default:
+		encoder.Encode(map[string]string{"error": "unknown request"})
+	}
+}
+
+// request sends a request to a running daemon and decodes its response
+func request(atpDir string, req string, response any) error {
+	conn, err := net.DialTimeout("unix", SocketPath(atpDir), socketTimeout)
+	if err != nil {
+		return fmt.Errorf("daemon is not running: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(socketTimeout))
+
+	if _, err := fmt.Fprintln(conn, req); err != nil {
+		return fmt.Errorf("failed to send request to daemon: %w", err)
+	}
+	if err := json.NewDecoder(conn).Decode(response); err != nil {
+		return fmt.Errorf("failed to read daemon response: %w", err)
+	}
+	return nil
+}
+
+// QueryStatus asks the running daemon for its status
+func QueryStatus(atpDir string) (*Status, error) {
+	var status Status
+	if err := request(atpDir, requestStatus, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Stop asks the running daemon to exit
+func Stop(atpDir string) error {
+	var response map[string]string
+	if err := request(atpDir, requestStop, &response); err != nil {
+		return err
+	}
+	if msg := response["error"]; msg != "" {
+		return fmt.Errorf("daemon refused to stop: %s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/events"
+)
+
+// fileState is what the watcher compares between polls
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// ignoredDirs hold data the daemon or sync maintain themselves
+var ignoredDirs = map[string]bool{
+	"cache": true,
+	".git":  true,
+}
+
+// scan records the state of every file in the ATP directory. Polling keeps
+// the daemon free of platform specific notification APIs, the directory is
+// small enough for it to be cheap.
+func (d *Daemon) scan() map[string]fileState {
+	files := make(map[string]fileState)
+	filepath.WalkDir(d.atpDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != d.atpDir && ignoredDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.ignored(path) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files
+}
+
+// ignored skips the daemon's own files, hidden state like locks and the
+// last sync time, and editor swap and backup files
+func (d *Daemon) ignored(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+		return true
+	}
+	switch filepath.Ext(name) {
+	case ".log", ".sock", ".swp", ".tmp":
+		return true
+	}
+	return name == "github_outbox.json" || strings.HasPrefix(name, events.LogFile)
+}
+
+func sameFiles(a map[string]fileState, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		other, ok := b[path]
+		if !ok || !other.modTime.Equal(state.modTime) || other.size != state.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,256 @@
+// Package events records what changed in an ATP directory as typed events
+// so other tools can react to them.
+//
+// Writers wrap their changes in Record, which compares the todos, reminders
+// and projects before and after and appends the resulting events to
+// events.jsonl in the ATP directory. The daemon follows that log to deliver
+// webhooks and atp serve follows it to feed its server-sent event stream, so
+// events reach them no matter which process made the change.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Type names a kind of event
+type Type string
+
+// Event types
+const (
+	TodoAdded           Type = "todo.added"
+	TodoCompleted       Type = "todo.completed"
+	TodoUnblocked       Type = "todo.unblocked"
+	ReminderActivated   Type = "reminder.activated"
+	RecurringGenerated  Type = "recurring.generated"
+	ProjectPhaseChanged Type = "project.phase_changed"
+	SyncCompleted       Type = "sync.completed"
+)
+
+// Types lists every event type
+var Types = []Type{TodoAdded, TodoCompleted, TodoUnblocked, ReminderActivated, RecurringGenerated, ProjectPhaseChanged, SyncCompleted}
+
+// Event is something that happened to the todos or projects
+type Event struct {
+	ID   string    `json:"id"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// Todo is set for the todo, reminder and recurring events
+	Todo *todo.Todo `json:"todo,omitempty"`
+	// Project and PreviousPhase are set for ProjectPhaseChanged
+	Project       *project.Project `json:"project,omitempty"`
+	PreviousPhase string           `json:"previous_phase,omitempty"`
+	// Source names the sync for SyncCompleted, github or caldav
+	Source string `json:"source,omitempty"`
+}
+
+// New returns an event with a fresh ID
+func New(typ Type, now time.Time) Event {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return Event{ID: hex.EncodeToString(id), Type: typ, Time: now.UTC()}
+}
+
+// Synced returns a SyncCompleted event for a sync source
+func Synced(source string, now time.Time) Event {
+	e := New(SyncCompleted, now)
+	e.Source = source
+	return e
+}
+
+// ------------------------------- Diffing -------------------------------
+
+// Snapshot is the state of an ATP directory that events are derived from.
+// A nil field is left out of the comparison.
+type Snapshot struct {
+	Todos     []*todo.Todo
+	Reminders []*todo.Todo
+	Projects  []*project.Project
+}
+
+// TakeSnapshot loads the todos, reminders and projects of an ATP directory
+func TakeSnapshot(atpDir string) (Snapshot, error) {
+	s, err := TodoSnapshot(atpDir)
+	if err != nil {
+		return s, err
+	}
+	projects, err := ProjectSnapshot(atpDir)
+	s.Projects = projects.Projects
+	return s, err
+}
+
+// TodoSnapshot loads only the todos and reminders
+func TodoSnapshot(atpDir string) (Snapshot, error) {
+	var s Snapshot
+	var err error
+	todoDir := filepath.Join(atpDir, "todo")
+
+	if s.Todos, err = loadTodos(todoDir); err != nil {
+		return s, err
+	}
+	if s.Reminders, err = todo.LoadReminderTasks(todo.ReminderTasksPath(todoDir)); err != nil {
+		return s, fmt.Errorf("failed to load reminders: %w", err)
+	}
+	return s, nil
+}
+
+// ProjectSnapshot loads only the projects
+func ProjectSnapshot(atpDir string) (Snapshot, error) {
+	projects, err := loadProjects(filepath.Join(atpDir, "project"))
+	return Snapshot{Projects: projects}, err
+}
+
+// Diff returns the events that turn before into after.
+//
+// Todos are compared with todo.Changes. A new open todo is a
+// ReminderActivated when a reminder with its description went away, a
+// RecurringGenerated when it carries a recur: label and a TodoAdded
+// otherwise. An open todo marked done is a TodoCompleted. Edits and
+// removals don't produce events.
+func Diff(before Snapshot, after Snapshot, now time.Time) []Event {
+	var events []Event
+
+	var removedReminders []*todo.Todo
+	for _, c := range todo.Changes(before.Reminders, after.Reminders) {
+		if c.Removed() {
+			removedReminders = append(removedReminders, c.Before)
+		}
+	}
+
+	for _, c := range todo.Changes(before.Todos, after.Todos) {
+		var typ Type
+		switch {
+		case c.Completed():
+			typ = TodoCompleted
+		case !c.Added() || c.After.Done:
+			continue
+		case take(&removedReminders, func(r *todo.Todo) bool { return r.Description == c.After.Description }):
+			typ = ReminderActivated
+		case c.After.Labels["recur"] != "":
+			typ = RecurringGenerated
+		default:
+			typ = TodoAdded
+		}
+		e := New(typ, now)
+		e.Todo = c.After
+		events = append(events, e)
+	}
+
+	// a todo is unblocked once the last open todo it waits for is done,
+	// todos are matched by description as they may have been edited too
+	blocked := make(map[string]int)
+	g := todo.NewGraph(before.Todos)
+	for _, t := range before.Todos {
+		if !t.Done && g.Blocked(t) {
+			blocked[t.Description]++
+		}
+	}
+	g = todo.NewGraph(after.Todos)
+	for _, t := range after.Todos {
+		if t.Done || g.Blocked(t) || blocked[t.Description] == 0 {
+			continue
+		}
+		blocked[t.Description]--
+		e := New(TodoUnblocked, now)
+		e.Todo = t
+		events = append(events, e)
+	}
+
+	phases := make(map[string]string)
+	for _, p := range before.Projects {
+		phases[p.Name] = p.Phase
+	}
+	for _, p := range after.Projects {
+		previous, ok := phases[p.Name]
+		if !ok || p.Done || previous == p.Phase {
+			continue
+		}
+		e := New(ProjectPhaseChanged, now)
+		e.Project = p
+		e.PreviousPhase = previous
+		events = append(events, e)
+	}
+
+	return events
+}
+
+// Output receives warnings about events that couldn't be recorded
+var Output io.Writer = os.Stderr
+
+// Record runs fn, which changes the ATP directory, and appends the events
+// for what it changed to the log. The caller holds the directory lock.
+// Failing to record events doesn't fail the change, it is reported to
+// Output.
+func Record(atpDir string, fn func() error) error {
+	return RecordSnapshot(atpDir, TakeSnapshot, fn)
+}
+
+// RecordSnapshot is Record with its own way of taking snapshots, e.g. one
+// that leaves out projects
+func RecordSnapshot(atpDir string, snapshot func(atpDir string) (Snapshot, error), fn func() error) error {
+	before, beforeErr := snapshot(atpDir)
+	if err := fn(); err != nil {
+		return err
+	}
+	if beforeErr != nil {
+		Warn(beforeErr)
+		return nil
+	}
+	after, err := snapshot(atpDir)
+	if err == nil {
+		err = Emit(atpDir, Diff(before, after, time.Now())...)
+	}
+	if err != nil {
+		Warn(err)
+	}
+	return nil
+}
+
+// Warn reports that events couldn't be recorded
+func Warn(err error) {
+	fmt.Fprintf(Output, "Warning: failed to record events: %v\n", err)
+}
+
+// take removes the first todo matching fn and reports whether there was one
+func take(todos *[]*todo.Todo, fn func(t *todo.Todo) bool) bool {
+	for i, t := range *todos {
+		if fn(t) {
+			*todos = append((*todos)[:i], (*todos)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func loadTodos(todoDir string) ([]*todo.Todo, error) {
+	var todos []*todo.Todo
+	for _, path := range []string{todo.ActiveTodoPath(todoDir), todo.DoneTodoPath(todoDir)} {
+		loaded, err := todo.LoadTodoFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to load todos: %w", err)
+		}
+		todos = append(todos, loaded...)
+	}
+	return todos, nil
+}
+
+func loadProjects(projectDir string) ([]*project.Project, error) {
+	var projects []*project.Project
+	for _, path := range []string{project.ActiveFilePath(projectDir), project.DoneFilePath(projectDir)} {
+		loaded, err := project.LoadProjectFile(path, nil)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to load projects: %w", err)
+		}
+		projects = append(projects, loaded...)
+	}
+	return projects, nil
+}
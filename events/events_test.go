@@ -0,0 +1,216 @@
+package events
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func todos(lines ...string) []*todo.Todo {
+	var out []*todo.Todo
+	for _, line := range lines {
+		out = append(out, todo.FromString(line))
+	}
+	return out
+}
+
+func projects(t *testing.T, lines ...string) []*project.Project {
+	var out []*project.Project
+	for _, line := range lines {
+		p, err := project.FromTodo(todo.FromString(line), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// summary renders events as "type: line" for comparison
+func summary(events []Event) []string {
+	var out []string
+	for _, e := range events {
+		switch {
+		case e.Todo != nil:
+			out = append(out, string(e.Type)+": "+e.Todo.String())
+		case e.Project != nil:
+			out = append(out, string(e.Type)+": "+e.Project.Name+" "+e.PreviousPhase+" -> "+e.Project.Phase)
+		default:
+			out = append(out, string(e.Type))
+		}
+	}
+	return out
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		before Snapshot
+		after  Snapshot
+		want   []string
+	}{
+		{
+			name:   "added",
+			before: Snapshot{Todos: todos("Fix bike")},
+			after:  Snapshot{Todos: todos("Fix bike", "Call mom @phone")},
+			want:   []string{"todo.added: Call mom @phone"},
+		},
+		{
+			name:   "duplicate line added",
+			before: Snapshot{Todos: todos("Fix bike")},
+			after:  Snapshot{Todos: todos("Fix bike", "Fix bike")},
+			want:   []string{"todo.added: Fix bike"},
+		},
+		{
+			name:   "completed",
+			before: Snapshot{Todos: todos("(A) Fix bike +garage", "Call mom")},
+			after:  Snapshot{Todos: todos("Call mom", "x 2025-03-12 (A) Fix bike +garage")},
+			want:   []string{"todo.completed: x 2025-03-12 (A) Fix bike +garage"},
+		},
+		{
+			name:   "edited and deleted todos don't count",
+			before: Snapshot{Todos: todos("Fix bike", "Call mom")},
+			after:  Snapshot{Todos: todos("(B) Fix bike due:2025-03-14")},
+			want:   nil,
+		},
+		{
+			name:   "completing the last blocker unblocks",
+			before: Snapshot{Todos: todos("Spec id:1", "Design id:2", "Build dep:1,2", "Docs dep:1")},
+			after:  Snapshot{Todos: todos("x Spec id:1", "Design id:2", "Build dep:1,2", "Docs dep:1")},
+			want:   []string{"todo.completed: x Spec id:1", "todo.unblocked: Docs dep:1"},
+		},
+		{
+			name:   "done todos added as done don't count",
+			before: Snapshot{Todos: todos()},
+			after:  Snapshot{Todos: todos("x 2025-03-12 Imported")},
+			want:   nil,
+		},
+		{
+			name: "reminder activated",
+			before: Snapshot{
+				Todos:     todos("Fix bike"),
+				Reminders: todos("Dentist remind:2025-03-12", "Taxes remind:2025-04-01"),
+			},
+			after: Snapshot{
+				Todos:     todos("Fix bike", "2025-03-12 Dentist"),
+				Reminders: todos("Taxes remind:2025-04-01"),
+			},
+			want: []string{"reminder.activated: 2025-03-12 Dentist"},
+		},
+		{
+			name:   "recurring generated",
+			before: Snapshot{Todos: todos()},
+			after:  Snapshot{Todos: todos("2025-03-12 Stretch +health recur:2025-03-12")},
+			want:   []string{"recurring.generated: 2025-03-12 Stretch +health recur:2025-03-12"},
+		},
+		{
+			name:   "project phase changed",
+			before: Snapshot{Projects: projects(t, "(A) Garden phase:1", "Boat phase:2", "Shed phase:1")},
+			after:  Snapshot{Projects: projects(t, "(A) Garden phase:2", "Boat phase:2", "x 2025-03-12 Shed phase:3", "New phase:1")},
+			want:   []string{"project.phase_changed: Garden 1 -> 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summary(Diff(tt.before, tt.after, now))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordAndRead(t *testing.T) {
+	atpDir := t.TempDir()
+	todoDir := filepath.Join(atpDir, "todo")
+	os.MkdirAll(todoDir, 0755)
+	os.WriteFile(todo.ActiveTodoPath(todoDir), []byte("Fix bike\n"), 0644)
+
+	err := Record(atpDir, func() error {
+		return todo.WriteTodoDir(todoDir, todos("Fix bike", "Call mom"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Emit(atpDir, Synced("github", time.Now()))
+
+	events, offset, err := Read(atpDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(events); !reflect.DeepEqual(got, []string{"todo.added: Call mom", "sync.completed"}) {
+		t.Errorf("logged events = %q", got)
+	}
+	if events[1].Source != "github" || events[0].ID == "" || events[0].ID == events[1].ID {
+		t.Errorf("events = %+v", events)
+	}
+
+	// nothing new, and a half written line waits for its newline
+	file, _ := os.OpenFile(LogPath(atpDir), os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString(`{"id":"x","type":"todo.added"`)
+	file.Close()
+	if events, next, _ := Read(atpDir, offset); len(events) != 0 || next != offset {
+		t.Errorf("Read() = %v, %d; want nothing at %d", events, next, offset)
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	atpDir := t.TempDir()
+	err := Record(atpDir, func() error { return os.ErrPermission })
+	if err != os.ErrPermission {
+		t.Errorf("Record() = %v, want the error of fn", err)
+	}
+	if events, _, _ := Read(atpDir, 0); len(events) != 0 {
+		t.Errorf("events were logged for a failed change: %v", events)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	atpDir := t.TempDir()
+	os.WriteFile(LogPath(atpDir), []byte(strings.Repeat(" ", MaxLogSize+1)+"\n"), 0644)
+	offset := End(atpDir)
+
+	Emit(atpDir, Synced("caldav", time.Now()))
+	if _, err := os.Stat(LogPath(atpDir) + ".1"); err != nil {
+		t.Fatalf("log wasn't rotated: %v", err)
+	}
+
+	// the old offset is past the end, so reading starts over
+	events, _, err := Read(atpDir, offset)
+	if err != nil || len(events) != 1 || events[0].Source != "caldav" {
+		t.Errorf("Read() after rotation = %v, %v", events, err)
+	}
+}
+
+func TestFollowAndBus(t *testing.T) {
+	atpDir := t.TempDir()
+	Emit(atpDir, Synced("old", time.Now()))
+
+	bus := NewBus()
+	ch, unsubscribe := bus.Subscribe(10)
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Follow(ctx, atpDir, End(atpDir), 10*time.Millisecond, bus.Publish)
+
+	Emit(atpDir, Synced("github", time.Now()))
+	select {
+	case e := <-ch:
+		if e.Source != "github" {
+			t.Errorf("got %+v, want the github sync", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+}
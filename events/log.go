@@ -0,0 +1,135 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogFile is the event log in the ATP directory, one JSON event per line
+const LogFile = "events.jsonl"
+
+// MaxLogSize is when the log is rotated to events.jsonl.1. Readers that
+// fall behind a rotation miss the events in between.
+const MaxLogSize = 1 << 20
+
+// LogPath returns the event log of an ATP directory
+func LogPath(atpDir string) string {
+	return filepath.Join(atpDir, LogFile)
+}
+
+// Emit appends events to the log
+func Emit(atpDir string, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+	}
+
+	path := LogPath(atpDir)
+	if info, err := os.Stat(path); err == nil && info.Size() > MaxLogSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate event log: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+	// one write so readers never see half of a batch
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	return nil
+}
+
+// Read returns the events logged after offset and the offset to read from
+// next. An offset past the end means the log was rotated, it is then read
+// from the start.
+func Read(atpDir string, offset int64) ([]Event, int64, error) {
+	file, err := os.Open(LogPath(atpDir))
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to read event log: %w", err)
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, fmt.Errorf("failed to read event log: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	// a line still being written is picked up next time
+	end := bytes.LastIndexByte(data, '\n') + 1
+	var events []Event
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			// skip what can't be decoded rather than stalling every reader
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, offset + int64(end), nil
+}
+
+// End returns the current end of the log, where Follow starts
+func End(atpDir string) int64 {
+	info, err := os.Stat(LogPath(atpDir))
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// Follow calls fn for every event logged after offset, checking the log
+// every poll, until the context is cancelled
+func Follow(ctx context.Context, atpDir string, offset int64, poll time.Duration, fn func(Event)) error {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		events, next, err := Read(atpDir, offset)
+		if err != nil {
+			return err
+		}
+		offset = next
+		for _, e := range events {
+			fn(e)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
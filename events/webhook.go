@@ -0,0 +1,138 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+)
+
+// Headers sent with every webhook delivery
+const (
+	EventHeader     = "X-Atp-Event"
+	DeliveryHeader  = "X-Atp-Delivery"
+	SignatureHeader = "X-Atp-Signature"
+)
+
+// Defaults for Webhooks
+const (
+	DefaultAttempts = 5
+	DefaultBackoff  = time.Second
+)
+
+// Webhooks posts events to the configured hooks
+type Webhooks struct {
+	Hooks  []config.WebhookConfig
+	Client *http.Client
+	// Attempts is how often a delivery is tried before giving up
+	Attempts int
+	// Backoff is the wait before the first retry, it doubles every time
+	Backoff time.Duration
+}
+
+// NewWebhooks returns a sender for the hooks with the default retries
+func NewWebhooks(hooks []config.WebhookConfig) *Webhooks {
+	return &Webhooks{
+		Hooks:    hooks,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		Attempts: DefaultAttempts,
+		Backoff:  DefaultBackoff,
+	}
+}
+
+// Sign returns the signature header value for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value in constant time, for receivers
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Deliver sends the event to every hook that wants it. Hooks are tried
+// independently, the error joins the ones that failed for good.
+func (w *Webhooks) Deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	var errs []error
+	for _, hook := range w.Hooks {
+		if !hook.Wants(string(e.Type)) {
+			continue
+		}
+		if err := w.send(ctx, hook, e, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", hook.URL, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send posts to one hook, retrying with backoff on network errors, 5xx, 408
+// and 429
+func (w *Webhooks) send(ctx context.Context, hook config.WebhookConfig, e Event, body []byte) error {
+	attempts := max(w.Attempts, 1)
+	backoff := w.Backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		if retry, err = w.post(ctx, hook, e, body); err == nil || !retry || attempt == attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying
+func (w *Webhooks) post(ctx context.Context, hook config.WebhookConfig, e Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "atp-webhook")
+	req.Header.Set(EventHeader, string(e.Type))
+	req.Header.Set(DeliveryHeader, e.ID)
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("unexpected status %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
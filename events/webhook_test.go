@@ -0,0 +1,149 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// receiver is a stand-in for a webhook endpoint. It answers with the
+// queued statuses first and 204 after that.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusNoContent
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, string) {
+	rc := &receiver{statuses: statuses}
+	ts := httptest.NewServer(rc)
+	t.Cleanup(ts.Close)
+	return rc, ts.URL
+}
+
+func testWebhooks(hooks ...config.WebhookConfig) *Webhooks {
+	w := NewWebhooks(hooks)
+	w.Backoff = time.Millisecond
+	w.Attempts = 3
+	return w
+}
+
+func completed() Event {
+	e := New(TodoCompleted, time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC))
+	e.Todo = todo.FromString("x 2025-03-12 Fix bike +garage")
+	return e
+}
+
+func TestWebhookSigned(t *testing.T) {
+	rc, url := newReceiver(t)
+	e := completed()
+
+	if err := testWebhooks(config.WebhookConfig{URL: url, Secret: "s3cret"}).Deliver(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(rc.requests))
+	}
+
+	req, body := rc.requests[0], rc.bodies[0]
+	if req.Header.Get(EventHeader) != "todo.completed" || req.Header.Get(DeliveryHeader) != e.ID {
+		t.Errorf("headers = %v", req.Header)
+	}
+	if !Verify("s3cret", body, req.Header.Get(SignatureHeader)) {
+		t.Errorf("signature %q doesn't match the body", req.Header.Get(SignatureHeader))
+	}
+	if Verify("wrong", body, req.Header.Get(SignatureHeader)) {
+		t.Error("signature matches the wrong secret")
+	}
+
+	var got Event
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != TodoCompleted || got.Todo.String() != "x 2025-03-12 Fix bike +garage" {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantReqs int
+		wantErr  string
+	}{
+		{"recovers after server errors", []int{500, 503}, 3, ""},
+		{"rate limited", []int{429}, 2, ""},
+		{"gives up", []int{500, 500, 500}, 3, "500 Internal Server Error"},
+		{"client errors aren't retried", []int{400}, 1, "400 Bad Request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, url := newReceiver(t, tt.statuses...)
+			err := testWebhooks(config.WebhookConfig{URL: url}).Deliver(context.Background(), completed())
+
+			if len(rc.requests) != tt.wantReqs {
+				t.Errorf("got %d requests, want %d", len(rc.requests), tt.wantReqs)
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Deliver() = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Deliver() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookEventFilter(t *testing.T) {
+	all, allURL := newReceiver(t)
+	some, someURL := newReceiver(t)
+	failing, failingURL := newReceiver(t, 404)
+
+	w := testWebhooks(
+		config.WebhookConfig{URL: allURL},
+		config.WebhookConfig{URL: someURL, Events: []string{"sync.completed"}},
+		config.WebhookConfig{URL: failingURL, Events: []string{"todo.completed"}},
+	)
+
+	// one hook failing doesn't stop the others
+	if err := w.Deliver(context.Background(), completed()); err == nil || !strings.Contains(err.Error(), failingURL) {
+		t.Errorf("Deliver() = %v, want the failing hook", err)
+	}
+	w.Deliver(context.Background(), Synced("github", time.Now()))
+
+	if len(all.requests) != 2 || len(some.requests) != 1 || len(failing.requests) != 1 {
+		t.Errorf("requests: all %d, sync only %d, failing %d", len(all.requests), len(some.requests), len(failing.requests))
+	}
+	if some.requests[0].Header.Get(EventHeader) != "sync.completed" {
+		t.Errorf("sync only hook got %s", some.requests[0].Header.Get(EventHeader))
+	}
+	if all.requests[0].Header.Get(SignatureHeader) != "" {
+		t.Error("unsigned hook got a signature")
+	}
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("csv", csvFormatter{})
+}
+
+type csvFormatter struct{}
+
+func (csvFormatter) Extension() string { return "csv" }
+
+// Write writes one table, todos or projects. Lists like projects and
+// labels are space separated in a single column.
+func (csvFormatter) Write(w io.Writer, data Data, opts Options) error {
+	if len(data.Todos) > 0 && len(data.Projects) > 0 {
+		return errors.New("csv holds a single table, export todos and projects separately")
+	}
+
+	var rows [][]string
+	if len(data.Projects) > 0 {
+		rows = append(rows, []string{"name", "phase", "active", "done", "killed", "repo", "completion_date"})
+		for _, p := range data.Projects {
+			rows = append(rows, []string{
+				p.Name,
+				p.Phase,
+				strconv.FormatBool(p.Active),
+				strconv.FormatBool(p.Done),
+				strconv.FormatBool(p.Killed()),
+				p.ToTodo().Labels["repo"],
+				formatDate(p.CompletionDate()),
+			})
+		}
+	} else {
+		rows = append(rows, []string{"done", "priority", "creation_date", "completion_date", "description", "projects", "contexts", "labels"})
+		for _, t := range data.Todos {
+			rows = append(rows, []string{
+				strconv.FormatBool(t.Done),
+				t.Priority,
+				formatDate(t.CreationDate),
+				formatDate(t.CompletionDate),
+				t.Description,
+				strings.Join(prefixed("+", t.Projects), " "),
+				strings.Join(prefixed("@", t.Contexts), " "),
+				strings.Join(labelList(t.Labels), " "),
+			})
+		}
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
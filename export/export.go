@@ -0,0 +1,127 @@
+// Package export writes todos and projects in formats other tools read.
+// Formatters register themselves by name so commands can list and look
+// them up.
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Ways the markdown formatter groups todos
+const (
+	GroupProject = "project"
+	GroupContext = "context"
+)
+
+// Data is what gets exported, a formatter writes whatever is set
+type Data struct {
+	Todos    []*todo.Todo
+	Projects []*project.Project
+}
+
+// Options change how a formatter writes the data
+type Options struct {
+	// GroupBy is project (default) or context for formats that group todos
+	GroupBy string
+	// Now is the export time, the current time when zero
+	Now time.Time
+}
+
+// Formatter writes data in one format
+type Formatter interface {
+	// Extension is the file extension for the format, without the dot
+	Extension() string
+	Write(w io.Writer, data Data, opts Options) error
+}
+
+var formatters = make(map[string]Formatter)
+
+// Register makes a formatter available by name. It panics when the name is
+// taken since that is a programming error.
+func Register(name string, f Formatter) {
+	if _, ok := formatters[name]; ok {
+		panic(fmt.Sprintf("export format %s registered twice", name))
+	}
+	formatters[name] = f
+}
+
+// Lookup returns the formatter registered under the name
+func Lookup(name string) (Formatter, error) {
+	f, ok := formatters[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format '%s' (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return f, nil
+}
+
+// Names lists the registered formats alphabetically
+func Names() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write exports the data with the named formatter
+func Write(w io.Writer, format string, data Data, opts Options) error {
+	f, err := Lookup(format)
+	if err != nil {
+		return err
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	return f.Write(w, data, opts)
+}
+
+// FilterProjects returns the projects whose todo.txt form the filter selects
+func FilterProjects(f todo.Filter, projects []*project.Project) []*project.Project {
+	selected := []*project.Project{}
+	for _, p := range projects {
+		if f.Match(p.ToTodo()) {
+			selected = append(selected, p)
+		}
+	}
+	return selected
+}
+
+// ------------------------------- Helpers -------------------------------
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// labelList formats labels as sorted key:value pairs
+func labelList(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+":"+labels[key])
+	}
+	return pairs
+}
+
+func prefixed(prefix string, values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		result = append(result, prefix+v)
+	}
+	return result
+}
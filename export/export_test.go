@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/ical"
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+var now = time.Date(2025, 3, 12, 9, 30, 0, 0, time.UTC)
+
+func testTodos() []*todo.Todo {
+	return []*todo.Todo{
+		todo.FromString("(A) 2025-03-01 Call mom +family @phone due:2025-03-10"),
+		todo.FromString("Fix bike, chain +garage +family"),
+		todo.FromString("Dentist @town remind:2025-04-02 id:7"),
+		todo.FromString("x 2025-03-05 2025-03-01 Pay rent due:2025-03-05"),
+	}
+}
+
+func export(t *testing.T, format string, data Data, opts Options) string {
+	t.Helper()
+	opts.Now = now
+	var out bytes.Buffer
+	if err := Write(&out, format, data, opts); err != nil {
+		t.Fatalf("%s export failed: %v", format, err)
+	}
+	return out.String()
+}
+
+func TestRegistry(t *testing.T) {
+	if got := strings.Join(Names(), ","); got != "csv,ical,json,markdown" {
+		t.Errorf("Names() = %s", got)
+	}
+	if _, err := Lookup("yaml"); err == nil {
+		t.Error("Lookup(yaml) should fail")
+	}
+	if f, err := Lookup("ICAL"); err != nil || f.Extension() != "ics" {
+		t.Errorf("Lookup(ICAL) = %v, %v", f, err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	p, _ := project.FromTodo(todo.FromString("(A) Garden phase:2"), nil)
+	p.SetPhase("2", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	out := export(t, "json", Data{Todos: testTodos()[:1], Projects: []*project.Project{p}}, Options{})
+
+	var decoded struct {
+		Todos    []*todo.Todo     `json:"todos"`
+		Projects []map[string]any `json:"projects"`
+	}
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, out)
+	}
+	if len(decoded.Todos) != 1 || decoded.Todos[0].Labels["due"] != "2025-03-10" || decoded.Todos[0].Priority != "A" {
+		t.Errorf("todos = %s", out)
+	}
+	if len(decoded.Projects) != 1 || decoded.Projects[0]["active"] != true || decoded.Projects[0]["phase_dates"] == nil {
+		t.Errorf("projects = %s", out)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	out := export(t, "csv", Data{Todos: testTodos()[:2]}, Options{})
+	want := "done,priority,creation_date,completion_date,description,projects,contexts,labels\n" +
+		"false,A,2025-03-01,,Call mom,+family,@phone,due:2025-03-10\n" +
+		"false,,,,\"Fix bike, chain\",+garage +family,,\n"
+	if out != want {
+		t.Errorf("csv =\n%s\nwant\n%s", out, want)
+	}
+
+	var buf bytes.Buffer
+	p, _ := project.FromTodo(todo.FromString("Garden"), nil)
+	if err := Write(&buf, "csv", Data{Todos: testTodos(), Projects: []*project.Project{p}}, Options{}); err == nil {
+		t.Error("csv with todos and projects should fail")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	out := export(t, "markdown", Data{Todos: testTodos()}, Options{})
+	want := `# Todos
+
+## +family
+
+- [ ] (A) Call mom @phone due:2025-03-10
+- [ ] Fix bike, chain +garage
+
+## +garage
+
+- [ ] Fix bike, chain +family
+
+## No project
+
+- [ ] Dentist @town id:7 remind:2025-04-02
+- [x] Pay rent due:2025-03-05
+`
+	if out != want {
+		t.Errorf("markdown =\n%s\nwant\n%s", out, want)
+	}
+
+	out = export(t, "markdown", Data{Todos: testTodos()}, Options{GroupBy: GroupContext})
+	for _, section := range []string{"## @phone", "## @town", "## No context"} {
+		if !strings.Contains(out, section) {
+			t.Errorf("markdown by context is missing %s:\n%s", section, out)
+		}
+	}
+}
+
+func TestICal(t *testing.T) {
+	out := export(t, "ical", Data{Todos: testTodos()}, Options{})
+
+	if got := strings.Count(out, "BEGIN:VTODO"); got != 2 {
+		t.Errorf("got %d VTODOs, want 2 for the due: todos", got)
+	}
+	if got := strings.Count(out, "BEGIN:VEVENT"); got != 1 {
+		t.Errorf("got %d VEVENTs, want 1 for the remind: todo", got)
+	}
+	for _, want := range []string{
+		"DUE;VALUE=DATE:20250310\r\n",
+		"PRIORITY:1\r\n",
+		"CATEGORIES:+family,@phone\r\n",
+		"STATUS:COMPLETED\r\n",
+		"UID:remind-atp-7\r\n",
+		"DTSTART;VALUE=DATE:20250402\r\nDTEND;VALUE=DATE:20250403\r\n",
+		"DTSTAMP:20250312T093000Z\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, out)
+		}
+	}
+
+	// uids don't change between exports
+	if ical.UID(testTodos()[0]) != ical.UID(testTodos()[0]) {
+		t.Error("UID is not stable")
+	}
+}
+
+func TestFilterProjects(t *testing.T) {
+	active, _ := project.FromTodo(todo.FromString("(A) Garden"), nil)
+	inactive, _ := project.FromTodo(todo.FromString("Boat"), nil)
+	f, _ := todo.ParseFilter([]string{"(A)"})
+
+	got := FilterProjects(f, []*project.Project{active, inactive})
+	if len(got) != 1 || got[0].Name != "Garden" {
+		t.Errorf("FilterProjects = %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/arjungandhi/atp/ical"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func init() {
+	Register("ical", icalFormatter{})
+}
+
+type icalFormatter struct{}
+
+func (icalFormatter) Extension() string { return "ics" }
+
+// Write writes a VTODO for every todo with a due: date and an all day
+// VEVENT for every remind: date. Other todos and projects have no date to
+// put in a calendar and are left out.
+func (icalFormatter) Write(w io.Writer, data Data, opts Options) error {
+	cal := ical.NewCalendar(ical.ProdID)
+	for _, t := range data.Todos {
+		if _, err := time.Parse("2006-01-02", t.Labels["due"]); err == nil {
+			cal.Components = append(cal.Components, ical.VTodo(t, opts.Now))
+		}
+		if remind, err := time.Parse("2006-01-02", t.Labels["remind"]); err == nil {
+			cal.Components = append(cal.Components, reminderEvent(t, remind, opts.Now))
+		}
+	}
+	return ical.Encode(w, cal)
+}
+
+func reminderEvent(t *todo.Todo, date time.Time, now time.Time) *ical.Component {
+	c := &ical.Component{Name: "VEVENT"}
+	c.Add("UID", "remind-"+ical.UID(t))
+	c.AddDateTime("DTSTAMP", now)
+	c.AddText("SUMMARY", t.Description)
+	c.AddDate("DTSTART", date)
+	c.AddDate("DTEND", date.AddDate(0, 0, 1))
+	c.Add("TRANSP", "TRANSPARENT")
+	if categories := ical.Categories(t); len(categories) > 0 {
+		c.AddList("CATEGORIES", categories)
+	}
+	return c
+}
@@ -0,0 +1,34 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func init() {
+	Register("json", jsonFormatter{})
+}
+
+type jsonFormatter struct{}
+
+type jsonExport struct {
+	Todos    []*todo.Todo       `json:"todos,omitempty"`
+	Projects []*project.Project `json:"projects,omitempty"`
+}
+
+func (jsonFormatter) Extension() string { return "json" }
+
+func (jsonFormatter) Write(w io.Writer, data Data, opts Options) error {
+	out := jsonExport{Todos: data.Todos, Projects: data.Projects}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("failed to encode json: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func init() {
+	Register("markdown", markdownFormatter{})
+}
+
+type markdownFormatter struct{}
+
+func (markdownFormatter) Extension() string { return "md" }
+
+// Write writes todos as checklists with a section per +project or
+// @context, todos with several tags are listed under each of them
+func (markdownFormatter) Write(w io.Writer, data Data, opts Options) error {
+	out := bufio.NewWriter(w)
+
+	if len(data.Todos) > 0 {
+		fmt.Fprintf(out, "# Todos\n")
+		writeTodoGroups(out, data.Todos, opts.GroupBy)
+	}
+	if len(data.Projects) > 0 {
+		if len(data.Todos) > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "# Projects\n")
+		writeProjects(out, data.Projects)
+	}
+
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write markdown: %w", err)
+	}
+	return nil
+}
+
+func writeTodoGroups(out *bufio.Writer, todos []*todo.Todo, groupBy string) {
+	prefix, other := "+", "No project"
+	tags := func(t *todo.Todo) []string { return t.Projects }
+	if groupBy == GroupContext {
+		prefix, other = "@", "No context"
+		tags = func(t *todo.Todo) []string { return t.Contexts }
+	}
+
+	groups := make(map[string][]*todo.Todo)
+	var ungrouped []*todo.Todo
+	for _, t := range todos {
+		if len(tags(t)) == 0 {
+			ungrouped = append(ungrouped, t)
+			continue
+		}
+		for _, tag := range tags(t) {
+			groups[tag] = append(groups[tag], t)
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+
+	for _, name := range names {
+		fmt.Fprintf(out, "\n## %s%s\n\n", prefix, name)
+		for _, t := range groups[name] {
+			fmt.Fprintf(out, "- %s %s\n", checkbox(t.Done), checklistText(t, prefix+name))
+		}
+	}
+	if len(ungrouped) > 0 {
+		fmt.Fprintf(out, "\n## %s\n\n", other)
+		for _, t := range ungrouped {
+			fmt.Fprintf(out, "- %s %s\n", checkbox(t.Done), checklistText(t, ""))
+		}
+	}
+}
+
+// checklistText is the todo without the tag of the section it is listed in
+func checklistText(t *todo.Todo, section string) string {
+	var parts []string
+	if t.Priority != "" {
+		parts = append(parts, "("+t.Priority+")")
+	}
+	parts = append(parts, t.Description)
+	for _, tag := range append(prefixed("+", t.Projects), prefixed("@", t.Contexts)...) {
+		if tag != section {
+			parts = append(parts, tag)
+		}
+	}
+	parts = append(parts, labelList(t.Labels)...)
+	return strings.Join(parts, " ")
+}
+
+func writeProjects(out *bufio.Writer, projects []*project.Project) {
+	sections := []struct {
+		title string
+		match func(p *project.Project) bool
+	}{
+		{"Active", func(p *project.Project) bool { return p.Active && !p.Done }},
+		{"Inactive", func(p *project.Project) bool { return !p.Active && !p.Done }},
+		{"Done", func(p *project.Project) bool { return p.Done }},
+	}
+
+	for _, section := range sections {
+		var lines []string
+		for _, p := range projects {
+			if !section.match(p) {
+				continue
+			}
+			line := fmt.Sprintf("- %s %s", checkbox(p.Done), p.Name)
+			if p.Phase != "" {
+				line += fmt.Sprintf(" (phase %s)", p.Phase)
+			}
+			if p.Killed() {
+				line += " (killed)"
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\n## %s\n\n%s\n", section.title, strings.Join(lines, "\n"))
+	}
+}
+
+func checkbox(done bool) string {
+	if done {
+		return "[x]"
+	}
+	return "[ ]"
+}
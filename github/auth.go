@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/arjungandhi/atp/config"
+	"golang.org/x/oauth2"
+)
+
+// TokenStatus describes who a token belongs to and what it may do
+type TokenStatus struct {
+	Login string
+	// Scopes are the OAuth scopes of classic tokens, fine-grained tokens
+	// don't report any
+	Scopes      []string
+	FineGrained bool
+	// Expiration is reported by GitHub for tokens that expire
+	Expiration string
+}
+
+// CheckToken asks a GitHub host about a token
+func CheckToken(host string, token string, opts ClientOptions) (*TokenStatus, error) {
+	ctx := context.Background()
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	// the cache would hide the scope headers of a 304, so skip it
+	opts.CacheDir = ""
+	tc := oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: newTransport(opts)}), ts)
+
+	restClient, _, err := newAPIClients(tc, host, opts.Endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	user, resp, err := restClient.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	status := &TokenStatus{
+		Login:      user.GetLogin(),
+		Expiration: resp.Header.Get("GitHub-Authentication-Token-Expiration"),
+	}
+
+	scopes, classic := resp.Header["X-Oauth-Scopes"]
+	status.FineGrained = !classic
+	if classic && len(scopes) > 0 {
+		for _, scope := range strings.Split(scopes[0], ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				status.Scopes = append(status.Scopes, scope)
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// TokenOwner is an owner on a host that needs a token
+type TokenOwner struct {
+	Host  string
+	Owner string // empty for the user's default token
+}
+
+// TokenOwners lists the owners a sync needs tokens for: the user's default
+// token on every host and the owner of every configured project. Owners with
+// a token in the config but no project are checked on github.com.
+func TokenOwners(cfg *config.Config) []TokenOwner {
+	var owners []TokenOwner
+	seen := make(map[TokenOwner]bool)
+	add := func(host string, owner string) {
+		key := TokenOwner{Host: strings.ToLower(host), Owner: strings.ToLower(owner)}
+		if !seen[key] {
+			seen[key] = true
+			owners = append(owners, TokenOwner{Host: host, Owner: owner})
+		}
+	}
+
+	projects := cfg.GetAllGitHubProjects()
+	for _, host := range projectHosts(projects) {
+		add(host, "")
+	}
+	for _, project := range projects {
+		add(project.Host(), scopeForProject(project).tokenOwner())
+	}
+
+	var configured []string
+	for owner := range cfg.GitHub.Auth.Tokens {
+		configured = append(configured, owner)
+	}
+	sort.Strings(configured)
+	for _, owner := range configured {
+		if !seen[TokenOwner{Host: config.DefaultGitHubHost, Owner: strings.ToLower(owner)}] && !ownerHasProject(projects, owner) {
+			add(config.DefaultGitHubHost, owner)
+		}
+	}
+
+	sort.SliceStable(owners, func(i, j int) bool {
+		if owners[i].Host != owners[j].Host {
+			return owners[i].Host < owners[j].Host
+		}
+		return owners[i].Owner < owners[j].Owner
+	})
+	return owners
+}
+
+func ownerHasProject(projects []config.GitHubProject, owner string) bool {
+	for _, project := range projects {
+		if strings.EqualFold(scopeForProject(project).tokenOwner(), owner) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// reChecklistItem matches markdown task list items, e.g. "- [ ] write tests"
+var reChecklistItem = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.+?)\s*$`)
+
+// reBlockedBy matches the start of a dependency note in an issue body, e.g.
+// "Blocked by #12" or "depends on owner/repo#3"
+var reBlockedBy = regexp.MustCompile(`(?i)\b(?:blocked by|depends on)\b:?`)
+
+// reIssueRef matches issue references: #12, owner/repo#12 or an issue URL
+var reIssueRef = regexp.MustCompile(`^\s*(?:,|and)?\s*(?:(https?://\S+/issues/\d+)|(?:([\w.-]+/[\w.-]+))?#(\d+))`)
+
+// checklistItem is a single task list entry from an issue body
+type checklistItem struct {
+	text    string
+	checked bool
+}
+
+// enrichTodo copies the issue metadata selected in the config onto the todo.
+// Contexts and projects are only ever added, local ones are kept.
+func enrichTodo(t *todo.Todo, issue IssueWithStatus, cfg config.EnrichConfig) {
+	for _, label := range issue.Labels {
+		if context, ok := lookupLabel(cfg.LabelContexts, label); ok {
+			t.Contexts = appendMissing(t.Contexts, context)
+		}
+		if project, ok := lookupLabel(cfg.LabelProjects, label); ok {
+			t.Projects = appendMissing(t.Projects, project)
+		}
+	}
+
+	if due := issueDueDate(issue, cfg); due != "" {
+		t.Labels["due"] = due
+	}
+
+	if cfg.Dependencies {
+		for _, url := range blockedBy(issue.Body, issue.URL) {
+			todo.AddReference(t, todo.DepLabel, url)
+		}
+	}
+}
+
+// issueDueDate picks the due date for an issue, the board's date field wins
+// over the milestone
+func issueDueDate(issue IssueWithStatus, cfg config.EnrichConfig) string {
+	if cfg.DueField != "" {
+		for name, date := range issue.DateFields {
+			if strings.EqualFold(name, cfg.DueField) && date != "" {
+				return date
+			}
+		}
+	}
+
+	if cfg.MilestoneDue && !issue.MilestoneDue.IsZero() {
+		return issue.MilestoneDue.Format("2006-01-02")
+	}
+
+	return ""
+}
+
+// lookupLabel finds a GitHub label in a mapping, ignoring case
+func lookupLabel(mapping map[string]string, label string) (string, bool) {
+	for name, value := range mapping {
+		if strings.EqualFold(name, label) && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func appendMissing(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// blockedBy returns the URLs of the issues the body says the issue at
+// issueURL is blocked by. Bare #123 references are in the issue's repo.
+func blockedBy(body string, issueURL string) []string {
+	ref, err := parseGitHubURL(issueURL)
+	if err != nil {
+		return nil
+	}
+
+	var urls []string
+	for _, line := range strings.Split(body, "\n") {
+		for _, loc := range reBlockedBy.FindAllStringIndex(line, -1) {
+			// a list of references follows, e.g. "blocked by #1, #2 and #3"
+			rest := line[loc[1]:]
+			for {
+				match := reIssueRef.FindStringSubmatch(rest)
+				if match == nil {
+					break
+				}
+				rest = rest[len(match[0]):]
+
+				switch {
+				case match[1] != "":
+					urls = appendMissing(urls, match[1])
+				case match[2] != "":
+					urls = appendMissing(urls, fmt.Sprintf("https://%s/%s/issues/%s", ref.host, match[2], match[3]))
+				default:
+					urls = appendMissing(urls, fmt.Sprintf("https://%s/%s/%s/issues/%s", ref.host, ref.owner, ref.repo, match[3]))
+				}
+			}
+		}
+	}
+	return urls
+}
+
+// parseChecklist returns the task list items in an issue body
+func parseChecklist(body string) []checklistItem {
+	var items []checklistItem
+	for _, line := range strings.Split(body, "\n") {
+		match := reChecklistItem.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		items = append(items, checklistItem{
+			text:    match[2],
+			checked: match[1] != " ",
+		})
+	}
+	return items
+}
+
+// subTodo builds the todo for a checklist item of the issue at parentURL.
+// It goes through the todo.txt parser so it matches itself once written.
+func subTodo(parentURL string, item checklistItem) *todo.Todo {
+	t := todo.FromString(sanitizeIssueTitle(item.text))
+	t.Labels[todo.ParentLabel] = parentURL
+	return t
+}
+
+// subTodoKey identifies a sub-todo by its parent and description
+func subTodoKey(parentURL string, description string) string {
+	return parentURL + "\x00" + description
+}
+
+// buildSubTodoMap indexes the todos that were created from checklist items
+func buildSubTodoMap(todos []*todo.Todo) map[string]*todo.Todo {
+	subTodos := make(map[string]*todo.Todo)
+	for _, t := range todos {
+		if parent := t.Labels[todo.ParentLabel]; parent != "" {
+			subTodos[subTodoKey(parent, t.Description)] = t
+		}
+	}
+	return subTodos
+}
+
+// planSubTodos expands the issue's checklist into sub-todos. Missing items are
+// created and items ticked off on GitHub complete their sub-todo. Returns the
+// newly created todos.
+func (p *SyncPlan) planSubTodos(issue IssueWithStatus, subTodos map[string]*todo.Todo) []*todo.Todo {
+	var created []*todo.Todo
+	for _, item := range parseChecklist(issue.Body) {
+		sub := subTodo(issue.URL, item)
+		key := subTodoKey(issue.URL, sub.Description)
+
+		existing, exists := subTodos[key]
+		if !exists {
+			if item.checked {
+				continue
+			}
+			subTodos[key] = sub
+			p.recordChange(issue.URL, nil, sub)
+			created = append(created, sub)
+			continue
+		}
+
+		if item.checked && !existing.Done {
+			before := existing.Clone()
+			existing.Done = true
+			existing.CompletionDate = time.Now()
+			p.recordChange(issue.URL, before, existing)
+		}
+	}
+	return created
+}
@@ -0,0 +1,192 @@
+package github
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func TestEnrichTodo(t *testing.T) {
+	cfg := config.EnrichConfig{
+		LabelContexts: map[string]string{"bug": "bug", "Needs Review": "review"},
+		LabelProjects: map[string]string{"frontend": "web"},
+		MilestoneDue:  true,
+		DueField:      "Target",
+	}
+
+	tests := []struct {
+		name     string
+		issue    IssueWithStatus
+		contexts []string
+		projects []string
+		due      string
+	}{
+		{
+			name: "labels and milestone",
+			issue: IssueWithStatus{ProjectIssue: ProjectIssue{
+				Labels:       []string{"BUG", "needs review", "frontend", "unmapped"},
+				MilestoneDue: time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC),
+			}},
+			contexts: []string{"home", "bug", "review"},
+			projects: []string{"github", "web"},
+			due:      "2025-03-01",
+		},
+		{
+			name: "date field wins over milestone",
+			issue: IssueWithStatus{
+				ProjectIssue: ProjectIssue{MilestoneDue: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+				DateFields:   map[string]string{"target": "2025-02-20"},
+			},
+			contexts: []string{"home"},
+			projects: []string{"github"},
+			due:      "2025-02-20",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := todo.FromString("Fix it +github @home")
+			enrichTodo(input, test.issue, cfg)
+
+			if !equalStrings(input.Contexts, test.contexts) {
+				t.Errorf("contexts = %v, want %v", input.Contexts, test.contexts)
+			}
+			if !equalStrings(input.Projects, test.projects) {
+				t.Errorf("projects = %v, want %v", input.Projects, test.projects)
+			}
+			if input.Labels["due"] != test.due {
+				t.Errorf("due = %q, want %q", input.Labels["due"], test.due)
+			}
+		})
+	}
+}
+
+func TestParseChecklist(t *testing.T) {
+	body := "Steps:\r\n- [ ] write tests\r\n  * [x] fix parser\n- [] not a task\n+ [ ] update docs  \n"
+
+	items := parseChecklist(body)
+	want := []checklistItem{
+		{text: "write tests"},
+		{text: "fix parser", checked: true},
+		{text: "update docs"},
+	}
+
+	if len(items) != len(want) {
+		t.Fatalf("parseChecklist() = %v, want %v", items, want)
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("parseChecklist()[%d] = %+v, want %+v", i, items[i], want[i])
+		}
+	}
+}
+
+func TestPlanSyncChecklist(t *testing.T) {
+	url := "https://github.com/acme/app/issues/1"
+	fetch := &projectFetch{
+		project: config.GitHubProject{Organization: "acme", ProjectNumber: 7},
+		issues: []IssueWithStatus{{
+			ProjectIssue: ProjectIssue{
+				Title: "Ship it", Number: 1, State: "open", URL: url,
+				Body: "- [ ] write tests\n- [ ] see docs:setup\n- [x] already done",
+			},
+			GitHubStatus: "In Progress",
+		}},
+		enrich: config.EnrichConfig{Checklists: true},
+	}
+
+	plan := planSync(nil, []*projectFetch{fetch})
+	if len(plan.todos) != 3 {
+		t.Fatalf("planSync() todos = %v, want issue and 2 sub-todos", plan.todos)
+	}
+
+	// round trip through todo.txt, a second sync must not duplicate anything
+	var written []*todo.Todo
+	for _, result := range plan.todos {
+		written = append(written, todo.FromString(result.String()))
+		if result.Labels["url"] == "" && result.Labels["parent"] != url {
+			t.Errorf("sub-todo %q missing parent label", result.String())
+		}
+	}
+
+	fetch.issues[0].Body = "- [x] write tests\n- [ ] see docs:setup\n- [x] already done"
+	plan = planSync(written, []*projectFetch{fetch})
+	if len(plan.todos) != 3 || len(plan.Local) != 1 || plan.Local[0].Action != ActionComplete {
+		t.Errorf("second planSync() = %d todos, changes %+v, want only the ticked sub-todo completed", len(plan.todos), plan.Local)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBlockedBy(t *testing.T) {
+	issueURL := "https://github.com/acme/app/issues/7"
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "same repo",
+			body: "Blocked by #12",
+			want: []string{"https://github.com/acme/app/issues/12"},
+		},
+		{
+			name: "list and other repos",
+			body: "Needs the API first.\r\nblocked by: #3, acme/api#4 and https://github.com/other/lib/issues/5.\nDepends on #3",
+			want: []string{
+				"https://github.com/acme/app/issues/3",
+				"https://github.com/acme/api/issues/4",
+				"https://github.com/other/lib/issues/5",
+			},
+		},
+		{
+			name: "mentions without a dependency",
+			body: "Related to #3, see #4",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := blockedBy(test.body, issueURL); !equalStrings(got, test.want) {
+				t.Errorf("blockedBy() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// enterprise issues keep their host
+	got := blockedBy("Blocked by #1", "https://ghe.example.com/acme/app/issues/2")
+	if !equalStrings(got, []string{"https://ghe.example.com/acme/app/issues/1"}) {
+		t.Errorf("blockedBy() on GHE = %v", got)
+	}
+}
+
+func TestEnrichDependencies(t *testing.T) {
+	issue := IssueWithStatus{ProjectIssue: ProjectIssue{
+		URL:  "https://github.com/acme/app/issues/7",
+		Body: "Blocked by #12",
+	}}
+
+	input := todo.FromString("Fix it +github dep:3")
+	enrichTodo(input, issue, config.EnrichConfig{})
+	if input.Labels["dep"] != "3" {
+		t.Errorf("dep = %q without the option", input.Labels["dep"])
+	}
+
+	enrichTodo(input, issue, config.EnrichConfig{Dependencies: true})
+	enrichTodo(input, issue, config.EnrichConfig{Dependencies: true})
+	if want := "3,https://github.com/acme/app/issues/12"; input.Labels["dep"] != want {
+		t.Errorf("dep = %q, want %q", input.Labels["dep"], want)
+	}
+}
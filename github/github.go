@@ -3,31 +3,89 @@ package github
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/bgentry/go-netrc/netrc"
+	"github.com/arjungandhi/atp/auth"
+	"github.com/arjungandhi/atp/config"
 	"github.com/google/go-github/v66/github"
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
 )
 
+// Output receives sync progress messages. Callers that need a clean stdout
+// (e.g. to print JSON) can point it somewhere else.
+var Output io.Writer = os.Stdout
+
+// outputMu keeps progress lines from concurrent fetches from interleaving
+var outputMu sync.Mutex
+
+func logf(format string, args ...interface{}) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Fprintf(Output, format, args...)
+}
+
 type IssueWithStatus struct {
 	ProjectIssue
 	GitHubStatus  string
 	ProjectItemID string
 	UpdatedAt     time.Time
+	// DateFields holds the board's date fields by name, as YYYY-MM-DD
+	DateFields map[string]string
 }
 
 type Client struct {
 	restClient   *github.Client
 	graphqlClient *githubv4.Client
 	ctx          context.Context
-	org          string
+	scope        scope
 	currentUser  string
+
+	// results reused for the lifetime of the client, i.e. one sync run
+	assignedIssues memo[[]ProjectIssue]
+	userPRs        memo[[]PullRequestInfo]
+	reviewRequests memo[[]PullRequestInfo]
+	metadataMu     sync.Mutex
+	metadata       map[int]*ProjectMetadata
+}
+
+// ClientOptions tune the HTTP stack used to talk to GitHub
+type ClientOptions struct {
+	// Timeout is the deadline for a single request, zero means no deadline
+	Timeout time.Duration
+	// CacheDir stores ETag cached responses, empty disables the cache
+	CacheDir string
+	// Concurrency is the maximum number of requests in flight at once
+	Concurrency int
+	// Auth selects where tokens are looked up
+	Auth config.AuthConfig
+	// Endpoints are the API URLs of GitHub Enterprise hosts, by web host
+	Endpoints map[string]Endpoint
+}
+
+// ClientOptionsFromConfig builds client options from the user's config
+func ClientOptionsFromConfig(cfg *config.Config, atpDir string) ClientOptions {
+	endpoints := make(map[string]Endpoint)
+	for _, project := range cfg.GetAllGitHubProjects() {
+		if project.BaseURL != "" {
+			endpoints[project.Host()] = endpointForProject(project)
+		}
+	}
+
+	return ClientOptions{
+		Timeout:     time.Duration(cfg.GitHub.Timeout) * time.Second,
+		CacheDir:    filepath.Join(atpDir, "cache", "github"),
+		Concurrency: cfg.GitHub.Concurrency,
+		Auth:        cfg.GitHub.Auth,
+		Endpoints:   endpoints,
+	}
 }
 
 type ProjectIssue struct {
@@ -39,6 +97,8 @@ type ProjectIssue struct {
 	URL       string
 	Labels    []string
 	UpdatedAt time.Time
+	// MilestoneDue is the due date of the issue's milestone, if any
+	MilestoneDue time.Time
 }
 
 type PullRequestInfo struct {
@@ -54,67 +114,115 @@ type PullRequestInfo struct {
 	UpdatedAt   time.Time
 	IsDraft     bool
 	IsReview    bool // true if this is a review request, false if it's the user's PR
+
+	// Status of the user's own PRs, see fetchPullRequestStatus
+	CIStatus       string
+	ReviewDecision string
+	Conflicting    bool
+	// StatusUnknown is set when the status couldn't be fetched, the todo
+	// keeps the status of the last sync
+	StatusUnknown bool
 }
 
 func NewClient(org string) (*Client, error) {
-	token, err := getGitHubToken()
+	return newClient(orgScope(org), newTransport(ClientOptions{}), ClientOptions{})
+}
+
+// NewClientWithOptions creates a client with its own HTTP stack built from opts
+func NewClientWithOptions(org string, opts ClientOptions) (*Client, error) {
+	return newClient(orgScope(org), newTransport(opts), opts)
+}
+
+func newClient(sc scope, transport http.RoundTripper, opts ClientOptions) (*Client, error) {
+	credential, err := resolveToken(opts.Auth, sc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GitHub token: %w", err)
 	}
 
+	// primary rate limits are waited out by rateLimitTransport, which caps
+	// the wait. go-github's own sleep would wait up to an hour for the reset
+	// before the transport sees the request.
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
+		&oauth2.Token{AccessToken: credential.Token},
 	)
-	tc := oauth2.NewClient(ctx, ts)
-	restClient := github.NewClient(tc)
-	graphqlClient := githubv4.NewClient(tc)
+	tc := oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport}), ts)
+	restClient, graphqlClient, err := newAPIClients(tc, sc.hostName(), opts.Endpoints)
+	if err != nil {
+		return nil, err
+	}
 
 	user, _, err := restClient.Users.Get(ctx, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current user: %w", err)
 	}
-	
 
 	return &Client{
 		restClient:    restClient,
 		graphqlClient: graphqlClient,
 		ctx:           ctx,
-		org:           org,
+		scope:         sc,
 		currentUser:   user.GetLogin(),
+		metadata:      make(map[int]*ProjectMetadata),
 	}, nil
 }
 
-func getGitHubToken() (string, error) {
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		return token, nil
-	}
+// session hands out one client per scope, all sharing a single HTTP
+// stack so concurrency and rate limits are accounted for across the run
+type session struct {
+	transport http.RoundTripper
+	opts      ClientOptions
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("could not get user home directory: %w", err)
+	mu      sync.Mutex
+	clients map[string]*memo[*Client]
+}
+
+func newSession(opts ClientOptions) *session {
+	return &session{
+		transport: newTransport(opts),
+		opts:      opts,
+		clients:   make(map[string]*memo[*Client]),
 	}
+}
 
-	netrcPath := filepath.Join(homeDir, ".netrc")
-	if _, err := os.Stat(netrcPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("no GitHub token found: set GITHUB_TOKEN environment variable or add github.com entry to ~/.netrc")
+func (s *session) client(sc scope) (*Client, error) {
+	s.mu.Lock()
+	m, ok := s.clients[sc.key()]
+	if !ok {
+		m = &memo[*Client]{}
+		s.clients[sc.key()] = m
 	}
+	s.mu.Unlock()
+
+	return m.get(func() (*Client, error) {
+		return newClient(sc, s.transport, s.opts)
+	})
+}
 
-	n, err := netrc.ParseFile(netrcPath)
+// resolveToken finds the token for the scope's owner with the configured
+// credential providers
+func resolveToken(cfg config.AuthConfig, sc scope) (*auth.Credential, error) {
+	resolver, err := auth.NewResolver(cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse .netrc file: %w", err)
+		return nil, err
 	}
+	return resolver.Resolve(sc.hostName(), sc.tokenOwner())
+}
 
-	machine := n.FindMachine("github.com")
-	if machine == nil {
-		return "", fmt.Errorf("no github.com entry found in .netrc file")
+// newAPIClients builds the REST and GraphQL clients for a host, pointing them
+// at the Enterprise Server endpoints for anything but github.com
+func newAPIClients(tc *http.Client, host string, endpoints map[string]Endpoint) (*github.Client, *githubv4.Client, error) {
+	endpoint, enterprise := endpointForHost(host, endpoints)
+	if !enterprise {
+		return github.NewClient(tc), githubv4.NewClient(tc), nil
 	}
 
-	if machine.Password == "" {
-		return "", fmt.Errorf("no password found for github.com in .netrc file")
+	restClient, err := github.NewClient(tc).WithEnterpriseURLs(endpoint.BaseURL, endpoint.BaseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid GitHub Enterprise URL for %s: %w", host, err)
 	}
 
-	return machine.Password, nil
+	return restClient, githubv4.NewEnterpriseClient(endpoint.GraphQLURL, tc), nil
 }
 
 func (c *Client) GetProjectIssues(projectNumber int, status string) ([]ProjectIssue, error) {
@@ -170,7 +278,7 @@ func (c *Client) parseIssueFromCard(card *github.ProjectCard) (ProjectIssue, err
 	}
 
 	repoName := parts[len(parts)-3]
-	issue, _, err := c.restClient.Issues.Get(c.ctx, c.org, repoName, issueNumber)
+	issue, _, err := c.restClient.Issues.Get(c.ctx, c.scope.owner, repoName, issueNumber)
 	if err != nil {
 		return ProjectIssue{}, fmt.Errorf("failed to get issue: %w", err)
 	}
@@ -194,14 +302,14 @@ func (c *Client) parseIssueFromCard(card *github.ProjectCard) (ProjectIssue, err
 
 func (c *Client) GetProjectV2Issues(projectNumber int, statusFilters []string) ([]IssueWithStatus, error) {
 	// Step 1: Get all issues assigned to current user (much smaller dataset)
-	fmt.Printf("Fetching issues assigned to %s...\n", c.currentUser)
+	logf("Fetching issues assigned to %s...\n", c.currentUser)
 	
 	assignedIssues, err := c.getAssignedIssues()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get assigned issues: %w", err)
 	}
 	
-	fmt.Printf("Found %d issues assigned to %s\n", len(assignedIssues), c.currentUser)
+	logf("Found %d issues assigned to %s\n", len(assignedIssues), c.currentUser)
 	
 	if len(assignedIssues) == 0 {
 		return []IssueWithStatus{}, nil
@@ -212,52 +320,149 @@ func (c *Client) GetProjectV2Issues(projectNumber int, statusFilters []string) (
 }
 
 func (c *Client) getAssignedIssues() ([]ProjectIssue, error) {
+	// every project in the same org looks at the same assigned issues
+	return c.assignedIssues.get(c.fetchAssignedIssues)
+}
+
+func (c *Client) fetchAssignedIssues() ([]ProjectIssue, error) {
 	var allIssues []ProjectIssue
 	
 	// Get both open and closed issues assigned to user
 	for _, state := range []string{"open", "closed"} {
-		opts := &github.IssueListOptions{
-			Filter:    "assigned",
-			State:     state,
-			ListOptions: github.ListOptions{PerPage: 100},
+		var issues []*github.Issue
+		var err error
+
+		switch c.scope.kind {
+		case config.OwnerUser:
+			issues, err = c.listAssignedIssues(state)
+		case config.OwnerRepos:
+			for _, repo := range c.scope.repos {
+				repoIssues, repoErr := c.listAssignedRepoIssues(repo, state)
+				if repoErr != nil {
+					err = repoErr
+					break
+				}
+				issues = append(issues, repoIssues...)
+			}
+		default:
+			issues, err = c.listAssignedOrgIssues(state)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s issues: %w", state, err)
 		}
 
-		for {
-			issues, resp, err := c.restClient.Issues.ListByOrg(c.ctx, c.org, opts)
-			if err != nil {
-				return nil, fmt.Errorf("failed to list %s issues: %w", state, err)
+		for _, issue := range issues {
+			// the issues API also returns pull requests, those are synced separately
+			if issue.IsPullRequest() {
+				continue
 			}
 
-			for _, issue := range issues {
-				var labels []string
-				for _, label := range issue.Labels {
-					labels = append(labels, label.GetName())
-				}
-
-				allIssues = append(allIssues, ProjectIssue{
-					ID:        int(issue.GetID()),
-					Title:     issue.GetTitle(),
-					Body:      issue.GetBody(),
-					Number:    issue.GetNumber(),
-					State:     issue.GetState(),
-					URL:       issue.GetHTMLURL(),
-					Labels:    labels,
-					UpdatedAt: issue.GetUpdatedAt().Time,
-				})
+			var labels []string
+			for _, label := range issue.Labels {
+				labels = append(labels, label.GetName())
 			}
 
-			if resp.NextPage == 0 {
-				break
-			}
-			opts.Page = resp.NextPage
+			allIssues = append(allIssues, ProjectIssue{
+				ID:        int(issue.GetID()),
+				Title:     issue.GetTitle(),
+				Body:      issue.GetBody(),
+				Number:    issue.GetNumber(),
+				State:     issue.GetState(),
+				URL:       issue.GetHTMLURL(),
+				Labels:    labels,
+				UpdatedAt: issue.GetUpdatedAt().Time,
+
+				MilestoneDue: issue.GetMilestone().GetDueOn().Time,
+			})
+		}
+	}
+
+	return allIssues, nil
+}
+
+// listAssignedOrgIssues lists issues assigned to the user in the scope's organization
+func (c *Client) listAssignedOrgIssues(state string) ([]*github.Issue, error) {
+	var allIssues []*github.Issue
+	opts := &github.IssueListOptions{
+		Filter:      "assigned",
+		State:       state,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	for {
+		issues, resp, err := c.restClient.Issues.ListByOrg(c.ctx, c.scope.owner, opts)
+		if err != nil {
+			return nil, err
 		}
+		allIssues = append(allIssues, issues...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allIssues, nil
+}
+
+// listAssignedIssues lists issues assigned to the user across every repo they
+// can see, a user-owned board can hold issues from anywhere
+func (c *Client) listAssignedIssues(state string) ([]*github.Issue, error) {
+	var allIssues []*github.Issue
+	opts := &github.IssueListOptions{
+		Filter:      "assigned",
+		State:       state,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	for {
+		issues, resp, err := c.restClient.Issues.List(c.ctx, true, opts)
+		if err != nil {
+			return nil, err
+		}
+		allIssues = append(allIssues, issues...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allIssues, nil
+}
+
+// listAssignedRepoIssues lists issues assigned to the user in a single repo
+func (c *Client) listAssignedRepoIssues(repo string, state string) ([]*github.Issue, error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var allIssues []*github.Issue
+	opts := &github.IssueListByRepoOptions{
+		Assignee:    c.currentUser,
+		State:       state,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	for {
+		issues, resp, err := c.restClient.Issues.ListByRepo(c.ctx, owner, name, opts)
+		if err != nil {
+			return nil, err
+		}
+		allIssues = append(allIssues, issues...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
 	return allIssues, nil
 }
 
 func (c *Client) getProjectStatusForIssues(projectNumber int, assignedIssues []ProjectIssue, statusFilters []string) ([]IssueWithStatus, error) {
-	fmt.Printf("Checking project status for %d assigned issues...\n", len(assignedIssues))
+	logf("Checking project status for %d assigned issues...\n", len(assignedIssues))
 	
 	// Create map of issue URLs for quick lookup
 	issueURLMap := make(map[string]ProjectIssue)
@@ -265,102 +470,84 @@ func (c *Client) getProjectStatusForIssues(projectNumber int, assignedIssues []P
 		issueURLMap[issue.URL] = issue
 	}
 	
-	var query struct {
-		Organization struct {
-			ProjectV2 struct {
-				Items struct {
-					PageInfo struct {
-						HasNextPage bool   `graphql:"hasNextPage"`
-						EndCursor   string `graphql:"endCursor"`
-					} `graphql:"pageInfo"`
-					Nodes []struct {
-						ID          string `graphql:"id"`
-						FieldValues struct {
-							Nodes []struct {
-								SingleSelectValue struct {
-									Name  string `graphql:"name"`
-									Field struct {
-										SingleSelectField struct {
-											Name string `graphql:"name"`
-										} `graphql:"... on ProjectV2SingleSelectField"`
-									} `graphql:"field"`
-								} `graphql:"... on ProjectV2ItemFieldSingleSelectValue"`
-							}
-						} `graphql:"fieldValues(first: 10)"`
-						Content struct {
-							Typename string `graphql:"__typename"`
-							Issue    struct {
-								URL string `graphql:"url"`
-							} `graphql:"... on Issue"`
-						} `graphql:"content"`
-					}
-				} `graphql:"items(first: 100, after: $cursor)"`
-			} `graphql:"projectV2(number: $projectNumber)"`
-		} `graphql:"organization(login: $org)"`
+	// Repo-list scopes may not use a board at all, every open issue is synced
+	if projectNumber == 0 {
+		var result []IssueWithStatus
+		for _, issue := range assignedIssues {
+			if issue.State == "open" {
+				result = append(result, IssueWithStatus{ProjectIssue: issue, UpdatedAt: issue.UpdatedAt})
+			}
+		}
+		return result, nil
 	}
 
 	var result []IssueWithStatus
-	var cursor *string
+	seen := make(map[string]bool)
 
-	for {
-		variables := map[string]interface{}{
-			"org":           githubv4.String(c.org),
-			"projectNumber": githubv4.Int(projectNumber),
-		}
-		
-		if cursor != nil {
-			variables["cursor"] = githubv4.String(*cursor)
-		} else {
-			variables["cursor"] = (*githubv4.String)(nil)
-		}
+	for _, root := range c.projectRoots() {
+		var cursor *string
 
-		err := c.graphqlClient.Query(c.ctx, &query, variables)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query project: %w", err)
-		}
+		for {
+			variables := map[string]interface{}{}
+			if cursor != nil {
+				variables["cursor"] = githubv4.String(*cursor)
+			} else {
+				variables["cursor"] = (*githubv4.String)(nil)
+			}
 
-		for _, item := range query.Organization.ProjectV2.Items.Nodes {
-			if item.Content.Typename != "Issue" {
-				continue
+			project, err := queryProjectV2[projectV2Items](c, root, projectNumber, variables)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query project: %w", err)
 			}
 
-			// Check if this is one of our assigned issues
-			if assignedIssue, exists := issueURLMap[item.Content.Issue.URL]; exists {
-				var currentStatus string
-				for _, fieldValue := range item.FieldValues.Nodes {
-					if fieldValue.SingleSelectValue.Field.SingleSelectField.Name == "Status" {
-						currentStatus = fieldValue.SingleSelectValue.Name
-						break
-					}
+			for _, item := range project.Items.Nodes {
+				if item.Content.Typename != "Issue" || seen[item.Content.Issue.URL] {
+					continue
 				}
 
-				// Check if status matches our filters
-				hasMatchingStatus := false
-				for _, status := range statusFilters {
-					if strings.EqualFold(currentStatus, status) {
-						hasMatchingStatus = true
-						break
+				// Check if this is one of our assigned issues
+				if assignedIssue, exists := issueURLMap[item.Content.Issue.URL]; exists {
+					var currentStatus string
+					dateFields := make(map[string]string)
+					for _, fieldValue := range item.FieldValues.Nodes {
+						if fieldValue.SingleSelectValue.Field.SingleSelectField.Name == "Status" {
+							currentStatus = fieldValue.SingleSelectValue.Name
+						}
+						if name := fieldValue.DateValue.Field.ProjectV2Field.Name; name != "" {
+							dateFields[name] = fieldValue.DateValue.Date
+						}
 					}
-				}
 
-				if hasMatchingStatus {
-					result = append(result, IssueWithStatus{
-						ProjectIssue:  assignedIssue,
-						GitHubStatus:  currentStatus,
-						ProjectItemID: item.ID,
-						UpdatedAt:     assignedIssue.UpdatedAt,
-					})
+					// Check if status matches our filters
+					hasMatchingStatus := false
+					for _, status := range statusFilters {
+						if strings.EqualFold(currentStatus, status) {
+							hasMatchingStatus = true
+							break
+						}
+					}
+
+					if hasMatchingStatus {
+						seen[item.Content.Issue.URL] = true
+						result = append(result, IssueWithStatus{
+							ProjectIssue:  assignedIssue,
+							GitHubStatus:  currentStatus,
+							ProjectItemID: item.ID,
+							UpdatedAt:     assignedIssue.UpdatedAt,
+							DateFields:    dateFields,
+						})
+					}
 				}
 			}
-		}
 
-		if !query.Organization.ProjectV2.Items.PageInfo.HasNextPage {
-			break
+			if !project.Items.PageInfo.HasNextPage {
+				break
+			}
+			cursor = &project.Items.PageInfo.EndCursor
 		}
-		cursor = &query.Organization.ProjectV2.Items.PageInfo.EndCursor
 	}
 
-	fmt.Printf("Found %d assigned issues matching status filters\n", len(result))
+	logf("Found %d assigned issues matching status filters\n", len(result))
 	return result, nil
 }
 
@@ -371,69 +558,47 @@ type ProjectMetadata struct {
 }
 
 func (c *Client) getProjectMetadata(projectNumber int) (*ProjectMetadata, error) {
-	fmt.Printf("DEBUG: Fetching project metadata for project %d\n", projectNumber)
+	logf("DEBUG: Fetching project metadata for project %d\n", projectNumber)
 	
-	// Single query - get project ID, fields and status options
-	var query struct {
-		Organization struct {
-			ProjectV2 struct {
-				ID     string `graphql:"id"`
-				Fields struct {
-					Nodes []struct {
-						Typename         string `graphql:"__typename"`
-						ProjectV2Field   struct {
-							ID   string `graphql:"id"`
-							Name string `graphql:"name"`
-						} `graphql:"... on ProjectV2Field"`
-						SingleSelectField struct {
-							ID      string `graphql:"id"`
-							Name    string `graphql:"name"`
-							Options []struct {
-								ID   string `graphql:"id"`
-								Name string `graphql:"name"`
-							} `graphql:"options"`
-						} `graphql:"... on ProjectV2SingleSelectField"`
-					}
-				} `graphql:"fields(first: 20)"`
-			} `graphql:"projectV2(number: $projectNumber)"`
-		} `graphql:"organization(login: $org)"`
-	}
-
-	variables := map[string]interface{}{
-		"org":           githubv4.String(c.org),
-		"projectNumber": githubv4.Int(projectNumber),
+	// Single query - get project ID, fields and status options. For repo-list
+	// scopes the first repo linked to the board wins.
+	var project *projectV2Fields
+	var err error
+	for _, root := range c.projectRoots() {
+		logf("DEBUG: Executing GraphQL query for project metadata\n")
+		project, err = queryProjectV2[projectV2Fields](c, root, projectNumber, map[string]interface{}{})
+		if err == nil && project.ID != "" {
+			break
+		}
 	}
-
-	fmt.Printf("DEBUG: Executing GraphQL query for project metadata\n")
-	err := c.graphqlClient.Query(c.ctx, &query, variables)
 	if err != nil {
-		fmt.Printf("DEBUG: GraphQL query failed: %v\n", err)
+		logf("DEBUG: GraphQL query failed: %v\n", err)
 		return nil, fmt.Errorf("failed to query project metadata: %w", err)
 	}
 	
-	fmt.Printf("DEBUG: GraphQL query successful, found %d fields\n", len(query.Organization.ProjectV2.Fields.Nodes))
+	logf("DEBUG: GraphQL query successful, found %d fields\n", len(project.Fields.Nodes))
 
 	metadata := &ProjectMetadata{
-		ID:            query.Organization.ProjectV2.ID,
+		ID:            project.ID,
 		StatusOptions: make(map[string]string),
 	}
 
 	// Find the Status field and its options
-	for _, field := range query.Organization.ProjectV2.Fields.Nodes {
+	for _, field := range project.Fields.Nodes {
 		var fieldID, fieldName string
 		
 		if field.Typename == "ProjectV2SingleSelectField" {
 			fieldID = field.SingleSelectField.ID
 			fieldName = field.SingleSelectField.Name
-			fmt.Printf("DEBUG: SingleSelectField - Name: %s, ID: %s\n", fieldName, fieldID)
+			logf("DEBUG: SingleSelectField - Name: %s, ID: %s\n", fieldName, fieldID)
 			
 			if fieldName == "Status" {
-				fmt.Printf("DEBUG: Found Status field with ID: %s\n", fieldID)
+				logf("DEBUG: Found Status field with ID: %s\n", fieldID)
 				metadata.StatusFieldID = fieldID
 				
-				fmt.Printf("DEBUG: Found %d status options\n", len(field.SingleSelectField.Options))
+				logf("DEBUG: Found %d status options\n", len(field.SingleSelectField.Options))
 				for _, option := range field.SingleSelectField.Options {
-					fmt.Printf("DEBUG: Status option - Name: %s, ID: %s\n", option.Name, option.ID)
+					logf("DEBUG: Status option - Name: %s, ID: %s\n", option.Name, option.ID)
 					metadata.StatusOptions[option.Name] = option.ID
 				}
 				break
@@ -441,7 +606,7 @@ func (c *Client) getProjectMetadata(projectNumber int) (*ProjectMetadata, error)
 		} else if field.Typename == "ProjectV2Field" {
 			fieldID = field.ProjectV2Field.ID
 			fieldName = field.ProjectV2Field.Name
-			fmt.Printf("DEBUG: ProjectV2Field - Name: %s, ID: %s\n", fieldName, fieldID)
+			logf("DEBUG: ProjectV2Field - Name: %s, ID: %s\n", fieldName, fieldID)
 		}
 	}
 
@@ -449,15 +614,32 @@ func (c *Client) getProjectMetadata(projectNumber int) (*ProjectMetadata, error)
 		return nil, fmt.Errorf("Status field not found in project")
 	}
 
-	fmt.Printf("DEBUG: Project metadata - ID: %s, StatusFieldID: %s, Options: %v\n", 
+	logf("DEBUG: Project metadata - ID: %s, StatusFieldID: %s, Options: %v\n", 
 		metadata.ID, metadata.StatusFieldID, metadata.StatusOptions)
 
 	return metadata, nil
 }
 
-func (c *Client) UpdateProjectItemStatus(projectNumber int, projectItemID string, newStatus string) error {
-	// Get fresh project metadata
+// cachedProjectMetadata fetches project metadata once per client
+func (c *Client) cachedProjectMetadata(projectNumber int) (*ProjectMetadata, error) {
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+
+	if metadata, ok := c.metadata[projectNumber]; ok {
+		return metadata, nil
+	}
+
 	metadata, err := c.getProjectMetadata(projectNumber)
+	if err != nil {
+		return nil, err
+	}
+	c.metadata[projectNumber] = metadata
+
+	return metadata, nil
+}
+
+func (c *Client) UpdateProjectItemStatus(projectNumber int, projectItemID string, newStatus string) error {
+	metadata, err := c.cachedProjectMetadata(projectNumber)
 	if err != nil {
 		return fmt.Errorf("failed to get project metadata: %w", err)
 	}
@@ -475,7 +657,7 @@ func (c *Client) UpdateProjectItemStatus(projectNumber int, projectItemID string
 		} `graphql:"updateProjectV2ItemFieldValue(input: $input)"`
 	}
 
-	fmt.Printf("DEBUG: Updating project item %s to status %s (optionID: %s)\n", projectItemID, newStatus, optionID)
+	logf("DEBUG: Updating project item %s to status %s (optionID: %s)\n", projectItemID, newStatus, optionID)
 
 	input := map[string]interface{}{
 		"projectId": githubv4.String(metadata.ID),
@@ -491,7 +673,7 @@ func (c *Client) UpdateProjectItemStatus(projectNumber int, projectItemID string
 		return fmt.Errorf("failed to update project item status: %w", err)
 	}
 
-	fmt.Printf("DEBUG: Mutation completed successfully, result: %+v\n", mutation)
+	logf("DEBUG: Mutation completed successfully, result: %+v\n", mutation)
 	return nil
 }
 
@@ -503,13 +685,13 @@ func getKeys(m map[string]string) []string {
 	return keys
 }
 
-func (c *Client) CloseIssue(repoName string, issueNumber int) error {
+func (c *Client) CloseIssue(owner string, repoName string, issueNumber int) error {
 	state := "closed"
 	issueRequest := &github.IssueRequest{
 		State: &state,
 	}
 
-	_, _, err := c.restClient.Issues.Edit(c.ctx, c.org, repoName, issueNumber, issueRequest)
+	_, _, err := c.restClient.Issues.Edit(c.ctx, owner, repoName, issueNumber, issueRequest)
 	if err != nil {
 		return fmt.Errorf("failed to close issue: %w", err)
 	}
@@ -517,85 +699,82 @@ func (c *Client) CloseIssue(repoName string, issueNumber int) error {
 	return nil
 }
 
-func (c *Client) UpdateIssueFromURL(issueURL string) error {
-	parts := strings.Split(issueURL, "/")
-	if len(parts) < 2 {
-		return fmt.Errorf("invalid issue URL")
+// CreateIssue opens a new issue in owner/repoName assigned to the current user
+func (c *Client) CreateIssue(owner string, repoName string, title string) (*github.Issue, error) {
+	issueRequest := &github.IssueRequest{
+		Title:     github.String(title),
+		Assignees: &[]string{c.currentUser},
 	}
 
-	repoName := parts[len(parts)-3]
-	issueNumberStr := parts[len(parts)-1]
-	issueNumber, err := strconv.Atoi(issueNumberStr)
+	issue, _, err := c.restClient.Issues.Create(c.ctx, owner, repoName, issueRequest)
 	if err != nil {
-		return fmt.Errorf("invalid issue number: %w", err)
+		return nil, fmt.Errorf("failed to create issue: %w", err)
 	}
 
-	return c.CloseIssue(repoName, issueNumber)
+	return issue, nil
 }
 
-func (c *Client) getIssueUpdateTime(issueURL string) (time.Time, error) {
-	parts := strings.Split(issueURL, "/")
-	if len(parts) < 7 {
-		return time.Time{}, fmt.Errorf("invalid GitHub URL format")
+// AddIssueToProject adds an issue to a Projects v2 board and returns the ID
+// of the new project item
+func (c *Client) AddIssueToProject(projectNumber int, issueNodeID string) (string, error) {
+	metadata, err := c.cachedProjectMetadata(projectNumber)
+	if err != nil {
+		return "", fmt.Errorf("failed to get project metadata: %w", err)
 	}
 
-	repoName := parts[4]
-	issueNumberStr := parts[6]
-	issueNumber, err := strconv.Atoi(issueNumberStr)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid issue number: %w", err)
+	var mutation struct {
+		AddProjectV2ItemById struct {
+			Item struct {
+				ID string
+			}
+		} `graphql:"addProjectV2ItemById(input: $input)"`
+	}
+
+	input := map[string]interface{}{
+		"projectId": githubv4.String(metadata.ID),
+		"contentId": githubv4.String(issueNodeID),
 	}
 
-	issue, _, err := c.restClient.Issues.Get(c.ctx, c.org, repoName, issueNumber)
+	err = c.graphqlClient.Mutate(c.ctx, &mutation, input, nil)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to get issue: %w", err)
+		return "", fmt.Errorf("failed to add issue to project: %w", err)
 	}
 
-	return issue.GetUpdatedAt().Time, nil
+	return mutation.AddProjectV2ItemById.Item.ID, nil
 }
 
-func (c *Client) lookupProjectItemID(projectNumber int, issueURL string) (string, error) {
-	// Query the project to find the item ID for this specific issue URL
-	var query struct {
-		Organization struct {
-			ProjectV2 struct {
-				Items struct {
-					Nodes []struct {
-						ID      string `graphql:"id"`
-						Content struct {
-							Typename string `graphql:"__typename"`
-							Issue    struct {
-								URL string `graphql:"url"`
-							} `graphql:"... on Issue"`
-						} `graphql:"content"`
-					}
-				} `graphql:"items(first: 100)"`
-			} `graphql:"projectV2(number: $projectNumber)"`
-		} `graphql:"organization(login: $org)"`
+func (c *Client) UpdateIssueFromURL(issueURL string) error {
+	ref, err := parseGitHubURL(issueURL)
+	if err != nil || ref.number == 0 {
+		return fmt.Errorf("invalid issue URL")
 	}
 
-	variables := map[string]interface{}{
-		"org":           githubv4.String(c.org),
-		"projectNumber": githubv4.Int(projectNumber),
-	}
+	return c.CloseIssue(ref.owner, ref.repo, ref.number)
+}
 
-	err := c.graphqlClient.Query(c.ctx, &query, variables)
+func (c *Client) getIssueUpdateTime(issueURL string) (time.Time, error) {
+	ref, err := parseGitHubURL(issueURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to query project for lookup: %w", err)
+		return time.Time{}, err
+	}
+	if ref.number == 0 {
+		return time.Time{}, fmt.Errorf("invalid GitHub URL format")
 	}
 
-	// Find the item with matching issue URL
-	for _, item := range query.Organization.ProjectV2.Items.Nodes {
-		if item.Content.Typename == "Issue" && item.Content.Issue.URL == issueURL {
-			return item.ID, nil
-		}
+	issue, _, err := c.restClient.Issues.Get(c.ctx, ref.owner, ref.repo, ref.number)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get issue: %w", err)
 	}
 
-	return "", fmt.Errorf("project item not found for issue URL: %s", issueURL)
+	return issue.GetUpdatedAt().Time, nil
 }
 
 // GetUserPullRequests fetches open pull requests created by or assigned to the current user
 func (c *Client) GetUserPullRequests() ([]PullRequestInfo, error) {
+	return c.userPRs.get(c.fetchUserPullRequests)
+}
+
+func (c *Client) fetchUserPullRequests() ([]PullRequestInfo, error) {
 	var allPRs []PullRequestInfo
 
 	// Search for PRs authored by the current user
@@ -604,7 +783,7 @@ func (c *Client) GetUserPullRequests() ([]PullRequestInfo, error) {
 	}
 
 	// Query: is:pr is:open author:username org:organization
-	query := fmt.Sprintf("is:pr is:open author:%s org:%s", c.currentUser, c.org)
+	query := fmt.Sprintf("is:pr is:open author:%s %s", c.currentUser, c.scope.searchQualifier())
 
 	for {
 		result, resp, err := c.restClient.Search.Issues(c.ctx, query, opts)
@@ -615,7 +794,7 @@ func (c *Client) GetUserPullRequests() ([]PullRequestInfo, error) {
 		for _, issue := range result.Issues {
 			pr, err := c.convertIssueToPR(issue, false)
 			if err != nil {
-				fmt.Printf("Warning: failed to convert issue to PR: %v\n", err)
+				logf("Warning: failed to convert issue to PR: %v\n", err)
 				continue
 			}
 			allPRs = append(allPRs, pr)
@@ -627,6 +806,15 @@ func (c *Client) GetUserPullRequests() ([]PullRequestInfo, error) {
 		opts.Page = resp.NextPage
 	}
 
+	// The search API doesn't know about checks, reviews or conflicts
+	runAll(len(allPRs), func(i int) error {
+		if err := c.fetchPullRequestStatus(&allPRs[i]); err != nil {
+			logf("Warning: failed to get status of %s: %v\n", allPRs[i].URL, err)
+			allPRs[i].StatusUnknown = true
+		}
+		return nil
+	})
+
 	return allPRs, nil
 }
 
@@ -654,7 +842,11 @@ func (c *Client) hasUserReviewed(owner, repo string, prNumber int) (bool, error)
 
 // GetReviewRequests fetches open pull requests where the current user is requested to review
 func (c *Client) GetReviewRequests() ([]PullRequestInfo, error) {
-	var allPRs []PullRequestInfo
+	return c.reviewRequests.get(c.fetchReviewRequests)
+}
+
+func (c *Client) fetchReviewRequests() ([]PullRequestInfo, error) {
+	var candidates []PullRequestInfo
 
 	// Search for PRs with review requested from the current user
 	opts := &github.SearchOptions{
@@ -662,7 +854,7 @@ func (c *Client) GetReviewRequests() ([]PullRequestInfo, error) {
 	}
 
 	// Query: is:pr is:open review-requested:username org:organization
-	query := fmt.Sprintf("is:pr is:open review-requested:%s org:%s", c.currentUser, c.org)
+	query := fmt.Sprintf("is:pr is:open review-requested:%s %s", c.currentUser, c.scope.searchQualifier())
 
 	for {
 		result, resp, err := c.restClient.Search.Issues(c.ctx, query, opts)
@@ -673,24 +865,10 @@ func (c *Client) GetReviewRequests() ([]PullRequestInfo, error) {
 		for _, issue := range result.Issues {
 			pr, err := c.convertIssueToPR(issue, true)
 			if err != nil {
-				fmt.Printf("Warning: failed to convert issue to PR: %v\n", err)
-				continue
-			}
-
-			// Skip PRs where the user has already approved or requested changes
-			reviewed, err := c.hasUserReviewed(pr.RepoOwner, pr.RepoName, pr.Number)
-			if err != nil {
-				fmt.Printf("Warning: failed to check review status for PR #%d: %v\n", pr.Number, err)
-				// On error, include the PR to be safe (don't silently drop it)
-				allPRs = append(allPRs, pr)
+				logf("Warning: failed to convert issue to PR: %v\n", err)
 				continue
 			}
-
-			if reviewed {
-				fmt.Printf("Skipping PR #%d - user has already approved or requested changes\n", pr.Number)
-			} else {
-				allPRs = append(allPRs, pr)
-			}
+			candidates = append(candidates, pr)
 		}
 
 		if resp.NextPage == 0 {
@@ -699,6 +877,35 @@ func (c *Client) GetReviewRequests() ([]PullRequestInfo, error) {
 		opts.Page = resp.NextPage
 	}
 
+	// Check review status for every PR at once
+	keep := make([]bool, len(candidates))
+	runAll(len(candidates), func(i int) error {
+		pr := candidates[i]
+
+		// Skip PRs where the user has already approved or requested changes
+		reviewed, err := c.hasUserReviewed(pr.RepoOwner, pr.RepoName, pr.Number)
+		if err != nil {
+			logf("Warning: failed to check review status for PR #%d: %v\n", pr.Number, err)
+			// On error, include the PR to be safe (don't silently drop it)
+			keep[i] = true
+			return nil
+		}
+
+		if reviewed {
+			logf("Skipping PR #%d - user has already approved or requested changes\n", pr.Number)
+		} else {
+			keep[i] = true
+		}
+		return nil
+	})
+
+	var allPRs []PullRequestInfo
+	for i, pr := range candidates {
+		if keep[i] {
+			allPRs = append(allPRs, pr)
+		}
+	}
+
 	return allPRs, nil
 }
 
@@ -715,10 +922,9 @@ func (c *Client) convertIssueToPR(issue *github.Issue, isReview bool) (PullReque
 		repoName = issue.Repository.GetName()
 	} else {
 		// Extract from URL if repository info not available
-		parts := strings.Split(issue.GetHTMLURL(), "/")
-		if len(parts) >= 5 {
-			repoOwner = parts[3]
-			repoName = parts[4]
+		if ref, err := parseGitHubURL(issue.GetHTMLURL()); err == nil {
+			repoOwner = ref.owner
+			repoName = ref.repo
 		}
 	}
 
@@ -0,0 +1,265 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+	"github.com/google/go-github/v66/github"
+)
+
+// DefaultNotificationReasons are the notification reasons synced when the
+// config doesn't list any: @-mentions of you or your team and activity on
+// threads you started
+var DefaultNotificationReasons = []string{"mention", "team_mention", "author"}
+
+// notificationScope is used for the notifications client, notifications
+// belong to the user rather than to any project
+func notificationScope(host string) scope {
+	return scope{kind: config.OwnerUser, host: host}
+}
+
+type NotificationInfo struct {
+	ID        string
+	Host      string
+	Reason    string
+	Title     string
+	Type      string // Issue, PullRequest, Discussion, ...
+	Repo      string // owner/name
+	URL       string // web URL of the thread subject
+	UpdatedAt time.Time
+}
+
+// fetchNotifications reads the notifications selected in the config from
+// every given host
+func fetchNotifications(sess *session, cfg config.NotificationsConfig, hosts []string) ([]NotificationInfo, error) {
+	reasons := cfg.Reasons
+	if len(reasons) == 0 {
+		reasons = DefaultNotificationReasons
+	}
+
+	results := make([][]NotificationInfo, len(hosts))
+	err := runAll(len(hosts), func(i int) error {
+		logf("Fetching notifications from %s (reasons: %v)...\n", hosts[i], reasons)
+
+		client, err := sess.client(notificationScope(hosts[i]))
+		if err != nil {
+			return fmt.Errorf("failed to create GitHub client: %w", err)
+		}
+
+		results[i], err = client.GetNotifications(reasons)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []NotificationInfo
+	for _, result := range results {
+		notifications = append(notifications, result...)
+	}
+	return notifications, nil
+}
+
+// threadKey is the notif: label of a thread. Thread IDs are only unique per
+// host, so threads from Enterprise hosts carry their host.
+func (n NotificationInfo) threadKey() string {
+	if n.Host == "" || strings.EqualFold(n.Host, config.DefaultGitHubHost) {
+		return n.ID
+	}
+	return strings.ToLower(n.Host) + "/" + n.ID
+}
+
+// splitThreadKey is the inverse of threadKey
+func splitThreadKey(key string) (string, string) {
+	if host, id, ok := strings.Cut(key, "/"); ok {
+		return host, id
+	}
+	return config.DefaultGitHubHost, key
+}
+
+// GetNotifications returns the user's unread notifications with one of the
+// given reasons
+func (c *Client) GetNotifications(reasons []string) ([]NotificationInfo, error) {
+	wanted := make(map[string]bool)
+	for _, reason := range reasons {
+		wanted[strings.ToLower(reason)] = true
+	}
+
+	var allNotifications []NotificationInfo
+	opts := &github.NotificationListOptions{
+		ListOptions: github.ListOptions{PerPage: 50},
+	}
+
+	for {
+		notifications, resp, err := c.restClient.Activity.ListNotifications(c.ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list notifications: %w", err)
+		}
+
+		for _, n := range notifications {
+			if !wanted[strings.ToLower(n.GetReason())] {
+				continue
+			}
+
+			allNotifications = append(allNotifications, NotificationInfo{
+				ID:        n.GetID(),
+				Host:      c.scope.hostName(),
+				Reason:    n.GetReason(),
+				Title:     n.GetSubject().GetTitle(),
+				Type:      n.GetSubject().GetType(),
+				Repo:      n.GetRepository().GetFullName(),
+				URL:       subjectWebURL(n.GetSubject().GetURL(), n.GetRepository().GetHTMLURL()),
+				UpdatedAt: n.GetUpdatedAt().Time,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allNotifications, nil
+}
+
+// MarkNotificationRead marks a notification thread as read
+func (c *Client) MarkNotificationRead(threadID string) error {
+	_, err := c.restClient.Activity.MarkThreadRead(c.ctx, threadID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return nil
+}
+
+// subjectWebURL turns the API URL of a notification subject into the page a
+// browser would open, e.g.
+// https://api.github.com/repos/owner/repo/pulls/12 -> https://github.com/owner/repo/pull/12
+// Subjects without an issue or pull request URL link to the repository.
+func subjectWebURL(apiURL string, repoURL string) string {
+	parts := strings.Split(apiURL, "/")
+	if len(parts) < 2 || repoURL == "" {
+		return repoURL
+	}
+
+	kind, number := parts[len(parts)-2], parts[len(parts)-1]
+	switch kind {
+	case "issues":
+		return repoURL + "/issues/" + number
+	case "pulls":
+		return repoURL + "/pull/" + number
+	case "discussions":
+		return repoURL + "/discussions/" + number
+	}
+
+	return repoURL
+}
+
+// notificationDescription prefixes the thread title with why it needs you
+func notificationDescription(n NotificationInfo) string {
+	var prefix string
+	switch n.Reason {
+	case "mention":
+		prefix = "Mentioned"
+	case "team_mention":
+		prefix = "Team mentioned"
+	case "author":
+		prefix = "Reply"
+	case "comment":
+		prefix = "Comment"
+	default:
+		prefix = "Notification"
+	}
+	return prefix + ": " + sanitizeIssueTitle(n.Title)
+}
+
+func createTodoFromNotification(n NotificationInfo) *todo.Todo {
+	t := todo.NewTodo()
+	t.Description = notificationDescription(n)
+	t.Projects = []string{"github"}
+
+	// link rather than url, the same issue may already be tracked as an
+	// assigned issue keyed by its url
+	t.Labels = map[string]string{
+		"repo":  n.Repo,
+		"notif": n.threadKey(),
+		"link":  n.URL,
+	}
+
+	return t
+}
+
+// planNotifications merges unread notifications into the planned todos. New
+// threads become todos, threads read elsewhere complete their todo and todos
+// completed locally mark their thread read.
+func (p *SyncPlan) planNotifications(notifications []NotificationInfo) {
+	existing := make(map[string]*todo.Todo)
+	for _, t := range p.todos {
+		if id := t.Labels["notif"]; id != "" && hasGitHubProject(t) {
+			existing[id] = t
+		}
+	}
+
+	unread := make(map[string]bool)
+	var created []*todo.Todo
+	for _, n := range notifications {
+		key := n.threadKey()
+		if unread[key] {
+			continue
+		}
+		unread[key] = true
+
+		t, exists := existing[key]
+		if !exists {
+			t = createTodoFromNotification(n)
+			p.recordChange(n.URL, nil, t)
+			created = append(created, t)
+			continue
+		}
+
+		before := t.Clone()
+		// new activity on a thread we already marked read, a thread whose
+		// read is still in the outbox only looks unread
+		if t.Done && t.Labels["synced"] == "true" && t.Labels[pendingLabel] == "" {
+			t.Done = false
+			t.CompletionDate = time.Time{}
+			delete(t.Labels, "synced")
+		}
+		if !t.Done {
+			t.Description = notificationDescription(n)
+		}
+		p.recordChange(n.URL, before, t)
+	}
+
+	for _, t := range p.todos {
+		id := t.Labels["notif"]
+		if existing[id] != t || t.Labels["synced"] == "true" || t.Labels[pendingLabel] != "" {
+			continue
+		}
+
+		switch {
+		case t.Done && unread[id]:
+			host, thread := splitThreadKey(id)
+			p.Remote = append(p.Remote, RemoteChange{
+				Action: ActionMarkRead,
+				URL:    t.Labels["link"],
+				Thread: thread,
+				scope:  notificationScope(host),
+				todo:   t,
+			})
+		case !unread[id]:
+			// read on GitHub, or completed locally and already read
+			before := t.Clone()
+			if !t.Done {
+				t.Done = true
+				t.CompletionDate = time.Now()
+			}
+			t.Labels["synced"] = "true"
+			p.recordChange(t.Labels["link"], before, t)
+		}
+	}
+
+	p.todos = append(p.todos, created...)
+}
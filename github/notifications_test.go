@@ -0,0 +1,62 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/arjungandhi/atp/todo"
+)
+
+func TestSubjectWebURL(t *testing.T) {
+	repo := "https://github.com/acme/app"
+	tests := []struct {
+		apiURL string
+		want   string
+	}{
+		{"https://api.github.com/repos/acme/app/issues/12", repo + "/issues/12"},
+		{"https://api.github.com/repos/acme/app/pulls/7", repo + "/pull/7"},
+		{"https://api.github.com/repos/acme/app/commits/abc123", repo},
+		{"", repo},
+	}
+
+	for _, test := range tests {
+		if got := subjectWebURL(test.apiURL, repo); got != test.want {
+			t.Errorf("subjectWebURL(%q) = %q, want %q", test.apiURL, got, test.want)
+		}
+	}
+}
+
+func TestPlanNotifications(t *testing.T) {
+	plan := &SyncPlan{todos: []*todo.Todo{
+		// completed locally, still unread on GitHub
+		todo.FromString("x Mentioned: Deploy plan +github repo:acme/app notif:1 link:https://github.com/acme/app/issues/1"),
+		// read on GitHub
+		todo.FromString("Reply: Old thread +github repo:acme/app notif:2 link:https://github.com/acme/app/issues/2"),
+		// marked read by an earlier sync, new activity since
+		todo.FromString("x Mentioned: Flaky test +github repo:acme/app notif:3 link:https://github.com/acme/app/pull/3 synced:true"),
+	}}
+
+	plan.planNotifications([]NotificationInfo{
+		{ID: "1", Reason: "mention", Title: "Deploy plan", Repo: "acme/app", URL: "https://github.com/acme/app/issues/1"},
+		{ID: "3", Reason: "mention", Title: "Flaky test", Repo: "acme/app", URL: "https://github.com/acme/app/pull/3"},
+		{ID: "4", Reason: "team_mention", Title: "Question for @team", Repo: "acme/app", URL: "https://github.com/acme/app/issues/4"},
+	})
+
+	if len(plan.Remote) != 1 || plan.Remote[0].Action != ActionMarkRead || plan.Remote[0].Thread != "1" {
+		t.Errorf("planNotifications() remote = %+v, want thread 1 marked read", plan.Remote)
+	}
+
+	byThread := map[string]*todo.Todo{}
+	for _, result := range plan.todos {
+		byThread[result.Labels["notif"]] = result
+	}
+
+	if result := byThread["2"]; !result.Done || result.Labels["synced"] != "true" {
+		t.Errorf("thread read on GitHub = %q, want completed", result.String())
+	}
+	if result := byThread["3"]; result.Done || result.Labels["synced"] != "" {
+		t.Errorf("thread with new activity = %q, want reopened", result.String())
+	}
+	if result := byThread["4"]; result == nil || result.Description != "Team mentioned: Question for" || result.Labels["url"] != "" {
+		t.Errorf("new thread todo = %v, want created without a url label", result)
+	}
+}
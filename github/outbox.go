@@ -0,0 +1,653 @@
+package github
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+	"github.com/google/go-github/v66/github"
+)
+
+const (
+	// outboxFile holds GitHub mutations that haven't been delivered yet
+	outboxFile = "github_outbox.json"
+	// outboxBaseBackoff is the wait after the first failed attempt, it
+	// doubles with every further failure up to outboxMaxBackoff
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = time.Hour
+	// outboxClaim is how long operations sent without the ATP lock held are
+	// left alone by other deliveries
+	outboxClaim = 10 * time.Minute
+	// pendingLabel marks a todo whose issue creation, close or mark read is
+	// waiting in the outbox, its value is the operation ID
+	pendingLabel = "pending"
+)
+
+// remote is what the outbox needs from GitHub. *Client implements it, tests
+// use a fake.
+type remote interface {
+	CloseIssue(owner string, repoName string, issueNumber int) error
+	UpdateProjectItemStatus(projectNumber int, projectItemID string, newStatus string) error
+	MarkNotificationRead(threadID string) error
+	CreateIssue(owner string, repoName string, title string) (*github.Issue, error)
+	AddIssueToProject(projectNumber int, issueNodeID string) (string, error)
+}
+
+// remoteFunc returns the remote for a scope
+type remoteFunc func(sc scope) (remote, error)
+
+// sessionRemotes hands out the session's clients as remotes
+func sessionRemotes(sess *session) remoteFunc {
+	return func(sc scope) (remote, error) {
+		return sess.client(sc)
+	}
+}
+
+// Operation is a GitHub mutation waiting in the outbox
+type Operation struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	URL       string    `json:"url,omitempty"`
+	Scope     opScope   `json:"scope"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// close and create
+	Owner  string `json:"owner,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	Number int    `json:"number,omitempty"`
+	Title  string `json:"title,omitempty"`
+
+	// status, and the board a created issue is added to
+	ProjectName   string `json:"project_name,omitempty"`
+	ProjectNumber int    `json:"project_number,omitempty"`
+	ProjectItemID string `json:"project_item_id,omitempty"`
+	Status        string `json:"status,omitempty"`
+
+	// read
+	Thread string `json:"thread,omitempty"`
+
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+
+	// SendingUntil is set while a delivery that doesn't hold the lock sends
+	// the operation, nothing else sends it until then
+	SendingUntil time.Time `json:"sending_until,omitempty"`
+
+	// Done is set once GitHub accepted the operation, it stays in the outbox
+	// until its result has been written to the todos
+	Done bool `json:"done,omitempty"`
+}
+
+// due reports whether the operation is to be sent now
+func (op *Operation) due(now time.Time, force bool) bool {
+	if op.Done || now.Before(op.SendingUntil) {
+		return false
+	}
+	return force || !now.Before(op.NextAttempt)
+}
+
+// opScope is the serialized form of a scope
+type opScope struct {
+	Kind  string   `json:"kind"`
+	Host  string   `json:"host,omitempty"`
+	Owner string   `json:"owner,omitempty"`
+	Repos []string `json:"repos,omitempty"`
+}
+
+func newOpScope(sc scope) opScope {
+	return opScope{Kind: sc.kind, Host: sc.host, Owner: sc.owner, Repos: sc.repos}
+}
+
+func (s opScope) scope() scope {
+	return scope{kind: s.Kind, host: s.Host, owner: s.Owner, repos: s.Repos}
+}
+
+// Target describes what the operation acts on, for listings
+func (op *Operation) Target() string {
+	switch op.Action {
+	case ActionCreate:
+		return fmt.Sprintf("%s/%s: %s", op.Owner, op.Repo, op.Title)
+	case ActionStatus:
+		return fmt.Sprintf("%s -> %s", op.URL, op.Status)
+	default:
+		return op.URL
+	}
+}
+
+// operationForChange turns a planned remote change into an outbox operation
+func operationForChange(change RemoteChange) (*Operation, error) {
+	op := &Operation{
+		Action:        change.Action,
+		URL:           change.URL,
+		Scope:         newOpScope(change.scope),
+		ProjectNumber: change.ProjectNumber,
+		ProjectItemID: change.ProjectItemID,
+		Status:        change.Status,
+		Thread:        change.Thread,
+	}
+
+	if change.Action == ActionClose {
+		ref, err := parseGitHubURL(change.URL)
+		if err != nil || ref.number == 0 {
+			return nil, fmt.Errorf("invalid issue URL '%s'", change.URL)
+		}
+		op.Owner, op.Repo, op.Number = ref.owner, ref.repo, ref.number
+	}
+
+	return op, nil
+}
+
+// dedupeKey identifies operations that would do the same thing
+func (op *Operation) dedupeKey() string {
+	switch op.Action {
+	case ActionStatus:
+		return op.Action + ":" + op.ProjectItemID
+	case ActionMarkRead:
+		return op.Action + ":" + op.Scope.Host + "/" + op.Thread
+	case ActionCreate:
+		return op.Action + ":" + op.ID
+	default:
+		return op.Action + ":" + op.URL
+	}
+}
+
+// run performs the operation against GitHub
+func (op *Operation) run(r remote) error {
+	switch op.Action {
+	case ActionClose:
+		return r.CloseIssue(op.Owner, op.Repo, op.Number)
+	case ActionStatus:
+		return r.UpdateProjectItemStatus(op.ProjectNumber, op.ProjectItemID, op.Status)
+	case ActionMarkRead:
+		return r.MarkNotificationRead(op.Thread)
+	case ActionCreate:
+		issue, err := r.CreateIssue(op.Owner, op.Repo, op.Title)
+		if err != nil {
+			return err
+		}
+		op.URL = issue.GetHTMLURL()
+		op.Number = issue.GetNumber()
+		logf("Created %s\n", op.URL)
+
+		if op.ProjectNumber == 0 {
+			return nil
+		}
+
+		// the issue exists at this point, a board failure is only a warning
+		itemID, err := r.AddIssueToProject(op.ProjectNumber, issue.GetNodeID())
+		if err != nil {
+			logf("Warning: failed to add %s to project %s: %v\n", op.URL, op.ProjectName, err)
+			op.ProjectName = ""
+			return nil
+		}
+		if err := r.UpdateProjectItemStatus(op.ProjectNumber, itemID, op.Status); err != nil {
+			logf("Warning: failed to set status of %s to %s: %v\n", op.URL, op.Status, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operation '%s'", op.Action)
+	}
+}
+
+// backoff is how long to wait before retrying after the given number of
+// failed attempts
+func backoff(attempts int) time.Duration {
+	wait := outboxBaseBackoff
+	for i := 1; i < attempts && wait < outboxMaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > outboxMaxBackoff {
+		wait = outboxMaxBackoff
+	}
+	return wait
+}
+
+// Outbox is the durable queue of GitHub mutations. Operations are recorded
+// before anything is sent so nothing is lost when GitHub can't be reached.
+type Outbox struct {
+	path       string
+	mu         sync.Mutex
+	Operations []*Operation `json:"operations"`
+}
+
+// LoadOutbox reads the outbox from the ATP directory
+func LoadOutbox(atpDir string) (*Outbox, error) {
+	outbox := &Outbox{path: filepath.Join(atpDir, outboxFile)}
+
+	data, err := os.ReadFile(outbox.path)
+	if os.IsNotExist(err) {
+		return outbox, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	if err := json.Unmarshal(data, outbox); err != nil {
+		return nil, fmt.Errorf("failed to parse outbox: %w", err)
+	}
+
+	return outbox, nil
+}
+
+// Save writes the outbox atomically
+func (o *Outbox) Save() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.save()
+}
+
+func (o *Outbox) save() error {
+	if o.Operations == nil {
+		o.Operations = []*Operation{}
+	}
+
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(o.path), ".outbox-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), o.path); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return nil
+}
+
+// Pending returns the operations that still have to reach GitHub
+func (o *Outbox) Pending() []*Operation {
+	var pending []*Operation
+	for _, op := range o.Operations {
+		if !op.Done {
+			pending = append(pending, op)
+		}
+	}
+	return pending
+}
+
+// Add queues an operation and returns the queued one. An undelivered
+// operation on the same target is kept, along with its backoff, when it does
+// the same thing and replaced otherwise, so a status changed twice offline is
+// only sent once.
+func (o *Outbox) Add(op *Operation) *Operation {
+	if op.ID == "" {
+		op.ID = newOperationID()
+	}
+	if op.CreatedAt.IsZero() {
+		op.CreatedAt = time.Now()
+	}
+
+	for i, existing := range o.Operations {
+		if existing.Done || existing.dedupeKey() != op.dedupeKey() {
+			continue
+		}
+		if existing.Status == op.Status {
+			return existing
+		}
+		op.ID = existing.ID
+		op.CreatedAt = existing.CreatedAt
+		o.Operations[i] = op
+		return op
+	}
+
+	o.Operations = append(o.Operations, op)
+	return op
+}
+
+// Discard drops a pending operation and returns it
+func (o *Outbox) Discard(id string) (*Operation, error) {
+	for i, op := range o.Operations {
+		if op.ID == id && !op.Done {
+			o.Operations = append(o.Operations[:i], o.Operations[i+1:]...)
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("no pending operation with id '%s'", id)
+}
+
+// Flush sends every due operation, or every pending one when force is set.
+// Failures are rescheduled with exponential backoff. The outbox is saved
+// after each delivered operation so a crash can't send it twice.
+func (o *Outbox) Flush(remotes remoteFunc, now time.Time, force bool) []*Operation {
+	return o.flush(remotes, now, force, func(*Operation) error { return o.save() })
+}
+
+// flush is Flush with the way a delivered operation is recorded left to
+// the caller, record runs with o.mu held
+func (o *Outbox) flush(remotes remoteFunc, now time.Time, force bool, record func(op *Operation) error) []*Operation {
+	var due []*Operation
+	for _, op := range o.Operations {
+		if op.due(now, force) {
+			due = append(due, op)
+		}
+	}
+
+	delivered := make([]bool, len(due))
+	runAll(len(due), func(i int) error {
+		op := due[i]
+
+		// run works on a copy, other goroutines may be saving the outbox
+		sent := *op
+		r, err := remotes(sent.Scope.scope())
+		if err == nil {
+			err = sent.run(r)
+		}
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		if err != nil {
+			op.Attempts++
+			op.LastError = err.Error()
+			op.NextAttempt = now.Add(backoff(op.Attempts))
+			logf("Warning: %s %s failed (attempt %d, retrying after %s): %v\n",
+				op.Action, op.Target(), op.Attempts, op.NextAttempt.Format("15:04:05"), err)
+			return nil
+		}
+
+		*op = sent
+		op.Done = true
+		op.LastError = ""
+		delivered[i] = true
+		if err := record(op); err != nil {
+			logf("Warning: %v\n", err)
+		}
+		return nil
+	})
+
+	var result []*Operation
+	for i, op := range due {
+		if delivered[i] {
+			result = append(result, op)
+		}
+	}
+	return result
+}
+
+// apply writes the results of delivered operations to the todos and returns
+// the todos it changed. Created todos get their issue, closed and read todos
+// are marked synced.
+func (o *Outbox) apply(todos []*todo.Todo) []*todo.Todo {
+	pending := make(map[string]*todo.Todo)
+	for _, t := range todos {
+		if id := t.Labels[pendingLabel]; id != "" {
+			pending[id] = t
+		}
+	}
+
+	var applied []*todo.Todo
+	for _, op := range o.Operations {
+		if !op.Done {
+			continue
+		}
+		t, ok := pending[op.ID]
+		if !ok {
+			continue
+		}
+		delete(t.Labels, pendingLabel)
+		switch op.Action {
+		case ActionCreate:
+			t.Labels["issue"] = strconv.Itoa(op.Number)
+			t.Labels["url"] = op.URL
+		case ActionClose, ActionMarkRead:
+			t.Labels["synced"] = "true"
+		}
+		applied = append(applied, t)
+	}
+	return applied
+}
+
+// claim marks the due operations as being sent and returns copies of them
+// to send
+func (o *Outbox) claim(now time.Time, force bool) []*Operation {
+	var claimed []*Operation
+	for _, op := range o.Operations {
+		if !op.due(now, force) {
+			continue
+		}
+		sent := *op
+		claimed = append(claimed, &sent)
+		op.SendingUntil = now.Add(outboxClaim)
+	}
+	return claimed
+}
+
+// merge records the outcome of sending a claimed operation. An operation
+// replaced while it was being sent keeps the replacement.
+func (o *Outbox) merge(sent *Operation) {
+	for _, op := range o.Operations {
+		if op.ID != sent.ID || op.Action != sent.Action || op.Status != sent.Status {
+			continue
+		}
+		*op = *sent
+		op.SendingUntil = time.Time{}
+		return
+	}
+}
+
+// prune drops delivered operations
+func (o *Outbox) prune() {
+	var kept []*Operation
+	for _, op := range o.Operations {
+		if !op.Done {
+			kept = append(kept, op)
+		}
+	}
+	o.Operations = kept
+}
+
+// deliver saves the outbox and todos, sends due operations, and writes
+// their results back. Returns the operations delivered this time.
+func (o *Outbox) deliver(todoDir string, todos []*todo.Todo, remotes remoteFunc, force bool) ([]*Operation, error) {
+	// record intent first, nothing is sent that isn't on disk
+	if err := o.Save(); err != nil {
+		return nil, err
+	}
+	if err := todo.WriteTodoDir(todoDir, todos); err != nil {
+		return nil, fmt.Errorf("failed to write todos: %w", err)
+	}
+
+	delivered := o.Flush(remotes, time.Now(), force)
+
+	// results of earlier runs that crashed before writing them are applied too
+	if len(o.apply(todos)) > 0 {
+		if err := todo.WriteTodoDir(todoDir, todos); err != nil {
+			return delivered, fmt.Errorf("failed to write todos: %w", err)
+		}
+	}
+
+	o.prune()
+	return delivered, o.Save()
+}
+
+// Lock runs fn holding the ATP directory lock
+type Lock func(fn func() error) error
+
+// deliverUnlocked is deliver for callers that don't hold the ATP lock. The
+// lock is only taken to queue operations with queue and write the todos it
+// returns, to record each delivered operation and to write the results back,
+// GitHub is contacted without it.
+func deliverUnlocked(todoDir string, lock Lock, remotes remoteFunc, force bool, queue func(o *Outbox) ([]*todo.Todo, error)) ([]*Operation, error) {
+	atpDir := filepath.Dir(todoDir)
+
+	var claimed []*Operation
+	err := lock(func() error {
+		outbox, err := LoadOutbox(atpDir)
+		if err != nil {
+			return err
+		}
+		var todos []*todo.Todo
+		if queue != nil {
+			if todos, err = queue(outbox); err != nil {
+				return err
+			}
+		}
+		claimed = outbox.claim(time.Now(), force)
+
+		// record intent first, nothing is sent that isn't on disk
+		if err := outbox.Save(); err != nil {
+			return err
+		}
+		if todos != nil {
+			if err := todo.WriteTodoDir(todoDir, todos); err != nil {
+				return fmt.Errorf("failed to write todos: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sending := &Outbox{Operations: claimed}
+	delivered := sending.flush(remotes, time.Now(), true, func(op *Operation) error {
+		return lock(func() error {
+			outbox, err := LoadOutbox(atpDir)
+			if err != nil {
+				return err
+			}
+			outbox.merge(op)
+			return outbox.Save()
+		})
+	})
+
+	// results of earlier runs that crashed before writing them are applied too
+	err = lock(func() error {
+		outbox, err := LoadOutbox(atpDir)
+		if err != nil {
+			return err
+		}
+		for _, op := range claimed {
+			outbox.merge(op)
+		}
+
+		todos, err := todo.LoadTodoDir(todoDir)
+		if err != nil {
+			return fmt.Errorf("failed to load todos: %w", err)
+		}
+		if len(outbox.apply(todos)) > 0 {
+			if err := todo.WriteTodoDir(todoDir, todos); err != nil {
+				return fmt.Errorf("failed to write todos: %w", err)
+			}
+		}
+
+		outbox.prune()
+		return outbox.Save()
+	})
+	return delivered, err
+}
+
+// lazyRemotes only builds a session, and so loads the config, when an
+// operation actually has to be sent
+func lazyRemotes(atpDir string, sess *session) remoteFunc {
+	var once sync.Once
+	var err error
+	return func(sc scope) (remote, error) {
+		once.Do(func() {
+			if sess != nil {
+				return
+			}
+			var cfg *config.Config
+			cfg, err = config.LoadConfig(atpDir)
+			if err != nil {
+				err = fmt.Errorf("failed to load config: %w", err)
+				return
+			}
+			sess = newSession(ClientOptionsFromConfig(cfg, atpDir))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return sess.client(sc)
+	}
+}
+
+// ReplayOutbox sends pending operations. Unless force is set only those
+// whose backoff has expired are sent.
+func ReplayOutbox(todoDir string, force bool) ([]*Operation, error) {
+	atpDir := filepath.Dir(todoDir)
+
+	outbox, err := LoadOutbox(atpDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(outbox.Operations) == 0 {
+		return nil, nil
+	}
+
+	todos, err := todo.LoadTodoDir(todoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	return outbox.deliver(todoDir, todos, lazyRemotes(atpDir, nil), force)
+}
+
+// ReplayOutboxUnlocked is ReplayOutbox for callers that don't hold the ATP
+// lock, it is only taken to read and write the outbox and todos
+func ReplayOutboxUnlocked(todoDir string, force bool, lock Lock) ([]*Operation, error) {
+	return deliverUnlocked(todoDir, lock, lazyRemotes(filepath.Dir(todoDir), nil), force, nil)
+}
+
+// DiscardOperation drops a pending operation. A discarded issue creation
+// makes its todo eligible for push again, a discarded close or read is
+// planned anew by the next sync.
+func DiscardOperation(todoDir string, id string) (*Operation, error) {
+	atpDir := filepath.Dir(todoDir)
+
+	outbox, err := LoadOutbox(atpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := outbox.Discard(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.Action != ActionStatus {
+		todos, err := todo.LoadTodoDir(todoDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load todos: %w", err)
+		}
+		for _, t := range todos {
+			if t.Labels[pendingLabel] == op.ID {
+				delete(t.Labels, pendingLabel)
+			}
+		}
+		if err := todo.WriteTodoDir(todoDir, todos); err != nil {
+			return nil, fmt.Errorf("failed to write todos: %w", err)
+		}
+	}
+
+	return op, outbox.Save()
+}
+
+func newOperationID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,283 @@
+package github
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/todo"
+	"github.com/google/go-github/v66/github"
+)
+
+// fakeRemote records calls and fails while fail is set
+type fakeRemote struct {
+	fail   bool
+	closed []string
+	status []string
+	read   []string
+	issues int
+}
+
+func (f *fakeRemote) err() error {
+	if f.fail {
+		return fmt.Errorf("network is unreachable")
+	}
+	return nil
+}
+
+func (f *fakeRemote) CloseIssue(owner string, repoName string, issueNumber int) error {
+	if err := f.err(); err != nil {
+		return err
+	}
+	f.closed = append(f.closed, fmt.Sprintf("%s/%s#%d", owner, repoName, issueNumber))
+	return nil
+}
+
+func (f *fakeRemote) UpdateProjectItemStatus(projectNumber int, projectItemID string, newStatus string) error {
+	if err := f.err(); err != nil {
+		return err
+	}
+	f.status = append(f.status, projectItemID+"="+newStatus)
+	return nil
+}
+
+func (f *fakeRemote) MarkNotificationRead(threadID string) error {
+	if err := f.err(); err != nil {
+		return err
+	}
+	f.read = append(f.read, threadID)
+	return nil
+}
+
+func (f *fakeRemote) CreateIssue(owner string, repoName string, title string) (*github.Issue, error) {
+	if err := f.err(); err != nil {
+		return nil, err
+	}
+	f.issues++
+	url := fmt.Sprintf("https://github.com/%s/%s/issues/%d", owner, repoName, f.issues)
+	return &github.Issue{Number: github.Int(f.issues), HTMLURL: github.String(url), NodeID: github.String("node")}, nil
+}
+
+func (f *fakeRemote) AddIssueToProject(projectNumber int, issueNodeID string) (string, error) {
+	if err := f.err(); err != nil {
+		return "", err
+	}
+	return "item", nil
+}
+
+func (f *fakeRemote) remotes(sc scope) (remote, error) {
+	return f, nil
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, time.Hour},
+	}
+
+	for _, test := range tests {
+		if got := backoff(test.attempts); got != test.want {
+			t.Errorf("backoff(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestOutboxAddDedupes(t *testing.T) {
+	outbox := &Outbox{}
+	outbox.Add(&Operation{Action: ActionStatus, ProjectItemID: "item1", Status: "Todo"})
+	first := outbox.Operations[0]
+	first.Attempts = 2
+
+	// the same change keeps its backoff
+	outbox.Add(&Operation{Action: ActionStatus, ProjectItemID: "item1", Status: "Todo"})
+	if len(outbox.Operations) != 1 || outbox.Operations[0].Attempts != 2 {
+		t.Fatalf("re-adding the same status: got %+v", outbox.Operations)
+	}
+
+	// a new status replaces the old one
+	outbox.Add(&Operation{Action: ActionStatus, ProjectItemID: "item1", Status: "Done"})
+	if len(outbox.Operations) != 1 || outbox.Operations[0].Status != "Done" || outbox.Operations[0].ID != first.ID {
+		t.Fatalf("changing the status: got %+v", outbox.Operations[0])
+	}
+
+	outbox.Add(&Operation{Action: ActionClose, URL: "https://github.com/me/app/issues/1"})
+	outbox.Add(&Operation{Action: ActionClose, URL: "https://github.com/me/app/issues/1"})
+	outbox.Add(&Operation{Action: ActionCreate, Title: "a"})
+	outbox.Add(&Operation{Action: ActionCreate, Title: "a"})
+	if len(outbox.Operations) != 4 {
+		t.Errorf("got %d operations, want 4", len(outbox.Operations))
+	}
+}
+
+func TestOutboxFlushRetries(t *testing.T) {
+	dir := t.TempDir()
+	remote := &fakeRemote{fail: true}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	outbox, err := LoadOutbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbox.Add(&Operation{Action: ActionClose, Owner: "me", Repo: "app", Number: 1})
+	outbox.Add(&Operation{Action: ActionMarkRead, Thread: "42"})
+
+	if delivered := outbox.Flush(remote.remotes, now, false); len(delivered) != 0 {
+		t.Fatalf("delivered %d operations while failing", len(delivered))
+	}
+	for _, op := range outbox.Operations {
+		if op.Attempts != 1 || op.LastError == "" || !op.NextAttempt.Equal(now.Add(30*time.Second)) {
+			t.Errorf("after failure: %+v", op)
+		}
+	}
+	if err := outbox.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the queue survives a restart
+	outbox, err = LoadOutbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outbox.Pending()) != 2 {
+		t.Fatalf("reloaded %d pending operations, want 2", len(outbox.Pending()))
+	}
+
+	// back online, but still backing off
+	remote.fail = false
+	if delivered := outbox.Flush(remote.remotes, now.Add(10*time.Second), false); len(delivered) != 0 {
+		t.Errorf("delivered %d operations during backoff", len(delivered))
+	}
+
+	delivered := outbox.Flush(remote.remotes, now.Add(time.Minute), false)
+	if len(delivered) != 2 || len(remote.closed) != 1 || len(remote.read) != 1 {
+		t.Errorf("after backoff: delivered %d, closed %v, read %v", len(delivered), remote.closed, remote.read)
+	}
+
+	outbox.prune()
+	if len(outbox.Operations) != 0 {
+		t.Errorf("prune left %d operations", len(outbox.Operations))
+	}
+}
+
+func TestOutboxDeliverCreates(t *testing.T) {
+	dir := t.TempDir()
+	remote := &fakeRemote{fail: true}
+
+	outbox, err := LoadOutbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushed := todo.FromString("Add dark mode +github repo:me/app")
+	op := outbox.Add(&Operation{Action: ActionCreate, Owner: "me", Repo: "app", Title: pushed.Description, ProjectNumber: 3, Status: statusPlanned})
+	pushed.Labels[pendingLabel] = op.ID
+	todos := []*todo.Todo{pushed}
+
+	if _, err := outbox.deliver(dir, todos, remote.remotes, true); err != nil {
+		t.Fatal(err)
+	}
+	if pushed.Labels[pendingLabel] != op.ID || pushed.Labels["url"] != "" {
+		t.Fatalf("failed create changed the todo: %v", pushed.Labels)
+	}
+
+	remote.fail = false
+	delivered, err := outbox.deliver(dir, todos, remote.remotes, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 1 {
+		t.Fatalf("delivered %d operations, want 1", len(delivered))
+	}
+	if pushed.Labels[pendingLabel] != "" || pushed.Labels["issue"] != "1" || pushed.Labels["url"] != "https://github.com/me/app/issues/1" {
+		t.Errorf("created todo labels = %v", pushed.Labels)
+	}
+	if len(remote.status) != 1 || remote.status[0] != "item="+statusPlanned {
+		t.Errorf("board status = %v", remote.status)
+	}
+
+	saved, err := todo.LoadTodoDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].Labels["url"] == "" {
+		t.Errorf("written todos = %v", saved)
+	}
+	if len(outbox.Operations) != 0 {
+		t.Errorf("outbox kept %d operations", len(outbox.Operations))
+	}
+}
+
+func TestOutboxDeliverUnlocked(t *testing.T) {
+	atpDir := t.TempDir()
+	todoDir := filepath.Join(atpDir, "todo")
+	if err := os.MkdirAll(todoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeRemote{fail: true}
+
+	held := false
+	lock := func(fn func() error) error {
+		held = true
+		defer func() { held = false }()
+		return fn()
+	}
+	remotes := func(sc scope) (remote, error) {
+		if held {
+			t.Error("GitHub was contacted with the lock held")
+		}
+		// a delivery holding the lock leaves the claimed operation alone
+		other, err := LoadOutbox(atpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent := other.Flush(fake.remotes, time.Now(), true); len(sent) != 0 {
+			t.Errorf("claimed operation was sent twice")
+		}
+		return fake, nil
+	}
+
+	pushed := todo.FromString("Add dark mode +github repo:me/app")
+	queue := func(outbox *Outbox) ([]*todo.Todo, error) {
+		op := outbox.Add(&Operation{Action: ActionCreate, Owner: "me", Repo: "app", Title: pushed.Description})
+		pushed.Labels[pendingLabel] = op.ID
+		return []*todo.Todo{pushed}, nil
+	}
+
+	if _, err := deliverUnlocked(todoDir, lock, remotes, true, queue); err != nil {
+		t.Fatal(err)
+	}
+	outbox, err := LoadOutbox(atpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outbox.Operations) != 1 || outbox.Operations[0].Attempts != 1 || !outbox.Operations[0].SendingUntil.IsZero() {
+		t.Fatalf("failed operation = %+v, want one attempt and no claim", outbox.Operations)
+	}
+
+	fake.fail = false
+	delivered, err := deliverUnlocked(todoDir, lock, remotes, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 1 || fake.issues != 1 {
+		t.Fatalf("delivered %d operations creating %d issues, want 1", len(delivered), fake.issues)
+	}
+
+	saved, err := todo.LoadTodoDir(todoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].Labels["url"] != "https://github.com/me/app/issues/1" || saved[0].Labels[pendingLabel] != "" {
+		t.Errorf("written todos = %v", saved)
+	}
+	if outbox, _ := LoadOutbox(atpDir); len(outbox.Operations) != 0 {
+		t.Errorf("outbox kept %d operations", len(outbox.Operations))
+	}
+}
@@ -0,0 +1,608 @@
+package github
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Actions recorded in a SyncPlan
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionComplete = "complete"
+	ActionReopen   = "reopen"
+	ActionClose    = "close"
+	ActionStatus   = "status"
+	ActionMarkRead = "read"
+)
+
+// Project board statuses that local priorities map to
+const (
+	statusInProgress = "In Progress"
+	statusPlanned    = "Planned-This-Week"
+)
+
+// SyncPlan is everything a sync would change, computed without touching
+// either todo.txt or GitHub. Apply it with ApplySync.
+type SyncPlan struct {
+	Local  []LocalChange  `json:"local"`
+	Remote []RemoteChange `json:"remote"`
+
+	// todos is the full todo list as it should be written once applied
+	todos []*todo.Todo
+	// session is reused on apply so clients aren't created twice
+	session *session
+	// fetches and notifications are what GitHub returned, kept so the plan
+	// can be recomputed against fresh todos without fetching again
+	fetches       []*projectFetch
+	notify        bool
+	notifications []NotificationInfo
+}
+
+// LocalChange is a planned change to a todo in the todo dir
+type LocalChange struct {
+	Action string `json:"action"`
+	URL    string `json:"url"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after"`
+}
+
+// RemoteChange is a planned mutation on GitHub
+type RemoteChange struct {
+	Action        string `json:"action"`
+	URL           string `json:"url"`
+	Status        string `json:"status,omitempty"`
+	ProjectNumber int    `json:"project_number,omitempty"`
+	ProjectItemID string `json:"project_item_id,omitempty"`
+	Thread        string `json:"thread,omitempty"`
+
+	scope scope
+	todo  *todo.Todo
+}
+
+// projectFetch holds everything fetched from GitHub for one configured project
+type projectFetch struct {
+	project        config.GitHubProject
+	issues         []IssueWithStatus
+	userPRs        []PullRequestInfo
+	reviewRequests []PullRequestInfo
+	// acceptRemote is set when GitHub changed since the last sync, in which
+	// case GitHub state wins over local edits
+	acceptRemote bool
+	// enrich selects the issue metadata copied onto todos
+	enrich config.EnrichConfig
+	// excludeDrafts skips the user's own draft PRs
+	excludeDrafts bool
+}
+
+// Empty reports whether applying the plan would change anything
+func (p *SyncPlan) Empty() bool {
+	return len(p.Local) == 0 && len(p.Remote) == 0
+}
+
+// Diff renders the plan as a human readable diff
+func (p *SyncPlan) Diff() string {
+	if p.Empty() {
+		return "No changes\n"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d local change(s), %d GitHub change(s)\n", len(p.Local), len(p.Remote))
+
+	if len(p.Local) > 0 {
+		sb.WriteString("\nLocal todos:\n")
+		for _, change := range p.Local {
+			if change.Action == ActionCreate {
+				fmt.Fprintf(&sb, "  %-8s + %s\n", change.Action, change.After)
+				continue
+			}
+			fmt.Fprintf(&sb, "  %-8s - %s\n", change.Action, change.Before)
+			fmt.Fprintf(&sb, "  %-8s + %s\n", "", change.After)
+		}
+	}
+
+	if len(p.Remote) > 0 {
+		sb.WriteString("\nGitHub:\n")
+		for _, change := range p.Remote {
+			switch change.Action {
+			case ActionStatus:
+				fmt.Fprintf(&sb, "  %-8s %s -> %s\n", change.Action, change.URL, change.Status)
+			default:
+				fmt.Fprintf(&sb, "  %-8s %s\n", change.Action, change.URL)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// PlanGitHubProject computes the sync plan for a single configured project
+func PlanGitHubProject(todoDir string, projectName string) (*SyncPlan, error) {
+	atpDir := filepath.Dir(todoDir)
+	cfg, err := config.LoadConfig(atpDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	project, err := cfg.GetGitHubProject(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	return planProjects(todoDir, cfg, []config.GitHubProject{*project})
+}
+
+// PlanAllGitHubProjects computes a single sync plan covering every configured project
+func PlanAllGitHubProjects(todoDir string) (*SyncPlan, error) {
+	atpDir := filepath.Dir(todoDir)
+	cfg, err := config.LoadConfig(atpDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return planProjects(todoDir, cfg, cfg.GetAllGitHubProjects())
+}
+
+func planProjects(todoDir string, cfg *config.Config, projects []config.GitHubProject) (*SyncPlan, error) {
+	atpDir := filepath.Dir(todoDir)
+
+	// Get last sync time for timestamp-based conflict resolution
+	lastSyncTime, err := getLastSyncTime(atpDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last sync time: %w", err)
+	}
+
+	for _, project := range projects {
+		if err := project.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch every project concurrently, the shared session bounds the
+	// number of requests in flight and reuses per-scope results
+	sess := newSession(ClientOptionsFromConfig(cfg, atpDir))
+	fetches := make([]*projectFetch, len(projects))
+	err = runAll(len(projects), func(i int) error {
+		project := projects[i]
+		logf("Syncing %s project %d (statuses: %v, assigned to you)...\n",
+			scopeForProject(project), project.ProjectNumber, project.StatusFilters)
+
+		client, err := sess.client(scopeForProject(project))
+		if err != nil {
+			return fmt.Errorf("failed to create GitHub client: %w", err)
+		}
+
+		fetches[i], err = fetchProject(client, project, lastSyncTime)
+		if err != nil {
+			return fmt.Errorf("failed to sync project %s: %w", project.Name, err)
+		}
+		fetches[i].enrich = cfg.GitHub.Enrich
+		fetches[i].excludeDrafts = cfg.GitHub.ExcludeDrafts
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	todos, err := todo.LoadTodoDir(todoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	var notifications []NotificationInfo
+	if cfg.GitHub.Notifications.Enabled {
+		notifications, err = fetchNotifications(sess, cfg.GitHub.Notifications, projectHosts(projects))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan := replan(todos, fetches, cfg.GitHub.Notifications.Enabled, notifications)
+	plan.session = sess
+	return plan, nil
+}
+
+// replan computes the plan for todos from data already fetched
+func replan(todos []*todo.Todo, fetches []*projectFetch, notify bool, notifications []NotificationInfo) *SyncPlan {
+	plan := planSync(todos, fetches)
+	plan.fetches = fetches
+	plan.notify = notify
+	plan.notifications = notifications
+	if notify {
+		plan.planNotifications(notifications)
+	}
+	return plan
+}
+
+// Replan reloads the todos and recomputes the plan from what was fetched,
+// so a plan fetched without holding the ATP lock can be applied to the todos
+// as they are once it is held.
+func (p *SyncPlan) Replan(todoDir string) error {
+	todos, err := todo.LoadTodoDir(todoDir)
+	if err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+	plan := replan(todos, p.fetches, p.notify, p.notifications)
+	plan.session = p.session
+	*p = *plan
+	return nil
+}
+
+func fetchProject(client *Client, project config.GitHubProject, lastSyncTime time.Time) (*projectFetch, error) {
+	fetch := &projectFetch{project: project}
+
+	err := runAll(3, func(i int) error {
+		var err error
+		switch i {
+		case 0:
+			fetch.issues, err = client.GetProjectV2Issues(project.ProjectNumber, project.StatusFilters)
+			if err != nil {
+				return fmt.Errorf("failed to fetch GitHub issues: %w", err)
+			}
+		case 1:
+			fetch.userPRs, err = client.GetUserPullRequests()
+			if err != nil {
+				return fmt.Errorf("failed to fetch user PRs: %w", err)
+			}
+			logf("Found %d open PRs created by you\n", len(fetch.userPRs))
+		case 2:
+			fetch.reviewRequests, err = client.GetReviewRequests()
+			if err != nil {
+				return fmt.Errorf("failed to fetch review requests: %w", err)
+			}
+			logf("Found %d open review requests\n", len(fetch.reviewRequests))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if any GitHub issues have been updated since our last sync
+	if !lastSyncTime.IsZero() {
+		logf("Checking for GitHub updates since last sync (%s)...\n", lastSyncTime.Format("15:04:05"))
+		fetch.acceptRemote = hasUpdatesSince(fetch.issues, lastSyncTime)
+	}
+	if fetch.acceptRemote {
+		logf("GitHub issues updated since last sync - accepting all GitHub changes\n")
+	} else {
+		logf("No GitHub updates since last sync - keeping local changes\n")
+	}
+
+	return fetch, nil
+}
+
+func hasUpdatesSince(issues []IssueWithStatus, lastSyncTime time.Time) bool {
+	for _, issue := range issues {
+		if issue.UpdatedAt.After(lastSyncTime) {
+			logf("  Found GitHub update: %s updated at %s (after %s)\n",
+				issue.Title, issue.UpdatedAt.Format("15:04:05"), lastSyncTime.Format("15:04:05"))
+			return true
+		}
+	}
+	return false
+}
+
+// planSync merges fetched GitHub state into the given todos. The todos are
+// cloned first so the caller's copies stay untouched.
+func planSync(todos []*todo.Todo, fetches []*projectFetch) *SyncPlan {
+	plan := &SyncPlan{Local: []LocalChange{}, Remote: []RemoteChange{}}
+
+	current := make([]*todo.Todo, len(todos))
+	for i, t := range todos {
+		current[i] = t.Clone()
+	}
+
+	existingGitHubTodos := buildGitHubTodoMap(current)
+	processedGitHubURLs := make(map[string]bool)
+	remoteWins := make(map[string]bool)
+	newTodos := filterNonGitHubTodos(current)
+	subTodos := buildSubTodoMap(current)
+
+	// Issues completed locally that GitHub doesn't know about yet
+	var completedLocally []*todo.Todo
+	for _, t := range current {
+		url := reconstructGitHubURL(t)
+		if url != "" && existingGitHubTodos[url] == t && needsRemoteClose(t) {
+			completedLocally = append(completedLocally, t)
+		}
+	}
+
+	// scopes whose issues and PRs were fetched, only their todos can go stale
+	var fetchedScopes []scope
+
+	for _, fetch := range fetches {
+		fetchedScopes = append(fetchedScopes, scopeForProject(fetch.project))
+
+		for _, issue := range fetch.issues {
+			if processedGitHubURLs[issue.URL] {
+				continue
+			}
+			processedGitHubURLs[issue.URL] = true
+			remoteWins[issue.URL] = fetch.acceptRemote
+
+			if fetch.enrich.Checklists && issue.State == "open" {
+				newTodos = append(newTodos, plan.planSubTodos(issue, subTodos)...)
+			}
+
+			existingTodo, exists := existingGitHubTodos[issue.URL]
+			if !exists {
+				newTodo := createTodoFromIssue(issue)
+				enrichTodo(newTodo, issue, fetch.enrich)
+				plan.recordChange(issue.URL, nil, newTodo)
+				newTodos = append(newTodos, newTodo)
+				continue
+			}
+
+			before := existingTodo.Clone()
+			// a todo whose close is still in the outbox keeps its local state,
+			// GitHub only looks open because the close hasn't arrived yet
+			if fetch.acceptRemote && existingTodo.Labels[pendingLabel] == "" {
+				// Accept all GitHub changes
+				updateExistingTodo(existingTodo, issue)
+			} else {
+				// Keep local state, only update title
+				existingTodo.Description = sanitizeIssueTitle(issue.Title)
+				if _, exists := existingTodo.Labels["repo"]; !exists {
+					repoName := extractRepoFromURL(issue.URL)
+					if repoName != "" {
+						existingTodo.Labels["repo"] = repoName
+					}
+				}
+				plan.planStatusChange(existingTodo, issue, fetch.project)
+			}
+			enrichTodo(existingTodo, issue, fetch.enrich)
+			plan.recordChange(issue.URL, before, existingTodo)
+			newTodos = append(newTodos, existingTodo)
+		}
+
+		// PRs and review requests always accept GitHub state (read-only)
+		prs := append(append([]PullRequestInfo{}, fetch.userPRs...), fetch.reviewRequests...)
+		for _, pr := range prs {
+			// excluded drafts count as gone, a todo synced before drafts were
+			// excluded is completed below like a closed PR
+			if pr.IsDraft && !pr.IsReview && fetch.excludeDrafts {
+				continue
+			}
+
+			if processedGitHubURLs[pr.URL] {
+				continue
+			}
+			processedGitHubURLs[pr.URL] = true
+
+			existingTodo, exists := existingGitHubTodos[pr.URL]
+			if !exists {
+				newTodo := createTodoFromPR(pr)
+				plan.recordChange(pr.URL, nil, newTodo)
+				newTodos = append(newTodos, newTodo)
+				continue
+			}
+
+			before := existingTodo.Clone()
+			updateExistingTodoFromPR(existingTodo, pr)
+			plan.recordChange(pr.URL, before, existingTodo)
+			newTodos = append(newTodos, existingTodo)
+		}
+	}
+
+	// Mark unprocessed GitHub todos as done (closed, merged, or moved out of filtered statuses).
+	// Walk the original order so the plan is stable between runs.
+	for _, t := range current {
+		url := reconstructGitHubURL(t)
+		if url == "" || processedGitHubURLs[url] || existingGitHubTodos[url] != t {
+			continue
+		}
+
+		if !t.Done && anyCovers(fetchedScopes, url) {
+			before := t.Clone()
+			t.Done = true
+			t.CompletionDate = time.Now()
+			plan.recordChange(url, before, t)
+		}
+		newTodos = append(newTodos, t)
+	}
+
+	// Close issues that were completed locally, unless GitHub won this round
+	for _, t := range completedLocally {
+		url := reconstructGitHubURL(t)
+		if remoteWins[url] || !t.Done {
+			continue
+		}
+		plan.Remote = append(plan.Remote, RemoteChange{
+			Action: ActionClose,
+			URL:    url,
+			scope:  scopeForURL(url),
+			todo:   t,
+		})
+	}
+
+	plan.todos = newTodos
+	return plan
+}
+
+// needsRemoteClose reports whether a locally completed issue todo still has
+// to be closed on GitHub
+func needsRemoteClose(t *todo.Todo) bool {
+	if !t.Done {
+		return false
+	}
+
+	// Skip PRs - we don't want to close them based on local todo state
+	if _, hasPR := t.Labels["pr"]; hasPR {
+		return false
+	}
+
+	// a close waiting in the outbox is already on its way
+	return t.Labels["synced"] != "true" && t.Labels[pendingLabel] == ""
+}
+
+// planStatusChange pushes a local priority change to the project board when
+// the board disagrees with it
+func (p *SyncPlan) planStatusChange(t *todo.Todo, issue IssueWithStatus, project config.GitHubProject) {
+	if t.Done || issue.ProjectItemID == "" {
+		return
+	}
+
+	expectedStatus := statusForPriority(t.Priority)
+	if (expectedStatus == statusInProgress) == strings.EqualFold(issue.GitHubStatus, statusInProgress) {
+		return
+	}
+
+	p.Remote = append(p.Remote, RemoteChange{
+		Action:        ActionStatus,
+		URL:           issue.URL,
+		Status:        expectedStatus,
+		ProjectNumber: project.ProjectNumber,
+		ProjectItemID: issue.ProjectItemID,
+		scope:         scopeForProject(project),
+	})
+}
+
+// recordChange adds a local change to the plan if the todo actually changed.
+// A nil before means the todo is new.
+func (p *SyncPlan) recordChange(url string, before *todo.Todo, after *todo.Todo) {
+	if before == nil {
+		p.Local = append(p.Local, LocalChange{Action: ActionCreate, URL: url, After: after.String()})
+		return
+	}
+
+	if before.String() == after.String() {
+		return
+	}
+
+	action := ActionUpdate
+	if !before.Done && after.Done {
+		action = ActionComplete
+	} else if before.Done && !after.Done {
+		action = ActionReopen
+	}
+
+	p.Local = append(p.Local, LocalChange{
+		Action: action,
+		URL:    url,
+		Before: before.String(),
+		After:  after.String(),
+	})
+}
+
+// ApplySync performs the remote mutations in the plan and writes the resulting todos
+func ApplySync(todoDir string, plan *SyncPlan) error {
+	atpDir := filepath.Dir(todoDir)
+
+	if err := applySync(todoDir, plan, lazyRemotes(atpDir, plan.session)); err != nil {
+		return err
+	}
+
+	// Update last sync time after successful sync
+	if err := updateLastSyncTime(atpDir); err != nil {
+		return fmt.Errorf("failed to update last sync time: %w", err)
+	}
+
+	return nil
+}
+
+// ApplySyncUnlocked is ApplySync for callers that don't hold the ATP lock.
+// Under the lock the plan is recomputed against the current todos, its
+// changes are queued and the todos written. The outbox is delivered with the
+// lock released and the results are written back under it again.
+func ApplySyncUnlocked(todoDir string, plan *SyncPlan, lock Lock) error {
+	atpDir := filepath.Dir(todoDir)
+
+	_, err := deliverUnlocked(todoDir, lock, lazyRemotes(atpDir, plan.session), false, func(outbox *Outbox) ([]*todo.Todo, error) {
+		if err := plan.Replan(todoDir); err != nil {
+			return nil, err
+		}
+		queueChanges(outbox, plan)
+		return plan.todos, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return lock(func() error {
+		if err := updateLastSyncTime(atpDir); err != nil {
+			return fmt.Errorf("failed to update last sync time: %w", err)
+		}
+		return nil
+	})
+}
+
+// applySync queues the plan's remote changes, delivers them through remotes
+// and writes the todos
+func applySync(todoDir string, plan *SyncPlan, remotes remoteFunc) error {
+	outbox, err := LoadOutbox(filepath.Dir(todoDir))
+	if err != nil {
+		return err
+	}
+
+	queueChanges(outbox, plan)
+
+	_, err = outbox.deliver(todoDir, plan.todos, remotes, false)
+	return err
+}
+
+// queueChanges adds the plan's remote changes to the outbox so they survive
+// being offline. Closed and read todos are marked pending until the outbox
+// delivered them, only then are they synced.
+func queueChanges(outbox *Outbox, plan *SyncPlan) {
+	for _, change := range plan.Remote {
+		op, err := operationForChange(change)
+		if err != nil {
+			logf("Warning: skipping %s of %s: %v\n", change.Action, change.URL, err)
+			continue
+		}
+		op = outbox.Add(op)
+		if change.Action == ActionClose || change.Action == ActionMarkRead {
+			change.todo.Labels[pendingLabel] = op.ID
+		}
+	}
+}
+
+// statusForPriority maps a local priority to the project board status
+func statusForPriority(priority string) string {
+	if priority == "A" {
+		return statusInProgress
+	}
+	return statusPlanned
+}
+
+// anyCovers reports whether the URL belongs to any of the scopes
+func anyCovers(scopes []scope, url string) bool {
+	for _, s := range scopes {
+		if s.covers(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectHosts lists the GitHub hosts the projects live on, github.com when
+// there are none
+func projectHosts(projects []config.GitHubProject) []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, project := range projects {
+		if host := project.Host(); !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = []string{config.DefaultGitHubHost}
+	}
+	return hosts
+}
+
+// scopeForURL returns a scope that can act on the issue or PR at url
+func scopeForURL(url string) scope {
+	ref, err := parseGitHubURL(url)
+	if err != nil {
+		return orgScope("")
+	}
+	return ref.scope()
+}
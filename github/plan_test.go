@@ -0,0 +1,258 @@
+package github
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func TestPlanSync(t *testing.T) {
+	todos := []*todo.Todo{
+		todo.FromString("Buy milk @home"),
+		todo.FromString("(A) Fix login +github repo:acme/app issue:1 url:https://github.com/acme/app/issues/1"),
+		todo.FromString("x 2025-02-16 Write docs +github repo:acme/app issue:2 url:https://github.com/acme/app/issues/2"),
+		todo.FromString("Old task +github repo:acme/app issue:3 url:https://github.com/acme/app/issues/3"),
+		todo.FromString("Other org task +github repo:other/app issue:4 url:https://github.com/other/app/issues/4"),
+	}
+	original := make([]string, len(todos))
+	for i, input := range todos {
+		original[i] = input.String()
+	}
+
+	fetch := &projectFetch{
+		project: config.GitHubProject{Name: "acme", Organization: "acme", ProjectNumber: 7},
+		issues: []IssueWithStatus{
+			{
+				ProjectIssue:  ProjectIssue{Title: "Fix login", Number: 1, State: "open", URL: "https://github.com/acme/app/issues/1"},
+				GitHubStatus:  "Planned-This-Week",
+				ProjectItemID: "item-1",
+			},
+			{
+				ProjectIssue:  ProjectIssue{Title: "Write docs", Number: 2, State: "open", URL: "https://github.com/acme/app/issues/2"},
+				GitHubStatus:  "In Progress",
+				ProjectItemID: "item-2",
+			},
+			{
+				ProjectIssue:  ProjectIssue{Title: "New bug", Number: 5, State: "open", URL: "https://github.com/acme/app/issues/5"},
+				GitHubStatus:  "In Progress",
+				ProjectItemID: "item-5",
+			},
+		},
+	}
+
+	plan := planSync(todos, []*projectFetch{fetch})
+
+	// planning must not touch the todos that were passed in
+	for i, input := range todos {
+		if input.String() != original[i] {
+			t.Errorf("planSync() mutated input todo %d: got %s, want %s", i, input.String(), original[i])
+		}
+	}
+
+	local := map[string]string{}
+	for _, change := range plan.Local {
+		local[change.URL] = change.Action
+	}
+	wantLocal := map[string]string{
+		"https://github.com/acme/app/issues/5": ActionCreate,
+		"https://github.com/acme/app/issues/3": ActionComplete,
+	}
+	if len(local) != len(wantLocal) {
+		t.Errorf("planSync() local changes = %v, want %v", local, wantLocal)
+	}
+	for url, action := range wantLocal {
+		if local[url] != action {
+			t.Errorf("planSync() local action for %s = %q, want %q", url, local[url], action)
+		}
+	}
+
+	remote := map[string]RemoteChange{}
+	for _, change := range plan.Remote {
+		remote[change.URL] = change
+	}
+	if len(remote) != 2 {
+		t.Fatalf("planSync() remote changes = %v, want 2", plan.Remote)
+	}
+	if change := remote["https://github.com/acme/app/issues/1"]; change.Action != ActionStatus || change.Status != statusInProgress || change.ProjectItemID != "item-1" {
+		t.Errorf("planSync() status change = %+v, want In Progress for item-1", change)
+	}
+	if change := remote["https://github.com/acme/app/issues/2"]; change.Action != ActionClose {
+		t.Errorf("planSync() close change = %+v, want close", change)
+	}
+
+	// every todo survives, including the one from an org that wasn't fetched
+	if len(plan.todos) != 6 {
+		t.Errorf("planSync() resulting todos = %d, want 6", len(plan.todos))
+	}
+	for _, result := range plan.todos {
+		if result.Labels["issue"] == "4" && result.Done {
+			t.Errorf("planSync() completed a todo from an org that was not synced")
+		}
+	}
+}
+
+func TestPlanSyncAcceptRemote(t *testing.T) {
+	todos := []*todo.Todo{
+		todo.FromString("x 2025-02-16 Write docs +github repo:acme/app issue:2 url:https://github.com/acme/app/issues/2"),
+	}
+
+	fetch := &projectFetch{
+		project: config.GitHubProject{Organization: "acme", ProjectNumber: 7},
+		issues: []IssueWithStatus{
+			{
+				ProjectIssue:  ProjectIssue{Title: "Write docs", Number: 2, State: "open", URL: "https://github.com/acme/app/issues/2"},
+				GitHubStatus:  "In Progress",
+				ProjectItemID: "item-2",
+			},
+		},
+		acceptRemote: true,
+	}
+
+	plan := planSync(todos, []*projectFetch{fetch})
+
+	if len(plan.Remote) != 0 {
+		t.Errorf("planSync() remote changes = %v, want none when GitHub wins", plan.Remote)
+	}
+	if len(plan.Local) != 1 || plan.Local[0].Action != ActionReopen {
+		t.Fatalf("planSync() local changes = %v, want a single reopen", plan.Local)
+	}
+	if !strings.HasPrefix(plan.Local[0].After, "(A) Write docs") {
+		t.Errorf("planSync() reopened todo = %q, want priority A", plan.Local[0].After)
+	}
+}
+
+func TestApplySyncFailedDelivery(t *testing.T) {
+	atpDir := t.TempDir()
+	todoDir := filepath.Join(atpDir, "todo")
+	if err := os.MkdirAll(todoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	remote := &fakeRemote{fail: true}
+
+	fetch := &projectFetch{
+		project: config.GitHubProject{Organization: "acme", ProjectNumber: 7},
+		issues: []IssueWithStatus{{
+			ProjectIssue:  ProjectIssue{Title: "Write docs", Number: 2, State: "open", URL: "https://github.com/acme/app/issues/2"},
+			GitHubStatus:  "In Progress",
+			ProjectItemID: "item-2",
+		}},
+	}
+	notifications := []NotificationInfo{
+		{ID: "1", Reason: "mention", Title: "Deploy plan", Repo: "acme/app", URL: "https://github.com/acme/app/issues/1"},
+	}
+	todos := []*todo.Todo{
+		todo.FromString("x 2025-02-16 Write docs +github repo:acme/app issue:2 url:https://github.com/acme/app/issues/2"),
+		todo.FromString("x Mentioned: Deploy plan +github repo:acme/app notif:1 link:https://github.com/acme/app/issues/1"),
+	}
+
+	plan := planSync(todos, []*projectFetch{fetch})
+	plan.planNotifications(notifications)
+	if len(plan.Remote) != 2 {
+		t.Fatalf("planned remote changes = %+v, want a close and a read", plan.Remote)
+	}
+	if err := applySync(todoDir, plan, remote.remotes); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := todo.LoadTodoDir(todoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range saved {
+		if result.Labels["synced"] != "" || result.Labels[pendingLabel] == "" {
+			t.Errorf("undelivered todo = %q, want pending and not synced", result.String())
+		}
+	}
+
+	// GitHub still shows the issue open and the thread unread, and changed
+	// since the last sync, but the todos wait for the outbox
+	fetch.acceptRemote = true
+	plan = planSync(saved, []*projectFetch{fetch})
+	plan.planNotifications(notifications)
+	if len(plan.Local) != 0 || len(plan.Remote) != 0 {
+		t.Errorf("second sync planned local %+v and remote %+v, want nothing", plan.Local, plan.Remote)
+	}
+	for _, result := range plan.todos {
+		if !result.Done {
+			t.Errorf("second sync reopened %q", result.String())
+		}
+	}
+
+	remote.fail = false
+	outbox, err := LoadOutbox(atpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.deliver(todoDir, plan.todos, remote.remotes, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(remote.closed) != 1 || len(remote.read) != 1 {
+		t.Errorf("delivered closes %v and reads %v, want one each", remote.closed, remote.read)
+	}
+
+	saved, err = todo.LoadTodoDir(todoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range saved {
+		if result.Labels["synced"] != "true" || result.Labels[pendingLabel] != "" {
+			t.Errorf("delivered todo = %q, want synced", result.String())
+		}
+	}
+}
+
+func TestSyncPlanReplan(t *testing.T) {
+	todoDir := t.TempDir()
+	fetch := &projectFetch{
+		project: config.GitHubProject{Organization: "acme", ProjectNumber: 7},
+		issues: []IssueWithStatus{{
+			ProjectIssue:  ProjectIssue{Title: "Write docs", Number: 2, State: "open", URL: "https://github.com/acme/app/issues/2"},
+			GitHubStatus:  "In Progress",
+			ProjectItemID: "item-2",
+		}},
+	}
+	plan := replan(nil, []*projectFetch{fetch}, false, nil)
+
+	// a todo added while GitHub was fetched survives the apply, and the
+	// issue it tracks isn't created twice
+	edited := []*todo.Todo{
+		todo.FromString("Buy milk @home"),
+		todo.FromString("Write docs +github repo:acme/app issue:2 url:https://github.com/acme/app/issues/2"),
+	}
+	if err := todo.WriteTodoDir(todoDir, edited); err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Replan(todoDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.todos) != 2 {
+		t.Errorf("replanned todos = %v, want the edited list", plan.todos)
+	}
+	for _, change := range plan.Local {
+		if change.Action == ActionCreate {
+			t.Errorf("replanned local changes = %+v, want no create", plan.Local)
+		}
+	}
+}
+
+func TestSyncPlanDiff(t *testing.T) {
+	plan := &SyncPlan{}
+	if plan.Diff() != "No changes\n" {
+		t.Errorf("Diff() on empty plan = %q", plan.Diff())
+	}
+
+	plan.Local = []LocalChange{{Action: ActionCreate, URL: "u1", After: "New bug +github"}}
+	plan.Remote = []RemoteChange{{Action: ActionStatus, URL: "u2", Status: statusInProgress}}
+
+	diff := plan.Diff()
+	for _, want := range []string{"+ New bug +github", "status   u2 -> In Progress", "1 local change(s), 1 GitHub change(s)"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("Diff() = %q, missing %q", diff, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package github
+
+import (
+	"sync"
+)
+
+// defaultConcurrency is the number of requests allowed in flight at once
+// when the config doesn't say otherwise
+const defaultConcurrency = 4
+
+// runAll calls fn for every index in [0, n) concurrently and returns the
+// error of the lowest failing index. The shared transport bounds how many of
+// them actually talk to GitHub at the same time.
+func runAll(n int, fn func(i int) error) error {
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// memo caches the result of a fetch so it runs at most once per client
+type memo[T any] struct {
+	once sync.Once
+	val  T
+	err  error
+}
+
+func (m *memo[T]) get(fetch func() (T, error)) (T, error) {
+	m.once.Do(func() {
+		m.val, m.err = fetch()
+	})
+	return m.val, m.err
+}
@@ -0,0 +1,156 @@
+package github
+
+import (
+	"github.com/arjungandhi/atp/todo"
+	"github.com/shurcooL/githubv4"
+)
+
+// CI states of a pull request's latest commit
+const (
+	CIPassing = "passing"
+	CIFailing = "failing"
+	CIPending = "pending"
+)
+
+// Review decisions on a pull request
+const (
+	ReviewApproved         = "approved"
+	ReviewChangesRequested = "changes_requested"
+	ReviewRequired         = "required"
+)
+
+// prStatusLabels are the labels owned by the PR status, they are rewritten on
+// every sync
+var prStatusLabels = []string{"ci", "review", "merge", "draft"}
+
+// fetchPullRequestStatus fills in the CI rollup, review decision and
+// mergeability of a pull request
+func (c *Client) fetchPullRequestStatus(pr *PullRequestInfo) error {
+	var query struct {
+		Repository struct {
+			PullRequest struct {
+				IsDraft        bool   `graphql:"isDraft"`
+				Mergeable      string `graphql:"mergeable"`
+				ReviewDecision string `graphql:"reviewDecision"`
+				Commits        struct {
+					Nodes []struct {
+						Commit struct {
+							StatusCheckRollup struct {
+								State string `graphql:"state"`
+							} `graphql:"statusCheckRollup"`
+						} `graphql:"commit"`
+					}
+				} `graphql:"commits(last: 1)"`
+			} `graphql:"pullRequest(number: $number)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner":  githubv4.String(pr.RepoOwner),
+		"name":   githubv4.String(pr.RepoName),
+		"number": githubv4.Int(pr.Number),
+	}
+
+	if err := c.graphqlClient.Query(c.ctx, &query, variables); err != nil {
+		return err
+	}
+
+	result := query.Repository.PullRequest
+	pr.IsDraft = result.IsDraft
+	pr.Conflicting = result.Mergeable == "CONFLICTING"
+
+	switch result.ReviewDecision {
+	case "APPROVED":
+		pr.ReviewDecision = ReviewApproved
+	case "CHANGES_REQUESTED":
+		pr.ReviewDecision = ReviewChangesRequested
+	case "REVIEW_REQUIRED":
+		pr.ReviewDecision = ReviewRequired
+	}
+
+	if len(result.Commits.Nodes) > 0 {
+		switch result.Commits.Nodes[0].Commit.StatusCheckRollup.State {
+		case "SUCCESS":
+			pr.CIStatus = CIPassing
+		case "FAILURE", "ERROR":
+			pr.CIStatus = CIFailing
+		case "PENDING", "EXPECTED":
+			pr.CIStatus = CIPending
+		}
+	}
+
+	return nil
+}
+
+// needsAction reports whether the author has to do something before the PR
+// can move forward
+func (pr PullRequestInfo) needsAction() bool {
+	return pr.CIStatus == CIFailing || pr.ReviewDecision == ReviewChangesRequested || pr.Conflicting
+}
+
+// readyToMerge reports whether the PR only waits for the author to merge it
+func (pr PullRequestInfo) readyToMerge() bool {
+	return !pr.IsDraft && !pr.Conflicting && pr.ReviewDecision == ReviewApproved && pr.CIStatus != CIFailing && pr.CIStatus != CIPending
+}
+
+// priority is the priority the PR's status calls for, empty when it
+// waits on someone else
+func (pr PullRequestInfo) priority() string {
+	switch {
+	case pr.IsReview:
+		// someone waits for the user's review
+		return "A"
+	case pr.needsAction():
+		return "A"
+	case pr.readyToMerge():
+		return "B"
+	}
+	return ""
+}
+
+// prStatusFromLabels reads back the status applyPRStatus stored on a todo
+func prStatusFromLabels(t *todo.Todo) PullRequestInfo {
+	return PullRequestInfo{
+		CIStatus:       t.Labels["ci"],
+		ReviewDecision: t.Labels["review"],
+		Conflicting:    t.Labels["merge"] == "conflicting",
+		IsDraft:        t.Labels["draft"] == "true",
+	}
+}
+
+// applyPRStatus encodes the state of a PR as labels and priority, so the PR
+// list reads as an action queue: (A) needs fixing or a review, (B) ready to
+// merge. A priority is only set when the status calls for one, and only
+// dropped when the previous status set it, so priorities set by hand stay.
+// A status that couldn't be fetched leaves the todo alone.
+func applyPRStatus(t *todo.Todo, pr PullRequestInfo) {
+	if pr.StatusUnknown {
+		return
+	}
+
+	previous := prStatusFromLabels(t).priority()
+	for _, key := range prStatusLabels {
+		delete(t.Labels, key)
+	}
+
+	if !pr.IsReview {
+		if pr.CIStatus != "" {
+			t.Labels["ci"] = pr.CIStatus
+		}
+		if pr.ReviewDecision != "" {
+			t.Labels["review"] = pr.ReviewDecision
+		}
+		if pr.Conflicting {
+			t.Labels["merge"] = "conflicting"
+		}
+		if pr.IsDraft {
+			t.Labels["draft"] = "true"
+		}
+	}
+
+	if priority := pr.priority(); priority != "" {
+		t.Priority = priority
+	} else if previous != "" && t.Priority == previous {
+		t.Priority = ""
+	}
+}
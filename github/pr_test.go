@@ -0,0 +1,116 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func TestApplyPRStatus(t *testing.T) {
+	// stale status labels from the previous sync must go away, the (A) came
+	// from the conflict
+	const previous = "(A) My PR +github ci:passing merge:conflicting draft:true"
+
+	tests := []struct {
+		name     string
+		before   string
+		pr       PullRequestInfo
+		labels   map[string]string
+		priority string
+	}{
+		{
+			name:     "failing ci needs action",
+			pr:       PullRequestInfo{CIStatus: CIFailing, ReviewDecision: ReviewRequired},
+			labels:   map[string]string{"ci": "failing", "review": "required"},
+			priority: "A",
+		},
+		{
+			name:     "changes requested needs action",
+			pr:       PullRequestInfo{CIStatus: CIPassing, ReviewDecision: ReviewChangesRequested},
+			labels:   map[string]string{"ci": "passing", "review": "changes_requested"},
+			priority: "A",
+		},
+		{
+			name:     "conflicts need action",
+			pr:       PullRequestInfo{ReviewDecision: ReviewApproved, Conflicting: true},
+			labels:   map[string]string{"review": "approved", "merge": "conflicting"},
+			priority: "A",
+		},
+		{
+			name:     "approved and green is ready to merge",
+			pr:       PullRequestInfo{CIStatus: CIPassing, ReviewDecision: ReviewApproved},
+			labels:   map[string]string{"ci": "passing", "review": "approved"},
+			priority: "B",
+		},
+		{
+			name:     "draft waiting on ci drops the status priority",
+			pr:       PullRequestInfo{CIStatus: CIPending, IsDraft: true},
+			labels:   map[string]string{"ci": "pending", "draft": "true"},
+			priority: "",
+		},
+		{
+			name:     "priority set by hand stays while waiting",
+			before:   "(C) My PR +github ci:passing merge:conflicting",
+			pr:       PullRequestInfo{CIStatus: CIPending},
+			labels:   map[string]string{"ci": "pending"},
+			priority: "C",
+		},
+		{
+			name:     "unknown status keeps the last sync",
+			pr:       PullRequestInfo{StatusUnknown: true},
+			labels:   map[string]string{"ci": "passing", "merge": "conflicting", "draft": "true"},
+			priority: "A",
+		},
+		{
+			name:     "review requests carry no status but need action",
+			before:   "My PR +github ci:passing",
+			pr:       PullRequestInfo{CIStatus: CIFailing, IsReview: true},
+			labels:   map[string]string{},
+			priority: "A",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := test.before
+			if before == "" {
+				before = previous
+			}
+			input := todo.FromString(before)
+			applyPRStatus(input, test.pr)
+
+			for _, key := range prStatusLabels {
+				if input.Labels[key] != test.labels[key] {
+					t.Errorf("label %s = %q, want %q", key, input.Labels[key], test.labels[key])
+				}
+			}
+			if input.Priority != test.priority {
+				t.Errorf("priority = %q, want %q", input.Priority, test.priority)
+			}
+		})
+	}
+}
+
+func TestPlanSyncExcludeDrafts(t *testing.T) {
+	existing := "My old draft +github repo:acme/app pr:2 url:https://github.com/acme/app/pull/2"
+	fetch := &projectFetch{
+		project: config.GitHubProject{Organization: "acme", ProjectNumber: 7},
+		userPRs: []PullRequestInfo{
+			{Title: "New draft", Number: 1, State: "open", URL: "https://github.com/acme/app/pull/1", RepoOwner: "acme", RepoName: "app", IsDraft: true},
+			{Title: "My old draft", Number: 2, State: "open", URL: "https://github.com/acme/app/pull/2", RepoOwner: "acme", RepoName: "app", IsDraft: true},
+		},
+		excludeDrafts: true,
+	}
+
+	plan := planSync([]*todo.Todo{todo.FromString(existing)}, []*projectFetch{fetch})
+
+	// the new draft isn't added, the old one is completed instead of being
+	// frozen in its last state
+	if len(plan.todos) != 1 || !plan.todos[0].Done || plan.todos[0].Description != "My old draft" {
+		t.Errorf("planSync() todos = %v, want the existing draft todo completed", plan.todos)
+	}
+	if len(plan.Local) != 1 {
+		t.Errorf("planSync() local changes = %+v, want the draft todo completed", plan.Local)
+	}
+}
@@ -0,0 +1,154 @@
+package github
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// PushedTodo is a local todo that was turned into a GitHub issue
+type PushedTodo struct {
+	Todo    *todo.Todo
+	Project string // name of the board the issue was added to, if any
+}
+
+// FindUnpushedTodos returns open +github todos that name a repo but have no
+// issue behind them yet, and none queued in the outbox
+func FindUnpushedTodos(todos []*todo.Todo) []*todo.Todo {
+	var unpushed []*todo.Todo
+	for _, t := range todos {
+		if t.Done || !hasGitHubProject(t) {
+			continue
+		}
+		// notification todos point at threads that already exist
+		if t.Labels["repo"] == "" || t.Labels["url"] != "" || t.Labels["notif"] != "" {
+			continue
+		}
+		if t.Labels[pendingLabel] != "" {
+			continue
+		}
+		unpushed = append(unpushed, t)
+	}
+	return unpushed
+}
+
+// PushTodos creates a GitHub issue for every unpushed todo, adds it to the
+// configured board for its repo and writes the issue: and url: labels back so
+// the next sync tracks it. Creations are queued in the outbox first, todos
+// whose issue couldn't be created yet keep a pending: label until it is.
+func PushTodos(todoDir string) ([]PushedTodo, error) {
+	atpDir := filepath.Dir(todoDir)
+
+	cfg, err := config.LoadConfig(atpDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	todos, err := todo.LoadTodoDir(todoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	outbox, err := LoadOutbox(atpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range FindUnpushedTodos(todos) {
+		op, err := createOperation(cfg, t)
+		if err != nil {
+			logf("Warning: failed to push todo '%s': %v\n", t.Description, err)
+			continue
+		}
+		op = outbox.Add(op)
+		t.Labels[pendingLabel] = op.ID
+	}
+
+	// creations queued by an earlier push are retried along with the new ones
+	queued := make(map[string]*todo.Todo)
+	for _, t := range todos {
+		if id := t.Labels[pendingLabel]; id != "" {
+			queued[id] = t
+		}
+	}
+	if len(queued) == 0 {
+		return nil, nil
+	}
+
+	sess := newSession(ClientOptionsFromConfig(cfg, atpDir))
+	delivered, err := outbox.deliver(todoDir, todos, sessionRemotes(sess), true)
+
+	var pushed []PushedTodo
+	for _, op := range delivered {
+		if t, ok := queued[op.ID]; ok && op.Action == ActionCreate {
+			pushed = append(pushed, PushedTodo{Todo: t, Project: op.ProjectName})
+		}
+	}
+
+	return pushed, err
+}
+
+// createOperation builds the outbox operation that creates the issue for a
+// todo and adds it to the board configured for its repo
+func createOperation(cfg *config.Config, t *todo.Todo) (*Operation, error) {
+	owner, repoName, err := splitRepo(t.Labels["repo"])
+	if err != nil {
+		return nil, err
+	}
+
+	// host: picks the GitHub instance when the same owner exists on several
+	host := t.Labels["host"]
+	project, hasProject := projectForRepo(cfg, host, t.Labels["repo"])
+
+	if host == "" {
+		host = config.DefaultGitHubHost
+	}
+	sc := ownerScope(host, owner)
+	if hasProject {
+		sc = scopeForProject(project)
+	}
+
+	op := &Operation{
+		Action: ActionCreate,
+		Scope:  newOpScope(sc),
+		Owner:  owner,
+		Repo:   repoName,
+		Title:  t.Description,
+	}
+	if hasProject {
+		op.ProjectName = project.Name
+		op.ProjectNumber = project.ProjectNumber
+		op.Status = statusForPriority(t.Priority)
+	}
+
+	return op, nil
+}
+
+// projectForRepo finds the first configured board whose scope covers the
+// repo, on the given host if one is set
+func projectForRepo(cfg *config.Config, host string, repo string) (config.GitHubProject, bool) {
+	for _, project := range cfg.GetAllGitHubProjects() {
+		if project.ProjectNumber == 0 || project.Validate() != nil {
+			continue
+		}
+		if host != "" && !strings.EqualFold(project.Host(), host) {
+			continue
+		}
+		if scopeForProject(project).coversRepo(repo) {
+			return project, true
+		}
+	}
+	return config.GitHubProject{}, false
+}
+
+func hasGitHubProject(t *todo.Todo) bool {
+	for _, project := range t.Projects {
+		if project == "github" {
+			return true
+		}
+	}
+	return false
+}
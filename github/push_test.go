@@ -0,0 +1,51 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func TestFindUnpushedTodos(t *testing.T) {
+	todos := []*todo.Todo{
+		todo.FromString("Add dark mode +github repo:me/app"),
+		todo.FromString("Tracked +github repo:me/app issue:1 url:https://github.com/me/app/issues/1"),
+		todo.FromString("x Done already +github repo:me/app"),
+		todo.FromString("No repo +github"),
+		todo.FromString("Not github repo:me/app"),
+		todo.FromString("Queued already +github repo:me/app pending:abc123"),
+	}
+
+	unpushed := FindUnpushedTodos(todos)
+	if len(unpushed) != 1 || unpushed[0].Description != "Add dark mode" {
+		t.Errorf("FindUnpushedTodos() = %v, want only 'Add dark mode'", unpushed)
+	}
+}
+
+func TestProjectForRepo(t *testing.T) {
+	cfg := &config.Config{GitHub: config.GitHubConfig{Projects: []config.GitHubProject{
+		{Name: "work", Organization: "acme", ProjectNumber: 7},
+		{Name: "enterprise", Organization: "acme", ProjectNumber: 2, BaseURL: "https://ghe.example.com/api/v3/"},
+		{Name: "issues-only", OwnerType: config.OwnerRepos, Repos: []string{"me/dotfiles"}},
+		{Name: "personal", OwnerType: config.OwnerUser, User: "me", ProjectNumber: 3},
+	}}}
+
+	tests := []struct {
+		host string
+		repo string
+		want string
+	}{
+		{"", "acme/app", "work"},
+		{"", "me/dotfiles", "personal"},
+		{"", "someone/else", ""},
+		{"ghe.example.com", "acme/app", "enterprise"},
+	}
+
+	for _, test := range tests {
+		project, ok := projectForRepo(cfg, test.host, test.repo)
+		if project.Name != test.want || ok != (test.want != "") {
+			t.Errorf("projectForRepo(%q) = %q, %v, want %q", test.repo, project.Name, ok, test.want)
+		}
+	}
+}
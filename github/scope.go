@@ -0,0 +1,250 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arjungandhi/atp/config"
+	"github.com/shurcooL/githubv4"
+)
+
+// scope is where a project's issues and pull requests live: an organization,
+// a user account or an explicit list of repositories
+type scope struct {
+	kind  string
+	host  string // web host, empty means github.com
+	owner string
+	repos []string // owner/name, only for repo-list scopes
+}
+
+func scopeForProject(project config.GitHubProject) scope {
+	return scope{
+		kind:  project.Type(),
+		host:  project.Host(),
+		owner: project.Owner(),
+		repos: project.Repos,
+	}
+}
+
+// orgScope is the scope of a single organization on github.com
+func orgScope(owner string) scope {
+	return ownerScope(config.DefaultGitHubHost, owner)
+}
+
+// ownerScope is the scope of a single owner, used when all we need is a
+// client that can act on its repositories
+func ownerScope(host string, owner string) scope {
+	return scope{kind: config.OwnerOrg, host: host, owner: owner}
+}
+
+// hostName returns the scope's host, defaulting to github.com
+func (s scope) hostName() string {
+	if s.host == "" {
+		return config.DefaultGitHubHost
+	}
+	return strings.ToLower(s.host)
+}
+
+// key identifies the scope, clients and fetched results are shared per key
+func (s scope) key() string {
+	if s.kind == config.OwnerRepos {
+		return s.hostName() + "/" + s.kind + ":" + strings.ToLower(strings.Join(s.repos, ","))
+	}
+	return s.hostName() + "/" + s.kind + ":" + strings.ToLower(s.owner)
+}
+
+// tokenOwner is the login whose token the scope's client uses. Repo lists use
+// the owner of their first repo.
+func (s scope) tokenOwner() string {
+	if s.kind == config.OwnerRepos && len(s.repos) > 0 {
+		owner, _, _ := strings.Cut(s.repos[0], "/")
+		return owner
+	}
+	return s.owner
+}
+
+func (s scope) String() string {
+	name := s.owner
+	if s.kind == config.OwnerRepos {
+		name = strings.Join(s.repos, ", ")
+	}
+	if s.hostName() != config.DefaultGitHubHost {
+		name += " on " + s.hostName()
+	}
+	return name
+}
+
+// searchQualifier limits a search query to the scope
+func (s scope) searchQualifier() string {
+	switch s.kind {
+	case config.OwnerUser:
+		return "user:" + s.owner
+	case config.OwnerRepos:
+		qualifiers := make([]string, len(s.repos))
+		for i, repo := range s.repos {
+			qualifiers[i] = "repo:" + repo
+		}
+		return strings.Join(qualifiers, " ")
+	default:
+		return "org:" + s.owner
+	}
+}
+
+// covers reports whether a GitHub URL belongs to the scope
+func (s scope) covers(url string) bool {
+	ref, err := parseGitHubURL(url)
+	if err != nil || ref.host != s.hostName() {
+		return false
+	}
+	return s.coversRepo(ref.owner + "/" + ref.repo)
+}
+
+// coversRepo reports whether an owner/name repo belongs to the scope
+func (s scope) coversRepo(repo string) bool {
+	if repo == "" {
+		return false
+	}
+
+	if s.kind == config.OwnerRepos {
+		for _, r := range s.repos {
+			if strings.EqualFold(r, repo) {
+				return true
+			}
+		}
+		return false
+	}
+
+	owner, _, _ := strings.Cut(repo, "/")
+	return strings.EqualFold(owner, s.owner)
+}
+
+// splitRepo splits an owner/name repo string
+func splitRepo(repo string) (string, string, error) {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repo '%s' (expected owner/name)", repo)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ---------------------------- Project V2 Queries ----------------------------
+
+// projectV2Items is the page of project items used to read issue statuses
+type projectV2Items struct {
+	Items struct {
+		PageInfo struct {
+			HasNextPage bool   `graphql:"hasNextPage"`
+			EndCursor   string `graphql:"endCursor"`
+		} `graphql:"pageInfo"`
+		Nodes []struct {
+			ID          string `graphql:"id"`
+			FieldValues struct {
+				Nodes []struct {
+					SingleSelectValue struct {
+						Name  string `graphql:"name"`
+						Field struct {
+							SingleSelectField struct {
+								Name string `graphql:"name"`
+							} `graphql:"... on ProjectV2SingleSelectField"`
+						} `graphql:"field"`
+					} `graphql:"... on ProjectV2ItemFieldSingleSelectValue"`
+					DateValue struct {
+						Date  string `graphql:"date"`
+						Field struct {
+							ProjectV2Field struct {
+								Name string `graphql:"name"`
+							} `graphql:"... on ProjectV2Field"`
+						} `graphql:"field"`
+					} `graphql:"... on ProjectV2ItemFieldDateValue"`
+				}
+			} `graphql:"fieldValues(first: 20)"`
+			Content struct {
+				Typename string `graphql:"__typename"`
+				Issue    struct {
+					URL string `graphql:"url"`
+				} `graphql:"... on Issue"`
+			} `graphql:"content"`
+		}
+	} `graphql:"items(first: 100, after: $cursor)"`
+}
+
+// projectV2Fields is the project ID and its fields, used to update statuses
+type projectV2Fields struct {
+	ID     string `graphql:"id"`
+	Fields struct {
+		Nodes []struct {
+			Typename       string `graphql:"__typename"`
+			ProjectV2Field struct {
+				ID   string `graphql:"id"`
+				Name string `graphql:"name"`
+			} `graphql:"... on ProjectV2Field"`
+			SingleSelectField struct {
+				ID      string `graphql:"id"`
+				Name    string `graphql:"name"`
+				Options []struct {
+					ID   string `graphql:"id"`
+					Name string `graphql:"name"`
+				} `graphql:"options"`
+			} `graphql:"... on ProjectV2SingleSelectField"`
+		}
+	} `graphql:"fields(first: 20)"`
+}
+
+// queryProjectV2 runs a projectV2 query rooted at the right owner for the
+// scope. T is the selection made on the project itself. For repo-list scopes
+// the project is looked up through the given repository.
+func queryProjectV2[T any](c *Client, repo string, projectNumber int, variables map[string]interface{}) (*T, error) {
+	variables["projectNumber"] = githubv4.Int(projectNumber)
+
+	switch c.scope.kind {
+	case config.OwnerUser:
+		var query struct {
+			User struct {
+				ProjectV2 T `graphql:"projectV2(number: $projectNumber)"`
+			} `graphql:"user(login: $owner)"`
+		}
+		variables["owner"] = githubv4.String(c.scope.owner)
+		if err := c.graphqlClient.Query(c.ctx, &query, variables); err != nil {
+			return nil, err
+		}
+		return &query.User.ProjectV2, nil
+
+	case config.OwnerRepos:
+		owner, name, err := splitRepo(repo)
+		if err != nil {
+			return nil, err
+		}
+		var query struct {
+			Repository struct {
+				ProjectV2 T `graphql:"projectV2(number: $projectNumber)"`
+			} `graphql:"repository(owner: $owner, name: $name)"`
+		}
+		variables["owner"] = githubv4.String(owner)
+		variables["name"] = githubv4.String(name)
+		if err := c.graphqlClient.Query(c.ctx, &query, variables); err != nil {
+			return nil, err
+		}
+		return &query.Repository.ProjectV2, nil
+
+	default:
+		var query struct {
+			Organization struct {
+				ProjectV2 T `graphql:"projectV2(number: $projectNumber)"`
+			} `graphql:"organization(login: $owner)"`
+		}
+		variables["owner"] = githubv4.String(c.scope.owner)
+		if err := c.graphqlClient.Query(c.ctx, &query, variables); err != nil {
+			return nil, err
+		}
+		return &query.Organization.ProjectV2, nil
+	}
+}
+
+// projectRoots lists what a project query has to be run against: the owner
+// once, or every repository of a repo-list scope
+func (c *Client) projectRoots() []string {
+	if c.scope.kind == config.OwnerRepos {
+		return c.scope.repos
+	}
+	return []string{""}
+}
@@ -0,0 +1,54 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/arjungandhi/atp/config"
+)
+
+func TestScopeForProject(t *testing.T) {
+	tests := []struct {
+		name      string
+		project   config.GitHubProject
+		qualifier string
+		covered   string
+		other     string
+	}{
+		{
+			name:      "org",
+			project:   config.GitHubProject{Organization: "acme"},
+			qualifier: "org:acme",
+			covered:   "https://github.com/Acme/app/issues/1",
+			other:     "https://github.com/me/app/issues/1",
+		},
+		{
+			name:      "user",
+			project:   config.GitHubProject{OwnerType: config.OwnerUser, User: "me"},
+			qualifier: "user:me",
+			covered:   "https://github.com/me/dotfiles/issues/2",
+			other:     "https://github.com/acme/app/issues/2",
+		},
+		{
+			name:      "repos",
+			project:   config.GitHubProject{OwnerType: config.OwnerRepos, Repos: []string{"me/dotfiles", "friend/tool"}},
+			qualifier: "repo:me/dotfiles repo:friend/tool",
+			covered:   "https://github.com/friend/tool/pull/3",
+			other:     "https://github.com/friend/other/issues/3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sc := scopeForProject(test.project)
+			if got := sc.searchQualifier(); got != test.qualifier {
+				t.Errorf("searchQualifier() = %q, want %q", got, test.qualifier)
+			}
+			if !sc.covers(test.covered) {
+				t.Errorf("covers(%q) = false, want true", test.covered)
+			}
+			if sc.covers(test.other) {
+				t.Errorf("covers(%q) = true, want false", test.other)
+			}
+		})
+	}
+}
@@ -27,192 +27,40 @@ func sanitizeIssueTitle(title string) string {
 }
 
 func SyncGitHubProject(todoDir string, projectName string) error {
-	atpDir := filepath.Dir(todoDir)
-	cfg, err := config.LoadConfig(atpDir)
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	project, err := cfg.GetGitHubProject(projectName)
+	plan, err := PlanGitHubProject(todoDir, projectName)
 	if err != nil {
 		return err
 	}
 
-	return SyncIssues(todoDir, project.Organization, project.ProjectNumber, project.StatusFilters)
+	return ApplySync(todoDir, plan)
 }
 
 func SyncAllGitHubProjects(todoDir string) error {
-	atpDir := filepath.Dir(todoDir)
-	cfg, err := config.LoadConfig(atpDir)
+	plan, err := PlanAllGitHubProjects(todoDir)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	for _, project := range cfg.GetAllGitHubProjects() {
-		fmt.Printf("Syncing %s project %d (statuses: %v, assigned to you)...\n", 
-			project.Organization, project.ProjectNumber, project.StatusFilters)
-		
-		err := SyncIssues(todoDir, project.Organization, project.ProjectNumber, project.StatusFilters)
-		if err != nil {
-			return fmt.Errorf("failed to sync project %s: %w", project.Name, err)
-		}
+		return err
 	}
 
-	return nil
+	return ApplySync(todoDir, plan)
 }
 
 func SyncIssues(todoDir string, organization string, projectNumber int, statusFilters []string) error {
 	atpDir := filepath.Dir(todoDir)
-
-	// Get last sync time for timestamp-based conflict resolution
-	lastSyncTime, err := getLastSyncTime(atpDir)
-	if err != nil {
-		return fmt.Errorf("failed to get last sync time: %w", err)
-	}
-
-	client, err := NewClient(organization)
-	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
-	}
-
-	// Check if any GitHub issues have been updated since our last sync
-	hasGitHubUpdates := false
-	if !lastSyncTime.IsZero() {
-		fmt.Printf("Checking for GitHub updates since last sync (%s)...\n", lastSyncTime.Format("15:04:05"))
-		hasGitHubUpdates, err = checkForGitHubUpdates(client, projectNumber, statusFilters, lastSyncTime)
-		if err != nil {
-			return fmt.Errorf("failed to check for GitHub updates: %w", err)
-		}
-	}
-
-	if hasGitHubUpdates {
-		fmt.Printf("GitHub issues updated since last sync - accepting all GitHub changes\n")
-	} else {
-		fmt.Printf("No GitHub updates since last sync - syncing local changes first\n")
-
-		// First, sync completed todos to GitHub (close issues that were marked done locally)
-		if err := SyncCompletedTodos(todoDir); err != nil {
-			return fmt.Errorf("failed to sync completed todos to GitHub: %w", err)
-		}
-
-		// Second, sync priority changes to GitHub (before pulling fresh data)
-		if err := syncPriorityChangesToGitHub(todoDir, organization, projectNumber, nil); err != nil {
-			return fmt.Errorf("failed to sync priority changes to GitHub: %w", err)
-		}
-	}
-
-	issues, err := client.GetProjectV2Issues(projectNumber, statusFilters)
-	if err != nil {
-		return fmt.Errorf("failed to fetch GitHub issues: %w", err)
-	}
-
-	// Fetch pull requests
-	fmt.Printf("Fetching open pull requests...\n")
-	userPRs, err := client.GetUserPullRequests()
-	if err != nil {
-		return fmt.Errorf("failed to fetch user PRs: %w", err)
-	}
-	fmt.Printf("Found %d open PRs created by you\n", len(userPRs))
-
-	reviewRequests, err := client.GetReviewRequests()
+	cfg, err := config.LoadConfig(atpDir)
 	if err != nil {
-		return fmt.Errorf("failed to fetch review requests: %w", err)
+		return fmt.Errorf("failed to load config: %w", err)
 	}
-	fmt.Printf("Found %d open review requests\n", len(reviewRequests))
 
-	todos, err := todo.LoadTodoDir(todoDir)
+	plan, err := planProjects(todoDir, cfg, []config.GitHubProject{{
+		Organization:  organization,
+		ProjectNumber: projectNumber,
+		StatusFilters: statusFilters,
+	}})
 	if err != nil {
-		return fmt.Errorf("failed to load todos: %w", err)
-	}
-
-	existingGitHubTodos := buildGitHubTodoMap(todos)
-	processedGitHubURLs := make(map[string]bool) // Track processed URLs to avoid duplicates
-	newTodos := filterNonGitHubTodos(todos)
-
-	// Process issues
-	for _, issue := range issues {
-		fmt.Printf("Processing issue: %s (%s)\n", issue.Title, issue.GitHubStatus)
-		processedGitHubURLs[issue.URL] = true
-
-		if existingTodo, exists := existingGitHubTodos[issue.URL]; exists {
-			fmt.Printf("  Updating existing todo\n")
-			if hasGitHubUpdates {
-				// Accept all GitHub changes
-				updateExistingTodo(existingTodo, issue)
-			} else {
-				// Keep local state, only update title
-				existingTodo.Description = sanitizeIssueTitle(issue.Title)
-				if _, exists := existingTodo.Labels["repo"]; !exists {
-					repoName := extractRepoFromURL(issue.URL)
-					if repoName != "" {
-						existingTodo.Labels["repo"] = repoName
-					}
-				}
-			}
-			newTodos = append(newTodos, existingTodo)
-		} else {
-			fmt.Printf("  Creating new todo\n")
-			newTodo := createTodoFromIssue(issue)
-			newTodos = append(newTodos, newTodo)
-		}
-	}
-
-	// Process user's PRs - always accept GitHub state (read-only)
-	for _, pr := range userPRs {
-		prURL := pr.URL
-		fmt.Printf("Processing PR: %s\n", pr.Title)
-		processedGitHubURLs[prURL] = true
-
-		if existingTodo, exists := existingGitHubTodos[prURL]; exists {
-			fmt.Printf("  Updating existing PR todo (accepting GitHub state)\n")
-			updateExistingTodoFromPR(existingTodo, pr)
-			newTodos = append(newTodos, existingTodo)
-		} else {
-			fmt.Printf("  Creating new PR todo\n")
-			newTodo := createTodoFromPR(pr)
-			newTodos = append(newTodos, newTodo)
-		}
-	}
-
-	// Process review requests - always accept GitHub state (read-only)
-	for _, pr := range reviewRequests {
-		prURL := pr.URL
-		fmt.Printf("Processing review request: %s\n", pr.Title)
-		processedGitHubURLs[prURL] = true
-
-		if existingTodo, exists := existingGitHubTodos[prURL]; exists {
-			fmt.Printf("  Updating existing review request todo (accepting GitHub state)\n")
-			updateExistingTodoFromPR(existingTodo, pr)
-			newTodos = append(newTodos, existingTodo)
-		} else {
-			fmt.Printf("  Creating new review request todo\n")
-			newTodo := createTodoFromPR(pr)
-			newTodos = append(newTodos, newTodo)
-		}
-	}
-
-	// Mark unprocessed GitHub todos as done (closed, merged, or moved out of filtered statuses)
-	for url, t := range existingGitHubTodos {
-		if !processedGitHubURLs[url] {
-			if !t.Done {
-				fmt.Printf("Marking unprocessed GitHub todo as done: %s\n", t.Description)
-				t.Done = true
-				t.CompletionDate = time.Now()
-			}
-			newTodos = append(newTodos, t)
-		}
-	}
-
-	if err := todo.WriteTodoDir(todoDir, newTodos); err != nil {
-		return fmt.Errorf("failed to write todos: %w", err)
-	}
-
-	// Update last sync time after successful sync
-	if err := updateLastSyncTime(atpDir); err != nil {
-		return fmt.Errorf("failed to update last sync time: %w", err)
+		return err
 	}
 
-	return nil
+	return ApplySync(todoDir, plan)
 }
 
 func buildGitHubTodoMap(todos []*todo.Todo) map[string]*todo.Todo {
@@ -235,7 +83,6 @@ func filterNonGitHubTodos(todos []*todo.Todo) []*todo.Todo {
 	return nonGitHubTodos
 }
 
-
 func updateExistingTodo(existingTodo *todo.Todo, issue IssueWithStatus) {
 	if issue.State == "closed" && !existingTodo.Done {
 		existingTodo.Done = true
@@ -247,7 +94,7 @@ func updateExistingTodo(existingTodo *todo.Todo, issue IssueWithStatus) {
 
 	existingTodo.Description = sanitizeIssueTitle(issue.Title)
 
-	if strings.EqualFold(issue.GitHubStatus, "In Progress") {
+	if strings.EqualFold(issue.GitHubStatus, statusInProgress) {
 		existingTodo.Priority = "A"
 	} else {
 		existingTodo.Priority = ""
@@ -276,7 +123,7 @@ func createTodoFromIssue(issue IssueWithStatus) *todo.Todo {
 		t.CompletionDate = time.Now()
 	}
 
-	if strings.EqualFold(issue.GitHubStatus, "In Progress") {
+	if strings.EqualFold(issue.GitHubStatus, statusInProgress) {
 		t.Priority = "A"
 	}
 
@@ -296,11 +143,11 @@ func createTodoFromIssue(issue IssueWithStatus) *todo.Todo {
 
 func extractRepoFromURL(url string) string {
 	// URL format: https://github.com/Pattern-Labs/the_cloud/issues/3484
-	parts := strings.Split(url, "/")
-	if len(parts) >= 5 {
-		return parts[3] + "/" + parts[4] // owner/repo
+	ref, err := parseGitHubURL(url)
+	if err != nil {
+		return ""
 	}
-	return ""
+	return ref.owner + "/" + ref.repo
 }
 
 func reconstructGitHubURL(todoItem *todo.Todo) string {
@@ -350,6 +197,8 @@ func createTodoFromPR(pr PullRequestInfo) *todo.Todo {
 
 	t.Projects = []string{"github"}
 
+	applyPRStatus(t, pr)
+
 	return t
 }
 
@@ -382,6 +231,8 @@ func updateExistingTodoFromPR(existingTodo *todo.Todo, pr PullRequestInfo) {
 			existingTodo.Labels["repo"] = repoName
 		}
 	}
+
+	applyPRStatus(existingTodo, pr)
 }
 
 func CompleteIssueFromTodo(todoDir string, todoItem *todo.Todo) error {
@@ -390,101 +241,26 @@ func CompleteIssueFromTodo(todoDir string, todoItem *todo.Todo) error {
 		return nil
 	}
 
-	parts := strings.Split(githubURL, "/")
-	if len(parts) < 7 {
-		return fmt.Errorf("invalid GitHub URL format")
-	}
-
-	org := parts[3]
-	repo := parts[4]
-	issueNumberStr := parts[6]
-	issueNumber, err := strconv.Atoi(issueNumberStr)
+	ref, err := parseGitHubURL(githubURL)
 	if err != nil {
-		return fmt.Errorf("invalid issue number: %w", err)
+		return err
 	}
-
-	client, err := NewClient(org)
-	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
+	if ref.number == 0 {
+		return fmt.Errorf("invalid GitHub URL format")
 	}
 
-	return client.CloseIssue(repo, issueNumber)
-}
-
-func SyncCompletedTodos(todoDir string) error {
-	todos, err := todo.LoadTodoDir(todoDir)
+	atpDir := filepath.Dir(todoDir)
+	cfg, err := config.LoadConfig(atpDir)
 	if err != nil {
-		return fmt.Errorf("failed to load todos: %w", err)
-	}
-
-	for _, t := range todos {
-		if t.Done {
-			githubURL := reconstructGitHubURL(t)
-			if githubURL != "" {
-				// Skip PRs - we don't want to close them based on local todo state
-				if _, hasPR := t.Labels["pr"]; hasPR {
-					continue
-				}
-
-				// Only sync completed issues
-				if syncedLabel, syncExists := t.Labels["synced"]; !syncExists || syncedLabel != "true" {
-					err := CompleteIssueFromTodo(todoDir, t)
-					if err != nil {
-						fmt.Printf("Warning: failed to close GitHub issue for todo '%s': %v\n", t.Description, err)
-						continue
-					}
-					t.Labels["synced"] = "true"
-				}
-			}
-		}
-	}
-
-	return todo.WriteTodoDir(todoDir, todos)
-}
-
-func syncPriorityChangesToGitHub(todoDir string, organization string, projectNumber int, todos []*todo.Todo) error {
-	// When called early in sync, load current todos
-	if todos == nil {
-		var err error
-		todos, err = todo.LoadTodoDir(todoDir)
-		if err != nil {
-			return nil // Skip priority sync if can't load todos
-		}
+		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	client, err := NewClient(organization)
+	client, err := newSession(ClientOptionsFromConfig(cfg, atpDir)).client(ref.scope())
 	if err != nil {
 		return fmt.Errorf("failed to create GitHub client: %w", err)
 	}
 
-	for _, todo := range todos {
-		githubURL := reconstructGitHubURL(todo)
-		if githubURL == "" {
-			continue
-		}
-
-		// Determine expected GitHub status based on current local priority
-		var expectedStatus string
-		if todo.Priority == "A" {
-			expectedStatus = "In Progress"
-		} else {
-			expectedStatus = "Planned-This-Week"
-		}
-
-		projectItemID, err := client.lookupProjectItemID(projectNumber, githubURL)
-		if err != nil {
-			fmt.Printf("Warning: failed to lookup project item ID for %s: %v\n", todo.Description, err)
-			continue
-		}
-
-		err = client.UpdateProjectItemStatus(projectNumber, projectItemID, expectedStatus)
-		if err != nil {
-			fmt.Printf("Warning: failed to update status for %s: %v\n", todo.Description, err)
-			continue
-		}
-	}
-
-	return nil
+	return client.CloseIssue(ref.owner, ref.repo, ref.number)
 }
 
 func getLastSyncTime(atpDir string) (time.Time, error) {
@@ -506,20 +282,3 @@ func updateLastSyncTime(atpDir string) error {
 	now := time.Now().Format(time.RFC3339)
 	return os.WriteFile(syncFile, []byte(now), 0644)
 }
-
-func checkForGitHubUpdates(client *Client, projectNumber int, statusFilters []string, lastSyncTime time.Time) (bool, error) {
-	issues, err := client.GetProjectV2Issues(projectNumber, statusFilters)
-	if err != nil {
-		return false, fmt.Errorf("failed to fetch GitHub issues: %w", err)
-	}
-
-	for _, issue := range issues {
-		if issue.UpdatedAt.After(lastSyncTime) {
-			fmt.Printf("  Found GitHub update: %s updated at %s (after %s)\n", 
-				issue.Title, issue.UpdatedAt.Format("15:04:05"), lastSyncTime.Format("15:04:05"))
-			return true, nil
-		}
-	}
-
-	return false, nil
-}
\ No newline at end of file
@@ -0,0 +1,370 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRateLimitRetries is how many times a rate limited request is retried
+	maxRateLimitRetries = 3
+	// maxRateLimitWait is the longest we are willing to sleep for a rate limit
+	// to reset before giving up and surfacing the error
+	maxRateLimitWait = 15 * time.Minute
+	// secondaryRateLimitWait is used when GitHub reports a secondary rate
+	// limit without telling us how long to back off
+	secondaryRateLimitWait = time.Minute
+)
+
+// newTransport builds the HTTP stack shared by every client in a sync run:
+// bounded concurrency and rate limit handling, an on-disk ETag cache and a
+// per-request timeout.
+func newTransport(opts ClientOptions) http.RoundTripper {
+	var transport http.RoundTripper = http.DefaultTransport
+	if opts.Timeout > 0 {
+		transport = &timeoutTransport{next: transport, timeout: opts.Timeout}
+	}
+	if opts.CacheDir != "" {
+		transport = &cacheTransport{next: transport, dir: opts.CacheDir}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	return &rateLimitTransport{
+		next: transport,
+		sem:  make(chan struct{}, concurrency),
+	}
+}
+
+// ------------------------------- Rate Limits -------------------------------
+
+// rateLimitTransport bounds the number of in-flight requests and waits out
+// primary and secondary rate limits before retrying
+type rateLimitTransport struct {
+	next http.RoundTripper
+	sem  chan struct{}
+
+	mu           sync.Mutex
+	blockedUntil time.Time
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := t.waitUntilUnblocked(req.Context()); err != nil {
+			return nil, err
+		}
+
+		select {
+		case t.sem <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		resp, err := t.next.RoundTrip(req)
+		<-t.sem
+		if err != nil {
+			return nil, err
+		}
+
+		wait, limited := rateLimitWait(resp, time.Now())
+		if !limited {
+			t.observe(resp)
+			return resp, nil
+		}
+
+		// out of retries or the wait is unreasonable, let the caller see the error
+		if attempt >= maxRateLimitRetries || wait > maxRateLimitWait {
+			return resp, nil
+		}
+
+		next, err := rewindRequest(req)
+		if err != nil {
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		logf("Rate limited by GitHub, retrying in %s...\n", wait.Round(time.Second))
+		t.block(wait)
+		req = next
+	}
+}
+
+// observe blocks new requests once the primary rate limit is used up
+func (t *rateLimitTransport) observe(resp *http.Response) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	wait := time.Until(time.Unix(reset, 0))
+	if wait > 0 && wait <= maxRateLimitWait {
+		t.block(wait)
+	}
+}
+
+func (t *rateLimitTransport) block(wait time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	until := time.Now().Add(wait)
+	if until.After(t.blockedUntil) {
+		t.blockedUntil = until
+	}
+}
+
+func (t *rateLimitTransport) waitUntilUnblocked(ctx context.Context) error {
+	t.mu.Lock()
+	wait := time.Until(t.blockedUntil)
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimitWait reports whether the response is a rate limit error and how
+// long to wait before retrying
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	// secondary rate limits tell us how long to back off
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		seconds, err := strconv.Atoi(retryAfter)
+		if err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	// primary rate limit, wait for the window to reset
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err == nil {
+			wait := time.Unix(reset, 0).Sub(now)
+			if wait < 0 {
+				wait = 0
+			}
+			return wait + time.Second, true
+		}
+	}
+
+	// secondary rate limits without a Retry-After only say so in the body
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit") {
+		return secondaryRateLimitWait, true
+	}
+
+	return 0, false
+}
+
+// rewindRequest returns a copy of the request that can be sent again
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return next, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body cannot be replayed")
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	next.Body = body
+
+	return next, nil
+}
+
+// ------------------------------- ETag Cache -------------------------------
+
+// cacheTransport caches GET responses on disk and revalidates them with
+// conditional requests. GitHub doesn't count 304 responses against the rate
+// limit.
+type cacheTransport struct {
+	next http.RoundTripper
+	dir  string
+}
+
+type cacheEntry struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+
+	path := filepath.Join(t.dir, cacheKey(req)+".json")
+	entry := loadCacheEntry(path)
+	if entry != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		return entry.response(req), nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry = &cacheEntry{ETag: etag, Header: resp.Header.Clone(), Body: body}
+	if err := entry.save(path); err != nil {
+		logf("Warning: failed to cache GitHub response: %v\n", err)
+	}
+
+	return resp, nil
+}
+
+// cacheKey identifies a request by URL and the headers that change the response
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.URL.String())
+	io.WriteString(h, "\x00"+req.Header.Get("Accept"))
+	io.WriteString(h, "\x00"+req.Header.Get("Authorization"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func loadCacheEntry(path string) *cacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.ETag == "" {
+		return nil
+	}
+
+	return &entry
+}
+
+func (e *cacheEntry) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// write to a temp file first so concurrent readers never see a partial entry
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".cache-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	// tells go-github not to record the stale rate limit headers
+	header.Set("X-From-Cache", "1")
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// -------------------------------- Timeouts --------------------------------
+
+// timeoutTransport puts a deadline on every request
+type timeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// keep the deadline alive until the caller is done with the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
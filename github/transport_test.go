@@ -0,0 +1,202 @@
+package github
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTransport(t *testing.T) {
+	var hits, revalidated int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, `{"login":"octocat"}`)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTransport(ClientOptions{CacheDir: t.TempDir()})}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "/user")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || string(body) != `{"login":"octocat"}` {
+			t.Errorf("request %d: got %d %q, want cached 200 body", i, resp.StatusCode, body)
+		}
+		if i == 1 && resp.Header.Get("X-From-Cache") != "1" {
+			t.Errorf("request %d: expected X-From-Cache header on revalidated response", i)
+		}
+	}
+
+	if hits != 2 || revalidated != 1 {
+		t.Errorf("server saw %d requests (%d conditional), want 2 (1 conditional)", hits, revalidated)
+	}
+}
+
+func TestRateLimitTransportRetriesSecondaryLimit(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"message":"You have exceeded a secondary rate limit"}`)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTransport(ClientOptions{})}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || hits != 2 {
+		t.Errorf("got status %d after %d requests, want 200 after 2", resp.StatusCode, hits)
+	}
+}
+
+func TestRateLimitTransportGivesUpOnLongPrimaryLimit(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTransport(ClientOptions{})}
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	// an hour is past maxRateLimitWait, the caller gets the 403 right away
+	if resp.StatusCode != http.StatusForbidden || hits != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("got status %d after %d requests in %s, want an immediate 403", resp.StatusCode, hits, time.Since(start))
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		body    string
+		wait    time.Duration
+		limited bool
+	}{
+		{"success", http.StatusOK, nil, "", 0, false},
+		{"retry after", http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}, "", 30 * time.Second, true},
+		{"primary limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1060"}, "", 61 * time.Second, true},
+		{"secondary limit body", http.StatusForbidden, nil, "secondary rate limit hit", secondaryRateLimitWait, true},
+		{"plain forbidden", http.StatusForbidden, nil, "Resource not accessible", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			for k, v := range test.header {
+				rec.Header().Set(k, v)
+			}
+			rec.WriteHeader(test.status)
+			io.WriteString(rec, test.body)
+
+			wait, limited := rateLimitWait(rec.Result(), now)
+			if wait != test.wait || limited != test.limited {
+				t.Errorf("rateLimitWait() = %s, %v, want %s, %v", wait, limited, test.wait, test.limited)
+			}
+		})
+	}
+}
+
+func TestTransportBoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTransport(ClientOptions{Concurrency: 2})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL + "/" + strconv.Itoa(i))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("saw %d concurrent requests, want at most 2", maxInFlight)
+	}
+}
+
+func TestTransportTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTransport(ClientOptions{Timeout: 20 * time.Millisecond})}
+	_, err := client.Get(server.URL)
+	if err == nil {
+		t.Fatal("Get() error = nil, want deadline exceeded")
+	}
+}
+
+func TestRunAll(t *testing.T) {
+	first := errors.New("first")
+	err := runAll(4, func(i int) error {
+		switch i {
+		case 1:
+			return first
+		case 3:
+			return errors.New("second")
+		}
+		return nil
+	})
+
+	if err != first {
+		t.Errorf("runAll() error = %v, want %v", err, first)
+	}
+}
@@ -0,0 +1,85 @@
+package github
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/arjungandhi/atp/config"
+)
+
+// Endpoint is where the REST and GraphQL APIs of a GitHub host live
+type Endpoint struct {
+	BaseURL    string
+	GraphQLURL string
+}
+
+// endpointForProject returns the API endpoint configured for a project, with
+// the GraphQL URL derived from the base URL when it isn't set
+func endpointForProject(project config.GitHubProject) Endpoint {
+	endpoint := Endpoint{BaseURL: project.BaseURL, GraphQLURL: project.GraphQLURL}
+	if endpoint.GraphQLURL == "" && endpoint.BaseURL != "" {
+		// https://ghe.example.com/api/v3/ -> https://ghe.example.com/api/graphql
+		base := strings.TrimSuffix(strings.TrimSuffix(endpoint.BaseURL, "/"), "/v3")
+		endpoint.GraphQLURL = base + "/graphql"
+	}
+	return endpoint
+}
+
+// endpointForHost returns the API endpoint of a host. Hosts that aren't
+// configured get the standard Enterprise Server layout.
+func endpointForHost(host string, endpoints map[string]Endpoint) (Endpoint, bool) {
+	if host == "" || strings.EqualFold(host, config.DefaultGitHubHost) {
+		return Endpoint{}, false
+	}
+
+	if endpoint, ok := endpoints[strings.ToLower(host)]; ok {
+		return endpoint, true
+	}
+
+	return Endpoint{
+		BaseURL:    "https://" + host + "/api/v3/",
+		GraphQLURL: "https://" + host + "/api/graphql",
+	}, true
+}
+
+// issueRef is a parsed GitHub web URL, e.g.
+// https://ghe.example.com/owner/repo/issues/12
+type issueRef struct {
+	host   string
+	owner  string
+	repo   string
+	kind   string // issues, pull, discussions, ... empty for repo URLs
+	number int
+}
+
+// parseGitHubURL splits a GitHub web URL into its parts. It works for any
+// host so todos from different GitHub instances never share a key.
+func parseGitHubURL(rawURL string) (issueRef, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return issueRef{}, fmt.Errorf("invalid GitHub URL '%s'", rawURL)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return issueRef{}, fmt.Errorf("invalid GitHub URL '%s'", rawURL)
+	}
+
+	ref := issueRef{host: strings.ToLower(u.Hostname()), owner: parts[0], repo: parts[1]}
+	if len(parts) >= 4 {
+		ref.kind = parts[2]
+		ref.number, err = strconv.Atoi(parts[3])
+		if err != nil {
+			return issueRef{}, fmt.Errorf("invalid issue number: %w", err)
+		}
+	}
+
+	return ref, nil
+}
+
+// scope returns a scope that can act on the referenced repository
+func (ref issueRef) scope() scope {
+	return ownerScope(ref.host, ref.owner)
+}
@@ -0,0 +1,93 @@
+package github
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/arjungandhi/atp/config"
+)
+
+func TestParseGitHubURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    issueRef
+		wantErr bool
+	}{
+		{"https://github.com/acme/app/issues/12", issueRef{host: "github.com", owner: "acme", repo: "app", kind: "issues", number: 12}, false},
+		{"https://GHE.example.com/acme/app/pull/3", issueRef{host: "ghe.example.com", owner: "acme", repo: "app", kind: "pull", number: 3}, false},
+		{"https://github.com/acme/app", issueRef{host: "github.com", owner: "acme", repo: "app"}, false},
+		{"https://github.com/acme", issueRef{}, true},
+		{"https://github.com/acme/app/issues/abc", issueRef{}, true},
+		{"not a url", issueRef{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseGitHubURL(test.url)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("parseGitHubURL(%q) = %+v, %v, want %+v", test.url, got, err, test.want)
+		}
+	}
+}
+
+func TestScopeCoversHost(t *testing.T) {
+	public := scopeForProject(config.GitHubProject{Organization: "acme"})
+	enterprise := scopeForProject(config.GitHubProject{Organization: "acme", BaseURL: "https://ghe.example.com/api/v3/"})
+
+	if public.key() == enterprise.key() {
+		t.Errorf("scopes on different hosts share the key %q", public.key())
+	}
+
+	url := "https://ghe.example.com/acme/app/issues/1"
+	if public.covers(url) || !enterprise.covers(url) {
+		t.Errorf("covers(%q) = %v (github.com), %v (ghe), want false, true", url, public.covers(url), enterprise.covers(url))
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	project := config.GitHubProject{BaseURL: "https://ghe.example.com/api/v3/"}
+	if got := endpointForProject(project).GraphQLURL; got != "https://ghe.example.com/api/graphql" {
+		t.Errorf("derived GraphQL URL = %q", got)
+	}
+
+	if _, enterprise := endpointForHost("github.com", nil); enterprise {
+		t.Error("endpointForHost(github.com) reported an enterprise endpoint")
+	}
+
+	restClient, _, err := newAPIClients(http.DefaultClient, "ghe.example.com", nil)
+	if err != nil {
+		t.Fatalf("newAPIClients() error = %v", err)
+	}
+	if got := restClient.BaseURL.String(); got != "https://ghe.example.com/api/v3/" {
+		t.Errorf("enterprise REST base URL = %q", got)
+	}
+}
+
+func TestTokenOwners(t *testing.T) {
+	cfg := &config.Config{GitHub: config.GitHubConfig{
+		Projects: []config.GitHubProject{
+			{Name: "work", Organization: "acme", ProjectNumber: 1},
+			{Name: "corp", Organization: "corp", ProjectNumber: 2, BaseURL: "https://ghe.example.com/api/v3/"},
+			{Name: "mine", OwnerType: config.OwnerRepos, Repos: []string{"me/dotfiles"}},
+		},
+		Auth: config.AuthConfig{Tokens: map[string]string{"acme": "x", "other": "y"}},
+	}}
+
+	want := []TokenOwner{
+		{"ghe.example.com", ""},
+		{"ghe.example.com", "corp"},
+		{"github.com", ""},
+		{"github.com", "acme"},
+		{"github.com", "me"},
+		{"github.com", "other"},
+	}
+
+	got := TokenOwners(cfg)
+	if len(got) != len(want) {
+		t.Fatalf("TokenOwners() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("TokenOwners()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
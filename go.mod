@@ -7,7 +7,9 @@ toolchain go1.24.0
 require (
 	github.com/arjungandhi/go-utils v0.8.0
 	github.com/bgentry/go-netrc v0.0.0-20140422174119-9fd32a8b3d3d
+	github.com/gdamore/tcell/v2 v2.8.1
 	github.com/google/go-github/v66 v66.0.0
+	github.com/junegunn/fzf v0.59.0
 	github.com/pelletier/go-toml/v2 v2.2.3
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/rwxrob/bonzai v0.20.10
@@ -20,9 +22,7 @@ require (
 	github.com/AlecAivazis/survey/v2 v2.3.7 // indirect
 	github.com/charlievieth/fastwalk v1.0.9 // indirect
 	github.com/gdamore/encoding v1.0.1 // indirect
-	github.com/gdamore/tcell/v2 v2.8.1 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
-	github.com/junegunn/fzf v0.59.0 // indirect
 	github.com/junegunn/go-shellwords v0.0.0-20250127100254-2aa3b3277741 // indirect
 	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
@@ -0,0 +1,304 @@
+// Package hooks runs user scripts from $ATP_DIR/hooks when todos and
+// projects change, in the spirit of Taskwarrior hooks.
+//
+// A hook is an executable named after its event, or the event followed by
+// a dot or dash and any suffix (on-add, on-add.chat, on-add-10-log). Hooks
+// for an event run in name order. Each gets the affected todo or project as
+// a JSON line on stdin; on-modify gets the original and then the changed
+// version. A non-zero exit vetoes the change. A JSON line on stdout replaces
+// the item for the following hooks and the write, other lines are messages
+// for the user.
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Hook events
+const (
+	OnAdd             = "on-add"
+	OnComplete        = "on-complete"
+	OnModify          = "on-modify"
+	PreSync           = "pre-sync"
+	PostSync          = "post-sync"
+	OnProjectActivate = "on-project-activate"
+)
+
+// Dir is where hooks live in the ATP directory
+const Dir = "hooks"
+
+// DefaultTimeout stops a hook that hangs
+const DefaultTimeout = 30 * time.Second
+
+// Output receives messages printed by hooks and their stderr
+var Output io.Writer = os.Stderr
+
+// VetoError is returned when a hook rejects a change
+type VetoError struct {
+	Hook    string
+	Message string
+}
+
+func (e *VetoError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s hook rejected the change", e.Hook)
+	}
+	return fmt.Sprintf("%s hook rejected the change: %s", e.Hook, e.Message)
+}
+
+// Runner runs the hooks of an ATP directory
+type Runner struct {
+	AtpDir  string
+	Timeout time.Duration
+}
+
+// New returns a runner for the hooks in the ATP directory
+func New(atpDir string) *Runner {
+	return &Runner{AtpDir: atpDir, Timeout: DefaultTimeout}
+}
+
+// Scripts returns the executables for an event in the order they run
+func (r *Runner) Scripts(event string) []string {
+	dir := filepath.Join(r.AtpDir, Dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var scripts []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name != event && !strings.HasPrefix(name, event+".") && !strings.HasPrefix(name, event+"-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+			continue
+		}
+		scripts = append(scripts, filepath.Join(dir, name))
+	}
+	sort.Strings(scripts)
+	return scripts
+}
+
+// run passes the input lines through every script for the event and
+// returns the last JSON line a script printed, nil when none did
+func (r *Runner) run(event string, input ...[]byte) ([]byte, error) {
+	var replaced []byte
+	for _, script := range r.Scripts(event) {
+		output, err := r.exec(event, script, input)
+		if err != nil {
+			return nil, err
+		}
+		if output != nil {
+			replaced = output
+			// the next hook sees the replaced item
+			input[len(input)-1] = output
+		}
+	}
+	return replaced, nil
+}
+
+func (r *Runner) exec(event string, script string, input [][]byte) ([]byte, error) {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdin bytes.Buffer
+	for _, line := range input {
+		stdin.Write(line)
+		stdin.WriteByte('\n')
+	}
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Dir = r.AtpDir
+	cmd.Env = append(os.Environ(), "ATP_DIR="+r.AtpDir, "ATP_HOOK="+event)
+	cmd.Stdin = &stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = Output
+	// children of a killed hook may keep its output open
+	cmd.WaitDelay = time.Second
+	runErr := cmd.Run()
+
+	var replaced []byte
+	var messages []string
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "{") && json.Valid([]byte(line)):
+			replaced = []byte(line)
+		default:
+			messages = append(messages, line)
+		}
+	}
+
+	name := filepath.Base(script)
+	if runErr != nil {
+		if ctx.Err() != nil {
+			return nil, &VetoError{Hook: name, Message: fmt.Sprintf("timed out after %s", timeout)}
+		}
+		if _, ok := runErr.(*exec.ExitError); !ok {
+			return nil, fmt.Errorf("failed to run hook %s: %w", name, runErr)
+		}
+		return nil, &VetoError{Hook: name, Message: strings.Join(messages, "; ")}
+	}
+	for _, message := range messages {
+		fmt.Fprintln(Output, message)
+	}
+	return replaced, nil
+}
+
+// ------------------------------- Todos -------------------------------
+
+// Todos runs on-add, on-complete and on-modify for the differences between
+// before and after, see todo.Changes. Todos a hook replaced are updated in
+// place. The first veto stops the change.
+func (r *Runner) Todos(before []*todo.Todo, after []*todo.Todo) error {
+	if len(r.Scripts(OnAdd))+len(r.Scripts(OnComplete))+len(r.Scripts(OnModify)) == 0 {
+		return nil
+	}
+
+	for _, c := range todo.Changes(before, after) {
+		var event string
+		var input [][]byte
+		switch {
+		case c.Added():
+			event = OnAdd
+		case c.Completed():
+			event = OnComplete
+		case c.Modified():
+			event = OnModify
+			original, err := json.Marshal(c.Before)
+			if err != nil {
+				return err
+			}
+			input = append(input, original)
+		default:
+			continue
+		}
+
+		current, err := json.Marshal(c.After)
+		if err != nil {
+			return err
+		}
+		output, err := r.run(event, append(input, current)...)
+		if err != nil {
+			return err
+		}
+		if output == nil {
+			continue
+		}
+		replaced := todo.NewTodo()
+		if err := json.Unmarshal(output, replaced); err != nil {
+			return fmt.Errorf("%s hook printed an invalid todo: %w", event, err)
+		}
+		*c.After = *replaced
+	}
+	return nil
+}
+
+// ------------------------------- Projects -------------------------------
+
+// Projects runs on-project-activate for the projects that are active in
+// after but weren't in before. Projects a hook replaced are updated in
+// place.
+func (r *Runner) Projects(before []*project.Project, after []*project.Project) error {
+	if len(r.Scripts(OnProjectActivate)) == 0 {
+		return nil
+	}
+
+	active := make(map[string]bool)
+	for _, p := range before {
+		active[p.Name] = p.Active
+	}
+
+	for _, p := range after {
+		if !p.Active || active[p.Name] {
+			continue
+		}
+		current, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		output, err := r.run(OnProjectActivate, current)
+		if err != nil {
+			return err
+		}
+		if output == nil {
+			continue
+		}
+		replaced := &project.Project{}
+		if err := json.Unmarshal(output, replaced); err != nil {
+			return fmt.Errorf("%s hook printed an invalid project: %w", OnProjectActivate, err)
+		}
+		// the hook only sees the repo label, keep the linked repo
+		replaced.Repo = p.Repo
+		*p = *replaced
+	}
+	return nil
+}
+
+// ------------------------------- Sync -------------------------------
+
+// syncInput is what the sync hooks get on stdin
+type syncInput struct {
+	Source string `json:"source"`
+	Error  string `json:"error,omitempty"`
+}
+
+// PreSync runs before a sync, a veto skips it
+func (r *Runner) PreSync(source string) error {
+	input, err := json.Marshal(syncInput{Source: source})
+	if err != nil {
+		return err
+	}
+	_, err = r.run(PreSync, input)
+	return err
+}
+
+// PostSync runs after a sync with its error, if any. A hook failing can't
+// undo the sync, it is only reported.
+func (r *Runner) PostSync(source string, syncErr error) {
+	in := syncInput{Source: source}
+	if syncErr != nil {
+		in.Error = syncErr.Error()
+	}
+	input, err := json.Marshal(in)
+	if err == nil {
+		_, err = r.run(PostSync, input)
+	}
+	if err != nil {
+		fmt.Fprintf(Output, "Warning: %v\n", err)
+	}
+}
+
+// Sync runs fn between the pre-sync and post-sync hooks
+func (r *Runner) Sync(source string, fn func() error) error {
+	if err := r.PreSync(source); err != nil {
+		return err
+	}
+	err := fn()
+	r.PostSync(source, err)
+	return err
+}
@@ -0,0 +1,251 @@
+package hooks
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func init() {
+	Output = io.Discard
+}
+
+// writeHook writes a shell script to the hooks directory
+func writeHook(t *testing.T, atpDir string, name string, script string) {
+	t.Helper()
+	dir := filepath.Join(atpDir, Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestScripts(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, "on-add", "true")
+	writeHook(t, dir, "on-add.log", "true")
+	writeHook(t, dir, "on-add-10-chat", "true")
+	writeHook(t, dir, "on-added", "true")
+	writeHook(t, dir, "on-modify", "true")
+	if err := os.WriteFile(filepath.Join(dir, Dir, "on-add.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, script := range New(dir).Scripts(OnAdd) {
+		names = append(names, filepath.Base(script))
+	}
+	if got := strings.Join(names, " "); got != "on-add on-add-10-chat on-add.log" {
+		t.Errorf("Scripts(on-add) = %s", got)
+	}
+	if scripts := New(t.TempDir()).Scripts(OnAdd); len(scripts) != 0 {
+		t.Errorf("Scripts without a hooks dir = %v", scripts)
+	}
+}
+
+func TestTodos(t *testing.T) {
+	tests := []struct {
+		name   string
+		hooks  map[string]string
+		before []string
+		after  []string
+		want   []string
+		veto   string
+		seen   string
+	}{
+		{
+			name:  "no hooks",
+			after: []string{"Call mom"},
+			want:  []string{"Call mom"},
+		},
+		{
+			name:  "on-add modifies",
+			hooks: map[string]string{OnAdd: `cat > /dev/null; echo '{"text":"Call mom +family"}'`},
+			after: []string{"Call mom"},
+			want:  []string{"Call mom +family"},
+		},
+		{
+			name: "on-add chains in name order",
+			hooks: map[string]string{
+				"on-add.1": `cat > /dev/null; echo '{"text":"Call mom +family"}'`,
+				"on-add.2": `cat > "$ATP_DIR/seen"`,
+			},
+			after: []string{"Call mom"},
+			want:  []string{"Call mom +family"},
+			seen:  `"text":"Call mom +family"`,
+		},
+		{
+			name:  "on-add veto",
+			hooks: map[string]string{OnAdd: `echo "no projects"; exit 1`},
+			after: []string{"Call mom"},
+			veto:  "no projects",
+		},
+		{
+			name:   "on-add skips existing todos",
+			hooks:  map[string]string{OnAdd: `exit 1`},
+			before: []string{"Call mom"},
+			after:  []string{"Call mom"},
+			want:   []string{"Call mom"},
+		},
+		{
+			name:   "on-complete",
+			hooks:  map[string]string{OnComplete: `cat > "$ATP_DIR/seen"`},
+			before: []string{"Call mom"},
+			after:  []string{"x Call mom"},
+			want:   []string{"x Call mom"},
+			seen:   `"done":true`,
+		},
+		{
+			name:   "on-modify gets both versions",
+			hooks:  map[string]string{OnModify: `wc -l < /dev/stdin | tr -d ' ' > "$ATP_DIR/seen"`},
+			before: []string{"Call mom"},
+			after:  []string{"(A) Call mom"},
+			want:   []string{"(A) Call mom"},
+			seen:   "2",
+		},
+		{
+			name:   "on-modify veto",
+			hooks:  map[string]string{OnModify: `exit 2`},
+			before: []string{"Call mom"},
+			after:  []string{"(A) Call mom"},
+			veto:   "on-modify hook rejected the change",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, script := range tt.hooks {
+				writeHook(t, dir, name, script)
+			}
+			var before, after []*todo.Todo
+			for _, line := range tt.before {
+				before = append(before, todo.FromString(line))
+			}
+			for _, line := range tt.after {
+				after = append(after, todo.FromString(line))
+			}
+
+			err := New(dir).Todos(before, after)
+			if tt.veto != "" {
+				var veto *VetoError
+				if !errors.As(err, &veto) || !strings.Contains(err.Error(), tt.veto) {
+					t.Fatalf("Todos() = %v, want veto %q", err, tt.veto)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, t := range after {
+				got = append(got, t.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("after = %q, want %q", got, tt.want)
+			}
+			if tt.seen != "" {
+				if seen := readFile(t, filepath.Join(dir, "seen")); !strings.Contains(seen, tt.seen) {
+					t.Errorf("hook saw %q, want %q", seen, tt.seen)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, OnAdd, "sleep 5")
+	r := New(dir)
+	r.Timeout = 100 * time.Millisecond
+
+	err := r.Todos(nil, []*todo.Todo{todo.FromString("Call mom")})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Todos() = %v, want a timeout", err)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, OnProjectActivate, `cat > /dev/null; echo "activated"; echo '{"name":"garden","active":true,"labels":{"area":"home"}}'`)
+
+	before := projects(t, "garden phase:1", "(A) taxes")
+	after := projects(t, "(A) garden phase:1", "(A) taxes")
+	if err := New(dir).Projects(before, after); err != nil {
+		t.Fatal(err)
+	}
+	if line := after[0].ToTodo().String(); !strings.Contains(line, "area:home") {
+		t.Errorf("garden = %q, want the hook's labels", line)
+	}
+	if after[1].Name != "taxes" {
+		t.Errorf("taxes = %+v, it was already active", after[1])
+	}
+
+	// a veto stops the activation
+	writeHook(t, dir, OnProjectActivate, "exit 1")
+	after[0].Active = true
+	if err := New(dir).Projects(before, after); err == nil {
+		t.Error("Projects() = nil, want a veto")
+	}
+}
+
+func projects(t *testing.T, lines ...string) []*project.Project {
+	t.Helper()
+	var projects []*project.Project
+	for _, line := range lines {
+		p, err := project.FromTodo(todo.FromString(line), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		projects = append(projects, p)
+	}
+	return projects
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, PostSync, `cat > "$ATP_DIR/seen"`)
+
+	ran := false
+	err := New(dir).Sync("github", func() error {
+		ran = true
+		return errors.New("offline")
+	})
+	if !ran || err == nil || err.Error() != "offline" {
+		t.Fatalf("Sync() = %v, ran %v", err, ran)
+	}
+	if seen := readFile(t, filepath.Join(dir, "seen")); !strings.Contains(seen, `{"source":"github","error":"offline"}`) {
+		t.Errorf("post-sync saw %q", seen)
+	}
+
+	// a pre-sync veto skips the sync
+	writeHook(t, dir, PreSync, "echo paused; exit 1")
+	ran = false
+	err = New(dir).Sync("github", func() error {
+		ran = true
+		return nil
+	})
+	var veto *VetoError
+	if ran || !errors.As(err, &veto) || veto.Message != "paused" {
+		t.Errorf("Sync() = %v, ran %v, want a veto", err, ran)
+	}
+}
@@ -0,0 +1,112 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Decode reads the first component in the input, usually a VCALENDAR
+func Decode(r io.Reader) (*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var stack []*Component
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch strings.ToUpper(p.Name) {
+		case "BEGIN":
+			c := &Component{Name: strings.ToUpper(p.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", i+1, p.Value)
+			}
+			if len(stack) == 1 {
+				return stack[0], nil
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property %s outside of a component", i+1, p.Name)
+			}
+			c := stack[len(stack)-1]
+			c.Props = append(c.Props, p)
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[0].Name)
+	}
+	return nil, fmt.Errorf("no calendar data")
+}
+
+// unfold joins continuation lines, which start with a space or tab
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseLine splits NAME;PARAM=VALUE:value, colons and semicolons inside
+// quoted parameter values don't count
+func parseLine(line string) (Prop, error) {
+	var p Prop
+	quoted := false
+	start := 0
+	var key string
+
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '=' && p.Name != "" && key == "":
+			key = strings.ToUpper(line[start:i])
+			start = i + 1
+		case c == ';' || c == ':':
+			part := line[start:i]
+			if p.Name == "" {
+				p.Name = strings.ToUpper(part)
+			} else if key != "" {
+				if p.Params == nil {
+					p.Params = make(map[string]string)
+				}
+				p.Params[key] = strings.Trim(part, `"`)
+				key = ""
+			}
+			start = i + 1
+			if c == ':' {
+				p.Value = line[i+1:]
+				return p, nil
+			}
+		}
+	}
+	return p, fmt.Errorf("invalid content line %q", line)
+}
@@ -0,0 +1,191 @@
+// Package ical reads and writes iCalendar (RFC 5545) data.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Date formats used for DATE and DATE-TIME values
+const (
+	DateFormat     = "20060102"
+	DateTimeFormat = "20060102T150405Z"
+)
+
+// ProdID identifies atp in calendars it writes
+const ProdID = "-//arjungandhi//atp//EN"
+
+// lineLength is the longest content line in octets, longer lines are folded
+const lineLength = 75
+
+// Prop is a content line like DUE;VALUE=DATE:20250310
+type Prop struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Component is a BEGIN/END block like VCALENDAR, VTODO or VEVENT
+type Component struct {
+	Name       string
+	Props      []Prop
+	Components []*Component
+}
+
+// NewCalendar returns a VCALENDAR with the required version and product id
+func NewCalendar(prodID string) *Component {
+	cal := &Component{Name: "VCALENDAR"}
+	cal.Add("VERSION", "2.0")
+	cal.Add("PRODID", prodID)
+	return cal
+}
+
+// Add appends a property, params are given as KEY=VALUE pairs
+func (c *Component) Add(name string, value string, params ...string) {
+	p := Prop{Name: name, Value: value}
+	for _, param := range params {
+		key, val, _ := strings.Cut(param, "=")
+		if p.Params == nil {
+			p.Params = make(map[string]string)
+		}
+		p.Params[key] = val
+	}
+	c.Props = append(c.Props, p)
+}
+
+// AddText appends a TEXT property, escaping the value
+func (c *Component) AddText(name string, text string) {
+	c.Add(name, Escape(text))
+}
+
+// AddDate appends an all day DATE property
+func (c *Component) AddDate(name string, date time.Time) {
+	c.Add(name, date.Format(DateFormat), "VALUE=DATE")
+}
+
+// AddDateTime appends a UTC DATE-TIME property
+func (c *Component) AddDateTime(name string, t time.Time) {
+	c.Add(name, t.UTC().Format(DateTimeFormat))
+}
+
+// AddList appends a comma separated list of TEXT values
+func (c *Component) AddList(name string, values []string) {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = Escape(value)
+	}
+	c.Add(name, strings.Join(escaped, ","))
+}
+
+// Get returns the first property with the name or nil
+func (c *Component) Get(name string) *Prop {
+	for i := range c.Props {
+		if strings.EqualFold(c.Props[i].Name, name) {
+			return &c.Props[i]
+		}
+	}
+	return nil
+}
+
+// Text returns the unescaped value of the first property with the name
+func (c *Component) Text(name string) string {
+	if p := c.Get(name); p != nil {
+		return Unescape(p.Value)
+	}
+	return ""
+}
+
+// Escape escapes a TEXT value
+func Escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// Unescape reverses Escape
+func Unescape(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+			switch text[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(text[i])
+			}
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+// String formats the property as an unfolded content line
+func (p Prop) String() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+
+	keys := make([]string, 0, len(p.Params))
+	for key := range p.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := p.Params[key]
+		if strings.ContainsAny(value, ":;,") {
+			value = `"` + value + `"`
+		}
+		fmt.Fprintf(&b, ";%s=%s", key, value)
+	}
+
+	b.WriteByte(':')
+	b.WriteString(p.Value)
+	return b.String()
+}
+
+// Encode writes the component with CRLF line endings and folded lines
+func Encode(w io.Writer, c *Component) error {
+	out := bufio.NewWriter(w)
+	encode(out, c)
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write calendar: %w", err)
+	}
+	return nil
+}
+
+func encode(out *bufio.Writer, c *Component) {
+	writeLine(out, "BEGIN:"+c.Name)
+	for _, p := range c.Props {
+		writeLine(out, p.String())
+	}
+	for _, child := range c.Components {
+		encode(out, child)
+	}
+	writeLine(out, "END:"+c.Name)
+}
+
+// writeLine folds the line every 75 octets without splitting utf-8
+// characters, continuation lines start with a space
+func writeLine(out *bufio.Writer, line string) {
+	limit := lineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		out.WriteString(line[:cut])
+		out.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space counts towards the next line
+		limit = lineLength - 1
+	}
+	out.WriteString(line)
+	out.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
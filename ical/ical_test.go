@@ -0,0 +1,106 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "plain"},
+		{"a, b; c", `a\, b\; c`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines", `two\nlines`},
+	}
+	for _, test := range tests {
+		if got := Escape(test.text); got != test.want {
+			t.Errorf("Escape(%q) = %q, want %q", test.text, got, test.want)
+		}
+		if got := Unescape(test.want); got != test.text {
+			t.Errorf("Unescape(%q) = %q, want %q", test.want, got, test.text)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cal := NewCalendar("-//atp//test//EN")
+	todo := &Component{Name: "VTODO"}
+	todo.Add("UID", "1@atp")
+	todo.AddDate("DUE", time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC))
+	todo.AddText("SUMMARY", strings.Repeat("é", 50))
+	cal.Components = append(cal.Components, todo)
+
+	var out bytes.Buffer
+	if err := Encode(&out, cal); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\r\n"), "\r\n")
+	want := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//atp//test//EN", "BEGIN:VTODO", "UID:1@atp", "DUE;VALUE=DATE:20250310"}
+	for i, line := range want {
+		if lines[i] != line {
+			t.Errorf("line %d = %q, want %q", i, lines[i], line)
+		}
+	}
+	if lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Errorf("last line = %q", lines[len(lines)-1])
+	}
+
+	// the summary is folded and every line stays within 75 octets
+	if !strings.HasPrefix(lines[7], " ") {
+		t.Errorf("summary was not folded: %q", lines[6:8])
+	}
+	for _, line := range lines {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:abc@example.com\r\n" +
+		"SUMMARY:Buy milk\\, eggs\r\n" +
+		"DESCRIPTION:a long description that was\r\n" +
+		"  folded\r\n" +
+		"DUE;TZID=\"Europe/Berlin;x\":20250310T120000\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"TRIGGER:-PT15M\r\n" +
+		"END:VALARM\r\n" +
+		"END:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+
+	cal, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Name != "VCALENDAR" || len(cal.Components) != 1 {
+		t.Fatalf("calendar = %+v", cal)
+	}
+	todo := cal.Components[0]
+	if got := todo.Text("SUMMARY"); got != "Buy milk, eggs" {
+		t.Errorf("SUMMARY = %q", got)
+	}
+	if got := todo.Text("DESCRIPTION"); got != "a long description that was folded" {
+		t.Errorf("DESCRIPTION = %q", got)
+	}
+	if due := todo.Get("DUE"); due == nil || due.Value != "20250310T120000" || due.Params["TZID"] != "Europe/Berlin;x" {
+		t.Errorf("DUE = %+v", due)
+	}
+	if len(todo.Components) != 1 || todo.Components[0].Name != "VALARM" {
+		t.Errorf("alarm = %+v", todo.Components)
+	}
+
+	for _, bad := range []string{"", "BEGIN:VCALENDAR\r\n", "VERSION:2.0\r\n", "BEGIN:VTODO\r\nEND:VEVENT\r\n"} {
+		if _, err := Decode(strings.NewReader(bad)); err == nil {
+			t.Errorf("Decode(%q) should fail", bad)
+		}
+	}
+}
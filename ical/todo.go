@@ -0,0 +1,237 @@
+package ical
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/todo"
+)
+
+// UIDLabel keeps the calendar UID of a synced todo
+const UIDLabel = "uid"
+
+// vtodoProps are the VTODO properties that come from todo fields, any
+// other property a calendar app adds is kept when a todo is updated
+var vtodoProps = []string{"UID", "DTSTAMP", "SUMMARY", "DUE", "DTSTART", "CREATED", "PRIORITY", "STATUS", "COMPLETED", "CATEGORIES"}
+
+// UID is stable across exports. It uses the todo's uid: or id: label when
+// there is one and otherwise hashes the description and creation date.
+func UID(t *todo.Todo) string {
+	if uid := t.Labels[UIDLabel]; uid != "" {
+		if unescaped, err := url.PathUnescape(uid); err == nil {
+			return unescaped
+		}
+		return uid
+	}
+	if id := t.Labels["id"]; id != "" {
+		return "atp-" + id
+	}
+	sum := sha1.Sum([]byte(formatDay(t.CreationDate) + " " + t.Description))
+	return "atp-" + hex.EncodeToString(sum[:8])
+}
+
+// SetUID stores the uid in the todo's uid: label. Characters the todo.txt
+// parser would take for tags, like the @ in uid@example.com, are escaped.
+func SetUID(t *todo.Todo, uid string) {
+	var b strings.Builder
+	for i := 0; i < len(uid); i++ {
+		c := uid[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	t.Labels[UIDLabel] = b.String()
+}
+
+// VTodo maps a todo to a VTODO:
+//
+//	description  SUMMARY
+//	due:         DUE
+//	remind:      DTSTART
+//	creation     CREATED
+//	priority     PRIORITY, A-I is 1-9
+//	done         STATUS and COMPLETED
+//	+proj @ctx   CATEGORIES
+func VTodo(t *todo.Todo, now time.Time) *Component {
+	c := &Component{Name: "VTODO"}
+	c.Add("UID", UID(t))
+	c.AddDateTime("DTSTAMP", now)
+	c.AddText("SUMMARY", t.Description)
+	if due, ok := labelDate(t, "due"); ok {
+		c.AddDate("DUE", due)
+	}
+	if remind, ok := labelDate(t, "remind"); ok {
+		c.AddDate("DTSTART", remind)
+	}
+	if !t.CreationDate.IsZero() {
+		c.AddDateTime("CREATED", t.CreationDate)
+	}
+	if t.Priority != "" {
+		c.Add("PRIORITY", priorityNumber(t.Priority))
+	}
+	if t.Done {
+		c.Add("STATUS", "COMPLETED")
+		if !t.CompletionDate.IsZero() {
+			c.AddDateTime("COMPLETED", t.CompletionDate)
+		}
+	} else {
+		c.Add("STATUS", "NEEDS-ACTION")
+	}
+	if categories := Categories(t); len(categories) > 0 {
+		c.AddList("CATEGORIES", categories)
+	}
+	return c
+}
+
+// Categories are the todo's +projects and @contexts
+func Categories(t *todo.Todo) []string {
+	var categories []string
+	for _, project := range t.Projects {
+		categories = append(categories, "+"+project)
+	}
+	for _, context := range t.Contexts {
+		categories = append(categories, "@"+context)
+	}
+	return categories
+}
+
+// MergeVTodo maps the todo onto an existing VTODO, keeping the properties
+// and alarms atp doesn't know about
+func MergeVTodo(existing *Component, t *todo.Todo, now time.Time) *Component {
+	c := VTodo(t, now)
+	if existing == nil {
+		return c
+	}
+	for _, p := range existing.Props {
+		if !isVTodoProp(p.Name) {
+			c.Props = append(c.Props, p)
+		}
+	}
+	c.Components = append(c.Components, existing.Components...)
+	return c
+}
+
+func isVTodoProp(name string) bool {
+	for _, known := range vtodoProps {
+		if strings.EqualFold(known, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateTodo copies a VTODO's fields onto the todo. Labels other than due:,
+// remind: and the uid: label are left alone, and projects and contexts are
+// only replaced when there are CATEGORIES since many apps drop them.
+func UpdateTodo(t *todo.Todo, c *Component) {
+	SetUID(t, c.Text("UID"))
+	if summary := c.Text("SUMMARY"); summary != "" {
+		t.Description = strings.Join(strings.Fields(summary), " ")
+	}
+
+	setLabelDate(t, "due", c.Get("DUE"))
+	setLabelDate(t, "remind", c.Get("DTSTART"))
+
+	if created, ok := propDate(c.Get("CREATED")); ok && t.CreationDate.IsZero() {
+		t.CreationDate = created
+	}
+
+	t.Priority = ""
+	if p := c.Get("PRIORITY"); p != nil {
+		t.Priority = priorityLetter(p.Value)
+	}
+
+	completed, hasCompleted := propDate(c.Get("COMPLETED"))
+	t.Done = strings.EqualFold(c.Text("STATUS"), "COMPLETED") || hasCompleted
+	t.CompletionDate = time.Time{}
+	if t.Done && hasCompleted {
+		t.CompletionDate = completed
+	}
+
+	if p := c.Get("CATEGORIES"); p != nil {
+		t.Projects, t.Contexts = []string{}, []string{}
+		for _, category := range splitList(p.Value) {
+			tag := strings.Join(strings.Fields(category[1:]), "_")
+			switch {
+			case tag == "":
+			case category[0] == '+':
+				t.Projects = append(t.Projects, tag)
+			case category[0] == '@':
+				t.Contexts = append(t.Contexts, tag)
+			}
+		}
+	}
+}
+
+// ------------------------------- Helpers -------------------------------
+
+func labelDate(t *todo.Todo, label string) (time.Time, bool) {
+	date, err := time.Parse("2006-01-02", t.Labels[label])
+	return date, err == nil
+}
+
+func setLabelDate(t *todo.Todo, label string, p *Prop) {
+	if date, ok := propDate(p); ok {
+		t.Labels[label] = date.Format("2006-01-02")
+	} else {
+		delete(t.Labels, label)
+	}
+}
+
+// propDate reads a DATE or DATE-TIME value as a day, date-times with a
+// TZID are taken at face value since todo.txt only has days
+func propDate(p *Prop) (time.Time, bool) {
+	if p == nil || len(p.Value) < 8 {
+		return time.Time{}, false
+	}
+	date, err := time.Parse(DateFormat, p.Value[:8])
+	return date, err == nil
+}
+
+func formatDay(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// priorityNumber maps A-I to the 1 (highest) to 9 scale, lower priorities
+// are 9
+func priorityNumber(priority string) string {
+	if p := priority[0]; p >= 'A' && p <= 'I' {
+		return strconv.Itoa(int(p-'A') + 1)
+	}
+	return "9"
+}
+
+// priorityLetter reverses priorityNumber, 0 means undefined
+func priorityLetter(value string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 1 || n > 9 {
+		return ""
+	}
+	return string(rune('A' + n - 1))
+}
+
+// splitList splits a comma separated value on unescaped commas
+func splitList(value string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, Unescape(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, Unescape(value[start:]))
+}
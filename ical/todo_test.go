@@ -0,0 +1,67 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/todo"
+)
+
+func TestVTodoRoundTrip(t *testing.T) {
+	lines := []string{
+		"(A) 2025-03-01 Call mom +family @phone due:2025-03-10 uid:atp-1",
+		"x 2025-03-05 (C) Pay rent +home remind:2025-03-04 uid:atp-2",
+		"Plain todo uid:atp-3",
+	}
+	for _, line := range lines {
+		original := todo.FromString(line)
+		c := VTodo(original, time.Now())
+
+		got := todo.NewTodo()
+		UpdateTodo(got, c)
+		got.CreationDate = original.CreationDate
+		if got.String() != original.String() {
+			t.Errorf("round trip of %q = %q", line, got.String())
+		}
+	}
+}
+
+func TestUpdateTodo(t *testing.T) {
+	c := &Component{Name: "VTODO"}
+	c.Add("UID", "abc@example.com")
+	c.AddText("SUMMARY", "Buy  milk")
+	c.Add("DUE", "20250310T120000", "TZID=Europe/Berlin")
+	c.Add("PRIORITY", "0")
+	c.Add("STATUS", "IN-PROCESS")
+
+	got := todo.FromString("(B) Old name +keep @me remind:2025-03-01 est:1h")
+	UpdateTodo(got, c)
+
+	want := "Buy milk +keep @me due:2025-03-10 est:1h uid:abc%40example.com"
+	if got.String() != want {
+		t.Errorf("UpdateTodo = %q, want %q", got.String(), want)
+	}
+	if UID(got) != "abc@example.com" {
+		t.Errorf("UID = %q", UID(got))
+	}
+	// the escaped label survives a trip through todo.txt
+	if parsed := todo.FromString(got.String()); UID(parsed) != "abc@example.com" || len(parsed.Contexts) != 1 {
+		t.Errorf("parsed %q has uid %q and contexts %v", got.String(), UID(parsed), parsed.Contexts)
+	}
+}
+
+func TestMergeVTodo(t *testing.T) {
+	existing := &Component{Name: "VTODO"}
+	existing.Add("UID", "atp-1")
+	existing.Add("SUMMARY", "Old")
+	existing.Add("X-APPLE-SORT-ORDER", "5")
+	existing.Components = append(existing.Components, &Component{Name: "VALARM"})
+
+	merged := MergeVTodo(existing, todo.FromString("New uid:atp-1"), time.Now())
+	if merged.Text("SUMMARY") != "New" || merged.Text("X-APPLE-SORT-ORDER") != "5" || len(merged.Components) != 1 {
+		t.Errorf("merged = %+v", merged)
+	}
+	if n := len(merged.Props); n != 5 {
+		t.Errorf("merged has %d props, want UID, DTSTAMP, SUMMARY, STATUS and X-APPLE-SORT-ORDER", n)
+	}
+}
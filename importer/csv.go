@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+func init() {
+	Register("csv", csvAdapter{})
+	Register("github", csvAdapter{defaults: GitHubMapping})
+}
+
+// Fields a csv column can be mapped to. label:<key> fields fill a key:value
+// label from the column.
+var Fields = []string{"description", "done", "priority", "creation_date", "completion_date", "due", "projects", "contexts", "tags", "labels"}
+
+// GitHubMapping reads the csv a GitHub project view exports
+var GitHubMapping = map[string]string{
+	"description": "Title",
+	"done":        "Status",
+	"tags":        "Labels",
+	"label:url":   "URL",
+	"label:repo":  "Repository",
+}
+
+// csvAdapter reads any csv through a field to column mapping. Without a
+// mapping columns named like the fields are used, so files written by
+// 'atp export csv' read back as they were.
+type csvAdapter struct {
+	defaults map[string]string
+}
+
+func (a csvAdapter) Read(r io.Reader, opts Options) (*Result, error) {
+	mapping := make(map[string]string)
+	for field := range a.defaults {
+		mapping[field] = a.defaults[field]
+	}
+	for field, column := range opts.Mapping {
+		mapping[field] = column
+	}
+	if len(mapping) == 0 {
+		for _, field := range Fields {
+			mapping[field] = field
+		}
+	}
+	if err := checkMapping(mapping); err != nil {
+		return nil, err
+	}
+
+	rows, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		if _, ok := rows[0].header[strings.ToLower(mapping["description"])]; !ok {
+			return nil, fmt.Errorf("csv has no '%s' column for the description, map one with description=<column>", mapping["description"])
+		}
+	}
+
+	result := &Result{}
+	for _, row := range rows {
+		get := func(field string) string {
+			if column, ok := mapping[field]; ok {
+				return row.get(column)
+			}
+			return ""
+		}
+
+		t := fromText(get("description"))
+		if t.Description == "" {
+			continue
+		}
+
+		if priority := strings.ToUpper(strings.Trim(get("priority"), "()")); len(priority) == 1 && priority >= "A" && priority <= "Z" {
+			t.Priority = priority
+		}
+		for _, tag := range strings.Fields(get("projects")) {
+			addUnique(&t.Projects, Tag(tag))
+		}
+		for _, tag := range strings.Fields(get("contexts")) {
+			addUnique(&t.Contexts, Tag(tag))
+		}
+		if tags := splitList(get("tags")); len(tags) > 0 {
+			t.Labels["tags"] = strings.Join(tags, ",")
+		}
+		for _, pair := range strings.Fields(get("labels")) {
+			if key, value, ok := strings.Cut(pair, ":"); ok && key != "" && value != "" {
+				t.Labels[key] = value
+			}
+		}
+		for field := range mapping {
+			if key, ok := strings.CutPrefix(field, "label:"); ok {
+				if value := get(field); value != "" {
+					t.Labels[key] = labelValue(value)
+				}
+			}
+		}
+
+		if created, ok := parseDate(get("creation_date")); ok {
+			t.CreationDate = day(created)
+		}
+		setDate(result, t, "due", get("due"))
+		if isDone(get("done")) {
+			completed, _ := parseDate(get("completion_date"))
+			complete(t, completed)
+		}
+
+		result.Todos = append(result.Todos, t)
+	}
+	return result, nil
+}
+
+// ParseMapping reads field=column pairs separated by commas
+func ParseMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(field) == "" || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("invalid mapping '%s' (expected field=column)", pair)
+		}
+		mapping[strings.ToLower(strings.TrimSpace(field))] = strings.TrimSpace(column)
+	}
+	return mapping, checkMapping(mapping)
+}
+
+func checkMapping(mapping map[string]string) error {
+	var unknown []string
+	for field := range mapping {
+		if strings.HasPrefix(field, "label:") {
+			continue
+		}
+		known := false
+		for _, f := range Fields {
+			known = known || f == field
+		}
+		if !known {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown csv fields %s (available: %s, label:<key>)", strings.Join(unknown, ", "), strings.Join(Fields, ", "))
+	}
+	if _, ok := mapping["description"]; !ok {
+		return fmt.Errorf("the csv mapping needs a description column")
+	}
+	return nil
+}
+
+// isDone reads the many ways a csv says a task is finished
+func isDone(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "x", "yes", "1", "done", "completed", "closed":
+		return true
+	}
+	return false
+}
+
+// splitList splits comma or space separated tags
+func splitList(value string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if tag = Tag(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,223 @@
+// Package importer turns tasks exported from other tools into todos.
+// Adapters register themselves by format name like the export formatters.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Options change how an adapter reads its input
+type Options struct {
+	// Mapping maps todo fields to column names for csv input, see Fields
+	Mapping map[string]string
+	// Project is added as a +project to every imported todo
+	Project string
+}
+
+// Result is what an adapter read. Warnings describe records that were
+// skipped or only partly understood.
+type Result struct {
+	Todos    []*todo.Todo
+	Warnings []string
+}
+
+func (r *Result) warn(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Adapter reads one external format
+type Adapter interface {
+	Read(r io.Reader, opts Options) (*Result, error)
+}
+
+var adapters = make(map[string]Adapter)
+
+// Register makes an adapter available by name. It panics when the name is
+// taken since that is a programming error.
+func Register(name string, a Adapter) {
+	if _, ok := adapters[name]; ok {
+		panic(fmt.Sprintf("import format %s registered twice", name))
+	}
+	adapters[name] = a
+}
+
+// Lookup returns the adapter registered under the name
+func Lookup(name string) (Adapter, error) {
+	a, ok := adapters[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown import format '%s' (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return a, nil
+}
+
+// Names lists the registered formats alphabetically
+func Names() []string {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Read imports the input with the named adapter
+func Read(r io.Reader, format string, opts Options) (*Result, error) {
+	a, err := Lookup(format)
+	if err != nil {
+		return nil, err
+	}
+	result, err := a.Read(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	if tag := Tag(opts.Project); tag != "" {
+		for _, t := range result.Todos {
+			addUnique(&t.Projects, tag)
+		}
+	}
+	return result, nil
+}
+
+// sourceLabels identify a record in the tool it came from, a todo with the
+// same value is the same record imported before
+var sourceLabels = []string{"uuid", "todoist", "url"}
+
+// Dedupe splits incoming todos into the ones that are new and the ones
+// that already exist, either with the same source label or the same
+// description and projects. An open todo isn't a duplicate of a done one
+// by its description alone, the task may simply have come up again.
+// Duplicates within incoming are dropped as well.
+func Dedupe(existing []*todo.Todo, incoming []*todo.Todo) (fresh []*todo.Todo, duplicates []*todo.Todo) {
+	sources := make(map[string]bool)
+	open := make(map[string]bool)
+	done := make(map[string]bool)
+	remember := func(t *todo.Todo) {
+		for _, key := range sourceKeys(t) {
+			sources[key] = true
+		}
+		if t.Done {
+			done[descriptionKey(t)] = true
+		} else {
+			open[descriptionKey(t)] = true
+		}
+	}
+	for _, t := range existing {
+		remember(t)
+	}
+
+	for _, t := range incoming {
+		key := descriptionKey(t)
+		duplicate := open[key] || (t.Done && done[key])
+		for _, source := range sourceKeys(t) {
+			duplicate = duplicate || sources[source]
+		}
+		if duplicate {
+			duplicates = append(duplicates, t)
+			continue
+		}
+		remember(t)
+		fresh = append(fresh, t)
+	}
+	return fresh, duplicates
+}
+
+func sourceKeys(t *todo.Todo) []string {
+	var keys []string
+	for _, label := range sourceLabels {
+		if value := t.Labels[label]; value != "" {
+			keys = append(keys, label+":"+value)
+		}
+	}
+	return keys
+}
+
+// descriptionKey compares descriptions ignoring case and spacing. The
+// parser takes +projects out of the description, they are added back so
+// the same title in two projects stays two todos.
+func descriptionKey(t *todo.Todo) string {
+	words := strings.Fields(strings.ToLower(t.Description))
+	var projects []string
+	for _, p := range t.Projects {
+		projects = append(projects, "+"+strings.ToLower(p))
+	}
+	sort.Strings(projects)
+	return strings.Join(append(words, projects...), " ")
+}
+
+// ------------------------------- Helpers -------------------------------
+
+var reNonWord = regexp.MustCompile(`\W+`)
+
+// Tag turns a name into a +project or @context tag the todo.txt parser
+// reads back whole, e.g. "home.garden" becomes home_garden
+func Tag(name string) string {
+	return strings.Trim(reNonWord.ReplaceAllString(strings.TrimSpace(name), "_"), "_")
+}
+
+// labelValue makes a value safe for a key:value label
+func labelValue(value string) string {
+	return strings.Join(strings.Fields(value), "_")
+}
+
+// fromText parses the +projects, @contexts and labels out of a task title.
+// The title is never a full todo.txt line, so a leading "x " or "(A)" stays
+// part of the description.
+func fromText(text string) *todo.Todo {
+	t := todo.FromString("~ " + strings.TrimSpace(text))
+	t.Description = strings.TrimSpace(strings.TrimPrefix(t.Description, "~"))
+	return t
+}
+
+func addUnique(values *[]string, value string) {
+	for _, v := range *values {
+		if strings.EqualFold(v, value) {
+			return
+		}
+	}
+	*values = append(*values, value)
+}
+
+// dateLayouts are tried in order when reading dates
+var dateLayouts = []string{
+	"2006-01-02",
+	"20060102T150405Z",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006/01/02",
+	"Jan 2 2006",
+	"Jan 2, 2006",
+	"January 2 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+}
+
+func parseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if d, err := time.Parse(layout, value); err == nil {
+			return d, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// day drops the time of day, todo.txt only has dates
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func complete(t *todo.Todo, date time.Time) {
+	t.Done = true
+	if !date.IsZero() {
+		t.CompletionDate = day(date)
+	}
+}
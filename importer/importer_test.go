@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/arjungandhi/atp/export"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func read(t *testing.T, format string, input string, opts Options) *Result {
+	t.Helper()
+	result, err := Read(strings.NewReader(input), format, opts)
+	if err != nil {
+		t.Fatalf("%s import failed: %v", format, err)
+	}
+	return result
+}
+
+func lines(todos []*todo.Todo) string {
+	var s []string
+	for _, t := range todos {
+		s = append(s, t.String())
+	}
+	return strings.Join(s, "\n")
+}
+
+func check(t *testing.T, format string, got []*todo.Todo, want string) {
+	t.Helper()
+	if lines(got) != want {
+		t.Errorf("%s import =\n%s\nwant\n%s", format, lines(got), want)
+	}
+}
+
+func TestTaskwarrior(t *testing.T) {
+	input := `[
+{"uuid":"a1","description":"Repot plants","status":"pending","entry":"20250301T120000Z","due":"20250310T000000Z","project":"home.garden","priority":"H","tags":["outside","weekend"]},
+{"uuid":"b2","description":"File taxes","status":"completed","entry":"20250201T120000Z","end":"20250305T180000Z"},
+{"uuid":"c3","description":"Old idea","status":"deleted"},
+{"uuid":"d4","description":"Water plants","status":"recurring"},
+{"uuid":"e5","description":"Call plumber","status":"waiting","wait":"20250320T000000Z"}
+]`
+	result := read(t, "taskwarrior", input, Options{})
+	check(t, "taskwarrior", result.Todos, `(A) 2025-03-01 Repot plants +home_garden due:2025-03-10 tags:outside,weekend uuid:a1
+x 2025-03-05 2025-02-01 File taxes uuid:b2
+Call plumber t:2025-03-20 uuid:e5`)
+	if len(result.Warnings) != 1 {
+		t.Errorf("warnings = %v, want one for the recurring template", result.Warnings)
+	}
+}
+
+func TestTodoist(t *testing.T) {
+	input := "\ufeffTYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+		"section,Errands,,,,,,,,\n" +
+		"task,Buy milk @store,,4,1,me,,2025-03-10,en,UTC\n" +
+		"note,remember oat milk,,,,,,,,\n" +
+		"task,Stretch,,1,1,me,,every day,en,UTC\n"
+	result := read(t, "todoist", input, Options{Project: "Home Life"})
+	check(t, "todoist", result.Todos, `(A) Buy milk +Home_Life @store due:2025-03-10
+Stretch +Home_Life todoist_date:every_day`)
+	if len(result.Warnings) != 1 {
+		t.Errorf("warnings = %v, want one for the recurring date", result.Warnings)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	input := "Task,Due Date,Owner,State\n" +
+		"Write report,03/10/2025,sam,open\n" +
+		"Book venue,2025-04-01,alex,done\n"
+	mapping, err := ParseMapping("description=Task,due=Due Date,label:owner=Owner,done=State")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := read(t, "csv", input, Options{Mapping: mapping})
+	check(t, "csv", result.Todos, `Write report owner:sam
+x Book venue due:2025-04-01 owner:alex`)
+	if len(result.Warnings) != 1 {
+		t.Errorf("warnings = %v, want one for 03/10/2025", result.Warnings)
+	}
+
+	if _, err := ParseMapping("title=Task"); err == nil {
+		t.Error("ParseMapping should reject unknown fields")
+	}
+	if _, err := Read(strings.NewReader("Name\nx\n"), "csv", Options{}); err == nil {
+		t.Error("csv without a description column should fail")
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	original := []*todo.Todo{
+		todo.FromString("(A) 2025-03-01 Call mom, soon +family @phone due:2025-03-10"),
+		todo.FromString("x 2025-03-05 2025-03-01 Pay rent +home"),
+	}
+	var out bytes.Buffer
+	if err := export.Write(&out, "csv", export.Data{Todos: original}, export.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	result := read(t, "csv", out.String(), Options{})
+	check(t, "csv round trip", result.Todos, lines(original))
+}
+
+func TestGitHub(t *testing.T) {
+	input := "Title,URL,Assignees,Status,Labels,Repository\n" +
+		"Fix login,https://github.com/acme/web/issues/4,sam,In Progress,\"bug, auth\",acme/web\n" +
+		"Ship v2,https://github.com/acme/web/issues/9,,Done,,acme/web\n"
+	result := read(t, "github", input, Options{})
+	check(t, "github", result.Todos, `Fix login repo:acme/web url:https://github.com/acme/web/issues/4 tags:bug,auth
+x Ship v2 repo:acme/web url:https://github.com/acme/web/issues/9`)
+}
+
+func TestMarkdown(t *testing.T) {
+	input := `# Todos
+
+## +family
+
+- [ ] (A) Call mom @phone due:2025-03-10
+- [x] Send card
+
+## Notes
+
+* [ ] x marks the spot
+- not a task
+  - [X] nested item
+`
+	result := read(t, "markdown", input, Options{})
+	check(t, "markdown", result.Todos, `(A) Call mom +family @phone due:2025-03-10
+x Send card +family
+x marks the spot
+x nested item`)
+}
+
+func TestDedupe(t *testing.T) {
+	existing := []*todo.Todo{
+		todo.FromString("Repot plants uuid:a1"),
+		todo.FromString("x 2025-03-01 Call  Mom"),
+		todo.FromString("x 2025-03-02 Pay rent todoist:9"),
+		todo.FromString("Write tests +alpha"),
+		todo.FromString("x 2025-03-03 Water lawn"),
+	}
+	incoming := []*todo.Todo{
+		todo.FromString("Repot the plants uuid:a1"),
+		todo.FromString("call mom"),
+		todo.FromString("Pay the rent todoist:9"),
+		todo.FromString("Write tests +beta"),
+		todo.FromString("write tests +Alpha"),
+		todo.FromString("x 2025-03-04 water  lawn"),
+		todo.FromString("Buy milk"),
+		todo.FromString("Buy milk @store"),
+	}
+
+	// an open todo isn't a done one come back unless its source says so, and
+	// the same title in another project is another todo
+	fresh, duplicates := Dedupe(existing, incoming)
+	check(t, "fresh", fresh, "call mom\nWrite tests +beta\nBuy milk")
+	check(t, "duplicates", duplicates, `Repot the plants uuid:a1
+Pay the rent todoist:9
+write tests +Alpha
+x 2025-03-04 water  lawn
+Buy milk @store`)
+}
+
+func TestLookup(t *testing.T) {
+	if got := strings.Join(Names(), ","); got != "csv,github,markdown,taskwarrior,todoist" {
+		t.Errorf("Names() = %s", got)
+	}
+	if _, err := Lookup("omnifocus"); err == nil {
+		t.Error("Lookup(omnifocus) should fail")
+	}
+}
@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("markdown", markdownAdapter{})
+}
+
+// markdownAdapter reads "- [ ]" and "- [x]" checklist items. Items under a
+// "## +project" or "## @context" heading get that tag, which is how
+// 'atp export markdown' groups them.
+type markdownAdapter struct{}
+
+var (
+	reChecklistItem = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	reHeading       = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	reItemPriority  = regexp.MustCompile(`^\(([A-Z])\)\s+`)
+)
+
+func (markdownAdapter) Read(r io.Reader, opts Options) (*Result, error) {
+	result := &Result{}
+	var section string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := reHeading.FindStringSubmatch(line); match != nil {
+			section = strings.TrimSpace(match[1])
+			continue
+		}
+		match := reChecklistItem.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		text := match[2]
+		priority := ""
+		if p := reItemPriority.FindStringSubmatch(text); p != nil {
+			priority = p[1]
+			text = text[len(p[0]):]
+		}
+
+		t := fromText(text)
+		if t.Description == "" {
+			continue
+		}
+		t.Priority = priority
+		if match[1] != " " {
+			complete(t, time.Time{})
+		}
+
+		if tag := Tag(section); tag != "" {
+			switch section[0] {
+			case '+':
+				addUnique(&t.Projects, tag)
+			case '@':
+				addUnique(&t.Contexts, tag)
+			}
+		}
+
+		result.Todos = append(result.Todos, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read markdown: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arjungandhi/atp/todo"
+)
+
+func init() {
+	Register("taskwarrior", taskwarriorAdapter{})
+}
+
+// taskwarriorAdapter reads the JSON array 'task export' prints
+type taskwarriorAdapter struct{}
+
+type taskwarriorTask struct {
+	UUID        string   `json:"uuid"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Entry       string   `json:"entry"`
+	End         string   `json:"end"`
+	Due         string   `json:"due"`
+	Wait        string   `json:"wait"`
+	Scheduled   string   `json:"scheduled"`
+	Project     string   `json:"project"`
+	Priority    string   `json:"priority"`
+	Tags        []string `json:"tags"`
+}
+
+// taskwarriorPriorities maps H, M and L to todo.txt priorities
+var taskwarriorPriorities = map[string]string{"H": "A", "M": "B", "L": "C"}
+
+func (taskwarriorAdapter) Read(r io.Reader, opts Options) (*Result, error) {
+	var tasks []taskwarriorTask
+	if err := json.NewDecoder(r).Decode(&tasks); err != nil {
+		return nil, fmt.Errorf("failed to read taskwarrior export: %w", err)
+	}
+
+	result := &Result{}
+	for _, task := range tasks {
+		switch task.Status {
+		case "deleted":
+			continue
+		case "recurring":
+			result.warn("skipped recurring template '%s', add it with 'atp todo recur edit'", task.Description)
+			continue
+		}
+
+		t := fromText(task.Description)
+		t.Priority = taskwarriorPriorities[task.Priority]
+		if task.UUID != "" {
+			t.Labels["uuid"] = task.UUID
+		}
+		if entry, ok := parseDate(task.Entry); ok {
+			t.CreationDate = day(entry)
+		}
+		if task.Status == "completed" {
+			end, _ := parseDate(task.End)
+			complete(t, end)
+		}
+
+		// projects are dotted hierarchies like home.garden
+		if tag := Tag(task.Project); tag != "" {
+			addUnique(&t.Projects, tag)
+		}
+		if len(task.Tags) > 0 {
+			tags := make([]string, 0, len(task.Tags))
+			for _, tag := range task.Tags {
+				tags = append(tags, Tag(tag))
+			}
+			t.Labels["tags"] = strings.Join(tags, ",")
+		}
+
+		setDate(result, t, "due", task.Due)
+		// waiting and scheduled tasks aren't actionable before that date
+		if task.Wait != "" {
+			setDate(result, t, "t", task.Wait)
+		} else {
+			setDate(result, t, "t", task.Scheduled)
+		}
+
+		result.Todos = append(result.Todos, t)
+	}
+	return result, nil
+}
+
+// setDate sets a date label, warning when the value isn't a date
+func setDate(result *Result, t *todo.Todo, label string, value string) {
+	if value == "" {
+		return
+	}
+	date, ok := parseDate(value)
+	if !ok {
+		result.warn("'%s': could not read %s date '%s'", t.Description, label, value)
+		return
+	}
+	t.Labels[label] = date.Format("2006-01-02")
+}
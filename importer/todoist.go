@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("todoist", todoistAdapter{})
+}
+
+// todoistAdapter reads the per project csv files of a Todoist backup. Only
+// open tasks are in a backup, sections and comments are skipped.
+type todoistAdapter struct{}
+
+// todoistPriorities maps Todoist's 4 (p1, highest) to 2 (p3), 1 is no priority
+var todoistPriorities = map[string]string{"4": "A", "3": "B", "2": "C"}
+
+func (todoistAdapter) Read(r io.Reader, opts Options) (*Result, error) {
+	rows, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, row := range rows {
+		if !strings.EqualFold(row.get("TYPE"), "task") {
+			continue
+		}
+
+		// labels are @words in the content, same as contexts
+		t := fromText(row.get("CONTENT"))
+		if t.Description == "" {
+			continue
+		}
+		t.Priority = todoistPriorities[row.get("PRIORITY")]
+		if id := row.get("ID"); id != "" {
+			t.Labels["todoist"] = id
+		}
+
+		if date := row.get("DATE"); date != "" {
+			if due, ok := parseDate(date); ok {
+				t.Labels["due"] = due.Format("2006-01-02")
+			} else {
+				// natural language dates like "every monday" are recurring
+				result.warn("'%s': could not read date '%s', kept as a todoist_date: label", t.Description, date)
+				t.Labels["todoist_date"] = labelValue(date)
+			}
+		}
+
+		result.Todos = append(result.Todos, t)
+	}
+	return result, nil
+}
+
+// row is a csv record addressed by header name
+type row struct {
+	header map[string]int
+	fields []string
+}
+
+func (r row) get(column string) string {
+	i, ok := r.header[strings.ToLower(strings.TrimSpace(column))]
+	if !ok || i >= len(r.fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.fields[i])
+}
+
+func readCSV(r io.Reader) ([]row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := make(map[string]int)
+	for i, name := range records[0] {
+		// excel likes to start files with a byte order mark
+		name = strings.TrimPrefix(name, "\ufeff")
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	rows := make([]row, 0, len(records)-1)
+	for _, record := range records[1:] {
+		rows = append(rows, row{header: header, fields: record})
+	}
+	return rows, nil
+}
@@ -0,0 +1,71 @@
+// Package lock serializes changes to the ATP directory between the daemon
+// and CLI invocations with an advisory file lock.
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned by TryAcquire when another process holds the lock
+var ErrLocked = errors.New("lock is held by another process")
+
+// pollInterval is how often Acquire retries a held lock
+const pollInterval = 50 * time.Millisecond
+
+// Lock is a held file lock
+type Lock struct {
+	file *os.File
+}
+
+// Path returns the lock guarding the todos and config in an ATP directory
+func Path(atpDir string) string {
+	return filepath.Join(atpDir, ".lock")
+}
+
+// TryAcquire takes the lock without waiting
+func TryAcquire(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := tryLock(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// Acquire waits up to timeout for the lock
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := TryAcquire(path)
+		if !errors.Is(err, ErrLocked) {
+			return l, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for %s: %w", timeout, path, err)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release gives up the lock
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	defer l.file.Close()
+
+	if err := unlock(l.file); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	l.file = nil
+	return nil
+}
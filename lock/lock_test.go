@@ -0,0 +1,57 @@
+//go:build unix
+
+package lock
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTryAcquire(t *testing.T) {
+	path := Path(t.TempDir())
+
+	held, err := TryAcquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := TryAcquire(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second TryAcquire() error = %v, want ErrLocked", err)
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := TryAcquire(path)
+	if err != nil {
+		t.Fatalf("TryAcquire() after release: %v", err)
+	}
+	again.Release()
+}
+
+func TestAcquireWaits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+
+	held, err := TryAcquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Acquire(path, 100*time.Millisecond); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire() on a held lock error = %v, want ErrLocked", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		held.Release()
+	}()
+
+	l, err := Acquire(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() after release: %v", err)
+	}
+	l.Release()
+}
@@ -0,0 +1,100 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/arjungandhi/atp/todo"
+)
+
+// jsonProject is the JSON form of a project. Dates are YYYY-MM-DD and the
+// text field holds the todo.txt line.
+type jsonProject struct {
+	Name           string            `json:"name"`
+	Phase          string            `json:"phase,omitempty"`
+	Active         bool              `json:"active"`
+	Done           bool              `json:"done"`
+	Killed         bool              `json:"killed"`
+	Repo           string            `json:"repo,omitempty"`
+	CompletionDate string            `json:"completion_date,omitempty"`
+	PhaseDates     map[string]string `json:"phase_dates,omitempty"`
+	Labels         map[string]string `json:"labels"`
+	Text           string            `json:"text"`
+}
+
+// MarshalJSON encodes the project with its labels and todo.txt line
+func (p *Project) MarshalJSON() ([]byte, error) {
+	t := p.ToTodo()
+	j := jsonProject{
+		Name:   p.Name,
+		Phase:  p.Phase,
+		Active: p.Active,
+		Done:   p.Done,
+		Killed: p.Killed(),
+		Repo:   t.Labels["repo"],
+		Labels: t.Labels,
+		Text:   t.String(),
+	}
+	if date := p.CompletionDate(); !date.IsZero() {
+		j.CompletionDate = date.Format("2006-01-02")
+	}
+	for phase, date := range p.PhaseDates() {
+		if j.PhaseDates == nil {
+			j.PhaseDates = make(map[string]string)
+		}
+		j.PhaseDates[phase] = date.Format("2006-01-02")
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a project. The text is parsed as a todo.txt line
+// and the fields, when a name is given, take precedence over it. Repos
+// aren't known here, the repo: label is kept as is.
+func (p *Project) UnmarshalJSON(data []byte) error {
+	var j jsonProject
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	if j.Name == "" && j.Text == "" {
+		return fmt.Errorf("a project needs a name or text")
+	}
+
+	// the line keeps what the fields don't have, like the creation date
+	t := todo.NewTodo()
+	if j.Text != "" {
+		t = todo.FromString(j.Text)
+	}
+	if j.Name != "" {
+		t.Description = j.Name
+		t.Labels = make(map[string]string)
+		for key, value := range j.Labels {
+			t.Labels[key] = value
+		}
+		delete(t.Labels, "phase")
+		if j.Phase != "" {
+			t.Labels["phase"] = j.Phase
+		}
+		t.Priority = ""
+		if j.Active {
+			t.Priority = "A"
+		}
+		t.Done = j.Done
+		t.CompletionDate = time.Time{}
+		if j.CompletionDate != "" {
+			date, err := time.Parse("2006-01-02", j.CompletionDate)
+			if err != nil {
+				return fmt.Errorf("invalid completion_date: %w", err)
+			}
+			t.CompletionDate = date
+		}
+	}
+
+	parsed, err := FromTodo(t, nil)
+	if err != nil {
+		return err
+	}
+	*p = *parsed
+	return nil
+}
@@ -0,0 +1,59 @@
+package project
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{
+			name: "text only",
+			json: `{"text": "(A) 2025-01-02 Garden phase:2 phase2:2025-03-01"}`,
+			want: "(A) 2025-01-02 Garden phase:2 phase2:2025-03-01",
+		},
+		{
+			name: "fields win over the text",
+			json: `{"name": "Garden", "phase": "3", "active": false, "labels": {"phase": "2", "phase2": "2025-03-01"}, "text": "(A) 2025-01-02 Garden phase:2 phase2:2025-03-01"}`,
+			want: "2025-01-02 Garden phase:3 phase2:2025-03-01",
+		},
+		{
+			name: "finished",
+			json: `{"name": "Boat", "done": true, "completion_date": "2025-03-12", "labels": {}}`,
+			want: "x 2025-03-12 Boat",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Project
+			if err := json.Unmarshal([]byte(tt.json), &p); err != nil {
+				t.Fatal(err)
+			}
+			if got := p.TodoString(); got != tt.want {
+				t.Errorf("decoded %q, want %q", got, tt.want)
+			}
+
+			data, err := json.Marshal(&p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var again Project
+			if err := json.Unmarshal(data, &again); err != nil {
+				t.Fatal(err)
+			}
+			if again.TodoString() != p.TodoString() {
+				t.Errorf("round trip %q, want %q", again.TodoString(), p.TodoString())
+			}
+		})
+	}
+
+	var p Project
+	if err := json.Unmarshal([]byte(`{}`), &p); err == nil {
+		t.Error("decoded a project without a name")
+	}
+}
@@ -2,8 +2,11 @@ package project
 
 import (
 	"fmt"
+	"regexp"
 	"slices"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/arjungandhi/atp/repo"
 	"github.com/arjungandhi/atp/todo"
@@ -91,6 +94,102 @@ func FromTodo(t *todo.Todo, repos []*repo.Repo) (*Project, error) {
 	return p, nil
 }
 
+// Matches reports whether a todo belongs to the project, either through a
+// +project tag matching the name (ignoring case, spaces and punctuation) or
+// a repo: label naming the project's repo
+func (p *Project) Matches(t *todo.Todo) bool {
+	name := normalizeName(p.Name)
+	for _, tag := range t.Projects {
+		if name != "" && normalizeName(tag) == name {
+			return true
+		}
+	}
+
+	if p.Repo != nil && t.Labels["repo"] != "" {
+		return strings.EqualFold(t.Labels["repo"], fmt.Sprintf("%s/%s", p.Repo.Owner, p.Repo.Name))
+	}
+
+	return false
+}
+
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// SetPhase moves the project to a phase and records the day it was entered
+// as a phase<name>: label, e.g. phase2:2025-03-01, so time spent per phase
+// can be measured. Entering a phase again keeps the first date.
+func (p *Project) SetPhase(phase string, date time.Time) {
+	if p.todo_data == nil {
+		p.todo_data = todo.NewTodo()
+	}
+	p.Phase = phase
+
+	key := "phase" + phase
+	if !rePhaseName.MatchString(phase) || p.todo_data.Labels[key] != "" {
+		return
+	}
+	p.todo_data.Labels[key] = date.Format("2006-01-02")
+}
+
+// rePhaseName matches phases that can be part of a label key
+var rePhaseName = regexp.MustCompile(`^\w+$`)
+
+// PhaseDates returns when each recorded phase was entered
+func (p *Project) PhaseDates() map[string]time.Time {
+	dates := make(map[string]time.Time)
+	if p.todo_data == nil {
+		return dates
+	}
+	for key, value := range p.todo_data.Labels {
+		phase, ok := strings.CutPrefix(key, "phase")
+		if !ok || phase == "" {
+			continue
+		}
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			dates[phase] = date
+		}
+	}
+	return dates
+}
+
+// Finish marks the project done on the given day
+func (p *Project) Finish(date time.Time) {
+	if p.todo_data == nil {
+		p.todo_data = todo.NewTodo()
+	}
+	p.Active = false
+	p.Done = true
+	p.todo_data.CompletionDate = date
+}
+
+// CompletionDate is when the project was finished or killed, zero when
+// unknown
+func (p *Project) CompletionDate() time.Time {
+	if p.todo_data == nil {
+		return time.Time{}
+	}
+	return p.todo_data.CompletionDate
+}
+
+// Kill gives up on the project. It moves to done.txt with a killed: label so
+// it isn't counted as finished.
+func (p *Project) Kill(date time.Time) {
+	p.Finish(date)
+	p.todo_data.Labels["killed"] = date.Format("2006-01-02")
+}
+
+// Killed reports whether the project was given up on rather than finished
+func (p *Project) Killed() bool {
+	return p.todo_data != nil && p.todo_data.Labels["killed"] != ""
+}
+
 // convert a project to a string
 func (p *Project) String() string {
 	// convert the project to a todo
@@ -3,6 +3,7 @@ package project
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/arjungandhi/atp/repo"
 	"github.com/arjungandhi/atp/todo"
@@ -201,3 +202,68 @@ func TestWriteProjectFile(t *testing.T) {
 		t.Errorf("Expected todo description 'Test Project', got %s", todos[0].Description)
 	}
 }
+
+func TestMatches(t *testing.T) {
+	p := &Project{Name: "Garden Planner", Repo: repo.NewRepo("me", "garden", "", "")}
+
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"Buy seeds +GardenPlanner", true},
+		{"Buy seeds +garden_planner", true},
+		{"Fix bug repo:me/garden", true},
+		{"Fix bug repo:me/other", false},
+		{"Buy seeds +Garden", false},
+		{"Buy seeds", false},
+	}
+
+	for _, test := range tests {
+		if got := p.Matches(todo.FromString(test.line)); got != test.want {
+			t.Errorf("Matches(%q) = %v, want %v", test.line, got, test.want)
+		}
+	}
+}
+
+func TestRollup(t *testing.T) {
+	p := &Project{Name: "Garden Planner", Repo: repo.NewRepo("me", "garden", "", "")}
+
+	var todos []*todo.Todo
+	for _, line := range []string{
+		"x Buy seeds +GardenPlanner",
+		"Fix bug repo:me/garden",
+		"Plan beds +GardenPlanner id:1",
+		"x Measure parent:1",
+		"Sketch parent:1",
+		"Call bank",
+	} {
+		todos = append(todos, todo.FromString(line))
+	}
+
+	// Plan beds counts through its two subtasks
+	subtasks := todo.NewSubtasks(todos)
+	s := Rollup(p, todos, subtasks)
+	if s.Done != 2 || s.Total != 4 || s.Percent() != 50 {
+		t.Errorf("Rollup() = %d/%d (%d%%), want 2/4 (50%%)", s.Done, s.Total, s.Percent())
+	}
+
+	if s := Rollup(&Project{Name: "Boat"}, todos, subtasks); s.Total != 0 || s.Percent() != 0 {
+		t.Errorf("Rollup(Boat) = %d/%d (%d%%)", s.Done, s.Total, s.Percent())
+	}
+}
+
+func TestKill(t *testing.T) {
+	p, err := FromTodo(todo.FromString("(A) Learn Piano phase:1"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Kill(time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC))
+
+	if p.Active || !p.Done || !p.Killed() {
+		t.Errorf("after Kill: active %v, done %v, killed %v", p.Active, p.Done, p.Killed())
+	}
+	if got, want := p.TodoString(), "x 2025-03-14 Learn Piano killed:2025-03-14 phase:1"; got != want {
+		t.Errorf("TodoString() = %q, want %q", got, want)
+	}
+}
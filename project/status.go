@@ -0,0 +1,53 @@
+package project
+
+import (
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Status is how far along a project is by its todos
+type Status struct {
+	Project *Project
+	Done    int
+	Total   int
+}
+
+// Rollup counts the done and all todos of a project. Subtasks of its todos
+// belong to it too, tagged or not, and a todo with subtasks counts through
+// them so splitting a step up doesn't count it twice. subtasks indexes the
+// todos, see todo.NewSubtasks.
+func Rollup(p *Project, todos []*todo.Todo, subtasks *todo.Subtasks) Status {
+	s := Status{Project: p}
+	seen := make(map[*todo.Todo]bool)
+	count := func(t *todo.Todo) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		if len(subtasks.Children(t)) > 0 {
+			return
+		}
+		s.Total++
+		if t.Done {
+			s.Done++
+		}
+	}
+
+	for _, t := range todos {
+		if !p.Matches(t) {
+			continue
+		}
+		count(t)
+		for _, sub := range subtasks.Descendants(t) {
+			count(sub)
+		}
+	}
+	return s
+}
+
+// Percent is the share of done todos, 0 for a project without todos
+func (s Status) Percent() int {
+	if s.Total == 0 {
+		return 0
+	}
+	return s.Done * 100 / s.Total
+}
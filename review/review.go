@@ -0,0 +1,236 @@
+// Package review gathers what a GTD style weekly review looks at: what got
+// done, what went stale, which projects are stuck and what is coming up.
+package review
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Defaults for Options
+const (
+	DefaultStaleDays = 14
+	DefaultHorizon   = 7
+)
+
+// Project decisions made during a review
+const (
+	DecisionKeep       = "keep"
+	DecisionDeactivate = "deactivate"
+	DecisionKill       = "kill"
+)
+
+// Options tune what the review considers
+type Options struct {
+	// StaleDays is how long an open todo can go without activity
+	StaleDays int
+	// Horizon is how many days ahead reminders and recurring todos are shown
+	Horizon int
+}
+
+// Occurrence is a recurring task and the days it comes up within the horizon
+type Occurrence struct {
+	Task  *todo.RecurringTask
+	Dates []time.Time
+}
+
+// Decision records what was decided for a project
+type Decision struct {
+	Project string
+	Action  string
+}
+
+// Weekly is the material for one weekly review
+type Weekly struct {
+	Date    time.Time
+	Start   time.Time
+	Options Options
+
+	Completed    []*todo.Todo
+	Stale        []*todo.Todo
+	NoNextAction []*project.Project
+	// Inactive are active projects nothing was completed for this week
+	Inactive  []*project.Project
+	Reminders []*todo.Todo
+	Recurring []Occurrence
+	Decisions []Decision
+}
+
+// NewWeekly prepares the review of the week ending on date
+func NewWeekly(date time.Time, opts Options, todos []*todo.Todo, reminders []*todo.Todo, recurring []*todo.RecurringTask, projects []*project.Project) *Weekly {
+	if opts.StaleDays <= 0 {
+		opts.StaleDays = DefaultStaleDays
+	}
+	if opts.Horizon <= 0 {
+		opts.Horizon = DefaultHorizon
+	}
+
+	day := startOfDay(date)
+	w := &Weekly{
+		Date:    date,
+		Start:   day.AddDate(0, 0, -6),
+		Options: opts,
+	}
+
+	staleBefore := day.AddDate(0, 0, -opts.StaleDays)
+	for _, t := range todos {
+		switch {
+		case t.Done && !t.CompletionDate.Before(w.Start) && !t.CompletionDate.After(date):
+			w.Completed = append(w.Completed, t)
+		case !t.Done:
+			if last := lastActivity(t); !last.IsZero() && last.Before(staleBefore) {
+				w.Stale = append(w.Stale, t)
+			}
+		}
+	}
+	sort.SliceStable(w.Stale, func(i, j int) bool {
+		return lastActivity(w.Stale[i]).Before(lastActivity(w.Stale[j]))
+	})
+
+	for _, p := range projects {
+		if p.Done || !p.Active {
+			continue
+		}
+		if !hasTodo(p, todos, func(t *todo.Todo) bool { return !t.Done }) {
+			w.NoNextAction = append(w.NoNextAction, p)
+		}
+		if !hasTodo(p, w.Completed, func(t *todo.Todo) bool { return true }) {
+			w.Inactive = append(w.Inactive, p)
+		}
+	}
+
+	end := day.AddDate(0, 0, opts.Horizon)
+	for _, r := range reminders {
+		if remind, err := time.ParseInLocation("2006-01-02", r.Labels["remind"], date.Location()); err == nil && remind.Before(end) {
+			w.Reminders = append(w.Reminders, r)
+		}
+	}
+	todo.SortRemindersByDate(w.Reminders)
+
+	for _, task := range recurring {
+		occurrence := Occurrence{Task: task}
+		for d := day.AddDate(0, 0, 1); d.Before(end); d = d.AddDate(0, 0, 1) {
+			if task.ShouldGenerateForDate(d) {
+				occurrence.Dates = append(occurrence.Dates, d)
+			}
+		}
+		if len(occurrence.Dates) > 0 {
+			w.Recurring = append(w.Recurring, occurrence)
+		}
+	}
+
+	return w
+}
+
+// Decide records a decision for the summary
+func (w *Weekly) Decide(p *project.Project, action string) {
+	w.Decisions = append(w.Decisions, Decision{Project: p.Name, Action: action})
+}
+
+// lastActivity is the latest date known for a todo: its creation date or
+// the day a recurring todo was generated
+func lastActivity(t *todo.Todo) time.Time {
+	last := t.CreationDate
+	if recur, err := time.Parse("2006-01-02", t.Labels["recur"]); err == nil && recur.After(last) {
+		last = recur
+	}
+	return last
+}
+
+func hasTodo(p *project.Project, todos []*todo.Todo, keep func(*todo.Todo) bool) bool {
+	for _, t := range todos {
+		if keep(t) && p.Matches(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Markdown renders the review summary
+func (w *Weekly) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Weekly review %s\n\n", w.Date.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Week of %s to %s\n", w.Start.Format("2006-01-02"), w.Date.Format("2006-01-02"))
+
+	section := func(title string, lines []string) {
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", title, len(lines))
+		if len(lines) == 0 {
+			b.WriteString("None\n")
+			return
+		}
+		for _, line := range lines {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+	}
+
+	section("Completed", todoLines(w.Completed))
+	section(fmt.Sprintf("Stale, no activity for %d days", w.Options.StaleDays), todoLines(w.Stale))
+	section("Active projects without a next action", projectLines(w.NoNextAction))
+
+	var decisions []string
+	for _, d := range w.Decisions {
+		decisions = append(decisions, fmt.Sprintf("%s: %s", d.Project, d.Action))
+	}
+	section("Project decisions", decisions)
+
+	section(fmt.Sprintf("Reminders in the next %d days", w.Options.Horizon), todoLines(w.Reminders))
+
+	var recurring []string
+	for _, o := range w.Recurring {
+		var dates []string
+		for _, d := range o.Dates {
+			dates = append(dates, d.Format("Mon 01-02"))
+		}
+		recurring = append(recurring, fmt.Sprintf("%s (%s)", o.Task.Todo.Description, strings.Join(dates, ", ")))
+	}
+	section(fmt.Sprintf("Recurring in the next %d days", w.Options.Horizon), recurring)
+
+	return b.String()
+}
+
+func todoLines(todos []*todo.Todo) []string {
+	var lines []string
+	for _, t := range todos {
+		lines = append(lines, t.String())
+	}
+	return lines
+}
+
+func projectLines(projects []*project.Project) []string {
+	var lines []string
+	for _, p := range projects {
+		lines = append(lines, p.TodoString())
+	}
+	return lines
+}
+
+// Dir is where review summaries are kept
+func Dir(atpDir string) string {
+	return filepath.Join(atpDir, "reviews")
+}
+
+// Write saves the summary as reviews/<date>-weekly.md and returns its path
+func (w *Weekly) Write(atpDir string) (string, error) {
+	dir := Dir(atpDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create reviews directory: %w", err)
+	}
+
+	path := filepath.Join(dir, w.Date.Format("2006-01-02")+"-weekly.md")
+	if err := os.WriteFile(path, []byte(w.Markdown()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write review: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,112 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func descriptions(todos []*todo.Todo) []string {
+	var names []string
+	for _, t := range todos {
+		names = append(names, t.Description)
+	}
+	return names
+}
+
+func projectNames(projects []*project.Project) []string {
+	var names []string
+	for _, p := range projects {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestNewWeekly(t *testing.T) {
+	date := time.Date(2025, 3, 14, 18, 0, 0, 0, time.UTC)
+
+	todos := []*todo.Todo{
+		todo.FromString("x 2025-03-12 2025-03-01 Ship beta +garden_planner"),
+		todo.FromString("x 2025-03-01 2025-02-20 Old win +garden_planner"),
+		todo.FromString("2025-02-01 Fix fence"),
+		todo.FromString("2025-03-10 Buy seeds +GardenPlanner"),
+		todo.FromString("Water plants recur:2025-02-01"),
+		todo.FromString("No date at all"),
+	}
+	reminders := []*todo.Todo{
+		todo.FromString("Renew passport remind:2025-03-18"),
+		todo.FromString("Cancel trial remind:2025-05-01"),
+	}
+	weekly, err := todo.RecurringTaskFromString("@weekly Plan week")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projects := []*project.Project{}
+	for _, line := range []string{"(A) Garden Planner", "(A) Learn Piano", "Someday Boat", "x Finished Thing"} {
+		p, err := project.FromTodo(todo.FromString(line), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		projects = append(projects, p)
+	}
+
+	w := NewWeekly(date, Options{}, todos, reminders, []*todo.RecurringTask{weekly}, projects)
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"Completed", descriptions(w.Completed), []string{"Ship beta"}},
+		{"Stale", descriptions(w.Stale), []string{"Fix fence", "Water plants"}},
+		{"NoNextAction", projectNames(w.NoNextAction), []string{"Learn Piano"}},
+		{"Inactive", projectNames(w.Inactive), []string{"Learn Piano"}},
+		{"Reminders", descriptions(w.Reminders), []string{"Renew passport"}},
+	}
+	for _, test := range tests {
+		if strings.Join(test.got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+
+	// the week after Friday the 14th has one Monday
+	if len(w.Recurring) != 1 || len(w.Recurring[0].Dates) != 1 || w.Recurring[0].Dates[0].Weekday() != time.Monday {
+		t.Errorf("Recurring = %+v, want one Monday", w.Recurring)
+	}
+}
+
+func TestWeeklyWrite(t *testing.T) {
+	atpDir := t.TempDir()
+	date := time.Date(2025, 3, 14, 18, 0, 0, 0, time.UTC)
+
+	p, err := project.FromTodo(todo.FromString("(A) Learn Piano"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWeekly(date, Options{}, nil, nil, nil, []*project.Project{p})
+	w.Decide(p, DecisionKill)
+
+	path, err := w.Write(atpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(atpDir, "reviews", "2025-03-14-weekly.md") {
+		t.Errorf("path = %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Weekly review 2025-03-14", "## Project decisions (1)", "- Learn Piano: kill", "## Completed (0)"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("summary is missing %q:\n%s", want, data)
+		}
+	}
+}
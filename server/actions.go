@@ -0,0 +1,107 @@
+package server
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+
+	"github.com/arjungandhi/atp/export"
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+//go:embed schema/*.json
+var schemas embed.FS
+
+func (srv *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	f, err := filter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	dir := srv.Store.ProjectDir()
+	if ok, err := read(w, r, project.ActiveFilePath(dir), project.DoneFilePath(dir)); !ok {
+		if err != nil {
+			writeError(w, err)
+		}
+		return
+	}
+	projects, err := srv.Store.Projects()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]*project.Project{"projects": export.FilterProjects(f, projects)})
+}
+
+// recur generates today's recurring todos and answers with the new ones
+func (srv *Server) recur(w http.ResponseWriter, r *http.Request) {
+	var added []*todo.Todo
+	err := srv.changeTodos(w, r, func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		var err error
+		added, err = todo.GenerateTodosFromRecurring(srv.Store.TodoDir(), srv.today(), srv.Contexts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recurring todos: %w", err)
+		}
+		return append(todos, added...), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]Item{"todos": newItems(added)})
+}
+
+// remind turns due reminders into todos and answers with the new todos
+func (srv *Server) remind(w http.ResponseWriter, r *http.Request) {
+	var activated []*todo.Todo
+	err := srv.write(w, r, srv.todoFiles(), func() error {
+		var err error
+		activated, err = todo.ActivateReminders(srv.Store.TodoDir(), srv.today())
+		if err != nil {
+			return fmt.Errorf("failed to process reminders: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]Item{"todos": newItems(activated)})
+}
+
+func (srv *Server) sync(w http.ResponseWriter, r *http.Request) {
+	if srv.Sync == nil {
+		writeError(w, httpError(http.StatusNotImplemented, "sync isn't available on this server"))
+		return
+	}
+	// the sync takes the lock itself, only while it reads and writes files,
+	// so other clients aren't blocked while it waits on the network
+	if err := srv.Sync(r.Context(), srv.lock); err != nil {
+		writeError(w, httpError(http.StatusBadGateway, "sync failed: %v", err))
+		return
+	}
+	tag, err := ETag(srv.todoFiles()...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", tag)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "synced"})
+}
+
+// schema serves the JSON schemas of the todo and project objects
+func (srv *Server) schema(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name != "todo" && name != "project" {
+		writeError(w, httpError(http.StatusNotFound, "no schema named %s (todo or project)", name))
+		return
+	}
+	data, err := schemas.ReadFile("schema/" + name + ".json")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(data)
+}
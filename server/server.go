@@ -0,0 +1,243 @@
+// Package server exposes an ATP directory as a JSON API over HTTP, for
+// phone shortcuts, status bar widgets and editor plugins.
+//
+// Every response for a file backed resource carries an ETag computed from
+// the files it was read from. Sending it back in If-Match makes a change
+// fail with 412 Precondition Failed when the files were changed in the
+// meantime, so clients never overwrite edits they haven't seen.
+package server
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/events"
+	"github.com/arjungandhi/atp/hooks"
+	"github.com/arjungandhi/atp/store"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// maxBody limits request bodies, a todo is a single line
+const maxBody = 1 << 20
+
+// ErrPrecondition is returned when If-Match doesn't match the current files
+var ErrPrecondition = errors.New("the files changed since they were read, reload and try again")
+
+// Server handles the API requests for a store
+type Server struct {
+	Store *store.Store
+	// Token has to be sent as "Authorization: Bearer <token>", an empty
+	// token turns authentication off
+	Token string
+	// Sync runs the configured GitHub and CalDAV syncs for POST /sync. It
+	// is called without the ATP directory lock, lock takes it and records
+	// events for the reads and writes that need it. Nil answers 501.
+	Sync func(ctx context.Context, lock func(fn func() error) error) error
+	// Events feeds GET /events, nil answers 501
+	Events *events.Bus
+	// Contexts hold back recurring templates until their context is open,
+	// nil generates them on schedule
+	Contexts todo.Availability
+	// Now is replaced in tests
+	Now func() time.Time
+
+	mux *http.ServeMux
+}
+
+// New returns a server for the store
+func New(s *store.Store, token string) *Server {
+	srv := &Server{Store: s, Token: token, Now: time.Now, mux: http.NewServeMux()}
+
+	srv.mux.HandleFunc("GET /todos", srv.listTodos)
+	srv.mux.HandleFunc("POST /todos", srv.addTodo)
+	srv.mux.HandleFunc("GET /todos/{id}", srv.getTodo)
+	srv.mux.HandleFunc("PUT /todos/{id}", srv.updateTodo)
+	srv.mux.HandleFunc("DELETE /todos/{id}", srv.deleteTodo)
+	srv.mux.HandleFunc("POST /todos/{id}/complete", srv.completeTodo)
+
+	srv.mux.HandleFunc("GET /reminders", srv.listReminders)
+	srv.mux.HandleFunc("POST /reminders", srv.addReminder)
+	srv.mux.HandleFunc("DELETE /reminders/{id}", srv.deleteReminder)
+
+	srv.mux.HandleFunc("GET /projects", srv.listProjects)
+
+	srv.mux.HandleFunc("POST /recur", srv.recur)
+	srv.mux.HandleFunc("POST /remind", srv.remind)
+	srv.mux.HandleFunc("POST /sync", srv.sync)
+
+	srv.mux.HandleFunc("GET /events", srv.stream)
+	srv.mux.HandleFunc("GET /schema/{name}", srv.schema)
+
+	return srv
+}
+
+// ServeHTTP checks the token and dispatches the request
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if srv.Token != "" && !srv.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="atp"`)
+		writeError(w, httpError(http.StatusUnauthorized, "missing or invalid token"))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+	srv.mux.ServeHTTP(w, r)
+}
+
+func (srv *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(srv.Token)) == 1
+}
+
+// today is the current day like the CLI commands record it
+func (srv *Server) today() time.Time {
+	now := srv.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ------------------------------- Concurrency -------------------------------
+
+// ETag returns a strong entity tag for the contents of the files, missing
+// files count as empty
+func ETag(paths ...string) (string, error) {
+	hash := sha1.New()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		hash.Write(data)
+		hash.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil))[:16] + `"`, nil
+}
+
+// read answers 304 Not Modified when If-None-Match has the current ETag of
+// the files and sets the ETag header otherwise. It reports whether the
+// request still needs a body.
+func read(w http.ResponseWriter, r *http.Request, paths ...string) (bool, error) {
+	tag, err := ETag(paths...)
+	if err != nil {
+		return false, err
+	}
+	w.Header().Set("ETag", tag)
+	if matchETag(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return false, nil
+	}
+	return true, nil
+}
+
+// write runs fn with the ATP directory locked after checking If-Match
+// against the files, and sets the ETag header to their new contents
+func (srv *Server) write(w http.ResponseWriter, r *http.Request, paths []string, fn func() error) error {
+	err := srv.lock(func() error {
+		if match := r.Header.Get("If-Match"); match != "" {
+			tag, err := ETag(paths...)
+			if err != nil {
+				return err
+			}
+			if !matchETag(match, tag) {
+				return ErrPrecondition
+			}
+		}
+		return fn()
+	})
+	if err != nil {
+		return err
+	}
+
+	tag, err := ETag(paths...)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", tag)
+	return nil
+}
+
+// lock runs fn with the ATP directory locked and records events for what
+// it changed
+func (srv *Server) lock(fn func() error) error {
+	l, err := srv.Store.Lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	return events.Record(srv.Store.AtpDir, fn)
+}
+
+// matchETag checks a comma separated If-Match or If-None-Match header
+func matchETag(header string, tag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.TrimPrefix(value, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ------------------------------- Responses -------------------------------
+
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+func httpError(code int, format string, args ...any) error {
+	return &statusError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var status *statusError
+	var tooLarge *http.MaxBytesError
+	var veto *hooks.VetoError
+	switch {
+	case errors.As(err, &status):
+		code = status.code
+	case errors.As(err, &tooLarge):
+		code = http.StatusRequestEntityTooLarge
+	case errors.Is(err, store.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrPrecondition):
+		code = http.StatusPreconditionFailed
+	case errors.As(err, &veto):
+		code = http.StatusConflict
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// decode reads a JSON request body into v
+func decode(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return err
+		}
+		if errors.Is(err, io.EOF) {
+			return httpError(http.StatusBadRequest, "missing request body")
+		}
+		return httpError(http.StatusBadRequest, "invalid JSON: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,433 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/events"
+	"github.com/arjungandhi/atp/hooks"
+	"github.com/arjungandhi/atp/lock"
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/store"
+	"github.com/arjungandhi/atp/todo"
+)
+
+type testEnv struct {
+	t   *testing.T
+	srv *Server
+	url string
+	dir string // the todo directory
+}
+
+func newTestEnv(t *testing.T, todos string) *testEnv {
+	t.Helper()
+	s := store.New(t.TempDir(), nil)
+	os.MkdirAll(s.TodoDir(), 0755)
+	os.WriteFile(todo.ActiveTodoPath(s.TodoDir()), []byte(todos), 0644)
+
+	srv := New(s, "secret")
+	srv.Now = func() time.Time { return time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC) }
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	return &testEnv{t: t, srv: srv, url: ts.URL, dir: s.TodoDir()}
+}
+
+// do sends a request with the token and decodes a JSON response into out
+func (e *testEnv) do(method string, path string, body string, header map[string]string, out any) *http.Response {
+	e.t.Helper()
+	req, err := http.NewRequest(method, e.url+path, strings.NewReader(body))
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			e.t.Fatalf("%s %s: %v in %s", method, path, err, data)
+		}
+	}
+	return resp
+}
+
+func (e *testEnv) file(name string) string {
+	e.t.Helper()
+	data, _ := os.ReadFile(filepath.Join(e.dir, name))
+	return strings.TrimSpace(string(data))
+}
+
+type itemList struct {
+	Todos     []Item `json:"todos"`
+	Reminders []Item `json:"reminders"`
+}
+
+func lines(items []Item) []string {
+	var out []string
+	for _, item := range items {
+		out = append(out, item.Todo.String())
+	}
+	return out
+}
+
+func TestAuth(t *testing.T) {
+	e := newTestEnv(t, "")
+
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req, _ := http.NewRequest("GET", e.url+"/todos", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status %d, want 401", header, resp.StatusCode)
+		}
+	}
+
+	if resp := e.do("GET", "/todos", "", nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("valid token: status %d", resp.StatusCode)
+	}
+}
+
+func TestTodos(t *testing.T) {
+	e := newTestEnv(t, "(A) Call mom @phone\nFix bike +garage\n")
+
+	var added Item
+	resp := e.do("POST", "/todos", `{"text": "Water plants +garden due:2025-03-14"}`, nil, &added)
+	if resp.StatusCode != http.StatusCreated || added.Todo.Labels["due"] != "2025-03-14" {
+		t.Fatalf("add: status %d, %+v", resp.StatusCode, added.Todo)
+	}
+
+	var list itemList
+	e.do("GET", "/todos?q=%2Bgarden+due:*", "", nil, &list)
+	if got := lines(list.Todos); len(got) != 1 || got[0] != "Water plants +garden due:2025-03-14" {
+		t.Errorf("filtered list = %v", got)
+	}
+
+	// update with separate fields
+	var updated Item
+	body := `{"description": "Water all plants", "projects": ["garden"], "labels": {"due": "2025-03-15"}}`
+	if resp := e.do("PUT", "/todos/"+added.ID, body, nil, &updated); resp.StatusCode != http.StatusOK {
+		t.Fatalf("update: status %d", resp.StatusCode)
+	}
+	if updated.ID == added.ID || updated.Todo.String() != "Water all plants +garden due:2025-03-15" {
+		t.Errorf("update = %+v", updated)
+	}
+
+	// the old ID is gone
+	if resp := e.do("GET", "/todos/"+added.ID, "", nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("stale id: status %d", resp.StatusCode)
+	}
+
+	var completed Item
+	e.do("POST", "/todos/"+updated.ID+"/complete", "", nil, &completed)
+	if !completed.Todo.Done {
+		t.Errorf("complete = %+v", completed.Todo)
+	}
+	if got := e.file("done.txt"); got != "x 2025-03-12 Water all plants +garden due:2025-03-15" {
+		t.Errorf("done.txt = %q", got)
+	}
+
+	e.do("GET", "/todos", "", nil, &list)
+	if len(list.Todos) != 2 {
+		t.Fatalf("open todos = %v", lines(list.Todos))
+	}
+	if resp := e.do("DELETE", "/todos/"+list.Todos[1].ID, "", nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: status %d", resp.StatusCode)
+	}
+	if got := e.file("todo.txt"); got != "(A) Call mom @phone" {
+		t.Errorf("todo.txt = %q", got)
+	}
+}
+
+func TestUpdateAnswersWrittenTodo(t *testing.T) {
+	e := newTestEnv(t, "Fix bike\n")
+	hook := filepath.Join(e.srv.Store.AtpDir, hooks.Dir, hooks.OnModify)
+	os.MkdirAll(filepath.Dir(hook), 0755)
+	os.WriteFile(hook, []byte("#!/bin/sh\ncat > /dev/null; echo '{\"text\":\"Fix bike +garage\"}'\n"), 0755)
+
+	// the answer is the todo as the hook rewrote it, so its id works
+	var updated Item
+	id := ID(todo.FromString("Fix bike"))
+	if resp := e.do("PUT", "/todos/"+id, `{"text": "(A) Fix bike"}`, nil, &updated); resp.StatusCode != http.StatusOK {
+		t.Fatalf("update: status %d", resp.StatusCode)
+	}
+	if updated.Todo.String() != "Fix bike +garage" {
+		t.Errorf("update = %q, want the hook's todo", updated.Todo.String())
+	}
+	if resp := e.do("GET", "/todos/"+updated.ID, "", nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("returned id: status %d", resp.StatusCode)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	e := newTestEnv(t, "")
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"POST", "/todos", ``, http.StatusBadRequest},
+		{"POST", "/todos", `{"text": ""}`, http.StatusBadRequest},
+		{"POST", "/todos", `{"text": `, http.StatusBadRequest},
+		{"POST", "/todos", `{"description": "x", "creation_date": "yesterday"}`, http.StatusBadRequest},
+		{"GET", "/todos?q=--bogus", ``, http.StatusBadRequest},
+		{"PUT", "/todos/nope", `{"text": "x"}`, http.StatusNotFound},
+		{"POST", "/reminders", `{"text": "Dentist"}`, http.StatusBadRequest},
+		{"GET", "/schema/nope", ``, http.StatusNotFound},
+		{"PATCH", "/todos", ``, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		resp := e.do(tt.method, tt.path, tt.body, nil, nil)
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s %s: status %d, want %d", tt.method, tt.path, tt.body, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	e := newTestEnv(t, "Fix bike\n")
+
+	var list itemList
+	resp := e.do("GET", "/todos", "", nil, &list)
+	tag := resp.Header.Get("ETag")
+	if tag == "" {
+		t.Fatal("no ETag")
+	}
+
+	if resp := e.do("GET", "/todos", "", map[string]string{"If-None-Match": tag}, nil); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d", resp.StatusCode)
+	}
+
+	// someone edits todo.txt in the meantime
+	os.WriteFile(filepath.Join(e.dir, "todo.txt"), []byte("Fix bike\nCall mom\n"), 0644)
+
+	resp = e.do("POST", "/todos/"+list.Todos[0].ID+"/complete", "", map[string]string{"If-Match": tag}, nil)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("stale If-Match: status %d", resp.StatusCode)
+	}
+	if got := e.file("todo.txt"); got != "Fix bike\nCall mom" {
+		t.Errorf("todo.txt changed by a stale write: %q", got)
+	}
+
+	resp = e.do("GET", "/todos", "", nil, nil)
+	fresh := resp.Header.Get("ETag")
+	if fresh == tag {
+		t.Fatal("ETag didn't change")
+	}
+	resp = e.do("POST", "/todos/"+list.Todos[0].ID+"/complete", "", map[string]string{"If-Match": fresh}, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("current If-Match: status %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("ETag"); got == "" || got == fresh {
+		t.Errorf("ETag after the change = %q", got)
+	}
+}
+
+func TestReminders(t *testing.T) {
+	e := newTestEnv(t, "")
+
+	e.do("POST", "/reminders", `{"text": "Dentist remind:2025-03-20"}`, nil, nil)
+	e.do("POST", "/reminders", `{"text": "Taxes remind:2025-03-10"}`, nil, nil)
+
+	var list itemList
+	e.do("GET", "/reminders", "", nil, &list)
+	if got := lines(list.Reminders); len(got) != 2 || got[0] != "Taxes remind:2025-03-10" {
+		t.Errorf("reminders = %v", got)
+	}
+
+	var activated itemList
+	e.do("POST", "/remind", "", nil, &activated)
+	if got := lines(activated.Todos); len(got) != 1 || got[0] != "2025-03-12 Taxes" {
+		t.Errorf("activated = %v", got)
+	}
+	if got := e.file("reminders.txt"); got != "Dentist remind:2025-03-20" {
+		t.Errorf("reminders.txt = %q", got)
+	}
+
+	if resp := e.do("DELETE", "/reminders/"+list.Reminders[1].ID, "", nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: status %d", resp.StatusCode)
+	}
+	if got := e.file("reminders.txt"); got != "" {
+		t.Errorf("reminders.txt = %q", got)
+	}
+}
+
+func TestRecur(t *testing.T) {
+	e := newTestEnv(t, "")
+	os.WriteFile(todo.RecurringTasksPath(e.dir), []byte("@daily Stretch +health\n"), 0644)
+
+	var added itemList
+	e.do("POST", "/recur", "", nil, &added)
+	if len(added.Todos) != 1 || added.Todos[0].Todo.Description != "Stretch" {
+		t.Fatalf("recur = %v", lines(added.Todos))
+	}
+
+	// a second run doesn't duplicate it
+	e.do("POST", "/recur", "", nil, &added)
+	if len(added.Todos) != 0 {
+		t.Errorf("second recur = %v", lines(added.Todos))
+	}
+	if got := e.file("todo.txt"); strings.Count(got, "Stretch") != 1 {
+		t.Errorf("todo.txt = %q", got)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	e := newTestEnv(t, "")
+	dir := e.srv.Store.ProjectDir()
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(project.ActiveFilePath(dir), []byte("(A) Garden phase:2 phase2:2025-03-01\nBoat\n"), 0644)
+
+	var list struct {
+		Projects []map[string]any `json:"projects"`
+	}
+	e.do("GET", "/projects?q=(A)", "", nil, &list)
+	if len(list.Projects) != 1 {
+		t.Fatalf("projects = %v", list.Projects)
+	}
+	garden := list.Projects[0]
+	if garden["name"] != "Garden" || garden["active"] != true || garden["phase"] != "2" {
+		t.Errorf("project = %v", garden)
+	}
+	if dates, _ := garden["phase_dates"].(map[string]any); dates["2"] != "2025-03-01" {
+		t.Errorf("phase_dates = %v", garden["phase_dates"])
+	}
+}
+
+// TestSchema checks that every field the API sends is in the schemas
+func TestSchema(t *testing.T) {
+	e := newTestEnv(t, "")
+
+	check := func(name string, value any) {
+		var schema struct {
+			Properties map[string]any `json:"properties"`
+		}
+		resp := e.do("GET", "/schema/"+name, "", nil, &schema)
+		if resp.Header.Get("Content-Type") != "application/schema+json" {
+			t.Errorf("%s schema content type = %q", name, resp.Header.Get("Content-Type"))
+		}
+
+		data, _ := json.Marshal(value)
+		var fields map[string]any
+		json.Unmarshal(data, &fields)
+		for field := range fields {
+			if _, ok := schema.Properties[field]; !ok {
+				t.Errorf("%s schema is missing %s", name, field)
+			}
+		}
+	}
+
+	check("todo", todo.FromString("x 2025-03-12 (A) 2025-03-01 Fix bike +garage @home due:2025-03-14"))
+	p, err := project.FromTodo(todo.FromString("x 2025-03-12 Garden phase:2 phase2:2025-03-01 killed:2025-03-12"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("project", p)
+}
+
+func TestSync(t *testing.T) {
+	e := newTestEnv(t, "")
+
+	if resp := e.do("POST", "/sync", "", nil, nil); resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("without sync: status %d", resp.StatusCode)
+	}
+
+	calls := 0
+	e.srv.Sync = func(ctx context.Context, withLock func(fn func() error) error) error {
+		calls++
+		if calls > 1 {
+			return errors.New("github is down")
+		}
+		// the network part of a sync runs without the lock
+		l, err := lock.TryAcquire(lock.Path(e.srv.Store.AtpDir))
+		if err != nil {
+			t.Errorf("sync holds the lock: %v", err)
+			return nil
+		}
+		l.Release()
+		return withLock(func() error { return nil })
+	}
+	if resp := e.do("POST", "/sync", "", nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("sync: status %d", resp.StatusCode)
+	}
+	var failed map[string]string
+	if resp := e.do("POST", "/sync", "", nil, &failed); resp.StatusCode != http.StatusBadGateway || failed["error"] != "sync failed: github is down" {
+		t.Errorf("failed sync: status %d, %v", resp.StatusCode, failed)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	e := newTestEnv(t, "")
+
+	if resp := e.do("GET", "/events", "", nil, nil); resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("without a bus: status %d", resp.StatusCode)
+	}
+
+	e.srv.Events = events.NewBus()
+	req, _ := http.NewRequest("GET", e.url+"/events?types=todo.added", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("content type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("first line = %q", line)
+	}
+	reader.ReadString('\n')
+
+	// changes made through the API are logged, the log feeds the bus
+	e.do("POST", "/todos", `{"text": "Fix bike"}`, nil, nil)
+	logged, _, err := events.Read(e.srv.Store.AtpDir, 0)
+	if err != nil || len(logged) != 1 {
+		t.Fatalf("logged events = %v, %v", logged, err)
+	}
+	e.srv.Events.Publish(events.Synced("github", time.Now()))
+	e.srv.Events.Publish(logged[0])
+
+	var got []string
+	for len(got) < 4 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, strings.TrimSuffix(line, "\n"))
+	}
+	if got[0] != "id: "+logged[0].ID || got[1] != "event: todo.added" || !strings.HasPrefix(got[2], "data: {") || got[3] != "" {
+		t.Errorf("stream = %q", got)
+	}
+	var sent events.Event
+	json.Unmarshal([]byte(strings.TrimPrefix(got[2], "data: ")), &sent)
+	if sent.Todo == nil || sent.Todo.String() != "Fix bike" {
+		t.Errorf("event data = %s", got[2])
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/events"
+)
+
+// heartbeat keeps idle event streams from being closed by proxies
+const heartbeat = 30 * time.Second
+
+// stream sends events as server-sent events. ?types=todo.added,... limits
+// the stream to those event types.
+func (srv *Server) stream(w http.ResponseWriter, r *http.Request) {
+	if srv.Events == nil {
+		writeError(w, httpError(http.StatusNotImplemented, "events aren't available on this server"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, httpError(http.StatusInternalServerError, "streaming isn't supported"))
+		return
+	}
+
+	types := make(map[events.Type]bool)
+	for _, typ := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if typ = strings.TrimSpace(typ); typ != "" {
+			types[events.Type(typ)] = true
+		}
+	}
+
+	ch, unsubscribe := srv.Events.Subscribe(64)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case e := <-ch:
+			if len(types) > 0 && !types[e.Type] {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+		}
+		flusher.Flush()
+	}
+}
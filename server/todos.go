@@ -0,0 +1,296 @@
+package server
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/hooks"
+	"github.com/arjungandhi/atp/store"
+	"github.com/arjungandhi/atp/timelog"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Item is a todo or reminder in a response
+type Item struct {
+	// ID is derived from the todo.txt line, so it changes whenever the todo
+	// does and a stale ID answers 404
+	ID   string     `json:"id"`
+	Todo *todo.Todo `json:"todo"`
+}
+
+// ID identifies a todo by its todo.txt line. Identical lines share an ID,
+// which is fine since they can't be told apart anyway.
+func ID(t *todo.Todo) string {
+	sum := sha1.Sum([]byte(t.String()))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func newItems(todos []*todo.Todo) []Item {
+	items := make([]Item, 0, len(todos))
+	for _, t := range todos {
+		items = append(items, Item{ID: ID(t), Todo: t})
+	}
+	return items
+}
+
+func find(todos []*todo.Todo, id string) (*todo.Todo, error) {
+	for _, t := range todos {
+		if ID(t) == id {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("todo %s %w", id, store.ErrNotFound)
+}
+
+func (srv *Server) todoFiles() []string {
+	dir := srv.Store.TodoDir()
+	return []string{todo.ActiveTodoPath(dir), todo.DoneTodoPath(dir)}
+}
+
+func (srv *Server) reminderFiles() []string {
+	return []string{todo.ReminderTasksPath(srv.Store.TodoDir())}
+}
+
+// changeTodos applies fn to the todos under the lock and writes them back
+func (srv *Server) changeTodos(w http.ResponseWriter, r *http.Request, fn func(todos []*todo.Todo) ([]*todo.Todo, error)) error {
+	return srv.write(w, r, srv.todoFiles(), func() error {
+		todos, err := srv.Store.Todos()
+		if err != nil {
+			return err
+		}
+		before := store.Clone(todos)
+		if todos, err = fn(todos); err != nil {
+			return err
+		}
+		if err := hooks.New(srv.Store.AtpDir).Todos(before, todos); err != nil {
+			return err
+		}
+		if err := timelog.StopDone(srv.Store.AtpDir, todos, time.Now()); err != nil {
+			return err
+		}
+		if err := todo.WriteTodoDir(srv.Store.TodoDir(), todos); err != nil {
+			return fmt.Errorf("failed to write todos: %w", err)
+		}
+		return nil
+	})
+}
+
+// changeReminders is changeTodos for reminders.txt
+func (srv *Server) changeReminders(w http.ResponseWriter, r *http.Request, fn func(reminders []*todo.Todo) ([]*todo.Todo, error)) error {
+	return srv.write(w, r, srv.reminderFiles(), func() error {
+		reminders, err := srv.Store.Reminders()
+		if err != nil {
+			return err
+		}
+		if reminders, err = fn(reminders); err != nil {
+			return err
+		}
+		if err := todo.WriteReminderTasks(todo.ReminderTasksPath(srv.Store.TodoDir()), reminders); err != nil {
+			return fmt.Errorf("failed to write reminders: %w", err)
+		}
+		return nil
+	})
+}
+
+// filter parses the q parameter, it takes the same arguments as atp todo list
+func filter(r *http.Request) (todo.Filter, error) {
+	f, err := todo.ParseFilter(strings.Fields(r.URL.Query().Get("q")))
+	if err != nil {
+		return f, httpError(http.StatusBadRequest, "invalid filter: %v", err)
+	}
+	return f, nil
+}
+
+// decodeTodo reads a todo from the body, either its fields or {"text": line}
+func decodeTodo(r *http.Request) (*todo.Todo, error) {
+	t := todo.NewTodo()
+	if err := decode(r, t); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(t.Description) == "" {
+		return nil, httpError(http.StatusBadRequest, "a todo needs a description")
+	}
+	return t, nil
+}
+
+// ------------------------------- Todos -------------------------------
+
+func (srv *Server) listTodos(w http.ResponseWriter, r *http.Request) {
+	f, err := filter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// the ETag is taken before loading so it is never newer than the body
+	if ok, err := read(w, r, srv.todoFiles()...); !ok {
+		if err != nil {
+			writeError(w, err)
+		}
+		return
+	}
+	todos, err := srv.Store.Todos()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]Item{"todos": newItems(f.Apply(todos))})
+}
+
+func (srv *Server) getTodo(w http.ResponseWriter, r *http.Request) {
+	if ok, err := read(w, r, srv.todoFiles()...); !ok {
+		if err != nil {
+			writeError(w, err)
+		}
+		return
+	}
+	todos, err := srv.Store.Todos()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	t, err := find(todos, r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Item{ID: ID(t), Todo: t})
+}
+
+func (srv *Server) addTodo(w http.ResponseWriter, r *http.Request) {
+	t, err := decodeTodo(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = srv.changeTodos(w, r, func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		return append(todos, t), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, Item{ID: ID(t), Todo: t})
+}
+
+func (srv *Server) updateTodo(w http.ResponseWriter, r *http.Request) {
+	updated, err := decodeTodo(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// answer with the todo as it was written, writing may change it
+	var written *todo.Todo
+	err = srv.changeTodos(w, r, func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		t, err := find(todos, r.PathValue("id"))
+		if err != nil {
+			return nil, err
+		}
+		*t = *updated
+		written = t
+		return todos, nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Item{ID: ID(written), Todo: written})
+}
+
+func (srv *Server) completeTodo(w http.ResponseWriter, r *http.Request) {
+	var completed *todo.Todo
+	err := srv.changeTodos(w, r, func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		t, err := find(todos, r.PathValue("id"))
+		if err != nil {
+			return nil, err
+		}
+		if !t.Done {
+			t.Done = true
+			t.CompletionDate = srv.today()
+		}
+		completed = t
+		return todos, nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Item{ID: ID(completed), Todo: completed})
+}
+
+func (srv *Server) deleteTodo(w http.ResponseWriter, r *http.Request) {
+	err := srv.changeTodos(w, r, func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		t, err := find(todos, r.PathValue("id"))
+		if err != nil {
+			return nil, err
+		}
+		return store.Remove(todos, t), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ------------------------------- Reminders -------------------------------
+
+func (srv *Server) listReminders(w http.ResponseWriter, r *http.Request) {
+	f, err := filter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if ok, err := read(w, r, srv.reminderFiles()...); !ok {
+		if err != nil {
+			writeError(w, err)
+		}
+		return
+	}
+	reminders, err := srv.Store.Reminders()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	reminders = f.Apply(reminders)
+	todo.SortRemindersByDate(reminders)
+	writeJSON(w, http.StatusOK, map[string][]Item{"reminders": newItems(reminders)})
+}
+
+func (srv *Server) addReminder(w http.ResponseWriter, r *http.Request) {
+	t, err := decodeTodo(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, err := time.Parse("2006-01-02", t.Labels["remind"]); err != nil {
+		writeError(w, httpError(http.StatusBadRequest, "a reminder needs a remind:YYYY-MM-DD label"))
+		return
+	}
+	err = srv.changeReminders(w, r, func(reminders []*todo.Todo) ([]*todo.Todo, error) {
+		return append(reminders, t), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, Item{ID: ID(t), Todo: t})
+}
+
+func (srv *Server) deleteReminder(w http.ResponseWriter, r *http.Request) {
+	err := srv.changeReminders(w, r, func(reminders []*todo.Todo) ([]*todo.Todo, error) {
+		t, err := find(reminders, r.PathValue("id"))
+		if err != nil {
+			return nil, err
+		}
+		return store.Remove(reminders, t), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// barWidth is the length of the longest bar in text charts
+const barWidth = 30
+
+// WriteText prints the report as tables with bar charts
+func (r *Report) WriteText(w io.Writer) {
+	chart(w, "Completed per day", r.PerDay)
+	chart(w, "Completed per week (starting)", r.PerWeek)
+	chart(w, "Completed per month", r.PerMonth)
+
+	fmt.Fprintf(w, "\nLead time (%d todos, mean %.1f days, median %.1f, p90 %.1f)\n",
+		r.LeadTime.Todos, r.LeadTime.MeanDays, r.LeadTime.MedianDays, r.LeadTime.P90Days)
+	bars(w, r.LeadTime.Buckets)
+
+	chart(w, "Completed by project", r.ByProject)
+	chart(w, "Completed by context", r.ByContext)
+
+	fmt.Fprintf(w, "\nRecurring adherence: %d of %d done (%.0f%%)\n",
+		r.Recurring.Completed, r.Recurring.Generated, r.Recurring.Rate*100)
+	for _, a := range r.RecurringByTask {
+		fmt.Fprintf(w, "  %-30s %3d/%-3d %4.0f%%\n", truncate(a.Task, 30), a.Completed, a.Generated, a.Rate*100)
+	}
+
+	chart(w, fmt.Sprintf("Projects finished per quarter (%d killed)", r.ProjectsKilled), r.ProjectsPerQuarter)
+
+	fmt.Fprintf(w, "\nAverage time per phase\n")
+	if len(r.Phases) == 0 {
+		fmt.Fprintf(w, "  no phase history yet\n")
+	}
+	for _, p := range r.Phases {
+		fmt.Fprintf(w, "  phase %-10s %6.1f days (%d projects)\n", p.Phase, p.AverageDays, p.Projects)
+	}
+}
+
+func chart(w io.Writer, title string, counts []Count) {
+	fmt.Fprintf(w, "\n%s\n", title)
+	if len(counts) == 0 {
+		fmt.Fprintf(w, "  none\n")
+		return
+	}
+	bars(w, counts)
+}
+
+func bars(w io.Writer, counts []Count) {
+	max, width := 0, 0
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+		if len(c.Label) > width {
+			width = len(c.Label)
+		}
+	}
+
+	for _, c := range counts {
+		length := 0
+		if max > 0 {
+			length = (c.Count*barWidth + max - 1) / max
+		}
+		line := fmt.Sprintf("  %-*s %4d %s", width, c.Label, c.Count, strings.Repeat("█", length))
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "…"
+}
+
+// WriteCSV writes the report as metric,label,value rows, one table that is
+// easy to filter in a spreadsheet or plotting tool
+func (r *Report) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	rows := [][]string{{"metric", "label", "value"}}
+
+	counts := func(metric string, counts []Count) {
+		for _, c := range counts {
+			rows = append(rows, []string{metric, c.Label, strconv.Itoa(c.Count)})
+		}
+	}
+	number := func(metric string, label string, v float64) {
+		rows = append(rows, []string{metric, label, strconv.FormatFloat(v, 'f', -1, 64)})
+	}
+
+	counts("completed_per_day", r.PerDay)
+	counts("completed_per_week", r.PerWeek)
+	counts("completed_per_month", r.PerMonth)
+	counts("lead_time", r.LeadTime.Buckets)
+	number("lead_time_days", "mean", r.LeadTime.MeanDays)
+	number("lead_time_days", "median", r.LeadTime.MedianDays)
+	number("lead_time_days", "p90", r.LeadTime.P90Days)
+	counts("completed_by_project", r.ByProject)
+	counts("completed_by_context", r.ByContext)
+	number("recurring_adherence", "all", r.Recurring.Rate)
+	for _, a := range r.RecurringByTask {
+		number("recurring_adherence", a.Task, a.Rate)
+	}
+	counts("projects_finished_per_quarter", r.ProjectsPerQuarter)
+	number("projects_killed", "all", float64(r.ProjectsKilled))
+	for _, p := range r.Phases {
+		number("phase_average_days", p.Phase, p.AverageDays)
+	}
+
+	if err := out.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+// Package stats computes productivity reports from completed todos and
+// projects.
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Default number of periods shown in the completion histograms
+const (
+	DefaultDays   = 14
+	DefaultWeeks  = 12
+	DefaultMonths = 12
+)
+
+// Options select how far back the completion histograms go
+type Options struct {
+	Days   int
+	Weeks  int
+	Months int
+}
+
+// Count is a number of items for a label, e.g. a day or a project
+type Count struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// LeadTime is the distribution of days from creation to completion
+type LeadTime struct {
+	Buckets    []Count `json:"buckets"`
+	Todos      int     `json:"todos"`
+	MeanDays   float64 `json:"mean_days"`
+	MedianDays float64 `json:"median_days"`
+	P90Days    float64 `json:"p90_days"`
+}
+
+// Adherence is how many generated recurring todos were completed
+type Adherence struct {
+	Task      string  `json:"task,omitempty"`
+	Generated int     `json:"generated"`
+	Completed int     `json:"completed"`
+	Rate      float64 `json:"rate"`
+}
+
+// PhaseTime is the average time projects spent in a phase
+type PhaseTime struct {
+	Phase       string  `json:"phase"`
+	Projects    int     `json:"projects"`
+	AverageDays float64 `json:"average_days"`
+}
+
+// Report holds all statistics
+type Report struct {
+	Generated time.Time `json:"generated"`
+
+	PerDay   []Count `json:"completed_per_day"`
+	PerWeek  []Count `json:"completed_per_week"`
+	PerMonth []Count `json:"completed_per_month"`
+
+	LeadTime  LeadTime `json:"lead_time"`
+	ByProject []Count  `json:"completed_by_project"`
+	ByContext []Count  `json:"completed_by_context"`
+
+	Recurring       Adherence   `json:"recurring"`
+	RecurringByTask []Adherence `json:"recurring_by_task"`
+
+	ProjectsPerQuarter []Count     `json:"projects_finished_per_quarter"`
+	ProjectsKilled     int         `json:"projects_killed"`
+	Phases             []PhaseTime `json:"time_per_phase"`
+}
+
+// leadTimeBuckets are the upper bounds, in days, of the lead time histogram
+var leadTimeBuckets = []struct {
+	label string
+	max   float64
+}{
+	{"same day", 1},
+	{"1-3 days", 4},
+	{"4-7 days", 8},
+	{"1-2 weeks", 15},
+	{"2-4 weeks", 29},
+	{"1-3 months", 91},
+	{"longer", math.Inf(1)},
+}
+
+// Compute builds the report as of now
+func Compute(todos []*todo.Todo, projects []*project.Project, now time.Time, opts Options) *Report {
+	if opts.Days <= 0 {
+		opts.Days = DefaultDays
+	}
+	if opts.Weeks <= 0 {
+		opts.Weeks = DefaultWeeks
+	}
+	if opts.Months <= 0 {
+		opts.Months = DefaultMonths
+	}
+
+	r := &Report{Generated: now}
+	r.completions(todos, now, opts)
+	r.leadTime(todos)
+	r.recurring(todos, now)
+	r.projects(projects, now)
+	return r
+}
+
+func (r *Report) completions(todos []*todo.Todo, now time.Time, opts Options) {
+	today := startOfDay(now)
+	week := startOfWeek(today)
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+
+	days := make([]Count, opts.Days)
+	for i := range days {
+		days[i].Label = today.AddDate(0, 0, i-opts.Days+1).Format("2006-01-02")
+	}
+	weeks := make([]Count, opts.Weeks)
+	for i := range weeks {
+		weeks[i].Label = week.AddDate(0, 0, 7*(i-opts.Weeks+1)).Format("2006-01-02")
+	}
+	months := make([]Count, opts.Months)
+	for i := range months {
+		months[i].Label = month.AddDate(0, i-opts.Months+1, 0).Format("2006-01")
+	}
+
+	byProject := make(map[string]int)
+	byContext := make(map[string]int)
+	for _, t := range todos {
+		if !t.Done || t.CompletionDate.IsZero() {
+			continue
+		}
+		done := startOfDay(t.CompletionDate.In(now.Location()))
+
+		if i := opts.Days - 1 - daysBetween(done, today); i >= 0 && i < opts.Days {
+			days[i].Count++
+		}
+		if i := opts.Weeks - 1 - daysBetween(startOfWeek(done), week)/7; i >= 0 && i < opts.Weeks {
+			weeks[i].Count++
+		}
+		if i := opts.Months - 1 - monthsBetween(done, month); i >= 0 && i < opts.Months {
+			months[i].Count++
+		}
+
+		for _, p := range t.Projects {
+			byProject["+"+p]++
+		}
+		for _, c := range t.Contexts {
+			byContext["@"+c]++
+		}
+	}
+
+	r.PerDay, r.PerWeek, r.PerMonth = days, weeks, months
+	r.ByProject = sortedCounts(byProject)
+	r.ByContext = sortedCounts(byContext)
+}
+
+func (r *Report) leadTime(todos []*todo.Todo) {
+	var days []float64
+	for _, t := range todos {
+		if !t.Done || t.CreationDate.IsZero() || t.CompletionDate.IsZero() || t.CompletionDate.Before(t.CreationDate) {
+			continue
+		}
+		days = append(days, t.CompletionDate.Sub(t.CreationDate).Hours()/24)
+	}
+
+	lt := LeadTime{Todos: len(days)}
+	for _, bucket := range leadTimeBuckets {
+		lt.Buckets = append(lt.Buckets, Count{Label: bucket.label})
+	}
+	if len(days) == 0 {
+		r.LeadTime = lt
+		return
+	}
+
+	sort.Float64s(days)
+	total := 0.0
+	for _, d := range days {
+		total += d
+		for i, bucket := range leadTimeBuckets {
+			if d < bucket.max {
+				lt.Buckets[i].Count++
+				break
+			}
+		}
+	}
+	lt.MeanDays = round(total / float64(len(days)))
+	lt.MedianDays = round(percentile(days, 0.5))
+	lt.P90Days = round(percentile(days, 0.9))
+	r.LeadTime = lt
+}
+
+// recurring measures how many generated recurring todos got done. Todos
+// generated today are left out, there is still time to do them.
+func (r *Report) recurring(todos []*todo.Todo, now time.Time) {
+	today := now.Format("2006-01-02")
+	byTask := make(map[string]*Adherence)
+
+	for _, t := range todos {
+		generated := t.Labels["recur"]
+		if generated == "" || generated >= today {
+			continue
+		}
+		a, ok := byTask[t.Description]
+		if !ok {
+			a = &Adherence{Task: t.Description}
+			byTask[t.Description] = a
+		}
+		a.Generated++
+		r.Recurring.Generated++
+		if t.Done {
+			a.Completed++
+			r.Recurring.Completed++
+		}
+	}
+
+	r.Recurring.Rate = rate(r.Recurring.Completed, r.Recurring.Generated)
+	for _, a := range byTask {
+		a.Rate = rate(a.Completed, a.Generated)
+		r.RecurringByTask = append(r.RecurringByTask, *a)
+	}
+	sort.Slice(r.RecurringByTask, func(i, j int) bool {
+		a, b := r.RecurringByTask[i], r.RecurringByTask[j]
+		if a.Rate != b.Rate {
+			return a.Rate < b.Rate
+		}
+		return a.Task < b.Task
+	})
+}
+
+func (r *Report) projects(projects []*project.Project, now time.Time) {
+	perQuarter := make(map[string]int)
+	phaseDays := make(map[string][]float64)
+
+	for _, p := range projects {
+		if p.Killed() {
+			r.ProjectsKilled++
+		} else if p.Done {
+			quarter := "unknown"
+			if done := p.CompletionDate(); !done.IsZero() {
+				quarter = fmt.Sprintf("%d-Q%d", done.Year(), (int(done.Month())-1)/3+1)
+			}
+			perQuarter[quarter]++
+		}
+
+		// a phase lasts until the next one starts, the project ends or now
+		type entered struct {
+			phase string
+			date  time.Time
+		}
+		var phases []entered
+		for phase, date := range p.PhaseDates() {
+			phases = append(phases, entered{phase, date})
+		}
+		sort.Slice(phases, func(i, j int) bool { return phases[i].date.Before(phases[j].date) })
+
+		for i, e := range phases {
+			end := now
+			switch {
+			case i+1 < len(phases):
+				end = phases[i+1].date
+			case p.Done && !p.CompletionDate().IsZero():
+				end = p.CompletionDate()
+			case p.Done:
+				continue
+			}
+			phaseDays[e.phase] = append(phaseDays[e.phase], end.Sub(e.date).Hours()/24)
+		}
+	}
+
+	var quarters []string
+	for quarter := range perQuarter {
+		quarters = append(quarters, quarter)
+	}
+	sort.Strings(quarters)
+	for _, quarter := range quarters {
+		r.ProjectsPerQuarter = append(r.ProjectsPerQuarter, Count{Label: quarter, Count: perQuarter[quarter]})
+	}
+
+	for phase, days := range phaseDays {
+		total := 0.0
+		for _, d := range days {
+			total += d
+		}
+		r.Phases = append(r.Phases, PhaseTime{Phase: phase, Projects: len(days), AverageDays: round(total / float64(len(days)))})
+	}
+	sort.Slice(r.Phases, func(i, j int) bool { return r.Phases[i].Phase < r.Phases[j].Phase })
+}
+
+// ------------------------------- Helpers -------------------------------
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek is the monday of the day's week
+func startOfWeek(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// daysBetween counts calendar days, rounding away DST shifts
+func daysBetween(from time.Time, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+func monthsBetween(from time.Time, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+func sortedCounts(counts map[string]int) []Count {
+	var result []Count
+	for label, count := range counts {
+		result = append(result, Count{Label: label, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return strings.ToLower(result[i].Label) < strings.ToLower(result[j].Label)
+	})
+	return result
+}
+
+// percentile of sorted values, interpolating between neighbours
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+func rate(completed int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round(float64(completed) / float64(total))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
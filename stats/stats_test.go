@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func TestCompute(t *testing.T) {
+	// a wednesday
+	now := time.Date(2025, 3, 12, 15, 0, 0, 0, time.UTC)
+
+	todos := []*todo.Todo{
+		todo.FromString("x 2025-03-12 2025-03-12 Same day +work @desk"),
+		todo.FromString("x 2025-03-10 2025-03-01 Monday +work"),
+		todo.FromString("x 2025-03-09 2025-02-01 Last sunday +home @desk"),
+		todo.FromString("x 2025-01-15 Old one"),
+		todo.FromString("Still open 2025-03-01"),
+		todo.FromString("x 2025-03-10 Water plants recur:2025-03-10"),
+		todo.FromString("Water plants recur:2025-03-11"),
+		todo.FromString("Water plants recur:2025-03-12"),
+	}
+
+	finished, _ := project.FromTodo(todo.FromString("Garden phase:2"), nil)
+	finished.SetPhase("1", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	finished.SetPhase("2", time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC))
+	finished.Finish(time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC))
+
+	killed, _ := project.FromTodo(todo.FromString("Boat"), nil)
+	killed.SetPhase("1", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	killed.Kill(time.Date(2025, 2, 21, 0, 0, 0, 0, time.UTC))
+
+	r := Compute(todos, []*project.Project{finished, killed}, now, Options{Days: 3, Weeks: 2, Months: 3})
+
+	counts := func(counts []Count) string {
+		var parts []string
+		for _, c := range counts {
+			parts = append(parts, c.Label+"="+strings.Repeat("#", c.Count))
+		}
+		return strings.Join(parts, " ")
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"PerDay", counts(r.PerDay), "2025-03-10=## 2025-03-11= 2025-03-12=#"},
+		{"PerWeek", counts(r.PerWeek), "2025-03-03=# 2025-03-10=###"},
+		{"PerMonth", counts(r.PerMonth), "2025-01=# 2025-02= 2025-03=####"},
+		{"ByProject", counts(r.ByProject), "+work=## +home=#"},
+		{"ByContext", counts(r.ByContext), "@desk=##"},
+		{"LeadTime", counts(r.LeadTime.Buckets), "same day=# 1-3 days= 4-7 days= 1-2 weeks=# 2-4 weeks= 1-3 months=# longer="},
+		{"ProjectsPerQuarter", counts(r.ProjectsPerQuarter), "2025-Q1=#"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s = %s, want %s", test.name, test.got, test.want)
+		}
+	}
+
+	if r.LeadTime.MedianDays != 9 {
+		t.Errorf("median lead time = %v, want 9", r.LeadTime.MedianDays)
+	}
+
+	// the one generated today doesn't count yet
+	if r.Recurring.Generated != 2 || r.Recurring.Completed != 1 || r.Recurring.Rate != 0.5 {
+		t.Errorf("Recurring = %+v, want 1 of 2", r.Recurring)
+	}
+
+	if r.ProjectsKilled != 1 {
+		t.Errorf("ProjectsKilled = %d, want 1", r.ProjectsKilled)
+	}
+	phases := make(map[string]PhaseTime)
+	for _, p := range r.Phases {
+		phases[p.Phase] = p
+	}
+	if phases["1"].AverageDays != 15 || phases["1"].Projects != 2 || phases["2"].AverageDays != 30 {
+		t.Errorf("Phases = %+v, want phase 1 averaging 15 days and phase 2 30", r.Phases)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	now := time.Date(2025, 3, 12, 15, 0, 0, 0, time.UTC)
+	r := Compute([]*todo.Todo{todo.FromString("x 2025-03-12 2025-03-11 Done +work")}, nil, now, Options{Days: 1, Weeks: 1, Months: 1})
+
+	var out bytes.Buffer
+	if err := r.WriteCSV(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"metric,label,value\n", "completed_per_day,2025-03-12,1\n", "completed_by_project,+work,1\n", "lead_time_days,mean,1\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("csv is missing %q:\n%s", want, out.String())
+		}
+	}
+}
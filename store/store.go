@@ -0,0 +1,227 @@
+// Package store reads and changes the todo, reminder and project files of
+// an ATP directory. Every change takes the directory lock and reloads the
+// files first, so long running front ends like the TUI don't overwrite what
+// the CLI or the daemon wrote in the meantime. Changes are recorded in the
+// event log.
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arjungandhi/atp/events"
+	"github.com/arjungandhi/atp/hooks"
+	"github.com/arjungandhi/atp/lock"
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/repo"
+	"github.com/arjungandhi/atp/timelog"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// DefaultLockTimeout is how long a change waits for another writer
+const DefaultLockTimeout = 2 * time.Minute
+
+// ErrNotFound is returned when the todo or project a change is for is gone,
+// usually because someone else edited it since it was loaded
+var ErrNotFound = errors.New("not found, it was changed or removed in the meantime")
+
+// Store is an ATP directory
+type Store struct {
+	AtpDir string
+	// Repos link projects to their repo: labels, nil is fine
+	Repos       []*repo.Repo
+	LockTimeout time.Duration
+}
+
+// New returns a store for the ATP directory
+func New(atpDir string, repos []*repo.Repo) *Store {
+	return &Store{AtpDir: atpDir, Repos: repos, LockTimeout: DefaultLockTimeout}
+}
+
+// TodoDir holds todo.txt, done.txt, reminders.txt and recurring.txt
+func (s *Store) TodoDir() string {
+	return filepath.Join(s.AtpDir, "todo")
+}
+
+// ProjectDir holds the project files
+func (s *Store) ProjectDir() string {
+	return filepath.Join(s.AtpDir, "project")
+}
+
+// Todos loads the active and done todos
+func (s *Store) Todos() ([]*todo.Todo, error) {
+	dir := s.TodoDir()
+	if err := ensure(todo.ActiveTodoPath(dir), todo.DoneTodoPath(dir)); err != nil {
+		return nil, err
+	}
+	todos, err := todo.LoadTodoDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+	return todos, nil
+}
+
+// Reminders loads the pending reminders
+func (s *Store) Reminders() ([]*todo.Todo, error) {
+	reminders, err := todo.LoadReminderTasks(todo.ReminderTasksPath(s.TodoDir()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// Projects loads the active and done projects
+func (s *Store) Projects() ([]*project.Project, error) {
+	dir := s.ProjectDir()
+	if err := ensure(project.ActiveFilePath(dir), project.DoneFilePath(dir)); err != nil {
+		return nil, err
+	}
+	projects, err := project.LoadProjectsDir(dir, s.Repos)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+	return projects, nil
+}
+
+// Lock takes the ATP directory lock, waiting up to LockTimeout
+func (s *Store) Lock() (*lock.Lock, error) {
+	timeout := s.LockTimeout
+	if timeout == 0 {
+		timeout = DefaultLockTimeout
+	}
+	l, err := lock.Acquire(lock.Path(s.AtpDir), timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", s.AtpDir, err)
+	}
+	return l, nil
+}
+
+// UpdateTodos applies fn to freshly loaded todos under the lock and writes
+// what it returns after running the hooks. Nothing is written when fn fails
+// or a hook vetoes the change.
+func (s *Store) UpdateTodos(fn func(todos []*todo.Todo) ([]*todo.Todo, error)) error {
+	return s.locked(func() error {
+		todos, err := s.Todos()
+		if err != nil {
+			return err
+		}
+		before := Clone(todos)
+		if todos, err = fn(todos); err != nil {
+			return err
+		}
+		if err := hooks.New(s.AtpDir).Todos(before, todos); err != nil {
+			return err
+		}
+		if err := timelog.StopDone(s.AtpDir, todos, time.Now()); err != nil {
+			return err
+		}
+		if err := todo.WriteTodoDir(s.TodoDir(), todos); err != nil {
+			return fmt.Errorf("failed to write todos: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateReminders is UpdateTodos for reminders.txt
+func (s *Store) UpdateReminders(fn func(reminders []*todo.Todo) ([]*todo.Todo, error)) error {
+	return s.locked(func() error {
+		reminders, err := s.Reminders()
+		if err != nil {
+			return err
+		}
+		if reminders, err = fn(reminders); err != nil {
+			return err
+		}
+		return todo.WriteReminderTasks(todo.ReminderTasksPath(s.TodoDir()), reminders)
+	})
+}
+
+// UpdateProjects is UpdateTodos for the project files
+func (s *Store) UpdateProjects(fn func(projects []*project.Project) ([]*project.Project, error)) error {
+	return s.locked(func() error {
+		projects, err := s.Projects()
+		if err != nil {
+			return err
+		}
+		// fn changes the projects in place, hooks compare with the files
+		before, err := s.Projects()
+		if err != nil {
+			return err
+		}
+		if projects, err = fn(projects); err != nil {
+			return err
+		}
+		if err := hooks.New(s.AtpDir).Projects(before, projects); err != nil {
+			return err
+		}
+		return project.WriteProjectsDir(s.ProjectDir(), projects)
+	})
+}
+
+func (s *Store) locked(fn func() error) error {
+	l, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	return events.Record(s.AtpDir, fn)
+}
+
+// Find returns the todo with the todo.txt line, or ErrNotFound
+func Find(todos []*todo.Todo, line string) (*todo.Todo, error) {
+	for _, t := range todos {
+		if t.String() == line {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("todo '%s' %w", line, ErrNotFound)
+}
+
+// FindProject returns the project with the todo.txt line, or ErrNotFound
+func FindProject(projects []*project.Project, line string) (*project.Project, error) {
+	for _, p := range projects {
+		if p.TodoString() == line {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("project '%s' %w", line, ErrNotFound)
+}
+
+// Clone copies todos so they can be compared after being changed in place
+func Clone(todos []*todo.Todo) []*todo.Todo {
+	clones := make([]*todo.Todo, len(todos))
+	for i, t := range todos {
+		clones[i] = t.Clone()
+	}
+	return clones
+}
+
+// Remove returns the todos without t
+func Remove(todos []*todo.Todo, t *todo.Todo) []*todo.Todo {
+	kept := make([]*todo.Todo, 0, len(todos))
+	for _, other := range todos {
+		if other != t {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+// ensure creates missing files so a fresh ATP directory loads as empty
+func ensure(paths ...string) error {
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/hooks"
+	"github.com/arjungandhi/atp/lock"
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+func TestEmptyDir(t *testing.T) {
+	s := New(t.TempDir(), nil)
+	todos, err := s.Todos()
+	if err != nil || len(todos) != 0 {
+		t.Errorf("Todos() = %v, %v", todos, err)
+	}
+	projects, err := s.Projects()
+	if err != nil || len(projects) != 0 {
+		t.Errorf("Projects() = %v, %v", projects, err)
+	}
+	reminders, err := s.Reminders()
+	if err != nil || len(reminders) != 0 {
+		t.Errorf("Reminders() = %v, %v", reminders, err)
+	}
+}
+
+func TestUpdateTodos(t *testing.T) {
+	s := New(t.TempDir(), nil)
+	err := s.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		return append(todos, todo.FromString("Call mom"), todo.FromString("Pay rent")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		t, err := Find(todos, "Call mom")
+		if err != nil {
+			return nil, err
+		}
+		t.Done = true
+		return todos, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, _ := os.ReadFile(todo.DoneTodoPath(s.TodoDir()))
+	if strings.TrimSpace(string(done)) != "x Call mom" {
+		t.Errorf("done.txt = %q", done)
+	}
+
+	// a stale line fails and writes nothing
+	err = s.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		if _, err := Find(todos, "Call mom"); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("stale update error = %v, want ErrNotFound", err)
+	}
+	if todos, _ := s.Todos(); len(todos) != 2 {
+		t.Errorf("todos = %v", todos)
+	}
+}
+
+func TestUpdateProjects(t *testing.T) {
+	s := New(t.TempDir(), nil)
+	err := s.UpdateProjects(func(projects []*project.Project) ([]*project.Project, error) {
+		p, err := project.FromTodo(todo.FromString("Garden"), nil)
+		return append(projects, p), err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	projects, _ := s.Projects()
+	if _, err := FindProject(projects, "Garden"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateWaitsForLock(t *testing.T) {
+	s := New(t.TempDir(), nil)
+	s.LockTimeout = 100 * time.Millisecond
+
+	l, err := lock.TryAcquire(lock.Path(s.AtpDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) { return todos, nil })
+	if !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("update while locked = %v, want ErrLocked", err)
+	}
+	l.Release()
+
+	if err := s.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) { return todos, nil }); err != nil {
+		t.Errorf("update after release = %v", err)
+	}
+}
+
+func TestUpdateTodosHookVeto(t *testing.T) {
+	s := New(t.TempDir(), nil)
+	dir := filepath.Join(s.AtpDir, hooks.Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho 'needs a project' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, hooks.OnAdd), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	hooks.Output = io.Discard
+
+	err := s.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		return append(todos, todo.FromString("Call mom")), nil
+	})
+	var veto *hooks.VetoError
+	if !errors.As(err, &veto) {
+		t.Fatalf("UpdateTodos() = %v, want a veto", err)
+	}
+	todos, err := s.Todos()
+	if err != nil || len(todos) != 0 {
+		t.Errorf("Todos() = %v, %v, want nothing written", todos, err)
+	}
+}
@@ -0,0 +1,236 @@
+package timelog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/todo"
+)
+
+// Total is the time spent on a label, e.g. a +project or a repo
+type Total struct {
+	Label   string        `json:"label"`
+	Spent   time.Duration `json:"-"`
+	Minutes int           `json:"minutes"`
+}
+
+// Comparison is a todo's estimate next to the time spent on it
+type Comparison struct {
+	Todo     string        `json:"todo"`
+	Done     bool          `json:"done"`
+	Estimate time.Duration `json:"-"`
+	Spent    time.Duration `json:"-"`
+
+	EstimateMinutes int     `json:"estimate_minutes"`
+	SpentMinutes    int     `json:"spent_minutes"`
+	Ratio           float64 `json:"ratio"`
+}
+
+// Report sums the time log
+type Report struct {
+	Since   time.Time     `json:"-"`
+	Running *Entry        `json:"running,omitempty"`
+	Total   time.Duration `json:"-"`
+	Minutes int           `json:"minutes"`
+
+	ByProject []Total `json:"by_project"`
+	ByRepo    []Total `json:"by_repo"`
+	ByPhase   []Total `json:"by_phase"`
+
+	// estimates of todos with both est: and spent:, and the ratio of the
+	// time spent on the completed ones to their estimates
+	Estimates     []Comparison `json:"estimates"`
+	EstimateRatio float64      `json:"estimate_ratio"`
+}
+
+// Compute sums the time logged since the given time, all of it when since
+// is zero. Entries are matched to todos by id to find their current tags and
+// to projects with project.Matches, a project's phase is the one it was in
+// when the work started.
+func Compute(entries []*Entry, todos []*todo.Todo, projects []*project.Project, since time.Time, now time.Time) *Report {
+	r := &Report{Since: since, Running: Active(entries)}
+
+	byProject := make(map[string]time.Duration)
+	byRepo := make(map[string]time.Duration)
+	byPhase := make(map[string]time.Duration)
+
+	for _, e := range entries {
+		if !since.IsZero() && !e.Running() && e.End.Before(since) {
+			continue
+		}
+		start := e.Start
+		if start.Before(since) {
+			start = since
+		}
+		spent := (&Entry{Start: start, End: e.End}).Duration(now)
+		if spent <= 0 {
+			continue
+		}
+		r.Total += spent
+
+		t := todo.FindID(todos, e.ID)
+		if t == nil {
+			t = todo.FromString(e.Todo)
+		}
+
+		for _, tag := range t.Projects {
+			byProject["+"+tag] += spent
+		}
+
+		repos := make(map[string]bool)
+		if repo := t.Labels["repo"]; repo != "" {
+			repos[strings.ToLower(repo)] = true
+		}
+		for _, p := range projects {
+			if !p.Matches(t) {
+				continue
+			}
+			if p.Repo != nil {
+				repos[strings.ToLower(p.Repo.String())] = true
+			}
+			if phase := phaseAt(p, e.Start); phase != "" {
+				byPhase[fmt.Sprintf("%s phase %s", p.Name, phase)] += spent
+			}
+		}
+		for repo := range repos {
+			byRepo[repo] += spent
+		}
+	}
+
+	r.Minutes = minutes(r.Total)
+	r.ByProject = sortedTotals(byProject)
+	r.ByRepo = sortedTotals(byRepo)
+	r.ByPhase = sortedTotals(byPhase)
+	r.estimates(todos)
+	return r
+}
+
+func (r *Report) estimates(todos []*todo.Todo) {
+	var estimated, spent time.Duration
+	for _, t := range todos {
+		c := Comparison{Todo: t.Description, Done: t.Done, Estimate: Estimate(t), Spent: Spent(t)}
+		if c.Estimate == 0 || c.Spent == 0 {
+			continue
+		}
+		c.EstimateMinutes = minutes(c.Estimate)
+		c.SpentMinutes = minutes(c.Spent)
+		c.Ratio = ratio(c.Spent, c.Estimate)
+		r.Estimates = append(r.Estimates, c)
+
+		if t.Done {
+			estimated += c.Estimate
+			spent += c.Spent
+		}
+	}
+	sort.SliceStable(r.Estimates, func(i, j int) bool { return r.Estimates[i].Ratio > r.Estimates[j].Ratio })
+	r.EstimateRatio = ratio(spent, estimated)
+}
+
+// phaseAt is the phase the project was in at the time: the last one
+// entered by then according to its phase<N>: labels, or its current phase
+// when there is no history
+func phaseAt(p *project.Project, at time.Time) string {
+	dates := p.PhaseDates()
+	if len(dates) == 0 {
+		return p.Phase
+	}
+
+	phase := ""
+	var entered time.Time
+	day := at.Format("2006-01-02")
+	for name, date := range dates {
+		if date.Format("2006-01-02") > day {
+			continue
+		}
+		if phase == "" || date.After(entered) || date.Equal(entered) && name > phase {
+			phase, entered = name, date
+		}
+	}
+	return phase
+}
+
+func sortedTotals(spent map[string]time.Duration) []Total {
+	var totals []Total
+	for label, d := range spent {
+		totals = append(totals, Total{Label: label, Spent: d, Minutes: minutes(d)})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Spent != totals[j].Spent {
+			return totals[i].Spent > totals[j].Spent
+		}
+		return totals[i].Label < totals[j].Label
+	})
+	return totals
+}
+
+func minutes(d time.Duration) int {
+	return int(d.Round(time.Minute) / time.Minute)
+}
+
+func ratio(spent time.Duration, estimate time.Duration) float64 {
+	if estimate == 0 {
+		return 0
+	}
+	return float64(int(float64(spent)/float64(estimate)*100+0.5)) / 100
+}
+
+// WriteText prints the report as tables
+func (r *Report) WriteText(w io.Writer, now time.Time) {
+	if r.Running != nil {
+		t := todo.FromString(r.Running.Todo)
+		fmt.Fprintf(w, "Running: %s (%s)\n\n", t.Description, FormatDuration(r.Running.Duration(now)))
+	}
+
+	title := "Time spent"
+	if !r.Since.IsZero() {
+		title += " since " + r.Since.Format("2006-01-02")
+	}
+	fmt.Fprintf(w, "%s: %s\n", title, FormatDuration(r.Total))
+
+	table(w, "By project", r.ByProject)
+	table(w, "By repo", r.ByRepo)
+	table(w, "By project phase", r.ByPhase)
+
+	fmt.Fprintf(w, "\nEstimates\n")
+	if len(r.Estimates) == 0 {
+		fmt.Fprintf(w, "  no todos with est: and spent: yet\n")
+		return
+	}
+	for _, c := range r.Estimates {
+		status := " "
+		if c.Done {
+			status = "x"
+		}
+		fmt.Fprintf(w, "  %s %-30s %7s of %-7s %4.0f%%\n", status, truncate(c.Todo, 30),
+			FormatDuration(c.Spent), FormatDuration(c.Estimate), c.Ratio*100)
+	}
+	if r.EstimateRatio > 0 {
+		fmt.Fprintf(w, "  done todos took %.0f%% of their estimates\n", r.EstimateRatio*100)
+	}
+}
+
+func table(w io.Writer, title string, totals []Total) {
+	fmt.Fprintf(w, "\n%s\n", title)
+	if len(totals) == 0 {
+		fmt.Fprintf(w, "  none\n")
+		return
+	}
+	width := 0
+	for _, t := range totals {
+		width = max(width, len(t.Label))
+	}
+	for _, t := range totals {
+		fmt.Fprintf(w, "  %-*s %8s\n", width, t.Label, FormatDuration(t.Spent))
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "…"
+}
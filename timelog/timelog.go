@@ -0,0 +1,226 @@
+// Package timelog tracks time spent on todos. Timers are recorded as
+// intervals in $ATP_DIR/timelog.jsonl and only one runs at a time. When a
+// timer stops, its time is added to the todo's spent: label, which reports
+// compare with the est: label.
+package timelog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/todo"
+)
+
+// LogFile is the time log in the ATP directory, one interval per line
+const LogFile = "timelog.jsonl"
+
+// Labels for the time spent on a todo and its estimate, e.g. spent:1h30m
+const (
+	SpentLabel    = "spent"
+	EstimateLabel = "est"
+)
+
+// ErrNoTimer is returned when stopping without a running timer
+var ErrNoTimer = errors.New("no timer is running")
+
+// Entry is an interval of work on a todo. The todo.txt line is kept so
+// reports still know the todo's tags after it was edited or deleted.
+type Entry struct {
+	ID    string    `json:"id"`
+	Todo  string    `json:"todo"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitempty"`
+}
+
+// Running reports whether the timer is still running
+func (e *Entry) Running() bool {
+	return e.End.IsZero()
+}
+
+// Duration is how long the entry ran, until now when it still runs
+func (e *Entry) Duration(now time.Time) time.Duration {
+	if e.Running() {
+		return now.Sub(e.Start)
+	}
+	return e.End.Sub(e.Start)
+}
+
+// Path returns the time log of an ATP directory
+func Path(atpDir string) string {
+	return filepath.Join(atpDir, LogFile)
+}
+
+// Load reads the time log, a missing log is empty
+func Load(atpDir string) ([]*Entry, error) {
+	data, err := os.ReadFile(Path(atpDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read time log: %w", err)
+	}
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		e := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("invalid time log entry on line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Write replaces the time log with the entries
+func Write(atpDir string, entries []*Entry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to encode time log entry: %w", err)
+		}
+	}
+
+	path := Path(atpDir)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write time log: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write time log: %w", err)
+	}
+	return nil
+}
+
+// Active returns the running timer, nil when none runs
+func Active(entries []*Entry) *Entry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Running() {
+			return entries[i]
+		}
+	}
+	return nil
+}
+
+// Start stops the running timer and starts one for t, which gets an id if
+// it has none. It returns the entries and the timer that was stopped.
+func Start(entries []*Entry, todos []*todo.Todo, t *todo.Todo, now time.Time) ([]*Entry, *Entry) {
+	stopped, _ := Stop(entries, todos, now)
+	id := todo.EnsureID(todos, t)
+	entries = append(entries, &Entry{ID: id, Todo: t.String(), Start: now})
+	return entries, stopped
+}
+
+// Stop stops the running timer and adds its time to the todo's spent:
+// label, if the todo is still there
+func Stop(entries []*Entry, todos []*todo.Todo, now time.Time) (*Entry, error) {
+	e := Active(entries)
+	if e == nil {
+		return nil, ErrNoTimer
+	}
+	if now.Before(e.Start) {
+		now = e.Start
+	}
+	e.End = now
+
+	if t := todo.FindID(todos, e.ID); t != nil {
+		AddSpent(t, e.Duration(now))
+		e.Todo = t.String()
+	}
+	return e, nil
+}
+
+// StopDone stops the running timer when its todo was completed and writes
+// the log. It is called wherever todos are written so completing a todo,
+// however it happens, stops its timer.
+func StopDone(atpDir string, todos []*todo.Todo, now time.Time) error {
+	if _, err := os.Stat(Path(atpDir)); os.IsNotExist(err) {
+		return nil
+	}
+	entries, err := Load(atpDir)
+	if err != nil {
+		return err
+	}
+	e := Active(entries)
+	if e == nil {
+		return nil
+	}
+	if t := todo.FindID(todos, e.ID); t == nil || !t.Done {
+		return nil
+	}
+	if _, err := Stop(entries, todos, now); err != nil {
+		return err
+	}
+	return Write(atpDir, entries)
+}
+
+// ------------------------------- Durations -------------------------------
+
+// Spent returns the time recorded in the todo's spent: label
+func Spent(t *todo.Todo) time.Duration {
+	d, _ := ParseDuration(t.Labels[SpentLabel])
+	return d
+}
+
+// Estimate returns the todo's est: label, zero when it has none
+func Estimate(t *todo.Todo) time.Duration {
+	d, _ := ParseDuration(t.Labels[EstimateLabel])
+	return d
+}
+
+// AddSpent adds d to the todo's spent: label
+func AddSpent(t *todo.Todo, d time.Duration) {
+	if t.Labels == nil {
+		t.Labels = make(map[string]string)
+	}
+	t.Labels[SpentLabel] = FormatDuration(Spent(t) + d)
+}
+
+// ParseDuration parses durations like 2h, 1h30m or 45m. A plain number is
+// minutes and d is a day of 8 hours, e.g. est:1d.
+func ParseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Minute, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n * float64(8*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// FormatDuration formats d in hours and minutes, e.g. 1h30m, 2h or 45m
+func FormatDuration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	hours, minutes := minutes/60, minutes%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+}
@@ -0,0 +1,195 @@
+package timelog
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/repo"
+	"github.com/arjungandhi/atp/todo"
+)
+
+var start = time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+
+func todos(lines ...string) []*todo.Todo {
+	var todos []*todo.Todo
+	for _, line := range lines {
+		todos = append(todos, todo.FromString(line))
+	}
+	return todos
+}
+
+func TestStartStop(t *testing.T) {
+	list := todos("Write report +work id:1", "Fix bike +home")
+
+	entries, stopped := Start(nil, list, list[0], start)
+	if stopped != nil || len(entries) != 1 || entries[0].ID != "1" {
+		t.Fatalf("Start() = %v, %v", entries, stopped)
+	}
+
+	// starting another timer stops the running one
+	entries, stopped = Start(entries, list, list[1], start.Add(90*time.Minute))
+	if stopped == nil || stopped.ID != "1" || stopped.Duration(start) != 90*time.Minute {
+		t.Fatalf("Start() stopped %+v", stopped)
+	}
+	if got := list[0].Labels[SpentLabel]; got != "1h30m" {
+		t.Errorf("spent = %s, want 1h30m", got)
+	}
+	if list[1].ID() != "2" || Active(entries) != entries[1] {
+		t.Errorf("new timer = %+v, todo %q", Active(entries), list[1].String())
+	}
+
+	stopped, err := Stop(entries, list, start.Add(2*time.Hour))
+	if err != nil || stopped.ID != "2" {
+		t.Fatalf("Stop() = %+v, %v", stopped, err)
+	}
+	if got := list[1].Labels[SpentLabel]; got != "30m" {
+		t.Errorf("spent = %s, want 30m", got)
+	}
+	if _, err := Stop(entries, list, start.Add(3*time.Hour)); !errors.Is(err, ErrNoTimer) {
+		t.Errorf("Stop() without a timer = %v", err)
+	}
+
+	// time adds up over several timers
+	entries, _ = Start(entries, list, list[0], start.Add(3*time.Hour))
+	Stop(entries, list, start.Add(3*time.Hour+45*time.Minute))
+	if got := list[0].Labels[SpentLabel]; got != "2h15m" {
+		t.Errorf("spent = %s, want 2h15m", got)
+	}
+}
+
+func TestLoadWriteAndStopDone(t *testing.T) {
+	dir := t.TempDir()
+	list := todos("Write report id:1")
+
+	// no log, nothing to stop
+	if err := StopDone(dir, list, start); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(Path(dir)); !os.IsNotExist(err) {
+		t.Errorf("StopDone() created the log")
+	}
+
+	entries, _ := Start(nil, list, list[0], start)
+	if err := Write(dir, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// an open todo keeps its timer
+	if err := StopDone(dir, list, start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(dir)
+	if err != nil || Active(loaded) == nil {
+		t.Fatalf("Load() = %v, %v, want a running timer", loaded, err)
+	}
+
+	list[0].Done = true
+	if err := StopDone(dir, list, start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = Load(dir)
+	if err != nil || len(loaded) != 1 || Active(loaded) != nil {
+		t.Fatalf("Load() = %v, %v, want a stopped timer", loaded, err)
+	}
+	if !loaded[0].End.Equal(start.Add(time.Hour)) || list[0].Labels[SpentLabel] != "1h" {
+		t.Errorf("entry = %+v, todo %q", loaded[0], list[0].String())
+	}
+}
+
+func TestDurations(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		text  string
+	}{
+		{"2h", 2 * time.Hour, "2h"},
+		{"1h30m", 90 * time.Minute, "1h30m"},
+		{"45m", 45 * time.Minute, "45m"},
+		{"90", 90 * time.Minute, "1h30m"},
+		{"1d", 8 * time.Hour, "8h"},
+		{"0.5d", 4 * time.Hour, "4h"},
+		{"", 0, "0m"},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+		if text := FormatDuration(got); text != tt.text {
+			t.Errorf("FormatDuration(%v) = %s, want %s", got, text, tt.text)
+		}
+	}
+	for _, value := range []string{"soon", "-1h", "xd"} {
+		if _, err := ParseDuration(value); err == nil {
+			t.Errorf("ParseDuration(%q) = nil, want an error", value)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	list := todos(
+		"x Write report +work est:2h spent:3h id:1",
+		"Fix login +atp est:1h spent:30m id:2",
+		"Call bank id:3 repo:acme/bank",
+	)
+	atp, err := project.FromTodo(todo.FromString("(A) atp phase:2 phase1:2025-03-01 phase2:2025-03-12 repo:arjun/atp"),
+		[]*repo.Repo{repo.NewRepo("arjun", "atp", "", "")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []*Entry{
+		// before since, left out
+		{ID: "1", Todo: "Write report +work id:1", Start: start.AddDate(0, 0, -2), End: start.AddDate(0, 0, -2).Add(time.Hour)},
+		{ID: "1", Todo: "Write report +work id:1", Start: start, End: start.Add(2 * time.Hour)},
+		// phase 1 on the day before phase 2 started
+		{ID: "2", Todo: "Fix login +atp id:2", Start: start.AddDate(0, 0, -1), End: start.AddDate(0, 0, -1).Add(20 * time.Minute)},
+		{ID: "2", Todo: "Fix login +atp id:2", Start: start.Add(3 * time.Hour), End: start.Add(3*time.Hour + 10*time.Minute)},
+		// deleted todo, its logged line is used
+		{ID: "9", Todo: "Old thing +home id:9", Start: start.Add(4 * time.Hour), End: start.Add(5 * time.Hour)},
+		{ID: "3", Todo: "Call bank id:3", Start: start.Add(6 * time.Hour)},
+	}
+
+	r := Compute(entries, list, []*project.Project{atp}, start.AddDate(0, 0, -1), start.Add(6*time.Hour+15*time.Minute))
+
+	if r.Total != 3*time.Hour+45*time.Minute || r.Minutes != 225 {
+		t.Errorf("Total = %v (%d minutes)", r.Total, r.Minutes)
+	}
+	if r.Running == nil || r.Running.ID != "3" {
+		t.Errorf("Running = %+v", r.Running)
+	}
+
+	check := func(name string, totals []Total, want string) {
+		t.Helper()
+		var got []string
+		for _, total := range totals {
+			got = append(got, total.Label+"="+FormatDuration(total.Spent))
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("%s = %s, want %s", name, strings.Join(got, " "), want)
+		}
+	}
+	check("ByProject", r.ByProject, "+work=2h +home=1h +atp=30m")
+	check("ByRepo", r.ByRepo, "arjun/atp=30m acme/bank=15m")
+	check("ByPhase", r.ByPhase, "atp phase 1=20m atp phase 2=10m")
+
+	if len(r.Estimates) != 2 || r.Estimates[0].Todo != "Write report" || r.Estimates[0].Ratio != 1.5 || r.Estimates[1].Ratio != 0.5 {
+		t.Errorf("Estimates = %+v", r.Estimates)
+	}
+	// only done todos count towards the ratio
+	if r.EstimateRatio != 1.5 {
+		t.Errorf("EstimateRatio = %v, want 1.5", r.EstimateRatio)
+	}
+
+	var text strings.Builder
+	r.WriteText(&text, start.Add(6*time.Hour+15*time.Minute))
+	for _, want := range []string{"Running: Call bank (15m)", "since 2025-03-11: 3h45m", "atp phase 1", "3h of 2h"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("WriteText() is missing %q:\n%s", want, text.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package todo
+
+// Change is a todo before and after a change. Before is nil for an added
+// todo and After is nil for a removed one.
+type Change struct {
+	Before *Todo
+	After  *Todo
+}
+
+// Added reports whether the todo is new
+func (c Change) Added() bool {
+	return c.Before == nil && c.After != nil
+}
+
+// Removed reports whether the todo is gone
+func (c Change) Removed() bool {
+	return c.Before != nil && c.After == nil
+}
+
+// Completed reports whether an open todo was marked done
+func (c Change) Completed() bool {
+	return c.Before != nil && c.After != nil && !c.Before.Done && c.After.Done
+}
+
+// Modified reports whether the todo changed in any other way
+func (c Change) Modified() bool {
+	return c.Before != nil && c.After != nil && !c.Completed()
+}
+
+// Changes compares two lists of todos by their todo.txt lines, counting
+// duplicate lines. A line only in after is paired with a line only in
+// before that has the same description; unpaired lines were added or
+// removed. Changes come in the order of after, followed by the removals.
+func Changes(before []*Todo, after []*Todo) []Change {
+	counts := make(map[string]int)
+	for _, t := range before {
+		counts[t.String()]++
+	}
+	var added []*Todo
+	for _, t := range after {
+		if line := t.String(); counts[line] > 0 {
+			counts[line]--
+		} else {
+			added = append(added, t)
+		}
+	}
+	var removed []*Todo
+	for _, t := range before {
+		if line := t.String(); counts[line] > 0 {
+			counts[line]--
+			removed = append(removed, t)
+		}
+	}
+
+	var changes []Change
+	for _, t := range added {
+		change := Change{After: t}
+		for i, old := range removed {
+			if old.Description == t.Description {
+				change.Before = old
+				removed = append(removed[:i], removed[i+1:]...)
+				break
+			}
+		}
+		changes = append(changes, change)
+	}
+	for _, old := range removed {
+		changes = append(changes, Change{Before: old})
+	}
+	return changes
+}
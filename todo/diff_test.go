@@ -0,0 +1,55 @@
+package todo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	lines := func(lines ...string) []*Todo {
+		var todos []*Todo
+		for _, line := range lines {
+			todos = append(todos, FromString(line))
+		}
+		return todos
+	}
+	describe := func(changes []Change) []string {
+		var out []string
+		for _, c := range changes {
+			switch {
+			case c.Added():
+				out = append(out, "added "+c.After.String())
+			case c.Removed():
+				out = append(out, "removed "+c.Before.String())
+			case c.Completed():
+				out = append(out, "completed "+c.After.String())
+			case c.Modified():
+				out = append(out, "modified "+c.Before.String()+" -> "+c.After.String())
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		before []*Todo
+		after  []*Todo
+		want   []string
+	}{
+		{"nothing", lines("Fix bike", "Call mom"), lines("Call mom", "Fix bike"), nil},
+		{"added", lines("Fix bike"), lines("Fix bike", "Fix bike", "Call mom"), []string{"added Fix bike", "added Call mom"}},
+		{"removed", lines("Fix bike", "Call mom"), lines("Call mom"), []string{"removed Fix bike"}},
+		{"completed", lines("(A) Fix bike"), lines("x 2025-03-12 (A) Fix bike"), []string{"completed x 2025-03-12 (A) Fix bike"}},
+		{"modified", lines("Fix bike", "Call mom"), lines("(B) Fix bike +garage", "Call mom"), []string{"modified Fix bike -> (B) Fix bike +garage"}},
+		{"reopened", lines("x 2025-03-12 Fix bike"), lines("Fix bike"), []string{"modified x 2025-03-12 Fix bike -> Fix bike"}},
+		{"renamed", lines("Fix bike"), lines("Fix the bike"), []string{"added Fix the bike", "removed Fix bike"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describe(Changes(tt.before, tt.after)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
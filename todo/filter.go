@@ -0,0 +1,188 @@
+package todo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Todo states a filter selects
+const (
+	StatusOpen = "open"
+	StatusDone = "done"
+	StatusAll  = "all"
+)
+
+// Dependency states a filter selects
+const (
+	BlockedShow = ""     // blocked or not (default)
+	BlockedHide = "hide" // only todos that don't wait for an open todo
+	BlockedOnly = "only" // only todos that wait for an open todo
+)
+
+// Filter selects todos. Every set field has to match, a zero Filter matches
+// every open todo.
+type Filter struct {
+	Status   string            // open (default), done or all
+	Projects []string          // +project tags, all required
+	Contexts []string          // @contexts, all required
+	Labels   map[string]string // key:value labels, a value of * only needs the key
+	Priority string            // a priority like A or a range like A-C
+	Text     []string          // words the description has to contain
+	// DueBy keeps todos with a due: date on or before this day
+	DueBy time.Time
+	// Blocked selects by dependencies, see NewGraph. Only Apply uses it,
+	// it needs the other todos.
+	Blocked string
+}
+
+var rePriorityFilter = regexp.MustCompile(`^\(([A-Z])(?:-([A-Z]))?\)$`)
+
+// ParseFilter builds a filter from command line arguments in todo.txt
+// syntax:
+//
+//	+project @context key:value key:* (A) (A-C) --done --all --due DATE
+//	--blocked --actionable words
+func ParseFilter(args []string) (Filter, error) {
+	f := Filter{Status: StatusOpen, Labels: map[string]string{}}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--done":
+			f.Status = StatusDone
+		case arg == "--all":
+			f.Status = StatusAll
+		case arg == "--blocked":
+			f.Blocked = BlockedOnly
+		case arg == "--actionable":
+			f.Blocked = BlockedHide
+		case arg == "--due":
+			if i+1 >= len(args) {
+				return f, fmt.Errorf("--due needs a date (YYYY-MM-DD or today)")
+			}
+			due, err := parseFilterDate(args[i+1])
+			if err != nil {
+				return f, err
+			}
+			f.DueBy = due
+			i++
+		case strings.HasPrefix(arg, "--"):
+			return f, fmt.Errorf("unknown argument: %s", arg)
+		case len(arg) > 1 && arg[0] == '+':
+			f.Projects = append(f.Projects, arg[1:])
+		case len(arg) > 1 && arg[0] == '@':
+			f.Contexts = append(f.Contexts, arg[1:])
+		case rePriorityFilter.MatchString(arg):
+			f.Priority = strings.Trim(arg, "()")
+		default:
+			if key, value, ok := strings.Cut(arg, ":"); ok && key != "" && value != "" {
+				f.Labels[key] = value
+				continue
+			}
+			f.Text = append(f.Text, arg)
+		}
+	}
+
+	return f, nil
+}
+
+func parseFilterDate(value string) (time.Time, error) {
+	if value == "today" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s' (expected YYYY-MM-DD or today)", value)
+	}
+	return date, nil
+}
+
+// Match reports whether the todo is selected by the filter
+func (f Filter) Match(t *Todo) bool {
+	switch f.Status {
+	case StatusAll:
+	case StatusDone:
+		if !t.Done {
+			return false
+		}
+	default:
+		if t.Done {
+			return false
+		}
+	}
+
+	for _, project := range f.Projects {
+		if !containsFold(t.Projects, project) {
+			return false
+		}
+	}
+	for _, context := range f.Contexts {
+		if !containsFold(t.Contexts, context) {
+			return false
+		}
+	}
+	for key, value := range f.Labels {
+		actual, ok := t.Labels[key]
+		if !ok || (value != "*" && actual != value) {
+			return false
+		}
+	}
+
+	if f.Priority != "" {
+		low, high, _ := strings.Cut(f.Priority, "-")
+		if high == "" {
+			high = low
+		}
+		if t.Priority == "" || t.Priority < low || t.Priority > high {
+			return false
+		}
+	}
+
+	description := strings.ToLower(t.Description)
+	for _, word := range f.Text {
+		if !strings.Contains(description, strings.ToLower(word)) {
+			return false
+		}
+	}
+
+	if !f.DueBy.IsZero() {
+		due := t.Labels["due"]
+		if due == "" || due > f.DueBy.Format("2006-01-02") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply returns the todos selected by the filter, in their original order
+func (f Filter) Apply(todos []*Todo) []*Todo {
+	var g *Graph
+	if f.Blocked != BlockedShow {
+		g = NewGraph(todos)
+	}
+
+	selected := []*Todo{}
+	for _, t := range todos {
+		if !f.Match(t) {
+			continue
+		}
+		if g != nil && g.Blocked(t) != (f.Blocked == BlockedOnly) {
+			continue
+		}
+		selected = append(selected, t)
+	}
+	return selected
+}
+
+func containsFold(values []string, want string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package todo
+
+import (
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	todos := []*Todo{
+		FromString("(A) 2025-03-01 Call mom +family @phone due:2025-03-10"),
+		FromString("(C) Fix bike +garage @home"),
+		FromString("Read book @home issue:owner/repo#1"),
+		FromString("x 2025-03-05 2025-03-01 Pay rent +home due:2025-03-05"),
+		FromString("Return book @home dep:2"),
+		FromString("Lend book id:2"),
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"Call mom", "Fix bike", "Read book", "Return book", "Lend book"}},
+		{[]string{"--all"}, []string{"Call mom", "Fix bike", "Read book", "Pay rent", "Return book", "Lend book"}},
+		{[]string{"--blocked"}, []string{"Return book"}},
+		{[]string{"--actionable", "book"}, []string{"Read book", "Lend book"}},
+		{[]string{"--done"}, []string{"Pay rent"}},
+		{[]string{"@home"}, []string{"Fix bike", "Read book", "Return book"}},
+		{[]string{"+Family"}, []string{"Call mom"}},
+		{[]string{"issue:*"}, []string{"Read book"}},
+		{[]string{"issue:owner/repo#2"}, nil},
+		{[]string{"(A-B)"}, []string{"Call mom"}},
+		{[]string{"(C)"}, []string{"Fix bike"}},
+		{[]string{"BIKE"}, []string{"Fix bike"}},
+		{[]string{"--all", "--due", "2025-03-06"}, []string{"Pay rent"}},
+		{[]string{"@home", "read"}, []string{"Read book"}},
+	}
+
+	for _, test := range tests {
+		f, err := ParseFilter(test.args)
+		if err != nil {
+			t.Fatalf("ParseFilter(%v) error: %v", test.args, err)
+		}
+		var got []string
+		for _, todo := range f.Apply(todos) {
+			got = append(got, todo.Description)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%v selected %v, want %v", test.args, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v selected %v, want %v", test.args, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, args := range [][]string{{"--due"}, {"--due", "soon"}, {"--bogus"}} {
+		if _, err := ParseFilter(args); err == nil {
+			t.Errorf("ParseFilter(%v) should fail", args)
+		}
+	}
+}
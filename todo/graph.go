@@ -0,0 +1,222 @@
+package todo
+
+import (
+	"strings"
+)
+
+// Labels for dependencies. Both hold comma separated references to other
+// todos, by their id: label or, for todos synced from elsewhere, their url:
+// label. dep:1,2 waits for todos 1 and 2, blocks:3 makes todo 3 wait.
+const (
+	DepLabel    = "dep"
+	BlocksLabel = "blocks"
+)
+
+// Lookup returns the todo a reference points to by its id: or url: label,
+// nil when there is none. It scans the list, NewGraph and NewSubtasks
+// index it once instead.
+func Lookup(todos []*Todo, ref string) *Todo {
+	if ref == "" {
+		return nil
+	}
+	if t := FindID(todos, ref); t != nil {
+		return t
+	}
+	for _, t := range todos {
+		if t.Labels["url"] == ref {
+			return t
+		}
+	}
+	return nil
+}
+
+// refIndex finds todos by reference without scanning the list. References
+// resolve like Lookup: an id before a url, the first todo with it wins.
+type refIndex struct {
+	ids  map[string]*Todo
+	urls map[string]*Todo
+}
+
+func newRefIndex(todos []*Todo) *refIndex {
+	x := &refIndex{
+		ids:  make(map[string]*Todo),
+		urls: make(map[string]*Todo),
+	}
+	for _, t := range todos {
+		if id := t.ID(); id != "" && x.ids[id] == nil {
+			x.ids[id] = t
+		}
+		if url := t.Labels["url"]; url != "" && x.urls[url] == nil {
+			x.urls[url] = t
+		}
+	}
+	return x
+}
+
+// lookup returns the todo ref points to, nil when there is none
+func (x *refIndex) lookup(ref string) *Todo {
+	if t := x.ids[ref]; t != nil {
+		return t
+	}
+	return x.urls[ref]
+}
+
+// References splits a comma separated label value
+func References(value string) []string {
+	var refs []string
+	for _, ref := range strings.Split(value, ",") {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// AddReference appends ref to the comma separated label unless it is there
+func AddReference(t *Todo, label string, ref string) {
+	refs := References(t.Labels[label])
+	for _, existing := range refs {
+		if existing == ref {
+			return
+		}
+	}
+	if t.Labels == nil {
+		t.Labels = make(map[string]string)
+	}
+	t.Labels[label] = strings.Join(append(refs, ref), ",")
+}
+
+// Graph is the dependency graph of a list of todos
+type Graph struct {
+	todos      []*Todo
+	deps       map[*Todo][]*Todo
+	dependents map[*Todo][]*Todo
+	missing    map[*Todo][]string
+}
+
+// NewGraph resolves the dep: and blocks: labels of the todos. References
+// to todos that aren't in the list are kept as missing and don't block.
+func NewGraph(todos []*Todo) *Graph {
+	g := &Graph{
+		todos:      todos,
+		deps:       make(map[*Todo][]*Todo),
+		dependents: make(map[*Todo][]*Todo),
+		missing:    make(map[*Todo][]string),
+	}
+
+	index := newRefIndex(todos)
+	for _, t := range todos {
+		for _, ref := range References(t.Labels[DepLabel]) {
+			if dep := index.lookup(ref); dep != nil {
+				g.link(t, dep)
+			} else {
+				g.missing[t] = append(g.missing[t], ref)
+			}
+		}
+		for _, ref := range References(t.Labels[BlocksLabel]) {
+			if blocked := index.lookup(ref); blocked != nil {
+				g.link(blocked, t)
+			} else {
+				g.missing[t] = append(g.missing[t], ref)
+			}
+		}
+	}
+	return g
+}
+
+// link makes t wait for dep, once
+func (g *Graph) link(t *Todo, dep *Todo) {
+	for _, existing := range g.deps[t] {
+		if existing == dep {
+			return
+		}
+	}
+	g.deps[t] = append(g.deps[t], dep)
+	g.dependents[dep] = append(g.dependents[dep], t)
+}
+
+// Dependencies returns the todos t waits for
+func (g *Graph) Dependencies(t *Todo) []*Todo {
+	return g.deps[t]
+}
+
+// Dependents returns the todos waiting for t
+func (g *Graph) Dependents(t *Todo) []*Todo {
+	return g.dependents[t]
+}
+
+// Missing returns the references of t that didn't resolve to a todo
+func (g *Graph) Missing(t *Todo) []string {
+	return g.missing[t]
+}
+
+// Blockers returns the open todos t waits for
+func (g *Graph) Blockers(t *Todo) []*Todo {
+	var blockers []*Todo
+	for _, dep := range g.deps[t] {
+		if !dep.Done {
+			blockers = append(blockers, dep)
+		}
+	}
+	return blockers
+}
+
+// Blocked reports whether t waits for an open todo
+func (g *Graph) Blocked(t *Todo) bool {
+	return len(g.Blockers(t)) > 0
+}
+
+// Actionable returns the open todos that don't wait for anything
+func Actionable(todos []*Todo) []*Todo {
+	g := NewGraph(todos)
+	var actionable []*Todo
+	for _, t := range todos {
+		if !t.Done && !g.Blocked(t) {
+			actionable = append(actionable, t)
+		}
+	}
+	return actionable
+}
+
+// Cycles returns the dependency cycles, each as the todos along it with
+// the first one repeated at the end. Todos in a cycle are blocked for good.
+func (g *Graph) Cycles() [][]*Todo {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*Todo]int)
+	var cycles [][]*Todo
+	var path []*Todo
+
+	var visit func(t *Todo)
+	visit = func(t *Todo) {
+		state[t] = visiting
+		path = append(path, t)
+		for _, dep := range g.deps[t] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				// the path from dep back to itself is a cycle
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == dep {
+						cycle := append([]*Todo{}, path[i:]...)
+						cycles = append(cycles, append(cycle, dep))
+						break
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[t] = visited
+	}
+
+	for _, t := range g.todos {
+		if state[t] == unvisited {
+			visit(t)
+		}
+	}
+	return cycles
+}
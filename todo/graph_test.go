@@ -0,0 +1,122 @@
+package todo
+
+import (
+	"strings"
+	"testing"
+)
+
+func descriptions(todos []*Todo) string {
+	var names []string
+	for _, t := range todos {
+		names = append(names, t.Description)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestGraph(t *testing.T) {
+	todos := []*Todo{
+		FromString("x Spec id:1"),
+		FromString("Design id:2"),
+		FromString("Build id:3 dep:1,2"),
+		FromString("Ship dep:3"),
+		FromString("Docs blocks:https://github.com/acme/app/issues/9"),
+		FromString("Fix bug +github url:https://github.com/acme/app/issues/9"),
+		FromString("Orphan dep:42"),
+	}
+	g := NewGraph(todos)
+
+	tests := []struct {
+		todo       *Todo
+		deps       string
+		dependents string
+		blockers   string
+		blocked    bool
+	}{
+		{todos[0], "", "Build", "", false},
+		{todos[1], "", "Build", "", false},
+		{todos[2], "Spec,Design", "Ship", "Design", true},
+		{todos[3], "Build", "", "Build", true},
+		{todos[4], "", "Fix bug", "", false},
+		{todos[5], "Docs", "", "Docs", true},
+		{todos[6], "", "", "", false},
+	}
+	for _, tt := range tests {
+		if got := descriptions(g.Dependencies(tt.todo)); got != tt.deps {
+			t.Errorf("Dependencies(%s) = %s, want %s", tt.todo.Description, got, tt.deps)
+		}
+		if got := descriptions(g.Dependents(tt.todo)); got != tt.dependents {
+			t.Errorf("Dependents(%s) = %s, want %s", tt.todo.Description, got, tt.dependents)
+		}
+		if got := descriptions(g.Blockers(tt.todo)); got != tt.blockers {
+			t.Errorf("Blockers(%s) = %s, want %s", tt.todo.Description, got, tt.blockers)
+		}
+		if got := g.Blocked(tt.todo); got != tt.blocked {
+			t.Errorf("Blocked(%s) = %v, want %v", tt.todo.Description, got, tt.blocked)
+		}
+	}
+	if missing := g.Missing(todos[6]); len(missing) != 1 || missing[0] != "42" {
+		t.Errorf("Missing(Orphan) = %v", missing)
+	}
+	if got := descriptions(Actionable(todos)); got != "Design,Docs,Orphan" {
+		t.Errorf("Actionable() = %s", got)
+	}
+
+	// completing the last blocker unblocks
+	todos[1].Done = true
+	if NewGraph(todos).Blocked(todos[2]) {
+		t.Error("Build is still blocked after Design was done")
+	}
+	if cycles := g.Cycles(); len(cycles) != 0 {
+		t.Errorf("Cycles() = %v, want none", cycles)
+	}
+}
+
+func TestRefIndex(t *testing.T) {
+	todos := []*Todo{
+		FromString("Spec id:1"),
+		FromString("Spec again id:1"),
+		FromString("Issue url:7"),
+		FromString("Seven id:7"),
+		FromString("Fix bug url:https://github.com/acme/app/issues/9"),
+	}
+	index := newRefIndex(todos)
+
+	// the index resolves like Lookup, an id before a url and the first todo
+	// with it
+	for _, ref := range []string{"1", "7", "https://github.com/acme/app/issues/9", "42", ""} {
+		if got, want := index.lookup(ref), Lookup(todos, ref); got != want {
+			t.Errorf("lookup(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
+
+func TestCycles(t *testing.T) {
+	todos := []*Todo{
+		FromString("A id:a dep:b"),
+		FromString("B id:b dep:c"),
+		FromString("C id:c dep:a"),
+		FromString("D id:d dep:a"),
+		FromString("Self id:s dep:s"),
+	}
+	cycles := NewGraph(todos).Cycles()
+	var got []string
+	for _, cycle := range cycles {
+		got = append(got, descriptions(cycle))
+	}
+	if strings.Join(got, " ") != "A,B,C,A Self,Self" {
+		t.Errorf("Cycles() = %v", got)
+	}
+}
+
+func TestAddReference(t *testing.T) {
+	todo := FromString("Build")
+	AddReference(todo, DepLabel, "1")
+	AddReference(todo, DepLabel, "2")
+	AddReference(todo, DepLabel, "1")
+	if todo.String() != "Build dep:1,2" {
+		t.Errorf("AddReference() = %q", todo.String())
+	}
+	if refs := References(" 1, ,2 "); len(refs) != 2 || refs[0] != "1" || refs[1] != "2" {
+		t.Errorf("References() = %v", refs)
+	}
+}
@@ -0,0 +1,51 @@
+package todo
+
+import (
+	"strconv"
+)
+
+// IDLabel gives a todo a short id that stays the same when the todo is
+// edited, e.g. id:12
+const IDLabel = "id"
+
+// ID returns the todo's id, empty when it has none
+func (todo *Todo) ID() string {
+	return todo.Labels[IDLabel]
+}
+
+// NextID returns the number after the highest numeric id in the todos
+func NextID(todos []*Todo) string {
+	highest := 0
+	for _, t := range todos {
+		if n, err := strconv.Atoi(t.ID()); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return strconv.Itoa(highest + 1)
+}
+
+// EnsureID gives the todo the next free id unless it has one and returns it
+func EnsureID(todos []*Todo, t *Todo) string {
+	if id := t.ID(); id != "" {
+		return id
+	}
+	id := NextID(todos)
+	if t.Labels == nil {
+		t.Labels = make(map[string]string)
+	}
+	t.Labels[IDLabel] = id
+	return id
+}
+
+// FindID returns the todo with the id, nil when there is none
+func FindID(todos []*Todo, id string) *Todo {
+	if id == "" {
+		return nil
+	}
+	for _, t := range todos {
+		if t.ID() == id {
+			return t
+		}
+	}
+	return nil
+}
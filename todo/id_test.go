@@ -0,0 +1,32 @@
+package todo
+
+import (
+	"testing"
+)
+
+func TestIDs(t *testing.T) {
+	todos := []*Todo{
+		FromString("Call mom id:3"),
+		FromString("Pay rent id:abc"),
+		FromString("Water plants"),
+	}
+
+	if got := NextID(todos); got != "4" {
+		t.Errorf("NextID() = %s, want 4", got)
+	}
+	if got := EnsureID(todos, todos[2]); got != "4" || todos[2].String() != "Water plants id:4" {
+		t.Errorf("EnsureID() = %s, todo %q", got, todos[2].String())
+	}
+	if got := EnsureID(todos, todos[0]); got != "3" {
+		t.Errorf("EnsureID() kept = %s, want 3", got)
+	}
+	if found := FindID(todos, "abc"); found != todos[1] {
+		t.Errorf("FindID(abc) = %v", found)
+	}
+	if found := FindID(todos, ""); found != nil {
+		t.Errorf("FindID('') = %v, want nil", found)
+	}
+	if got := NextID(nil); got != "1" {
+		t.Errorf("NextID(nil) = %s, want 1", got)
+	}
+}
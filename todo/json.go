@@ -0,0 +1,99 @@
+package todo
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonTodo is the JSON form of a todo. Dates are YYYY-MM-DD like in
+// todo.txt and the text field holds the todo.txt line.
+type jsonTodo struct {
+	Done           bool              `json:"done"`
+	Priority       string            `json:"priority,omitempty"`
+	CreationDate   string            `json:"creation_date,omitempty"`
+	CompletionDate string            `json:"completion_date,omitempty"`
+	Description    string            `json:"description"`
+	Projects       []string          `json:"projects"`
+	Contexts       []string          `json:"contexts"`
+	Labels         map[string]string `json:"labels"`
+	Text           string            `json:"text,omitempty"`
+}
+
+// MarshalJSON encodes every field of the todo along with its todo.txt line
+func (todo *Todo) MarshalJSON() ([]byte, error) {
+	j := jsonTodo{
+		Done:           todo.Done,
+		Priority:       todo.Priority,
+		CreationDate:   formatJSONDate(todo.CreationDate),
+		CompletionDate: formatJSONDate(todo.CompletionDate),
+		Description:    todo.Description,
+		Projects:       todo.Projects,
+		Contexts:       todo.Contexts,
+		Labels:         todo.Labels,
+		Text:           todo.String(),
+	}
+	if j.Projects == nil {
+		j.Projects = []string{}
+	}
+	if j.Contexts == nil {
+		j.Contexts = []string{}
+	}
+	if j.Labels == nil {
+		j.Labels = map[string]string{}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a todo. When only text is given it is parsed as a
+// todo.txt line, otherwise the separate fields are used.
+func (todo *Todo) UnmarshalJSON(data []byte) error {
+	var j jsonTodo
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	if j.Description == "" && j.Text != "" {
+		*todo = *FromString(j.Text)
+		return nil
+	}
+
+	parsed := NewTodo()
+	parsed.Done = j.Done
+	parsed.Priority = j.Priority
+	parsed.Description = j.Description
+	if j.Projects != nil {
+		parsed.Projects = j.Projects
+	}
+	if j.Contexts != nil {
+		parsed.Contexts = j.Contexts
+	}
+	if j.Labels != nil {
+		parsed.Labels = j.Labels
+	}
+
+	var err error
+	if parsed.CreationDate, err = parseJSONDate(j.CreationDate); err != nil {
+		return fmt.Errorf("invalid creation_date: %w", err)
+	}
+	if parsed.CompletionDate, err = parseJSONDate(j.CompletionDate); err != nil {
+		return fmt.Errorf("invalid completion_date: %w", err)
+	}
+
+	*todo = *parsed
+	return nil
+}
+
+func formatJSONDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func parseJSONDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,39 @@
+package todo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	line := "x 2025-03-05 2025-03-01 Pay rent +home @bank due:2025-03-05"
+	original := FromString(line)
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"done":true`, `"creation_date":"2025-03-01"`, `"labels":{"due":"2025-03-05"}`, `"projects":["home"]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("json is missing %s: %s", want, data)
+		}
+	}
+
+	var decoded Todo
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.String() != original.String() {
+		t.Errorf("round trip = %q, want %q", decoded.String(), original.String())
+	}
+
+	// a bare todo.txt line is parsed
+	var fromText Todo
+	if err := json.Unmarshal([]byte(`{"text":"(A) Call mom @phone"}`), &fromText); err != nil {
+		t.Fatal(err)
+	}
+	if fromText.Priority != "A" || fromText.Description != "Call mom" {
+		t.Errorf("text todo = %q", fromText.String())
+	}
+}
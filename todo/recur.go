@@ -111,6 +111,46 @@ func (rt *RecurringTask) ShouldGenerateForDate(date time.Time) bool {
 
 
 
+// Availability tells on which days a @context can be worked in
+type Availability interface {
+	OpenOn(context string, day time.Time) bool
+}
+
+// ShouldGenerateIn is ShouldGenerateForDate for templates targeting
+// contexts. A template with contexts is generated on the first day one of
+// them is open, on or after its scheduled date, so a weekly @errands todo
+// scheduled for Monday shows up on Saturday. Without availability it is
+// ShouldGenerateForDate.
+func (rt *RecurringTask) ShouldGenerateIn(date time.Time, open Availability) bool {
+	if open == nil || len(rt.Todo.Contexts) == 0 {
+		return rt.ShouldGenerateForDate(date)
+	}
+	openOn := func(day time.Time) bool {
+		for _, context := range rt.Todo.Contexts {
+			if open.OpenOn(context, day) {
+				return true
+			}
+		}
+		return false
+	}
+	if !openOn(date) {
+		return false
+	}
+
+	// look back over the closed days before date for the scheduled one,
+	// windows repeat weekly so a week back is enough
+	for i := 0; i < 7; i++ {
+		day := date.AddDate(0, 0, -i)
+		if i > 0 && openOn(day) {
+			return false
+		}
+		if rt.ShouldGenerateForDate(day) {
+			return true
+		}
+	}
+	return false
+}
+
 // Generate a Todo from this recurring task for a specific date
 func (rt *RecurringTask) GenerateTodo(date time.Time) *Todo {
 	// Create a copy of the template todo
@@ -216,8 +256,10 @@ func RecurringTasksPath(dir string) string {
 	return filepath.Join(dir, "recur.txt")
 }
 
-// Generate todos from recurring tasks for a specific date, avoiding duplicates
-func GenerateTodosFromRecurring(todoDir string, date time.Time) ([]*Todo, error) {
+// Generate todos from recurring tasks for a specific date, avoiding duplicates.
+// Templates targeting contexts wait for a day the context is open, open may
+// be nil.
+func GenerateTodosFromRecurring(todoDir string, date time.Time, open Availability) ([]*Todo, error) {
 	recurPath := RecurringTasksPath(todoDir)
 	tasks, err := LoadRecurringTasks(recurPath)
 	if err != nil {
@@ -235,7 +277,7 @@ func GenerateTodosFromRecurring(todoDir string, date time.Time) ([]*Todo, error)
 	dateStr := date.Format("2006-01-02")
 
 	for _, task := range tasks {
-		if task.ShouldGenerateForDate(date) {
+		if task.ShouldGenerateIn(date, open) {
 			// Check if we already generated this task for this date
 			if !todoExistsForRecurringTask(existingTodos, task.Todo.Description, dateStr) {
 				todo := task.GenerateTodo(date)
@@ -260,8 +302,8 @@ func todoExistsForRecurringTask(todos []*Todo, description string, dateStr strin
 }
 
 // Add generated todos to the todo directory
-func AddRecurringTodosToDir(todoDir string, date time.Time) error {
-	newTodos, err := GenerateTodosFromRecurring(todoDir, date)
+func AddRecurringTodosToDir(todoDir string, date time.Time, open Availability) error {
+	newTodos, err := GenerateTodosFromRecurring(todoDir, date, open)
 	if err != nil {
 		return err
 	}
@@ -380,7 +380,7 @@ func TestGenerateTodosFromRecurring(t *testing.T) {
 
 	// Test on Monday (should generate both daily and weekly)
 	monday := time.Date(2025, 6, 16, 9, 0, 0, 0, time.UTC)
-	todos, err := GenerateTodosFromRecurring(tmpDir, monday)
+	todos, err := GenerateTodosFromRecurring(tmpDir, monday, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -391,7 +391,7 @@ func TestGenerateTodosFromRecurring(t *testing.T) {
 
 	// Test on Tuesday (should generate only daily)
 	tuesday := time.Date(2025, 6, 17, 9, 0, 0, 0, time.UTC)
-	todos, err = GenerateTodosFromRecurring(tmpDir, tuesday)
+	todos, err = GenerateTodosFromRecurring(tmpDir, tuesday, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -460,7 +460,7 @@ func TestAddRecurringTodosToDir(t *testing.T) {
 
 	// Add recurring todos
 	date := time.Date(2025, 6, 14, 9, 0, 0, 0, time.UTC)
-	err = AddRecurringTodosToDir(tmpDir, date)
+	err = AddRecurringTodosToDir(tmpDir, date, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -484,7 +484,7 @@ func TestAddRecurringTodosToDir(t *testing.T) {
 	}
 
 	// Try adding again - should not duplicate
-	err = AddRecurringTodosToDir(tmpDir, date)
+	err = AddRecurringTodosToDir(tmpDir, date, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -562,4 +562,39 @@ func TestCronValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// weekends has errands open on weekends only
+type weekends struct{}
+
+func (weekends) OpenOn(context string, day time.Time) bool {
+	return context != "errands" || day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+}
+
+func TestShouldGenerateIn(t *testing.T) {
+	errands, err := RecurringTaskFromString("@weekly Buy groceries @errands")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := RecurringTaskFromString("@weekly Water plants @home")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monday := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 14; i++ {
+		day := monday.AddDate(0, 0, i)
+		// the Monday errand waits for Saturday, Sunday already had its chance
+		want := day.Weekday() == time.Saturday
+		if got := errands.ShouldGenerateIn(day, weekends{}); got != want {
+			t.Errorf("errands on %s = %v, want %v", day.Format("Mon 2006-01-02"), got, want)
+		}
+		want = day.Weekday() == time.Monday
+		if got := plain.ShouldGenerateIn(day, weekends{}); got != want {
+			t.Errorf("plain on %s = %v, want %v", day.Format("Mon 2006-01-02"), got, want)
+		}
+		if got := errands.ShouldGenerateIn(day, nil); got != want {
+			t.Errorf("errands without contexts on %s = %v, want %v", day.Format("Mon 2006-01-02"), got, want)
+		}
+	}
+}
@@ -117,22 +117,29 @@ func RemoveDueReminders(reminders []*Todo, dueReminders []*Todo) []*Todo {
 
 // ProcessReminders moves due reminders from reminders.txt to active todos
 func ProcessReminders(todoDir string, date time.Time) error {
+	_, err := ActivateReminders(todoDir, date)
+	return err
+}
+
+// ActivateReminders moves due reminders from reminders.txt to active todos
+// and returns the todos that were added
+func ActivateReminders(todoDir string, date time.Time) ([]*Todo, error) {
 	reminderPath := ReminderTasksPath(todoDir)
 	
 	// Load existing reminders
 	reminders, err := LoadReminderTasks(reminderPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	
 	if len(reminders) == 0 {
-		return nil // No reminders to process
+		return nil, nil // No reminders to process
 	}
 	
 	// Get due reminders
 	dueReminders := GetDueReminders(reminders, date)
 	if len(dueReminders) == 0 {
-		return nil // No due reminders
+		return nil, nil // No due reminders
 	}
 	
 	// Create copies of due reminders with remind labels removed and creation date set
@@ -179,14 +186,14 @@ func ProcessReminders(todoDir string, date time.Time) error {
 	
 	// Write updated files
 	if err := WriteTodoDir(todoDir, allTodos); err != nil {
-		return fmt.Errorf("failed to write todos: %w", err)
+		return nil, fmt.Errorf("failed to write todos: %w", err)
 	}
 	
 	if err := WriteReminderTasks(reminderPath, remainingReminders); err != nil {
-		return fmt.Errorf("failed to write reminders: %w", err)
+		return nil, fmt.Errorf("failed to write reminders: %w", err)
 	}
 	
-	return nil
+	return processedTodos, nil
 }
 
 // SortRemindersByDate sorts reminders by their remind date
@@ -0,0 +1,137 @@
+package todo
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// FocusContext marks the todos picked to work on today
+const FocusContext = "today"
+
+// Agenda sections, from most to least pressing
+const (
+	SectionOverdue   = "Overdue"
+	SectionDueToday  = "Due today"
+	SectionPriority  = "Priority A"
+	SectionReminders = "New reminders"
+	SectionReviews   = "Review requests"
+)
+
+// AgendaItem is a todo ranked into an agenda section
+type AgendaItem struct {
+	Todo    *Todo
+	Section string
+}
+
+// IsFocused reports whether the todo is in today's focus set, either through
+// the @today context or a t: threshold of today
+func IsFocused(t *Todo, date time.Time) bool {
+	if t.Labels["t"] == date.Format("2006-01-02") {
+		return true
+	}
+	for _, context := range t.Contexts {
+		if context == FocusContext {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFocus adds or removes the todo from the focus set. A t: threshold of
+// today is dropped when unfocusing, other thresholds are left alone.
+func SetFocus(t *Todo, date time.Time, focused bool) {
+	var contexts []string
+	for _, context := range t.Contexts {
+		if context != FocusContext {
+			contexts = append(contexts, context)
+		}
+	}
+	if focused {
+		contexts = append(contexts, FocusContext)
+	} else if t.Labels["t"] == date.Format("2006-01-02") {
+		delete(t.Labels, "t")
+	}
+	if contexts == nil {
+		contexts = []string{}
+	}
+	t.Contexts = contexts
+}
+
+// isReviewRequest matches pull requests synced for review, their
+// descriptions are prefixed by the GitHub sync
+func isReviewRequest(t *Todo) bool {
+	return strings.HasPrefix(t.Description, "Review: ")
+}
+
+// RankForToday puts the open todos that need attention today into agenda
+// sections. Each todo lands in the most pressing section it qualifies for.
+// Todos whose t: threshold is still in the future are left out, as are
+// blocked todos and todos not in any section. newReminders are the reminders activated today.
+func RankForToday(todos []*Todo, newReminders []*Todo, date time.Time) []AgendaItem {
+	today := date.Format("2006-01-02")
+
+	reminded := make(map[string]bool)
+	for _, reminder := range newReminders {
+		reminded[reminder.String()] = true
+	}
+
+	// todos waiting for others can't be worked on today
+	g := NewGraph(todos)
+
+	var items []AgendaItem
+	for _, t := range todos {
+		if t.Done || g.Blocked(t) {
+			continue
+		}
+		if threshold := t.Labels["t"]; threshold > today {
+			continue
+		}
+
+		var section string
+		due := t.Labels["due"]
+		switch {
+		case due != "" && due < today:
+			section = SectionOverdue
+		case due == today:
+			section = SectionDueToday
+		case t.Priority == "A":
+			section = SectionPriority
+		case reminded[t.String()]:
+			section = SectionReminders
+		case isReviewRequest(t):
+			section = SectionReviews
+		default:
+			continue
+		}
+		items = append(items, AgendaItem{Todo: t, Section: section})
+	}
+
+	order := map[string]int{
+		SectionOverdue:   0,
+		SectionDueToday:  1,
+		SectionPriority:  2,
+		SectionReminders: 3,
+		SectionReviews:   4,
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if order[a.Section] != order[b.Section] {
+			return order[a.Section] < order[b.Section]
+		}
+		// oldest due date first, then by priority with none last
+		if a.Todo.Labels["due"] != b.Todo.Labels["due"] {
+			return a.Todo.Labels["due"] < b.Todo.Labels["due"]
+		}
+		return priorityRank(a.Todo.Priority) < priorityRank(b.Todo.Priority)
+	})
+
+	return items
+}
+
+func priorityRank(priority string) string {
+	if priority == "" {
+		return "~"
+	}
+	return priority
+}
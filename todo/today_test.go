@@ -0,0 +1,90 @@
+package todo
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRankForToday(t *testing.T) {
+	date := parseDate("2025-03-10")
+
+	reminder := FromString("2025-03-10 Renew passport")
+	todos := []*Todo{
+		FromString("Someday maybe"),
+		FromString("Review: Fix login +github url:https://github.com/me/app/pull/3"),
+		reminder,
+		FromString("(B) Pay rent due:2025-03-10"),
+		FromString("(A) Ship release"),
+		FromString("(A) Call bank due:2025-03-10"),
+		FromString("x Done already due:2025-03-01"),
+		FromString("(A) Not yet t:2025-03-20"),
+		FromString("(A) Waiting on taxes dep:taxes"),
+		FromString("File taxes due:2025-03-01 id:taxes"),
+	}
+
+	items := RankForToday(todos, []*Todo{reminder}, date)
+
+	want := []struct {
+		description string
+		section     string
+	}{
+		{"File taxes", SectionOverdue},
+		{"Call bank", SectionDueToday},
+		{"Pay rent", SectionDueToday},
+		{"Ship release", SectionPriority},
+		{"Renew passport", SectionReminders},
+		{"Review: Fix login", SectionReviews},
+	}
+
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d: %v", len(items), len(want), items)
+	}
+	for i, w := range want {
+		if items[i].Todo.Description != w.description || items[i].Section != w.section {
+			t.Errorf("item %d = %q in %q, want %q in %q", i, items[i].Todo.Description, items[i].Section, w.description, w.section)
+		}
+	}
+}
+
+func TestSetFocus(t *testing.T) {
+	date := parseDate("2025-03-10")
+
+	tests := []struct {
+		line    string
+		focused bool
+		want    string
+	}{
+		{"Write report @work", true, "Write report @work @today"},
+		{"Write report @today @work", true, "Write report @work @today"},
+		{"Write report @today", false, "Write report"},
+		{"Write report t:2025-03-10", false, "Write report"},
+		{"Write report t:2025-03-12", false, "Write report t:2025-03-12"},
+	}
+
+	for _, test := range tests {
+		todo := FromString(test.line)
+		SetFocus(todo, date, test.focused)
+		if got := todo.String(); got != test.want {
+			t.Errorf("SetFocus(%q, %v) = %q, want %q", test.line, test.focused, got, test.want)
+		}
+		if IsFocused(todo, date) != test.focused {
+			t.Errorf("IsFocused(%q) = %v after SetFocus(%v)", todo.String(), !test.focused, test.focused)
+		}
+	}
+}
+
+func TestActivateReminders(t *testing.T) {
+	dir := t.TempDir()
+	content := "Renew passport remind:2025-03-10 +personal\nCancel trial remind:2025-04-01\n"
+	if err := os.WriteFile(ReminderTasksPath(dir), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	activated, err := ActivateReminders(dir, parseDate("2025-03-10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activated) != 1 || activated[0].String() != "2025-03-10 Renew passport +personal" {
+		t.Errorf("activated = %v, want only the passport reminder", activated)
+	}
+}
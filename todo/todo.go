@@ -36,6 +36,28 @@ func NewTodo() *Todo {
 	}
 }
 
+// Clone returns a deep copy of the todo
+func (todo *Todo) Clone() *Todo {
+	clone := &Todo{
+		Done:           todo.Done,
+		CreationDate:   todo.CreationDate,
+		CompletionDate: todo.CompletionDate,
+		Priority:       todo.Priority,
+		Description:    todo.Description,
+		Projects:       make([]string, len(todo.Projects)),
+		Contexts:       make([]string, len(todo.Contexts)),
+		Labels:         make(map[string]string, len(todo.Labels)),
+	}
+
+	copy(clone.Projects, todo.Projects)
+	copy(clone.Contexts, todo.Contexts)
+	for k, v := range todo.Labels {
+		clone.Labels[k] = v
+	}
+
+	return clone
+}
+
 // Parse a todo.txt todo line
 func FromString(line string) *Todo {
 	todo := NewTodo()
@@ -57,6 +79,8 @@ func FromString(line string) *Todo {
 			}
 			line = strings.Replace(line, completionDateMatch[0], "", 1)
 		}
+		// the priority comes right after the completion date
+		line = strings.TrimLeft(line, " ")
 	}
 
 	// Match priority, e.g., (A), (B), etc.
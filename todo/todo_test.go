@@ -50,6 +50,20 @@ func TestFromString(t *testing.T) {
 				Labels:         map[string]string{},
 			},
 		},
+		{
+			// Test with priority after the completion date
+			line: "x 2025-02-16 (B) 2025-02-15 Call Mom",
+			expectedTodo: Todo{
+				Done:           true,
+				Priority:       "B",
+				CreationDate:   parseDate("2025-02-15"),
+				CompletionDate: parseDate("2025-02-16"),
+				Description:    "Call Mom",
+				Projects:       []string{},
+				Contexts:       []string{},
+				Labels:         map[string]string{},
+			},
+		},
 		{
 			// Test with metadata
 			line: "x 2025-02-15 2025-02-14 Call Dad +Home @phone due:2025-02-20",
@@ -96,6 +110,30 @@ func TestToString(t *testing.T) {
 	}
 }
 
+func TestClone(t *testing.T) {
+	original := FromString("(A) 2025-02-15 Call Mom +Family @phone due:2025-02-20")
+	clone := original.Clone()
+
+	if !reflect.DeepEqual(original, clone) {
+		t.Fatalf("Clone() = %v, want %v", clone, original)
+	}
+
+	// mutating the clone must not leak into the original
+	clone.Projects[0] = "Work"
+	clone.Contexts = append(clone.Contexts, "office")
+	clone.Labels["due"] = "2025-03-01"
+
+	if original.Projects[0] != "Family" {
+		t.Errorf("Clone() shares Projects with original: got %v", original.Projects)
+	}
+	if len(original.Contexts) != 1 {
+		t.Errorf("Clone() shares Contexts with original: got %v", original.Contexts)
+	}
+	if original.Labels["due"] != "2025-02-20" {
+		t.Errorf("Clone() shares Labels with original: got %v", original.Labels)
+	}
+}
+
 func TestLoadAndSave(t *testing.T) {
 	// Prepare test data
 	todos := []*Todo{
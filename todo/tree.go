@@ -0,0 +1,173 @@
+package todo
+
+import (
+	"fmt"
+)
+
+// ParentLabel makes a todo a subtask of another, referenced by its id: or
+// url: label, e.g. parent:4 or parent:https://github.com/owner/repo/issues/7
+const ParentLabel = "parent"
+
+// Subtasks resolves the parent: labels of a list of todos once, so parents
+// and children are looked up without scanning the list again
+type Subtasks struct {
+	parents  map[*Todo]*Todo
+	children map[*Todo][]*Todo
+}
+
+// NewSubtasks indexes the subtasks of the todos
+func NewSubtasks(todos []*Todo) *Subtasks {
+	s := &Subtasks{
+		parents:  make(map[*Todo]*Todo),
+		children: make(map[*Todo][]*Todo),
+	}
+
+	index := newRefIndex(todos)
+	for _, t := range todos {
+		ref := t.Labels[ParentLabel]
+		if ref == "" {
+			continue
+		}
+		parent := index.lookup(ref)
+		if parent == nil || parent == t {
+			continue
+		}
+		s.parents[t] = parent
+		s.children[parent] = append(s.children[parent], t)
+	}
+	return s
+}
+
+// Parent returns the todo t is a subtask of, nil when it has none in the
+// list
+func (s *Subtasks) Parent(t *Todo) *Todo {
+	return s.parents[t]
+}
+
+// Children returns the direct subtasks of t
+func (s *Subtasks) Children(t *Todo) []*Todo {
+	return s.children[t]
+}
+
+// Descendants returns the subtasks of t and their subtasks
+func (s *Subtasks) Descendants(t *Todo) []*Todo {
+	var descendants []*Todo
+	seen := map[*Todo]bool{t: true}
+	queue := []*Todo{t}
+	for len(queue) > 0 {
+		for _, child := range s.Children(queue[0]) {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			descendants = append(descendants, child)
+			queue = append(queue, child)
+		}
+		queue = queue[1:]
+	}
+	return descendants
+}
+
+// Progress counts the done and all direct subtasks of t
+func (s *Subtasks) Progress(t *Todo) (done int, total int) {
+	for _, child := range s.Children(t) {
+		total++
+		if child.Done {
+			done++
+		}
+	}
+	return done, total
+}
+
+// ProgressString is the progress of t like (3/5), empty without subtasks
+func (s *Subtasks) ProgressString(t *Todo) string {
+	done, total := s.Progress(t)
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("(%d/%d)", done, total)
+}
+
+// CompletableParent returns the open parent of t once all of its subtasks
+// are done, nil otherwise. It is what to offer completing after t is done.
+func (s *Subtasks) CompletableParent(t *Todo) *Todo {
+	parent := s.Parent(t)
+	if parent == nil || parent.Done {
+		return nil
+	}
+	if done, total := s.Progress(parent); done < total {
+		return nil
+	}
+	return parent
+}
+
+// Node is a todo in a tree of subtasks
+type Node struct {
+	Todo     *Todo
+	Children []*Node
+	Depth    int
+}
+
+// Tree arranges todos under their parents, keeping their order. Todos whose
+// parent isn't in the list are roots.
+func Tree(todos []*Todo) []*Node {
+	nodes := make(map[*Todo]*Node, len(todos))
+	for _, t := range todos {
+		nodes[t] = &Node{Todo: t}
+	}
+
+	index := make(map[*Todo]int, len(todos))
+	for i, t := range todos {
+		index[t] = i
+	}
+
+	subtasks := NewSubtasks(todos)
+	var roots []*Node
+	for _, t := range todos {
+		parent := subtasks.Parent(t)
+		if parent == nil || firstInCycle(subtasks, t, parent, index) {
+			roots = append(roots, nodes[t])
+			continue
+		}
+		nodes[parent].Children = append(nodes[parent].Children, nodes[t])
+	}
+
+	var depth func(n *Node, d int)
+	depth = func(n *Node, d int) {
+		n.Depth = d
+		for _, child := range n.Children {
+			depth(child, d+1)
+		}
+	}
+	for _, root := range roots {
+		depth(root, 0)
+	}
+	return roots
+}
+
+// firstInCycle reports whether following parents from t's parent leads
+// back to t and t comes first in the list among the todos along the way.
+// Cycles are cut there so every todo still shows up once.
+func firstInCycle(subtasks *Subtasks, t *Todo, parent *Todo, index map[*Todo]int) bool {
+	seen := make(map[*Todo]bool)
+	for p := parent; p != nil && !seen[p]; p = subtasks.Parent(p) {
+		if p == t {
+			return true
+		}
+		if index[p] < index[t] {
+			return false
+		}
+		seen[p] = true
+	}
+	return false
+}
+
+// Flatten lists the nodes depth first, each parent before its subtasks
+func Flatten(nodes []*Node) []*Node {
+	var flat []*Node
+	for _, n := range nodes {
+		flat = append(flat, n)
+		flat = append(flat, Flatten(n.Children)...)
+	}
+	return flat
+}
@@ -0,0 +1,92 @@
+package todo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubtasks(t *testing.T) {
+	todos := []*Todo{
+		FromString("Launch site id:1"),
+		FromString("x Write copy parent:1"),
+		FromString("Build pages parent:1 id:2"),
+		FromString("x Header parent:2"),
+		FromString("Footer parent:2"),
+		FromString("Fix issue url:https://github.com/acme/app/issues/9"),
+		FromString("x Reproduce parent:https://github.com/acme/app/issues/9"),
+		FromString("Orphan parent:42"),
+	}
+
+	tests := []struct {
+		todo        *Todo
+		parent      string
+		children    string
+		descendants string
+		progress    string
+	}{
+		{todos[0], "", "Write copy,Build pages", "Write copy,Build pages,Header,Footer", "(1/2)"},
+		{todos[2], "Launch site", "Header,Footer", "Header,Footer", "(1/2)"},
+		{todos[4], "Build pages", "", "", ""},
+		{todos[5], "", "Reproduce", "Reproduce", "(1/1)"},
+		{todos[7], "", "", "", ""},
+	}
+	subtasks := NewSubtasks(todos)
+	for _, tt := range tests {
+		parent := ""
+		if p := subtasks.Parent(tt.todo); p != nil {
+			parent = p.Description
+		}
+		if parent != tt.parent {
+			t.Errorf("Parent(%s) = %s, want %s", tt.todo.Description, parent, tt.parent)
+		}
+		if got := descriptions(subtasks.Children(tt.todo)); got != tt.children {
+			t.Errorf("Children(%s) = %s, want %s", tt.todo.Description, got, tt.children)
+		}
+		if got := descriptions(subtasks.Descendants(tt.todo)); got != tt.descendants {
+			t.Errorf("Descendants(%s) = %s, want %s", tt.todo.Description, got, tt.descendants)
+		}
+		if got := subtasks.ProgressString(tt.todo); got != tt.progress {
+			t.Errorf("ProgressString(%s) = %s, want %s", tt.todo.Description, got, tt.progress)
+		}
+	}
+
+	if p := subtasks.CompletableParent(todos[3]); p != nil {
+		t.Errorf("CompletableParent(Header) = %s, want none while Footer is open", p.Description)
+	}
+	todos[4].Done = true
+	if p := subtasks.CompletableParent(todos[4]); p != todos[2] {
+		t.Errorf("CompletableParent(Footer) = %v, want Build pages", p)
+	}
+	if p := subtasks.CompletableParent(todos[6]); p != todos[5] {
+		t.Errorf("CompletableParent(Reproduce) = %v, want Fix issue", p)
+	}
+}
+
+func TestTree(t *testing.T) {
+	todos := []*Todo{
+		FromString("Footer parent:2"),
+		FromString("Launch site id:1"),
+		FromString("Build pages parent:1 id:2"),
+		FromString("Call bank"),
+		FromString("Loop a id:3 parent:4"),
+		FromString("Loop b id:4 parent:3"),
+		FromString("Orphan parent:42"),
+	}
+
+	var lines []string
+	for _, n := range Flatten(Tree(todos)) {
+		lines = append(lines, strings.Repeat(" ", n.Depth)+n.Todo.Description)
+	}
+	want := []string{
+		"Launch site",
+		" Build pages",
+		"  Footer",
+		"Call bank",
+		"Loop a",
+		" Loop b",
+		"Orphan",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Tree() =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arjungandhi/atp/todo"
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	styleDefault  = tcell.StyleDefault
+	styleBorder   = tcell.StyleDefault.Foreground(tcell.ColorGray)
+	styleFocused  = tcell.StyleDefault.Foreground(tcell.ColorYellow).Bold(true)
+	styleSelected = tcell.StyleDefault.Reverse(true)
+	styleDim      = tcell.StyleDefault.Foreground(tcell.ColorGray)
+	styleError    = tcell.StyleDefault.Foreground(tcell.ColorRed)
+)
+
+// paneHelp lists the keys that do something in each pane
+var paneHelp = [paneCount]string{
+	"a add  x done  p priority  e edit  t tag  s snooze  T today  / filter  tab pane  q quit",
+	"a add  x finish  p activate  e edit  / filter  tab pane  q quit",
+	"a add  x dismiss  p priority  e edit  t tag  s snooze  / filter  tab pane  q quit",
+}
+
+// draw lays out the todos on the left and projects above reminders on the
+// right, with the filter on top and the status and keys at the bottom
+func (a *App) draw() {
+	a.screen.Clear()
+	width, height := a.screen.Size()
+	if width < 20 || height < 8 {
+		text(a.screen, 0, 0, width, styleDefault, "terminal too small")
+		a.screen.Show()
+		return
+	}
+
+	header := "atp"
+	if a.filter != "" || a.filtering {
+		header += "  filter: " + a.filter
+	}
+	text(a.screen, 0, 0, width, styleFocused, header)
+	if a.filtering {
+		a.screen.ShowCursor(len([]rune(header)), 0)
+	}
+
+	body := height - 3
+	left := width * 3 / 5
+	top := body / 2
+	a.drawPane(paneTodos, 0, 1, left, body)
+	a.drawPane(paneProjects, left, 1, width-left, top)
+	a.drawPane(paneReminders, left, 1+top, width-left, body-top)
+
+	switch {
+	case a.input != nil:
+		line := a.input.label + ": " + string(a.input.value)
+		text(a.screen, 0, height-2, width, styleDefault, line)
+		a.screen.ShowCursor(min(len([]rune(line)), width-1), height-2)
+	case strings.HasPrefix(a.status, "Error:"):
+		text(a.screen, 0, height-2, width, styleError, a.status)
+	default:
+		text(a.screen, 0, height-2, width, styleDefault, a.status)
+	}
+	if a.input == nil && !a.filtering {
+		a.screen.HideCursor()
+	}
+	text(a.screen, 0, height-1, width, styleDim, paneHelp[a.pane])
+
+	a.screen.Show()
+}
+
+func (a *App) drawPane(pane int, x int, y int, width int, height int) {
+	border := styleBorder
+	if pane == a.pane {
+		border = styleFocused
+	}
+	box(a.screen, x, y, width, height, border)
+
+	counts := fmt.Sprintf("%d", a.rows(pane))
+	if pane == paneTodos && a.snoozed > 0 {
+		counts += fmt.Sprintf(", %d snoozed", a.snoozed)
+	}
+	if pane == paneTodos && a.blocked > 0 {
+		counts += fmt.Sprintf(", %d blocked", a.blocked)
+	}
+	title := fmt.Sprintf(" %d %s (%s) ", pane+1, paneTitles[pane], counts)
+	text(a.screen, x+2, y, width-4, border, title)
+
+	inner := height - 2
+	if inner <= 0 {
+		return
+	}
+	offset := 0
+	if a.cursor[pane] >= inner {
+		offset = a.cursor[pane] - inner + 1
+	}
+	for row := 0; row < inner && offset+row < a.rows(pane); row++ {
+		i := offset + row
+		style := styleDefault
+		if pane == a.pane && i == a.cursor[pane] {
+			style = styleSelected
+		}
+		text(a.screen, x+1, y+1+row, width-2, style, a.rowText(pane, i))
+	}
+}
+
+func (a *App) rowText(pane int, i int) string {
+	switch pane {
+	case paneTodos:
+		t := a.todos[i]
+		line := strings.Repeat("  ", a.depth[t]) + t.String()
+		if progress := a.subtasks.ProgressString(t); progress != "" {
+			line += " " + progress
+		}
+		if todo.IsFocused(t, a.today()) {
+			return "* " + line
+		}
+		return "  " + line
+	case paneProjects:
+		p := a.projects[i]
+		phase := "-"
+		if p.Phase != "" {
+			phase = p.Phase
+		}
+		marker := " "
+		if p.Active {
+			marker = "*"
+		}
+		return fmt.Sprintf("%s phase %-3s %s", marker, phase, p.Name)
+	default:
+		r := a.reminders[i]
+		return fmt.Sprintf("%s  %s", r.Labels["remind"], r.String())
+	}
+}
+
+// text writes a line cut to width, padding selected rows to the full width
+func text(s tcell.Screen, x int, y int, width int, style tcell.Style, line string) {
+	col := 0
+	for _, r := range line {
+		if col >= width {
+			return
+		}
+		s.SetContent(x+col, y, r, nil, style)
+		col++
+	}
+	if style == styleSelected {
+		for ; col < width; col++ {
+			s.SetContent(x+col, y, ' ', nil, style)
+		}
+	}
+}
+
+func box(s tcell.Screen, x int, y int, width int, height int, style tcell.Style) {
+	if width < 2 || height < 2 {
+		return
+	}
+	right, bottom := x+width-1, y+height-1
+	for col := x + 1; col < right; col++ {
+		s.SetContent(col, y, tcell.RuneHLine, nil, style)
+		s.SetContent(col, bottom, tcell.RuneHLine, nil, style)
+	}
+	for row := y + 1; row < bottom; row++ {
+		s.SetContent(x, row, tcell.RuneVLine, nil, style)
+		s.SetContent(right, row, tcell.RuneVLine, nil, style)
+	}
+	s.SetContent(x, y, tcell.RuneULCorner, nil, style)
+	s.SetContent(right, y, tcell.RuneURCorner, nil, style)
+	s.SetContent(x, bottom, tcell.RuneLLCorner, nil, style)
+	s.SetContent(right, bottom, tcell.RuneLRCorner, nil, style)
+}
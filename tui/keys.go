@@ -0,0 +1,498 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/store"
+	"github.com/arjungandhi/atp/todo"
+	"github.com/gdamore/tcell/v2"
+)
+
+// nextPriority is what p cycles through
+var nextPriority = map[string]string{"": "A", "A": "B", "B": "C", "C": ""}
+
+func (a *App) handle(ev tcell.Event) {
+	switch ev := ev.(type) {
+	case *tcell.EventResize:
+		a.screen.Sync()
+	case *tcell.EventKey:
+		switch {
+		case a.input != nil:
+			a.handleInput(ev)
+		case a.filtering:
+			a.handleFilter(ev)
+		default:
+			a.handleKey(ev)
+		}
+	}
+}
+
+func (a *App) handleInput(ev *tcell.EventKey) {
+	in := a.input
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		a.input = nil
+		if err := in.submit(strings.TrimSpace(string(in.value))); err != nil {
+			a.setStatus(err, "")
+		}
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		a.input = nil
+		a.status = ""
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(in.value) > 0 {
+			in.value = in.value[:len(in.value)-1]
+		}
+	case tcell.KeyCtrlU:
+		in.value = nil
+	case tcell.KeyRune:
+		in.value = append(in.value, ev.Rune())
+	}
+}
+
+// handleFilter narrows the panes as the filter is typed
+func (a *App) handleFilter(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		a.filtering = false
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		a.filtering = false
+		a.filter = ""
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if r := []rune(a.filter); len(r) > 0 {
+			a.filter = string(r[:len(r)-1])
+		}
+	case tcell.KeyCtrlU:
+		a.filter = ""
+	case tcell.KeyRune:
+		a.filter += string(ev.Rune())
+	}
+	a.refresh()
+}
+
+func (a *App) handleKey(ev *tcell.EventKey) {
+	a.status = ""
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		a.quit = true
+	case tcell.KeyTab:
+		a.pane = (a.pane + 1) % paneCount
+	case tcell.KeyBacktab:
+		a.pane = (a.pane + paneCount - 1) % paneCount
+	case tcell.KeyDown:
+		a.move(1)
+	case tcell.KeyUp:
+		a.move(-1)
+	case tcell.KeyHome:
+		a.cursor[a.pane] = 0
+	case tcell.KeyEnd:
+		a.move(a.rows(a.pane))
+	case tcell.KeyEscape:
+		a.filter = ""
+		a.refresh()
+	case tcell.KeyRune:
+		a.handleRune(ev.Rune())
+	}
+}
+
+func (a *App) handleRune(r rune) {
+	switch r {
+	case 'q':
+		a.quit = true
+	case '1', '2', '3':
+		a.pane = int(r - '1')
+	case 'j':
+		a.move(1)
+	case 'k':
+		a.move(-1)
+	case 'g':
+		a.cursor[a.pane] = 0
+	case 'G':
+		a.move(a.rows(a.pane))
+	case '/':
+		a.filtering = true
+	case 'r':
+		a.setStatus(a.reload(), "Reloaded")
+	case 'a':
+		a.add()
+	case 'x':
+		a.complete()
+	case 'p':
+		a.prioritize()
+	case 'e':
+		a.edit()
+	case 't':
+		a.tag()
+	case 's':
+		a.snooze()
+	case 'T':
+		a.toggleFocus()
+	}
+}
+
+func (a *App) move(delta int) {
+	a.cursor[a.pane] = max(0, min(a.cursor[a.pane]+delta, a.rows(a.pane)-1))
+}
+
+// ------------------------------- Actions -------------------------------
+
+func (a *App) add() {
+	switch a.pane {
+	case paneTodos:
+		a.prompt("New todo", "", func(value string) error {
+			if value == "" {
+				return nil
+			}
+			t := todo.FromString(value)
+			return a.changed(a.store.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) {
+				return append(todos, t), nil
+			}), "Added: %s", t.String())
+		})
+	case paneProjects:
+		a.prompt("New project", "", func(value string) error {
+			if value == "" {
+				return nil
+			}
+			return a.changed(a.store.UpdateProjects(func(projects []*project.Project) ([]*project.Project, error) {
+				p, err := project.FromTodo(todo.FromString(value), a.store.Repos)
+				return append(projects, p), err
+			}), "Added project: %s", value)
+		})
+	case paneReminders:
+		date := a.today().AddDate(0, 0, 1).Format("2006-01-02")
+		a.prompt("New reminder", "remind:"+date+" ", func(value string) error {
+			r := todo.FromString(value)
+			if _, err := time.Parse("2006-01-02", r.Labels["remind"]); err != nil {
+				return fmt.Errorf("a reminder needs a remind:YYYY-MM-DD label")
+			}
+			return a.changed(a.store.UpdateReminders(func(reminders []*todo.Todo) ([]*todo.Todo, error) {
+				return append(reminders, r), nil
+			}), "Added reminder: %s", r.String())
+		})
+	}
+}
+
+func (a *App) complete() {
+	line, ok := a.selected()
+	if !ok {
+		return
+	}
+	switch a.pane {
+	case paneTodos:
+		open := 0
+		if t, err := store.Find(a.allTodos, line); err == nil {
+			for _, child := range a.subtasks.Descendants(t) {
+				if !child.Done {
+					open++
+				}
+			}
+		}
+		if open == 0 {
+			a.completeTodo(line, false)
+			return
+		}
+		a.prompt(fmt.Sprintf("Also complete %d open subtasks? [y/N]", open), "", func(value string) error {
+			a.completeTodo(line, strings.HasPrefix(strings.ToLower(value), "y"))
+			return nil
+		})
+	case paneProjects:
+		a.updateProject(line, "Finished", func(p *project.Project) error {
+			p.Finish(a.today())
+			return nil
+		})
+	case paneReminders:
+		err := a.store.UpdateReminders(func(reminders []*todo.Todo) ([]*todo.Todo, error) {
+			r, err := store.Find(reminders, line)
+			return store.Remove(reminders, r), err
+		})
+		a.setStatus(a.changed(err, ""), "Dismissed reminder")
+	}
+}
+
+// completeTodo completes a todo, with its open subtasks when asked to, and
+// offers to complete the parent once all of its subtasks are done
+func (a *App) completeTodo(line string, subtasks bool) {
+	var result, parentLine, parentName string
+	err := a.store.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		t, err := store.Find(todos, line)
+		if err != nil {
+			return nil, err
+		}
+		index := todo.NewSubtasks(todos)
+		completed := []*todo.Todo{t}
+		if subtasks {
+			completed = append(completed, index.Descendants(t)...)
+		}
+		for _, c := range completed {
+			if !c.Done {
+				c.Done = true
+				c.CompletionDate = a.today()
+			}
+		}
+		result = t.String()
+		if parent := index.CompletableParent(t); parent != nil {
+			parentLine, parentName = parent.String(), parent.Description
+		}
+		return todos, nil
+	})
+	a.setStatus(a.changed(err, ""), "Completed: %s", result)
+	if err != nil || parentLine == "" {
+		return
+	}
+	a.prompt(fmt.Sprintf("All subtasks of %s are done, complete it too? [y/N]", parentName), "", func(value string) error {
+		if strings.HasPrefix(strings.ToLower(value), "y") {
+			a.completeTodo(parentLine, false)
+		}
+		return nil
+	})
+}
+
+// prioritize cycles todo priorities and turns projects on and off
+func (a *App) prioritize() {
+	line, ok := a.selected()
+	if !ok {
+		return
+	}
+	switch a.pane {
+	case paneTodos:
+		a.updateTodo(line, "Prioritized", func(t *todo.Todo) error {
+			t.Priority = nextPriority[t.Priority]
+			return nil
+		})
+	case paneProjects:
+		a.updateProject(line, "Toggled", func(p *project.Project) error {
+			if !p.Active {
+				p.Active = true
+				if p.Phase == "" {
+					p.SetPhase("1", a.today())
+				}
+			} else {
+				p.Active = false
+			}
+			return nil
+		})
+	case paneReminders:
+		a.updateReminder(line, "Prioritized", func(r *todo.Todo) error {
+			r.Priority = nextPriority[r.Priority]
+			return nil
+		})
+	}
+}
+
+func (a *App) edit() {
+	line, ok := a.selected()
+	if !ok {
+		return
+	}
+	a.prompt("Edit", line, func(value string) error {
+		if value == "" || value == line {
+			return nil
+		}
+		edited := todo.FromString(value)
+		switch a.pane {
+		case paneTodos:
+			a.updateTodo(line, "Saved", func(t *todo.Todo) error {
+				*t = *edited
+				return nil
+			})
+		case paneProjects:
+			a.updateProject(line, "Saved", func(p *project.Project) error {
+				updated, err := project.FromTodo(edited, a.store.Repos)
+				if err != nil {
+					return err
+				}
+				*p = *updated
+				return nil
+			})
+		case paneReminders:
+			a.updateReminder(line, "Saved", func(r *todo.Todo) error {
+				*r = *edited
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+// tag adds +projects, @contexts and key:value labels, a leading - removes
+// one
+func (a *App) tag() {
+	line, ok := a.selected()
+	if !ok || a.pane == paneProjects {
+		return
+	}
+	a.prompt("Tags (+project @context key:value, -tag removes)", "", func(value string) error {
+		apply := func(t *todo.Todo) error {
+			for _, tag := range strings.Fields(value) {
+				applyTag(t, tag)
+			}
+			return nil
+		}
+		if a.pane == paneReminders {
+			a.updateReminder(line, "Tagged", apply)
+		} else {
+			a.updateTodo(line, "Tagged", apply)
+		}
+		return nil
+	})
+}
+
+func applyTag(t *todo.Todo, tag string) {
+	remove := strings.HasPrefix(tag, "-")
+	tag = strings.TrimPrefix(tag, "-")
+	if len(tag) < 2 {
+		return
+	}
+
+	edit := func(values []string, value string) []string {
+		kept := []string{}
+		for _, v := range values {
+			if v != value {
+				kept = append(kept, v)
+			}
+		}
+		if !remove {
+			kept = append(kept, value)
+		}
+		return kept
+	}
+
+	switch {
+	case tag[0] == '+':
+		t.Projects = edit(t.Projects, tag[1:])
+	case tag[0] == '@':
+		t.Contexts = edit(t.Contexts, tag[1:])
+	default:
+		key, value, _ := strings.Cut(tag, ":")
+		if remove {
+			delete(t.Labels, key)
+		} else if key != "" && value != "" {
+			t.Labels[key] = value
+		}
+	}
+}
+
+// snooze hides a todo behind a t: threshold date or moves a reminder
+func (a *App) snooze() {
+	line, ok := a.selected()
+	if !ok || a.pane == paneProjects {
+		return
+	}
+	a.prompt("Snooze until (Nd, Nw, tomorrow or YYYY-MM-DD)", "1d", func(value string) error {
+		until, err := parseSnooze(value, a.today())
+		if err != nil {
+			return err
+		}
+		date := until.Format("2006-01-02")
+		if a.pane == paneReminders {
+			a.updateReminder(line, "Snoozed to "+date, func(r *todo.Todo) error {
+				r.Labels["remind"] = date
+				return nil
+			})
+			return nil
+		}
+		a.updateTodo(line, "Snoozed to "+date, func(t *todo.Todo) error {
+			t.Labels["t"] = date
+			todo.SetFocus(t, a.today(), false)
+			return nil
+		})
+		return nil
+	})
+}
+
+func parseSnooze(value string, today time.Time) (time.Time, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "tomorrow" {
+		return today.AddDate(0, 0, 1), nil
+	}
+	if n, err := strconv.Atoi(strings.TrimRight(value, "dw")); err == nil && n > 0 && len(value) > 1 {
+		switch value[len(value)-1] {
+		case 'd':
+			return today.AddDate(0, 0, n), nil
+		case 'w':
+			return today.AddDate(0, 0, 7*n), nil
+		}
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid snooze '%s'", value)
+	}
+	return date, nil
+}
+
+// toggleFocus adds or removes the todo from today's focus set
+func (a *App) toggleFocus() {
+	line, ok := a.selected()
+	if !ok || a.pane != paneTodos {
+		return
+	}
+	a.updateTodo(line, "Toggled focus", func(t *todo.Todo) error {
+		todo.SetFocus(t, a.today(), !todo.IsFocused(t, a.today()))
+		return nil
+	})
+}
+
+// ------------------------------- Helpers -------------------------------
+
+func (a *App) updateTodo(line string, done string, fn func(t *todo.Todo) error) {
+	var result string
+	err := a.store.UpdateTodos(func(todos []*todo.Todo) ([]*todo.Todo, error) {
+		t, err := store.Find(todos, line)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(t); err != nil {
+			return nil, err
+		}
+		result = t.String()
+		return todos, nil
+	})
+	a.setStatus(a.changed(err, ""), "%s: %s", done, result)
+}
+
+func (a *App) updateReminder(line string, done string, fn func(r *todo.Todo) error) {
+	var result string
+	err := a.store.UpdateReminders(func(reminders []*todo.Todo) ([]*todo.Todo, error) {
+		r, err := store.Find(reminders, line)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(r); err != nil {
+			return nil, err
+		}
+		result = r.String()
+		return reminders, nil
+	})
+	a.setStatus(a.changed(err, ""), "%s: %s", done, result)
+}
+
+func (a *App) updateProject(line string, done string, fn func(p *project.Project) error) {
+	var result string
+	err := a.store.UpdateProjects(func(projects []*project.Project) ([]*project.Project, error) {
+		p, err := store.FindProject(projects, line)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(p); err != nil {
+			return nil, err
+		}
+		result = p.Name
+		return projects, nil
+	})
+	a.setStatus(a.changed(err, ""), "%s: %s", done, result)
+}
+
+// changed reloads after a write so the panes show what is on disk, even
+// when the write failed because the file changed underneath
+func (a *App) changed(err error, format string, args ...any) error {
+	if reloadErr := a.reload(); err == nil {
+		err = reloadErr
+	}
+	if format != "" {
+		a.setStatus(err, format, args...)
+	}
+	return err
+}
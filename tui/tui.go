@@ -0,0 +1,239 @@
+// Package tui is a full screen terminal interface for todos, projects and
+// reminders. All changes go through the store, so they take the ATP lock
+// and are applied to freshly loaded files.
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/store"
+	"github.com/arjungandhi/atp/todo"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Panes, in tab order
+const (
+	paneTodos = iota
+	paneProjects
+	paneReminders
+	paneCount
+)
+
+var paneTitles = [paneCount]string{"Todos", "Projects by phase", "Reminders"}
+
+// App is the TUI state. The caller owns the screen, Run only draws on it.
+type App struct {
+	screen tcell.Screen
+	store  *store.Store
+	// Now is the clock for completion and snooze dates
+	Now func() time.Time
+
+	// everything loaded from disk
+	allTodos     []*todo.Todo
+	allProjects  []*project.Project
+	allReminders []*todo.Todo
+
+	// what the panes show after filtering and sorting
+	todos     []*todo.Todo
+	projects  []*project.Project
+	reminders []*todo.Todo
+	snoozed   int
+	blocked   int
+	// depth indents subtasks below their parent in the todos pane
+	depth map[*todo.Todo]int
+	// subtasks indexes allTodos for the progress of parents
+	subtasks *todo.Subtasks
+
+	pane      int
+	cursor    [paneCount]int
+	filter    string
+	filtering bool
+	input     *input
+	status    string
+	quit      bool
+}
+
+// input is a one line prompt at the bottom of the screen
+type input struct {
+	label  string
+	value  []rune
+	submit func(value string) error
+}
+
+// New creates the app for an initialized screen
+func New(screen tcell.Screen, s *store.Store) *App {
+	return &App{screen: screen, store: s, Now: time.Now}
+}
+
+// Run draws and handles events until the user quits
+func (a *App) Run() error {
+	if err := a.reload(); err != nil {
+		return err
+	}
+	for !a.quit {
+		a.draw()
+		ev := a.screen.PollEvent()
+		if ev == nil {
+			return nil
+		}
+		a.handle(ev)
+	}
+	return nil
+}
+
+// reload reads the files again and keeps the cursors where they were
+func (a *App) reload() error {
+	todos, err := a.store.Todos()
+	if err != nil {
+		return err
+	}
+	projects, err := a.store.Projects()
+	if err != nil {
+		return err
+	}
+	reminders, err := a.store.Reminders()
+	if err != nil {
+		return err
+	}
+	a.allTodos, a.allProjects, a.allReminders = todos, projects, reminders
+	a.refresh()
+	return nil
+}
+
+// refresh rebuilds the panes from the loaded data and the filter
+func (a *App) refresh() {
+	filter, err := todo.ParseFilter(strings.Fields(a.filter))
+	if err != nil {
+		// half typed filters like "--du" match on text alone
+		filter = todo.Filter{Text: strings.Fields(a.filter)}
+	}
+	today := a.today()
+
+	// blocked todos are hidden unless the filter asks for them
+	graph := todo.NewGraph(a.allTodos)
+	a.subtasks = todo.NewSubtasks(a.allTodos)
+
+	a.todos, a.snoozed, a.blocked = nil, 0, 0
+	for _, t := range a.allTodos {
+		if t.Done || !filter.Match(t) {
+			continue
+		}
+		if threshold := t.Labels["t"]; threshold > today.Format("2006-01-02") {
+			a.snoozed++
+			continue
+		}
+		if graph.Blocked(t) != (filter.Blocked == todo.BlockedOnly) {
+			if filter.Blocked != todo.BlockedOnly {
+				a.blocked++
+			}
+			continue
+		}
+		a.todos = append(a.todos, t)
+	}
+	sort.SliceStable(a.todos, func(i, j int) bool {
+		return todoKey(a.todos[i], today) < todoKey(a.todos[j], today)
+	})
+	// subtasks follow their parent, in the same order
+	nodes := todo.Flatten(todo.Tree(a.todos))
+	a.depth = make(map[*todo.Todo]int, len(nodes))
+	for i, n := range nodes {
+		a.todos[i] = n.Todo
+		a.depth[n.Todo] = n.Depth
+	}
+
+	a.projects = nil
+	for _, p := range a.allProjects {
+		if !p.Done && filter.Match(p.ToTodo()) {
+			a.projects = append(a.projects, p)
+		}
+	}
+	sort.SliceStable(a.projects, func(i, j int) bool {
+		pi, pj := a.projects[i], a.projects[j]
+		if pi.Active != pj.Active {
+			return pi.Active
+		}
+		if pi.Phase != pj.Phase {
+			return pi.Phase < pj.Phase
+		}
+		return strings.ToLower(pi.Name) < strings.ToLower(pj.Name)
+	})
+
+	a.reminders = nil
+	for _, r := range a.allReminders {
+		if filter.Match(r) {
+			a.reminders = append(a.reminders, r)
+		}
+	}
+	todo.SortRemindersByDate(a.reminders)
+
+	for pane := range a.cursor {
+		a.cursor[pane] = max(0, min(a.cursor[pane], a.rows(pane)-1))
+	}
+}
+
+// todoKey orders today's focus first, then by priority, due date and name
+func todoKey(t *todo.Todo, today time.Time) string {
+	focus := "1"
+	if todo.IsFocused(t, today) {
+		focus = "0"
+	}
+	priority := t.Priority
+	if priority == "" {
+		priority = "~"
+	}
+	due := t.Labels["due"]
+	if due == "" {
+		due = "9999-99-99"
+	}
+	return focus + priority + due + strings.ToLower(t.Description)
+}
+
+func (a *App) rows(pane int) int {
+	switch pane {
+	case paneTodos:
+		return len(a.todos)
+	case paneProjects:
+		return len(a.projects)
+	default:
+		return len(a.reminders)
+	}
+}
+
+func (a *App) today() time.Time {
+	now := a.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// selected returns the todo.txt line of the row under the cursor, it is
+// how changes find the item again after reloading
+func (a *App) selected() (string, bool) {
+	i := a.cursor[a.pane]
+	if i >= a.rows(a.pane) {
+		return "", false
+	}
+	switch a.pane {
+	case paneTodos:
+		return a.todos[i].String(), true
+	case paneProjects:
+		return a.projects[i].TodoString(), true
+	default:
+		return a.reminders[i].String(), true
+	}
+}
+
+// setStatus shows a message, or the error when there is one
+func (a *App) setStatus(err error, format string, args ...any) {
+	if err != nil {
+		a.status = "Error: " + err.Error()
+		return
+	}
+	a.status = fmt.Sprintf(format, args...)
+}
+
+func (a *App) prompt(label string, value string, submit func(value string) error) {
+	a.input = &input{label: label, value: []rune(value), submit: submit}
+}
@@ -0,0 +1,279 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arjungandhi/atp/project"
+	"github.com/arjungandhi/atp/store"
+	"github.com/arjungandhi/atp/todo"
+	"github.com/gdamore/tcell/v2"
+)
+
+func newTestApp(t *testing.T, todos string, projects string, reminders string) (*App, tcell.SimulationScreen) {
+	t.Helper()
+	s := store.New(t.TempDir(), nil)
+	os.MkdirAll(s.TodoDir(), 0755)
+	os.MkdirAll(s.ProjectDir(), 0755)
+	os.WriteFile(todo.ActiveTodoPath(s.TodoDir()), []byte(todos), 0644)
+	os.WriteFile(todo.ReminderTasksPath(s.TodoDir()), []byte(reminders), 0644)
+	os.WriteFile(project.ActiveFilePath(s.ProjectDir()), []byte(projects), 0644)
+
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(120, 16)
+	t.Cleanup(screen.Fini)
+
+	app := New(screen, s)
+	app.Now = func() time.Time { return time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC) }
+	if err := app.reload(); err != nil {
+		t.Fatal(err)
+	}
+	return app, screen
+}
+
+// contents returns the screen as lines without trailing spaces
+func contents(a *App, screen tcell.SimulationScreen) string {
+	a.draw()
+	cells, width, height := screen.GetContents()
+	lines := make([]string, height)
+	for y := 0; y < height; y++ {
+		var b strings.Builder
+		for x := 0; x < width; x++ {
+			if runes := cells[y*width+x].Runes; len(runes) > 0 {
+				b.WriteRune(runes[0])
+			} else {
+				b.WriteRune(' ')
+			}
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func press(a *App, keys ...any) {
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			for _, r := range k {
+				a.handle(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+			}
+		case tcell.Key:
+			a.handle(tcell.NewEventKey(k, 0, tcell.ModNone))
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestDraw(t *testing.T) {
+	app, screen := newTestApp(t,
+		"Fix bike +garage\n(A) Call mom @phone\nWater plants @today\nLater t:2025-04-01\n",
+		"(A) Garden phase:2\nBoat\n",
+		"Dentist remind:2025-03-20\n")
+	got := contents(app, screen)
+
+	for _, want := range []string{
+		"1 Todos (3, 1 snoozed)",
+		"2 Projects by phase (2)",
+		"3 Reminders (1)",
+		"* Water plants @today",
+		"(A) Call mom @phone",
+		"* phase 2   Garden",
+		"  phase -   Boat",
+		"2025-03-20  Dentist remind:2025-03-20",
+		"a add  x done",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("screen is missing %q:\n%s", want, got)
+		}
+	}
+
+	// focus first, then priority
+	if strings.Index(got, "Water plants") > strings.Index(got, "Call mom") || strings.Index(got, "Call mom") > strings.Index(got, "Fix bike") {
+		t.Errorf("todos are out of order:\n%s", got)
+	}
+}
+
+func TestCompleteAndPrioritize(t *testing.T) {
+	app, _ := newTestApp(t, "(A) Call mom\nFix bike\n", "", "")
+	dir := app.store.TodoDir()
+
+	press(app, "j", "p")
+	if got := readFile(t, todo.ActiveTodoPath(dir)); got != "(A) Call mom\n(A) Fix bike" {
+		t.Errorf("todo.txt after p = %q", got)
+	}
+	if !strings.HasPrefix(app.status, "Prioritized: (A) Fix bike") {
+		t.Errorf("status = %q", app.status)
+	}
+
+	press(app, "g", "x")
+	if got := readFile(t, todo.DoneTodoPath(dir)); got != "x 2025-03-12 (A) Call mom" {
+		t.Errorf("done.txt = %q", got)
+	}
+	if len(app.todos) != 1 {
+		t.Errorf("todos pane = %v", app.todos)
+	}
+}
+
+func TestSubtasks(t *testing.T) {
+	app, screen := newTestApp(t, "Launch site id:1\nWrite copy parent:1\nBuild pages parent:1\nCall bank\n", "", "")
+	dir := app.store.TodoDir()
+
+	got := contents(app, screen)
+	for _, want := range []string{"  Launch site id:1 (0/2)", "    Build pages parent:1", "    Write copy parent:1"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("screen is missing %q:\n%s", want, got)
+		}
+	}
+	// subtasks follow their parent
+	if strings.Index(got, "Call bank") > strings.Index(got, "Launch site") || strings.Index(got, "Launch site") > strings.Index(got, "Build pages") {
+		t.Errorf("todos are out of order:\n%s", got)
+	}
+
+	// completing the last open subtask offers to complete the parent
+	press(app, "j", "j", "x")
+	if app.input != nil {
+		t.Fatalf("prompted while Write copy is open: %s", app.input.label)
+	}
+	press(app, "x")
+	if app.input == nil || app.input.label != "All subtasks of Launch site are done, complete it too? [y/N]" {
+		t.Fatalf("input = %+v", app.input)
+	}
+	press(app, "y", tcell.KeyEnter)
+	if got := readFile(t, todo.ActiveTodoPath(dir)); got != "Call bank" {
+		t.Errorf("todo.txt = %q", got)
+	}
+
+	// completing a parent offers to complete its open subtasks
+	app, _ = newTestApp(t, "Launch site id:1\nWrite copy parent:1\nx Build pages parent:1\n", "", "")
+	dir = app.store.TodoDir()
+	press(app, "x")
+	if app.input == nil || app.input.label != "Also complete 1 open subtasks? [y/N]" {
+		t.Fatalf("input = %+v", app.input)
+	}
+	press(app, "y", tcell.KeyEnter)
+	if got := readFile(t, todo.ActiveTodoPath(dir)); got != "" {
+		t.Errorf("todo.txt = %q", got)
+	}
+}
+
+func TestEditTagAndSnooze(t *testing.T) {
+	app, screen := newTestApp(t, "Fix bike\n", "", "Dentist remind:2025-03-20\n")
+	dir := app.store.TodoDir()
+
+	press(app, "e", tcell.KeyCtrlU, "Fix the bike +garage", tcell.KeyEnter)
+	press(app, "t", "@home due:2025-03-14", tcell.KeyEnter)
+	if got := readFile(t, todo.ActiveTodoPath(dir)); got != "Fix the bike +garage @home due:2025-03-14" {
+		t.Errorf("todo.txt = %q", got)
+	}
+
+	press(app, "s")
+	if got := contents(app, screen); !strings.Contains(got, "Snooze until (Nd, Nw, tomorrow or YYYY-MM-DD): 1d") {
+		t.Errorf("snooze prompt missing:\n%s", got)
+	}
+	press(app, tcell.KeyEnter)
+	if got := readFile(t, todo.ActiveTodoPath(dir)); !strings.Contains(got, "t:2025-03-13") {
+		t.Errorf("todo.txt after snooze = %q", got)
+	}
+	if len(app.todos) != 0 || app.snoozed != 1 {
+		t.Errorf("snoozed todo is still shown: %v", app.todos)
+	}
+
+	press(app, "3", "s", tcell.KeyCtrlU, "2w", tcell.KeyEnter)
+	if got := readFile(t, todo.ReminderTasksPath(dir)); got != "Dentist remind:2025-03-26" {
+		t.Errorf("reminders.txt = %q", got)
+	}
+
+	press(app, "s", tcell.KeyCtrlU, "someday", tcell.KeyEnter)
+	if app.status != "Error: invalid snooze 'someday'" {
+		t.Errorf("status = %q", app.status)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	app, screen := newTestApp(t, "Fix bike +garage\nCall mom @phone\n", "Garage cleanup\nBoat\n", "")
+
+	press(app, "/", "gara")
+	if len(app.todos) != 0 || len(app.projects) != 1 {
+		t.Errorf("text filter: todos %v, projects %d", app.todos, len(app.projects))
+	}
+	press(app, tcell.KeyCtrlU, "+garage")
+	if len(app.todos) != 1 || app.todos[0].Description != "Fix bike" {
+		t.Errorf("+garage filter: todos %v", app.todos)
+	}
+	press(app, tcell.KeyEnter)
+	if got := contents(app, screen); !strings.Contains(got, "filter: +garage") {
+		t.Errorf("filter not shown:\n%s", got)
+	}
+
+	// keys act on the list again, escape clears the filter
+	press(app, tcell.KeyEscape)
+	if len(app.todos) != 2 || app.filter != "" {
+		t.Errorf("after escape: todos %v, filter %q", app.todos, app.filter)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	app, _ := newTestApp(t, "", "Boat\n(A) Garden phase:2\n", "")
+	dir := app.store.ProjectDir()
+
+	// Garden sorts first since it's active
+	press(app, "2", "j", "p")
+	if got := readFile(t, project.ActiveFilePath(dir)); !strings.Contains(got, "(A) Boat phase:1 phase1:2025-03-12") {
+		t.Errorf("projects after activating = %q", got)
+	}
+
+	press(app, "a", "Shed", tcell.KeyEnter)
+	press(app, "G", "x")
+	if got := readFile(t, project.DoneFilePath(dir)); !strings.HasPrefix(got, "x 2025-03-12") {
+		t.Errorf("done projects = %q", got)
+	}
+}
+
+func TestStaleChange(t *testing.T) {
+	app, _ := newTestApp(t, "Fix bike\n", "", "")
+
+	// someone else edits the todo after the TUI loaded it
+	os.WriteFile(filepath.Join(app.store.TodoDir(), "todo.txt"), []byte("Fix bike today\n"), 0644)
+	press(app, "x")
+	if !strings.Contains(app.status, "changed or removed") {
+		t.Errorf("status = %q", app.status)
+	}
+	if len(app.todos) != 1 || app.todos[0].Description != "Fix bike today" {
+		t.Errorf("TUI didn't reload: %v", app.todos)
+	}
+}
+
+func TestRun(t *testing.T) {
+	app, screen := newTestApp(t, "Fix bike\n", "", "")
+	screen.InjectKey(tcell.KeyRune, 'T', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+
+	done := make(chan error)
+	go func() { done <- app.Run() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't quit")
+	}
+
+	if got := readFile(t, todo.ActiveTodoPath(app.store.TodoDir())); got != "Fix bike @today" {
+		t.Errorf("todo.txt = %q", got)
+	}
+}